@@ -3,12 +3,23 @@ package main
 import (
 	"Lin_studio/internal/api/handler"
 	"Lin_studio/internal/api/router"
+	"Lin_studio/internal/cache"
 	"Lin_studio/internal/config"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/mailer"
 	"Lin_studio/internal/repository"
 	"Lin_studio/internal/service"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -18,13 +29,50 @@ func main() {
 
 	// 加载配置
 	cfg := config.GetConfig()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("配置无效: %v", err)
+	}
 	log.Println("配置已加载")
 
 	// 初始化数据库
 	config.InitDB()
 	db := config.DB
+	if err := repository.AutoMigrate(db); err != nil {
+		log.Fatalf("数据表迁移失败: %v", err)
+	}
 	log.Println("数据库已初始化")
 
+	// 初始化上传存储
+	store, err := storage.New(cfg.Upload)
+	if err != nil {
+		log.Fatalf("初始化上传存储失败: %v", err)
+	}
+	log.Printf("上传存储已初始化 (驱动: %s)", cfg.Upload.Driver)
+	uploadValidator := utils.NewUploadValidator(cfg.Upload)
+
+	// 初始化应用缓存
+	appCache, err := cache.New(cfg.Cache)
+	if err != nil {
+		log.Fatalf("初始化缓存失败: %v", err)
+	}
+	log.Printf("缓存已初始化 (驱动: %s)", cfg.Cache.Driver)
+
+	// 草稿文章使用的文件需要签名URL才能访问
+	var urlSigner *utils.URLSigner
+	if cfg.Upload.SignDrafts {
+		urlSigner = utils.NewURLSigner(cfg.Upload.SigningSecret, cfg.Upload.SignedURLTTL)
+	}
+
+	// 邮件模板和发送器，未配置SMTP_HOST时邮件只输出到日志
+	mailTemplates, err := mailer.NewTemplates(cfg.Mail.Locale)
+	if err != nil {
+		log.Fatalf("加载邮件模板失败: %v", err)
+	}
+	mailSender, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("初始化邮件发送失败: %v", err)
+	}
+
 	// 初始化仓库
 	log.Println("初始化仓库...")
 	userRepo := repository.NewUserRepository(db)
@@ -33,28 +81,77 @@ func main() {
 	articleRepo := repository.NewArticleRepository()
 	commentRepo := repository.NewCommentRepository()
 	toolRepo := repository.NewToolRepository()
+	mediaRepo := repository.NewMediaRepository()
+	spamRepo := repository.NewSpamRepository()
+	reactionRepo := repository.NewReactionRepository()
+	emailRepo := repository.NewEmailRepository()
+	notificationRepo := repository.NewNotificationRepository()
+	analyticsRepo := repository.NewAnalyticsRepository()
+	seriesRepo := repository.NewSeriesRepository()
 	log.Println("仓库初始化完成")
 
 	// 初始化服务
 	log.Println("初始化服务...")
+	eventBus := event.NewBus()
+	imageProcessor := service.NewImageProcessor(mediaRepo, store, cfg.Upload)
+	imageProcessor.Start()
+	defer imageProcessor.Stop()
 	authService := service.NewAuthService(userRepo)
-	userService := service.NewUserService(userRepo)
-	categoryService := service.NewCategoryService(categoryRepo)
-	tagService := service.NewTagService(tagRepo)
-	articleService := service.NewArticleService(articleRepo, tagRepo, userRepo, categoryRepo)
-	commentService := service.NewCommentService(commentRepo, userRepo)
+	mediaService := service.NewMediaService(mediaRepo, store, uploadValidator, imageProcessor, urlSigner)
+	fileService := service.NewFileService(store, mediaRepo, urlSigner)
+	userService := service.NewUserService(userRepo, mediaService)
+	categoryService := service.NewCategoryService(categoryRepo, appCache)
+	tagService := service.NewTagService(tagRepo, appCache)
+	articleService := service.NewArticleService(articleRepo, tagRepo, userRepo, categoryRepo, seriesRepo, mediaService, appCache)
+	// 启动时在后台重新计算相关文章，补齐升级前发布的文章
+	go func() {
+		if err := articleService.RebuildRelatedArticles(context.Background()); err != nil {
+			log.Printf("计算相关文章失败: %v", err)
+		}
+	}()
+	spamFilter := service.NewSpamFilter(spamRepo, cfg.Spam)
+	commentService := service.NewCommentService(commentRepo, userRepo, spamFilter, eventBus, cfg.Comment)
+	reactionService := service.NewReactionService(reactionRepo, eventBus, cfg.Reaction)
+	mailService := service.NewMailService(emailRepo, userRepo, commentRepo, mailTemplates, cfg.Mail)
+	mailService.Subscribe(eventBus)
+	notificationService := service.NewNotificationService(notificationRepo, userRepo, commentRepo, articleRepo, eventBus)
+	notificationService.Subscribe(eventBus)
+	realtimeService := service.NewRealtimeService(userRepo, commentRepo, cfg.Realtime)
+	realtimeService.Subscribe(eventBus)
+	editingService := service.NewEditingService(articleRepo, cfg.Editing)
+	mailWorker := service.NewMailWorker(emailRepo, mailSender, cfg.Mail)
+	mailWorker.Start()
+	defer mailWorker.Stop()
 	toolService := service.NewToolService(toolRepo)
+	seriesService := service.NewSeriesService(seriesRepo, articleRepo, mediaService, appCache)
+	analyticsService := service.NewAnalyticsService(analyticsRepo, articleRepo, toolRepo, cfg.Analytics)
+	analyticsService.Subscribe(eventBus)
+	analyticsService.Start()
+	defer analyticsService.Stop()
+	viewTracker := service.NewViewTracker(articleRepo, toolRepo, analyticsService, cfg.Views)
+	viewTracker.Start()
+	defer viewTracker.Stop()
+	ogImageService := service.NewOGImageService(articleRepo, userRepo, categoryRepo, store, cfg.OG)
 	log.Println("服务初始化完成")
 
 	// 初始化处理器
 	log.Println("初始化处理器...")
 	authHandler := handler.NewAuthHandler(authService)
-	userHandler := handler.NewUserHandler(userService)
+	userHandler := handler.NewUserHandler(userService, notificationService)
 	categoryHandler := handler.NewCategoryHandler(categoryService)
 	tagHandler := handler.NewTagHandler(tagService)
-	articleHandler := handler.NewArticleHandler(articleService)
-	commentHandler := handler.NewCommentHandler(commentService)
-	toolHandler := handler.NewToolHandler(toolService)
+	articleHandler := handler.NewArticleHandler(articleService, reactionService, editingService, viewTracker)
+	commentHandler := handler.NewCommentHandler(commentService, reactionService)
+	toolHandler := handler.NewToolHandler(toolService, viewTracker)
+	ogHandler := handler.NewOGHandler(ogImageService)
+	mediaHandler := handler.NewMediaHandler(mediaService)
+	fileHandler := handler.NewFileHandler(fileService)
+	emailHandler := handler.NewEmailHandler(mailService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+	realtimeHandler := handler.NewRealtimeHandler(realtimeService)
+	editingHandler := handler.NewEditingHandler(editingService)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsService)
+	seriesHandler := handler.NewSeriesHandler(seriesService)
 	log.Println("处理器初始化完成")
 
 	// 设置路由
@@ -67,6 +164,15 @@ func main() {
 		articleHandler,
 		commentHandler,
 		toolHandler,
+		ogHandler,
+		mediaHandler,
+		fileHandler,
+		emailHandler,
+		notificationHandler,
+		realtimeHandler,
+		editingHandler,
+		analyticsHandler,
+		seriesHandler,
 	)
 	log.Println("路由设置完成")
 
@@ -82,8 +188,28 @@ func main() {
 		}
 	}()
 	
-	if err := r.Run(serverAddr); err != nil {
-		log.Printf("服务器启动失败: %v", err)
-		os.Exit(1)
+	// 不设置读写超时，SSE和WebSocket连接需要长时间保持
+	srv := &http.Server{Addr: serverAddr, Handler: r}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	// 收到退出信号后停止接收新请求，返回后执行defer写入缓冲的浏览量、停止后台任务
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("服务器启动失败: %v", err)
+			os.Exit(1)
+		}
+	case sig := <-quit:
+		log.Printf("收到信号 %v，正在关闭服务器", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("关闭服务器失败: %v", err)
+		}
 	}
 }
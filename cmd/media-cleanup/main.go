@@ -0,0 +1,163 @@
+package main
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "只列出将被删除的文件，不实际删除")
+	grace := flag.Duration("grace", 24*time.Hour, "上传后超过该时长仍未被引用的文件才会被删除")
+	skipRebuild := flag.Bool("skip-rebuild", false, "跳过清理前的引用关系重建")
+	flag.Parse()
+
+	// 初始化配置
+	cfg := config.GetConfig()
+
+	// 初始化数据库连接
+	config.InitDB()
+	if err := repository.AutoMigrate(config.DB); err != nil {
+		log.Fatalf("数据表迁移失败: %v", err)
+	}
+
+	// 初始化上传存储
+	store, err := storage.New(cfg.Upload)
+	if err != nil {
+		log.Fatalf("初始化上传存储失败: %v", err)
+	}
+
+	mediaRepo := repository.NewMediaRepository()
+	processor := service.NewImageProcessor(mediaRepo, store, cfg.Upload)
+	mediaService := service.NewMediaService(mediaRepo, store, utils.NewUploadValidator(cfg.Upload), processor, nil)
+
+	ctx := context.Background()
+
+	// 重建引用关系，避免引用记录缺失(例如引用跟踪上线前保存的文章)导致误删
+	// 演练时只在内存中计算引用的文件，不写入数据库
+	referenced := make(map[uint]bool)
+	sync := func(refType string, refID uint, urls ...string) error {
+		if !*dryRun {
+			return mediaService.SyncReferences(ctx, refType, refID, urls...)
+		}
+		ids, err := mediaService.ResolveReferences(ctx, urls...)
+		for _, id := range ids {
+			referenced[id] = true
+		}
+		return err
+	}
+	if !*skipRebuild {
+		if err := rebuildReferences(ctx, config.DB, sync); err != nil {
+			log.Fatalf("重建媒体引用失败: %v", err)
+		}
+	}
+
+	media, err := mediaService.CleanupUnreferenced(ctx, *grace, *dryRun)
+	if err != nil {
+		log.Fatalf("清理媒体文件失败: %v", err)
+	}
+	if *dryRun {
+		// 排除引用记录缺失但实际被引用的文件
+		kept := media[:0]
+		for _, m := range media {
+			if !referenced[m.ID] {
+				kept = append(kept, m)
+			}
+		}
+		media = kept
+	}
+
+	var size int64
+	for _, m := range media {
+		size += m.Size
+		log.Printf("%s %s (%d 字节)", action(*dryRun), m.Key, m.Size)
+	}
+	log.Printf("媒体文件清理完成! %s %d 个文件，共 %.2f MB", action(*dryRun), len(media), float64(size)/1024/1024)
+}
+
+// action 返回日志中使用的操作名称
+func action(dryRun bool) string {
+	if dryRun {
+		return "将删除"
+	}
+	return "已删除"
+}
+
+// rebuildReferences 扫描文章、用户、项目和系列，通过sync重新生成媒体引用关系
+func rebuildReferences(ctx context.Context, db *gorm.DB, sync func(refType string, refID uint, urls ...string) error) error {
+	log.Println("开始重建媒体引用...")
+	startTime := time.Now()
+
+	// 文章封面和正文图片
+	var articles []domain.Article
+	result := db.WithContext(ctx).Select("id", "cover_image", "content").
+		FindInBatches(&articles, 100, func(tx *gorm.DB, batch int) error {
+			for i := range articles {
+				if err := sync(domain.MediaRefArticle, articles[i].ID, service.ArticleMediaURLs(&articles[i])...); err != nil {
+					return fmt.Errorf("文章ID=%d: %w", articles[i].ID, err)
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	// 用户头像
+	var users []domain.User
+	result = db.WithContext(ctx).Select("id", "avatar").
+		FindInBatches(&users, 100, func(tx *gorm.DB, batch int) error {
+			for i := range users {
+				if err := sync(domain.MediaRefUser, users[i].ID, users[i].Avatar); err != nil {
+					return fmt.Errorf("用户ID=%d: %w", users[i].ID, err)
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	// 项目图集和正文图片
+	var projects []domain.Project
+	result = db.WithContext(ctx).Select("id", "images", "content").
+		FindInBatches(&projects, 100, func(tx *gorm.DB, batch int) error {
+			for i := range projects {
+				if err := sync(domain.MediaRefProject, projects[i].ID, service.ProjectMediaURLs(&projects[i])...); err != nil {
+					return fmt.Errorf("项目ID=%d: %w", projects[i].ID, err)
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	// 系列封面
+	var series []domain.Series
+	result = db.WithContext(ctx).Select("id", "cover_image").
+		FindInBatches(&series, 100, func(tx *gorm.DB, batch int) error {
+			for i := range series {
+				if err := sync(domain.MediaRefSeries, series[i].ID, series[i].CoverImage); err != nil {
+					return fmt.Errorf("系列ID=%d: %w", series[i].ID, err)
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	log.Printf("媒体引用重建完成，耗时: %v", time.Since(startTime))
+	return nil
+}
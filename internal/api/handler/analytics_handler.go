@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsHandler 内容统计处理器
+type AnalyticsHandler struct {
+	analyticsService service.AnalyticsService
+}
+
+// NewAnalyticsHandler 创建内容统计处理器实例
+func NewAnalyticsHandler(analyticsService service.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+	}
+}
+
+// GetSeries 获取时间序列
+// 参数: type、id 限定内容，from、to 为日期范围(默认最近30天)，period 为 day 或 month
+func (h *AnalyticsHandler) GetSeries(c *gin.Context) {
+	query, ok := analyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	points, err := h.analyticsService.GetSeries(c.Request.Context(), query)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "获取统计成功", points)
+}
+
+// GetTopItems 获取指标合计最高的内容
+// 参数: type 限定内容类型，metric 为 views、visitors、likes 或 comments，limit 最多100
+func (h *AnalyticsHandler) GetTopItems(c *gin.Context) {
+	query, ok := analyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.analyticsService.GetTopItems(c.Request.Context(), query)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "获取排行成功", items)
+}
+
+// GetTopReferrers 获取浏览量最高的来源域名，域名为空表示直接访问
+func (h *AnalyticsHandler) GetTopReferrers(c *gin.Context) {
+	query, ok := analyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	referrers, err := h.analyticsService.GetTopReferrers(c.Request.Context(), query)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "获取来源成功", referrers)
+}
+
+// GetSources 获取来源域名和推广活动(UTM)的浏览量，通过type和id查询单篇文章的来源
+func (h *AnalyticsHandler) GetSources(c *gin.Context) {
+	query, ok := analyticsQuery(c)
+	if !ok {
+		return
+	}
+
+	sources, err := h.analyticsService.GetSources(c.Request.Context(), query)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	utils.SuccessResponse(c, "获取来源成功", sources)
+}
+
+// analyticsQuery 解析统计查询参数，参数无效时已写入响应
+func analyticsQuery(c *gin.Context) (service.AnalyticsQuery, bool) {
+	query := service.AnalyticsQuery{
+		ItemType: c.Query("type"),
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Period:   c.DefaultQuery("period", "day"),
+		Metric:   c.Query("metric"),
+	}
+
+	if idStr := c.Query("id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的内容ID", err.Error())
+			return query, false
+		}
+		query.ItemID = uint(id)
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的数量", err.Error())
+			return query, false
+		}
+		query.Limit = limit
+	}
+	return query, true
+}
+
+// analyticsError 返回统计查询的错误响应
+func analyticsError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidAnalyticsQuery) {
+		utils.BadRequestResponse(c, err.Error(), nil)
+		return
+	}
+	utils.InternalServerErrorResponse(c, "获取统计失败: "+err.Error())
+}
@@ -4,20 +4,35 @@ import (
 	"Lin_studio/internal/domain"
 	"Lin_studio/internal/service"
 	"Lin_studio/internal/utils"
+	"errors"
+	"log"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ArticleHandler 文章处理器
 type ArticleHandler struct {
-	articleService service.ArticleService
+	articleService  service.ArticleService
+	reactionService service.ReactionService
+	editingService  service.EditingService
+	viewTracker     service.ViewTracker
 }
 
 // NewArticleHandler 创建文章处理器实例
-func NewArticleHandler(articleService service.ArticleService) *ArticleHandler {
+func NewArticleHandler(
+	articleService service.ArticleService,
+	reactionService service.ReactionService,
+	editingService service.EditingService,
+	viewTracker service.ViewTracker,
+) *ArticleHandler {
 	return &ArticleHandler{
-		articleService: articleService,
+		articleService:  articleService,
+		reactionService: reactionService,
+		editingService:  editingService,
+		viewTracker:     viewTracker,
 	}
 }
 
@@ -101,6 +116,7 @@ func (h *ArticleHandler) GetArticles(c *gin.Context) {
 	}
 	
 	// 返回结果
+	setArticlesCacheValidator(c, articles, pagination.Total)
 	utils.SuccessResponse(c, "获取文章列表成功", gin.H{
 		"articles":   articles,
 		"pagination": pagination,
@@ -122,10 +138,37 @@ func (h *ArticleHandler) GetArticleBySlug(c *gin.Context) {
 		return
 	}
 	
-	// 增加浏览量
-	go h.articleService.ViewArticle(c.Request.Context(), article.ID)
+	// 表态统计
+	summaries, err := h.reactionService.GetSummaries(
+		c.Request.Context(),
+		domain.ReactionTargetArticle,
+		[]uint{article.ID},
+		reactionActor(c, h.reactionService),
+	)
+	if err != nil {
+		log.Printf("获取文章表态失败: %v", err)
+	} else {
+		article.Reactions = summaries[article.ID].Counts
+		article.MyReactions = summaries[article.ID].Mine
+	}
 	
-	// 返回文章
+	// 记录浏览，去重后定期批量写入
+	h.viewTracker.Track(service.ViewTargetArticle, article.ID, viewVisitor(c))
+	
+	// 返回文章，ETag中的版本号用于修改时的If-Match
+	// 统计数量、表态和系列导航不改变版本号，需要加入缓存校验数据
+	parts := []interface{}{article.Likes, article.CommentsCount, article.Views, article.Reactions, article.MyReactions}
+	if nav := article.Series; nav != nil {
+		parts = append(parts, nav.ID, nav.Position, nav.Total)
+		if nav.Prev != nil {
+			parts = append(parts, "prev", nav.Prev.ID, nav.Prev.Title, nav.Prev.Slug)
+		}
+		if nav.Next != nil {
+			parts = append(parts, "next", nav.Next.ID, nav.Next.Title, nav.Next.Slug)
+		}
+	}
+	c.Header("ETag", utils.VersionETag(article.Version))
+	utils.SetCacheValidator(c, article.UpdatedAt, parts...)
 	utils.SuccessResponse(c, "获取文章成功", article)
 }
 
@@ -229,11 +272,24 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 		utils.BadRequestResponse(c, "无效的请求数据", err.Error())
 		return
 	}
+
+	// 检查是否与其他正在编辑的人冲突
+	userID := c.GetUint("user_id")
+	sessionID := c.GetHeader("X-Edit-Session")
+	conflict, err := h.editingService.CheckSave(uint(id), userID, sessionID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error(), gin.H{"conflict": conflict})
+		return
+	}
+	if conflict != "" {
+		c.Header("X-Edit-Conflict", conflict)
+	}
 	
-	// 更新文章
+	// 更新文章，携带If-Match时只更新该版本
 	article, err := h.articleService.UpdateArticle(
 		c.Request.Context(),
 		uint(id),
+		c.GetUint("if_match_version"),
 		req.Title,
 		req.Excerpt,
 		req.Content,
@@ -244,9 +300,15 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	)
 	
 	if err != nil {
+		if errors.Is(err, service.ErrVersionConflict) {
+			utils.PreconditionFailedResponse(c, err.Error())
+			return
+		}
 		utils.BadRequestResponse(c, "更新文章失败", err.Error())
 		return
 	}
+	h.editingService.Saved(article.ID, userID, sessionID)
+	c.Header("ETag", utils.VersionETag(article.Version))
 	
 	// 检查是否需要渲染Markdown
 	renderHTML := c.Query("render_html") == "true"
@@ -258,9 +320,10 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 		if err != nil {
 			// 如果获取失败，仍然返回基本更新成功信息
 			utils.SuccessResponse(c, "文章更新成功", gin.H{
-				"id":    article.ID,
-				"title": article.Title,
-				"slug":  article.Slug,
+				"id":      article.ID,
+				"title":   article.Title,
+				"slug":    article.Slug,
+				"version": article.Version,
 			})
 			return
 		}
@@ -272,9 +335,10 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	
 	// 返回基本更新成功信息
 	utils.SuccessResponse(c, "文章更新成功", gin.H{
-		"id":    article.ID,
-		"title": article.Title,
-		"slug":  article.Slug,
+		"id":      article.ID,
+		"title":   article.Title,
+		"slug":    article.Slug,
+		"version": article.Version,
 	})
 }
 
@@ -288,9 +352,13 @@ func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
 		return
 	}
 	
-	// 删除文章
-	err = h.articleService.DeleteArticle(c.Request.Context(), uint(id))
+	// 删除文章，携带If-Match时只删除该版本
+	err = h.articleService.DeleteArticle(c.Request.Context(), uint(id), c.GetUint("if_match_version"))
 	if err != nil {
+		if errors.Is(err, service.ErrVersionConflict) {
+			utils.PreconditionFailedResponse(c, err.Error())
+			return
+		}
 		utils.BadRequestResponse(c, "删除文章失败", err.Error())
 		return
 	}
@@ -301,6 +369,13 @@ func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
 
 // UploadCoverImage 上传文章封面图片
 func (h *ArticleHandler) UploadCoverImage(c *gin.Context) {
+	// 获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.UnauthorizedResponse(c, "未授权")
+		return
+	}
+	
 	// 获取上传的文件
 	file, err := c.FormFile("cover")
 	if err != nil {
@@ -309,8 +384,12 @@ func (h *ArticleHandler) UploadCoverImage(c *gin.Context) {
 	}
 	
 	// 上传封面图片
-	url, err := h.articleService.UploadCoverImage(c.Request.Context(), file)
+	url, err := h.articleService.UploadCoverImage(c.Request.Context(), file, userID.(uint))
 	if err != nil {
+		if errors.Is(err, utils.ErrInvalidUpload) {
+			utils.BadRequestResponse(c, "上传封面图片失败", err.Error())
+			return
+		}
 		utils.InternalServerErrorResponse(c, "上传封面图片失败: "+err.Error())
 		return
 	}
@@ -341,28 +420,85 @@ func (h *ArticleHandler) GetFeaturedArticles(c *gin.Context) {
 	}
 	
 	// 返回结果
+	setArticlesCacheValidator(c, articles, int64(len(articles)))
 	utils.SuccessResponse(c, "获取精选文章成功", gin.H{
 		"articles": articles,
 	})
 }
 
-// LikeArticle 点赞文章
-func (h *ArticleHandler) LikeArticle(c *gin.Context) {
-	// 获取文章ID
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+// GetRelatedArticles 获取与文章相关的已发布文章
+// 参数: limit 返回数量，默认5，最多10
+func (h *ArticleHandler) GetRelatedArticles(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit < 1 {
+		limit = 5
+	}
+
+	article, err := h.articleService.GetArticleBySlug(c.Request.Context(), c.Param("slug"), false)
 	if err != nil {
-		utils.BadRequestResponse(c, "无效的文章ID", err.Error())
+		utils.NotFoundResponse(c, "文章不存在")
 		return
 	}
-	
-	// 点赞文章
-	err = h.articleService.LikeArticle(c.Request.Context(), uint(id))
+
+	articles, err := h.articleService.RelatedArticles(c.Request.Context(), article.ID, limit)
 	if err != nil {
-		utils.InternalServerErrorResponse(c, "点赞文章失败: "+err.Error())
+		utils.InternalServerErrorResponse(c, "获取相关文章失败: "+err.Error())
 		return
 	}
-	
-	// 返回结果
-	utils.SuccessResponse(c, "点赞成功", nil)
-} 
\ No newline at end of file
+
+	setArticlesCacheValidator(c, articles, int64(len(articles)))
+	utils.SuccessResponse(c, "获取相关文章成功", gin.H{
+		"articles": articles,
+	})
+}
+
+// LikeArticle 点赞文章，同一访问者再次点赞时取消
+func (h *ArticleHandler) LikeArticle(c *gin.Context) {
+	toggleReaction(c, h.reactionService, domain.ReactionTargetArticle, domain.ReactionLike)
+}
+
+// ReactToArticle 切换文章的表态
+func (h *ArticleHandler) ReactToArticle(c *gin.Context) {
+	kind, ok := bindReactionKind(c)
+	if !ok {
+		return
+	}
+	toggleReaction(c, h.reactionService, domain.ReactionTargetArticle, kind)
+}
+
+// setArticlesCacheValidator 以最后修改的文章和各文章的点赞、评论数量作为列表的缓存校验数据
+// 浏览量变化频繁，不影响缓存校验
+func setArticlesCacheValidator(c *gin.Context, articles []domain.Article, total int64) {
+	var lastModified time.Time
+	parts := []interface{}{total}
+	for _, article := range articles {
+		if article.UpdatedAt.After(lastModified) {
+			lastModified = article.UpdatedAt
+		}
+		parts = append(parts, article.ID, article.Version, article.Likes, article.CommentsCount)
+	}
+	utils.SetCacheValidator(c, lastModified, parts...)
+}
+
+// viewVisitor 返回当前请求的访问者，用于浏览量去重和来源统计
+// 单页应用通过ref参数传入页面的document.referrer，没有时使用请求的Referer
+// 页面地址中的utm_source、utm_medium和utm_campaign参数原样传入
+func viewVisitor(c *gin.Context) service.ViewVisitor {
+	visitor := service.ViewVisitor{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Referrer:  c.Query("ref"),
+		Campaign: service.ViewCampaign{
+			Source:   c.Query("utm_source"),
+			Medium:   c.Query("utm_medium"),
+			Campaign: c.Query("utm_campaign"),
+		},
+	}
+	if visitor.Referrer == "" {
+		visitor.Referrer = c.Request.Referer()
+	}
+	if id := c.GetUint("user_id"); id != 0 {
+		visitor.UserID = &id
+	}
+	return visitor
+}
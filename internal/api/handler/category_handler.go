@@ -4,6 +4,7 @@ import (
 	"Lin_studio/internal/service"
 	"Lin_studio/internal/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -42,6 +43,17 @@ func (h *CategoryHandler) GetAllCategories(c *gin.Context) {
 		return
 	}
 
+	// 以最后修改的分类和各分类的文章数量作为缓存校验数据
+	var lastModified time.Time
+	parts := make([]interface{}, 0, len(categories))
+	for _, category := range categories {
+		if category.UpdatedAt.After(lastModified) {
+			lastModified = category.UpdatedAt
+		}
+		parts = append(parts, category.ID, category.Version, category.ArticleCount)
+	}
+	utils.SetCacheValidator(c, lastModified, parts...)
+
 	// 返回分类列表
 	utils.SuccessResponse(c, "获取分类列表成功", gin.H{
 		"categories": categories,
@@ -100,7 +112,9 @@ func (h *CategoryHandler) GetCategoryByID(c *gin.Context) {
 		return
 	}
 
-	// 返回分类
+	// 返回分类，ETag中的版本号用于修改时的If-Match，文章数量不改变版本号
+	c.Header("ETag", utils.VersionETag(category.Version))
+	utils.SetCacheValidator(c, category.UpdatedAt, category.ArticleCount)
 	utils.SuccessResponse(c, "获取分类成功", category)
 }
 
@@ -116,6 +130,8 @@ func (h *CategoryHandler) GetCategoryBySlug(c *gin.Context) {
 		return
 	}
 
-	// 返回分类
+	// 返回分类，ETag中的版本号用于修改时的If-Match，文章数量不改变版本号
+	c.Header("ETag", utils.VersionETag(category.Version))
+	utils.SetCacheValidator(c, category.UpdatedAt, category.ArticleCount)
 	utils.SuccessResponse(c, "获取分类成功", category)
 } 
\ No newline at end of file
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"Lin_studio/internal/domain"
 	"Lin_studio/internal/service"
 	"Lin_studio/internal/utils"
+	"errors"
+	"log"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -10,13 +14,40 @@ import (
 
 // CommentHandler 评论处理器
 type CommentHandler struct {
-	commentService service.CommentService
+	commentService  service.CommentService
+	reactionService service.ReactionService
 }
 
 // NewCommentHandler 创建评论处理器实例
-func NewCommentHandler(commentService service.CommentService) *CommentHandler {
+func NewCommentHandler(commentService service.CommentService, reactionService service.ReactionService) *CommentHandler {
 	return &CommentHandler{
-		commentService: commentService,
+		commentService:  commentService,
+		reactionService: reactionService,
+	}
+}
+
+// applyReactions 为评论及其回复填充表态统计，失败时保留评论数据
+func (h *CommentHandler) applyReactions(c *gin.Context, comments []domain.Comment) {
+	var ids []uint
+	for i := range comments {
+		ids = comments[i].CollectIDs(ids)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	summaries, err := h.reactionService.GetSummaries(
+		c.Request.Context(),
+		domain.ReactionTargetComment,
+		ids,
+		reactionActor(c, h.reactionService),
+	)
+	if err != nil {
+		log.Printf("获取评论表态失败: %v", err)
+		return
+	}
+	for i := range comments {
+		comments[i].ApplyReactions(summaries)
 	}
 }
 
@@ -57,7 +88,7 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 	
 	// 获取状态（可选，仅管理员可用）
 	var status *string
-	userRole, exists := c.Get("userRole")
+	userRole, exists := c.Get("role")
 	if exists && userRole.(string) == "admin" {
 		statusStr := c.Query("status")
 		if statusStr != "" {
@@ -80,6 +111,7 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 		utils.InternalServerErrorResponse(c, "获取评论列表失败: "+err.Error())
 		return
 	}
+	h.applyReactions(c, comments)
 	
 	// 转换为响应格式
 	commentsResponse := make([]interface{}, len(comments))
@@ -94,6 +126,84 @@ func (h *CommentHandler) GetComments(c *gin.Context) {
 	})
 }
 
+// GetCommentTree 获取嵌套的评论树
+func (h *CommentHandler) GetCommentTree(c *gin.Context) {
+	page, limit := utils.GetPagination(c)
+
+	itemType := c.Query("item_type")
+	if itemType == "" {
+		utils.BadRequestResponse(c, "缺少内容类型参数", nil)
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Query("item_id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的内容ID", err.Error())
+		return
+	}
+
+	// 展开的回复层级（可选）
+	depth, _ := strconv.Atoi(c.Query("depth"))
+
+	comments, pagination, err := h.commentService.GetCommentTree(
+		c.Request.Context(),
+		itemType,
+		uint(itemID),
+		page,
+		limit,
+		depth,
+	)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取评论树失败: "+err.Error())
+		return
+	}
+	h.applyReactions(c, comments)
+
+	commentsResponse := make([]interface{}, len(comments))
+	for i, comment := range comments {
+		commentsResponse[i] = comment.ToResponse()
+	}
+
+	utils.SuccessResponse(c, "获取评论树成功", gin.H{
+		"comments":   commentsResponse,
+		"pagination": pagination,
+		"max_depth":  h.commentService.MaxDepth(),
+	})
+}
+
+// GetReplies 分页加载评论的回复
+func (h *CommentHandler) GetReplies(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的评论ID", err.Error())
+		return
+	}
+
+	page, limit := utils.GetPagination(c)
+	depth, _ := strconv.Atoi(c.Query("depth"))
+
+	replies, pagination, err := h.commentService.GetReplies(c.Request.Context(), uint(id), page, limit, depth)
+	if err != nil {
+		if errors.Is(err, service.ErrCommentNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "获取回复列表失败: "+err.Error())
+		return
+	}
+	h.applyReactions(c, replies)
+
+	repliesResponse := make([]interface{}, len(replies))
+	for i, reply := range replies {
+		repliesResponse[i] = reply.ToResponse()
+	}
+
+	utils.SuccessResponse(c, "获取回复列表成功", gin.H{
+		"replies":    repliesResponse,
+		"pagination": pagination,
+	})
+}
+
 // GetCommentByID 根据ID获取评论
 func (h *CommentHandler) GetCommentByID(c *gin.Context) {
 	// 获取评论ID
@@ -110,9 +220,11 @@ func (h *CommentHandler) GetCommentByID(c *gin.Context) {
 		utils.NotFoundResponse(c, "评论不存在")
 		return
 	}
+	comments := []domain.Comment{*comment}
+	h.applyReactions(c, comments)
 	
 	// 返回结果
-	utils.SuccessResponse(c, "获取评论成功", comment.ToResponse())
+	utils.SuccessResponse(c, "获取评论成功", comments[0].ToResponse())
 }
 
 // CreateComment 创建评论
@@ -124,9 +236,12 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		ItemID         uint   `json:"item_id" binding:"required"`
 		ParentID       *uint  `json:"parent_id"`
 		AnonymousAuthor *struct {
-			Name  string `json:"name"`
-			Email string `json:"email"`
+			Name        string `json:"name"`
+			Email       string `json:"email"`
+			NotifyEmail bool   `json:"notify_email"` // 勾选接收邮件通知，确认邮箱后生效
 		} `json:"anonymous_author"`
+		Website   string `json:"website"`    // 隐藏字段，正常用户不会填写
+		FormToken string `json:"form_token"` // 通过 /comments/form-token 获取
 	}
 	
 	// 绑定请求体
@@ -137,7 +252,7 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	
 	// 获取用户ID（可选）
 	var userID *uint
-	userIDInterface, exists := c.Get("userID")
+	userIDInterface, exists := c.Get("user_id")
 	if exists {
 		uid := userIDInterface.(uint)
 		userID = &uid
@@ -145,9 +260,11 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	
 	// 获取匿名信息
 	var anonymousName, anonymousEmail string
+	var notifyEmail bool
 	if req.AnonymousAuthor != nil {
 		anonymousName = req.AnonymousAuthor.Name
 		anonymousEmail = req.AnonymousAuthor.Email
+		notifyEmail = req.AnonymousAuthor.NotifyEmail
 	}
 	
 	// 创建评论
@@ -159,11 +276,27 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		userID,
 		anonymousName,
 		anonymousEmail,
+		notifyEmail,
 		req.ParentID,
+		service.CommentClient{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Honeypot:  req.Website,
+			FormToken: req.FormToken,
+		},
 	)
 	
 	if err != nil {
-		utils.BadRequestResponse(c, "创建评论失败", err.Error())
+		switch {
+		case errors.Is(err, service.ErrCommentTargetNotFound):
+			utils.NotFoundResponse(c, err.Error())
+		case errors.Is(err, service.ErrCommentTargetClosed):
+			utils.ForbiddenResponse(c, err.Error())
+		case errors.Is(err, service.ErrCommentRejected):
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error(), nil)
+		default:
+			utils.BadRequestResponse(c, "创建评论失败", err.Error())
+		}
 		return
 	}
 	
@@ -171,10 +304,17 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 	utils.CreatedResponse(c, "评论创建成功", comment.ToResponse())
 }
 
+// GetFormToken 获取评论表单令牌，提交评论时随表单一起提交
+func (h *CommentHandler) GetFormToken(c *gin.Context) {
+	utils.SuccessResponse(c, "获取表单令牌成功", gin.H{
+		"form_token": h.commentService.IssueFormToken(),
+	})
+}
+
 // UpdateComment 更新评论
 func (h *CommentHandler) UpdateComment(c *gin.Context) {
 	// 获取用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		utils.UnauthorizedResponse(c, "未授权")
 		return
@@ -208,7 +348,14 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 	)
 	
 	if err != nil {
-		utils.BadRequestResponse(c, "更新评论失败", err.Error())
+		switch {
+		case errors.Is(err, service.ErrCommentNotFound):
+			utils.NotFoundResponse(c, err.Error())
+		case errors.Is(err, service.ErrCommentEditForbidden), errors.Is(err, service.ErrCommentEditExpired):
+			utils.ForbiddenResponse(c, err.Error())
+		default:
+			utils.BadRequestResponse(c, "更新评论失败", err.Error())
+		}
 		return
 	}
 	
@@ -216,10 +363,41 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 	utils.SuccessResponse(c, "评论更新成功", comment.ToResponse())
 }
 
+// GetCommentRevisions 获取评论的修改记录
+func (h *CommentHandler) GetCommentRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的评论ID", err.Error())
+		return
+	}
+
+	revisions, err := h.commentService.GetCommentRevisions(
+		c.Request.Context(),
+		uint(id),
+		c.GetUint("user_id"),
+		c.GetString("role") == "admin",
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCommentNotFound):
+			utils.NotFoundResponse(c, err.Error())
+		case errors.Is(err, service.ErrCommentEditForbidden):
+			utils.ForbiddenResponse(c, "无权查看此评论的修改记录")
+		default:
+			utils.InternalServerErrorResponse(c, "获取修改记录失败: "+err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, "获取修改记录成功", gin.H{
+		"revisions": revisions,
+	})
+}
+
 // DeleteComment 删除评论
 func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	// 获取用户ID和角色
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		utils.UnauthorizedResponse(c, "未授权")
 		return
@@ -227,7 +405,7 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	
 	// 检查是否为管理员
 	isAdmin := false
-	userRole, exists := c.Get("userRole")
+	userRole, exists := c.Get("role")
 	if exists && userRole.(string) == "admin" {
 		isAdmin = true
 	}
@@ -257,31 +435,24 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 	utils.SuccessResponse(c, "评论删除成功", nil)
 }
 
-// LikeComment 点赞评论
+// LikeComment 点赞评论，同一访问者再次点赞时取消
 func (h *CommentHandler) LikeComment(c *gin.Context) {
-	// 获取评论ID
-	idStr := c.Param("id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
-	if err != nil {
-		utils.BadRequestResponse(c, "无效的评论ID", err.Error())
-		return
-	}
-	
-	// 点赞评论
-	err = h.commentService.LikeComment(c.Request.Context(), uint(id))
-	if err != nil {
-		utils.BadRequestResponse(c, "点赞评论失败", err.Error())
+	toggleReaction(c, h.reactionService, domain.ReactionTargetComment, domain.ReactionLike)
+}
+
+// ReactToComment 切换评论的表态
+func (h *CommentHandler) ReactToComment(c *gin.Context) {
+	kind, ok := bindReactionKind(c)
+	if !ok {
 		return
 	}
-	
-	// 返回结果
-	utils.SuccessResponse(c, "点赞成功", nil)
+	toggleReaction(c, h.reactionService, domain.ReactionTargetComment, kind)
 }
 
 // ApproveComment 批准评论
 func (h *CommentHandler) ApproveComment(c *gin.Context) {
 	// 检查权限
-	userRole, exists := c.Get("userRole")
+	userRole, exists := c.Get("role")
 	if !exists || userRole.(string) != "admin" {
 		utils.ForbiddenResponse(c, "无权操作")
 		return
@@ -296,7 +467,7 @@ func (h *CommentHandler) ApproveComment(c *gin.Context) {
 	}
 	
 	// 批准评论
-	err = h.commentService.ApproveComment(c.Request.Context(), uint(id))
+	err = h.commentService.ApproveComment(c.Request.Context(), uint(id), c.GetUint("user_id"))
 	if err != nil {
 		utils.BadRequestResponse(c, "批准评论失败", err.Error())
 		return
@@ -309,7 +480,7 @@ func (h *CommentHandler) ApproveComment(c *gin.Context) {
 // MarkCommentAsSpam 标记评论为垃圾信息
 func (h *CommentHandler) MarkCommentAsSpam(c *gin.Context) {
 	// 检查权限
-	userRole, exists := c.Get("userRole")
+	userRole, exists := c.Get("role")
 	if !exists || userRole.(string) != "admin" {
 		utils.ForbiddenResponse(c, "无权操作")
 		return
@@ -324,7 +495,7 @@ func (h *CommentHandler) MarkCommentAsSpam(c *gin.Context) {
 	}
 	
 	// 标记为垃圾信息
-	err = h.commentService.MarkCommentAsSpam(c.Request.Context(), uint(id))
+	err = h.commentService.MarkCommentAsSpam(c.Request.Context(), uint(id), c.GetUint("user_id"))
 	if err != nil {
 		utils.BadRequestResponse(c, "标记评论失败", err.Error())
 		return
@@ -332,4 +503,121 @@ func (h *CommentHandler) MarkCommentAsSpam(c *gin.Context) {
 	
 	// 返回结果
 	utils.SuccessResponse(c, "评论已标记为垃圾信息", nil)
-} 
\ No newline at end of file
+}
+
+// GetModerationQueue 获取评论审核队列
+func (h *CommentHandler) GetModerationQueue(c *gin.Context) {
+	page, limit := utils.GetPagination(c)
+
+	// 筛选条件（可选）
+	var itemID uint
+	if itemIDStr := c.Query("item_id"); itemIDStr != "" {
+		id, err := strconv.ParseUint(itemIDStr, 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的内容ID", err.Error())
+			return
+		}
+		itemID = uint(id)
+	}
+
+	var minScore *float64
+	if minScoreStr := c.Query("min_score"); minScoreStr != "" {
+		score, err := strconv.ParseFloat(minScoreStr, 64)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的分数", err.Error())
+			return
+		}
+		minScore = &score
+	}
+
+	items, pagination, err := h.commentService.GetModerationQueue(
+		c.Request.Context(),
+		c.Query("status"),
+		c.Query("item_type"),
+		itemID,
+		minScore,
+		page,
+		limit,
+	)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取审核队列失败: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "获取审核队列成功", gin.H{
+		"comments":   items,
+		"pagination": pagination,
+	})
+}
+
+// BulkModerate 批量审核评论
+func (h *CommentHandler) BulkModerate(c *gin.Context) {
+	var req struct {
+		Action string `json:"action" binding:"required,oneof=approve spam delete"`
+		IDs    []uint `json:"ids"`
+		Filter *struct {
+			Status   string   `json:"status"`
+			ItemType string   `json:"item_type"`
+			ItemID   uint     `json:"item_id"`
+			MinScore *float64 `json:"min_score"`
+		} `json:"filter"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "无效的请求数据", err.Error())
+		return
+	}
+
+	var filter *service.ModerationCriteria
+	if req.Filter != nil {
+		filter = &service.ModerationCriteria{
+			Status:   req.Filter.Status,
+			ItemType: req.Filter.ItemType,
+			ItemID:   req.Filter.ItemID,
+			MinScore: req.Filter.MinScore,
+		}
+	}
+
+	result, err := h.commentService.BulkModerate(c.Request.Context(), req.Action, req.IDs, filter, c.GetUint("user_id"))
+	if err != nil {
+		utils.BadRequestResponse(c, "批量审核失败", err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "批量审核完成", result)
+}
+
+// GetModerationLogs 获取评论审核日志
+func (h *CommentHandler) GetModerationLogs(c *gin.Context) {
+	page, limit := utils.GetPagination(c)
+
+	var commentID, moderatorID *uint
+	if idStr := c.Query("comment_id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的评论ID", err.Error())
+			return
+		}
+		cid := uint(id)
+		commentID = &cid
+	}
+	if idStr := c.Query("moderator_id"); idStr != "" {
+		id, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的审核人ID", err.Error())
+			return
+		}
+		mid := uint(id)
+		moderatorID = &mid
+	}
+
+	logs, pagination, err := h.commentService.GetModerationLogs(c.Request.Context(), commentID, moderatorID, page, limit)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取审核日志失败: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "获取审核日志成功", gin.H{
+		"logs":       logs,
+		"pagination": pagination,
+	})
+}
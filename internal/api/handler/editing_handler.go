@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// EditingHandler 文章协同编辑处理器
+type EditingHandler struct {
+	editingService service.EditingService
+}
+
+// NewEditingHandler 创建文章协同编辑处理器实例
+func NewEditingHandler(editingService service.EditingService) *EditingHandler {
+	return &EditingHandler{
+		editingService: editingService,
+	}
+}
+
+// Connect 建立文章编辑的WebSocket连接
+// 客户端发送 {"type":"lock|unlock|sync|ping"}，服务端推送在线编辑者、软锁和保存通知
+// 连接建立后收到的welcome消息中包含session_id，保存文章时通过X-Edit-Session请求头带回
+func (h *EditingHandler) Connect(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的文章ID", err.Error())
+		return
+	}
+
+	session, err := h.editingService.Join(c.Request.Context(), uint(id), c.GetUint("user_id"), c.GetString("username"))
+	if err != nil {
+		if errors.Is(err, service.ErrArticleNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "加入编辑失败: "+err.Error())
+		return
+	}
+	defer h.editingService.Leave(session)
+
+	server := websocket.Server{Handshake: acceptTokenProtocol, Handler: func(ws *websocket.Conn) {
+		idleTimeout := h.editingService.IdleTimeout()
+
+		// 读取指令，连接断开或超过空闲时间没有消息时结束
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				_ = ws.SetReadDeadline(time.Now().Add(idleTimeout))
+				var cmd service.EditCommand
+				if err := websocket.JSON.Receive(ws, &cmd); err != nil {
+					return
+				}
+				h.editingService.Handle(session, cmd)
+			}
+		}()
+
+		for {
+			select {
+			case <-done:
+				return
+			case msg, ok := <-session.C:
+				if !ok {
+					return
+				}
+				_ = ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				if err := websocket.JSON.Send(ws, msg); err != nil {
+					return
+				}
+			}
+		}
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// acceptTokenProtocol 客户端通过子协议传递令牌时只回应bearer，令牌不会出现在响应中
+func acceptTokenProtocol(config *websocket.Config, req *http.Request) error {
+	for _, protocol := range config.Protocol {
+		if protocol == utils.WebSocketTokenProtocol {
+			config.Protocol = []string{utils.WebSocketTokenProtocol}
+			return nil
+		}
+	}
+	config.Protocol = nil
+	return nil
+}
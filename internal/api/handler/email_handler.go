@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailHandler 邮件通知处理器
+type EmailHandler struct {
+	mailService service.MailService
+}
+
+// NewEmailHandler 创建邮件通知处理器实例
+func NewEmailHandler(mailService service.MailService) *EmailHandler {
+	return &EmailHandler{
+		mailService: mailService,
+	}
+}
+
+// GetPreferences 获取当前用户的邮件通知偏好
+func (h *EmailHandler) GetPreferences(c *gin.Context) {
+	preference, err := h.mailService.GetPreference(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取通知偏好失败: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "获取通知偏好成功", preference)
+}
+
+// UpdatePreferences 修改当前用户的邮件通知偏好
+func (h *EmailHandler) UpdatePreferences(c *gin.Context) {
+	var req service.EmailPreferenceUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "无效的请求数据", err.Error())
+		return
+	}
+
+	preference, err := h.mailService.UpdatePreference(c.Request.Context(), c.GetUint("user_id"), req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMailLocale) {
+			utils.BadRequestResponse(c, err.Error(), nil)
+			return
+		}
+		utils.InternalServerErrorResponse(c, "修改通知偏好失败: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "通知偏好已更新", preference)
+}
+
+// emailPage 退订、邮箱确认的确认和结果页面，修改只在提交表单的POST请求中执行
+var emailPage = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html lang="zh">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .Action}}<form method="post" action="{{.Action}}"><button type="submit">{{.Button}}</button></form>{{end}}
+</body>
+</html>`))
+
+// emailPageData 页面数据
+type emailPageData struct {
+	Title   string
+	Message string
+	Action  string // 确认表单的提交地址，为空时不显示表单
+	Button  string // 确认表单的按钮文字
+}
+
+// ConfirmUnsubscribe 显示退订确认页面，不修改通知偏好
+// 邮件客户端和安全网关会预先访问邮件中的链接，GET请求不能直接退订
+func (h *EmailHandler) ConfirmUnsubscribe(c *gin.Context) {
+	err := h.mailService.VerifyUnsubscribe(c.Query("email"), c.Query("kind"), c.Query("token"))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrUnsubscribeDisabled) {
+			status = http.StatusServiceUnavailable
+		}
+		renderEmailPage(c, status, emailPageData{Title: "退订失败", Message: err.Error()})
+		return
+	}
+
+	renderEmailPage(c, http.StatusOK, emailPageData{
+		Title:   "退订邮件通知",
+		Message: "确认后将不再向 " + c.Query("email") + " 发送此类通知。",
+		Action:  c.Request.URL.RequestURI(),
+		Button:  "确认退订",
+	})
+}
+
+// Unsubscribe 退订通知，处理确认页面提交的表单和邮件客户端一键退订(RFC 8058)的POST请求
+// 浏览器提交表单时返回结果页面，其他请求返回JSON
+func (h *EmailHandler) Unsubscribe(c *gin.Context) {
+	err := h.mailService.Unsubscribe(
+		c.Request.Context(),
+		c.Query("email"),
+		c.Query("kind"),
+		c.Query("token"),
+	)
+	page := c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "退订失败: " + err.Error()
+		switch {
+		case errors.Is(err, service.ErrInvalidUnsubscribeToken):
+			status, message = http.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrUnsubscribeDisabled):
+			status, message = http.StatusServiceUnavailable, err.Error()
+		}
+		if page {
+			renderEmailPage(c, status, emailPageData{Title: "退订失败", Message: message})
+			return
+		}
+		utils.ErrorResponse(c, status, message, nil)
+		return
+	}
+
+	if page {
+		renderEmailPage(c, http.StatusOK, emailPageData{Title: "已退订", Message: "以后不会再收到此类通知，可以在个人设置中重新开启。"})
+		return
+	}
+	utils.SuccessResponse(c, "已退订", nil)
+}
+
+// ConfirmEmailPage 显示邮箱确认页面，原因同ConfirmUnsubscribe，GET请求不确认邮箱
+func (h *EmailHandler) ConfirmEmailPage(c *gin.Context) {
+	if err := h.mailService.VerifyConfirmation(c.Query("email"), c.Query("token")); err != nil {
+		renderEmailPage(c, http.StatusBadRequest, emailPageData{Title: "确认失败", Message: err.Error()})
+		return
+	}
+
+	renderEmailPage(c, http.StatusOK, emailPageData{
+		Title:   "确认邮箱",
+		Message: "确认后，" + c.Query("email") + " 将收到评论的回复和审核通知。",
+		Action:  c.Request.URL.RequestURI(),
+		Button:  "确认邮箱",
+	})
+}
+
+// ConfirmEmail 确认匿名评论者的邮箱，处理确认页面提交的表单
+func (h *EmailHandler) ConfirmEmail(c *gin.Context) {
+	err := h.mailService.ConfirmEmail(c.Request.Context(), c.Query("email"), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidConfirmToken) {
+			renderEmailPage(c, http.StatusBadRequest, emailPageData{Title: "确认失败", Message: err.Error()})
+			return
+		}
+		renderEmailPage(c, http.StatusInternalServerError, emailPageData{Title: "确认失败", Message: "确认邮箱失败: " + err.Error()})
+		return
+	}
+
+	renderEmailPage(c, http.StatusOK, emailPageData{Title: "邮箱已确认", Message: "以后评论收到回复或通过审核时会发送邮件通知，可以通过邮件中的链接随时退订。"})
+}
+
+// renderEmailPage 返回退订或邮箱确认页面
+func renderEmailPage(c *gin.Context, status int, data emailPageData) {
+	var buf bytes.Buffer
+	if err := emailPage.Execute(&buf, data); err != nil {
+		utils.InternalServerErrorResponse(c, "生成页面失败: "+err.Error())
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
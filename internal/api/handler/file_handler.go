@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FileHandler 上传文件访问处理器
+type FileHandler struct {
+	fileService service.FileService
+}
+
+// NewFileHandler 创建上传文件访问处理器实例
+func NewFileHandler(fileService service.FileService) *FileHandler {
+	return &FileHandler{
+		fileService: fileService,
+	}
+}
+
+// ServeUpload 输出上传的文件，支持Range、条件请求和签名URL
+func (h *FileHandler) ServeUpload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+	if key == "" {
+		utils.NotFoundResponse(c, "文件不存在")
+		return
+	}
+
+	file, err := h.fileService.Open(
+		c.Request.Context(),
+		key,
+		c.Request.URL.Path,
+		c.Query("expires"),
+		c.Query("signature"),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			utils.NotFoundResponse(c, "文件不存在")
+		case errors.Is(err, service.ErrFileForbidden):
+			utils.ForbiddenResponse(c, err.Error())
+		default:
+			utils.InternalServerErrorResponse(c, "读取文件失败: "+err.Error())
+		}
+		return
+	}
+	defer file.Content.Close()
+
+	// 缓存策略
+	switch {
+	case file.Private:
+		c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(file.MaxAge.Seconds())))
+	case file.Immutable:
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	default:
+		c.Header("Cache-Control", "public, max-age=3600")
+	}
+
+	c.Header("ETag", file.ETag)
+	if file.ContentType != "" {
+		c.Header("Content-Type", file.ContentType)
+	}
+	// 上传目录只应提供图片，禁止浏览器嗅探类型或执行脚本
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox")
+
+	// ServeContent处理Range、If-Range、If-None-Match和If-Modified-Since
+	http.ServeContent(c.Writer, c.Request, "", file.ModTime, file.Content)
+}
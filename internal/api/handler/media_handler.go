@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MediaHandler 媒体库处理器
+type MediaHandler struct {
+	mediaService service.MediaService
+}
+
+// NewMediaHandler 创建媒体库处理器实例
+func NewMediaHandler(mediaService service.MediaService) *MediaHandler {
+	return &MediaHandler{
+		mediaService: mediaService,
+	}
+}
+
+// GetMediaList 获取媒体文件列表
+// 管理员可以浏览全部文件，其他用户只能看到自己上传的文件
+func (h *MediaHandler) GetMediaList(c *gin.Context) {
+	// 获取查询参数
+	page, limit := utils.GetPagination(c)
+
+	// 获取过滤参数
+	var contentType *string
+	contentTypeStr := c.Query("content_type")
+	if contentTypeStr != "" {
+		contentType = &contentTypeStr
+	}
+	unused := c.Query("unused") == "true"
+
+	var uploaderID *uint
+	role, _ := c.Get("role")
+	if role != "admin" {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			utils.UnauthorizedResponse(c, "未授权")
+			return
+		}
+		uid := userID.(uint)
+		uploaderID = &uid
+	}
+
+	// 查询媒体文件
+	media, counts, pagination, err := h.mediaService.GetMediaList(
+		c.Request.Context(),
+		page,
+		limit,
+		contentType,
+		uploaderID,
+		unused,
+	)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取媒体文件列表失败: "+err.Error())
+		return
+	}
+
+	// 转换为响应格式
+	mediaResponse := make([]domain.MediaResponse, len(media))
+	for i := range media {
+		mediaResponse[i] = media[i].ToResponse(counts[media[i].ID])
+	}
+
+	utils.SuccessResponse(c, "获取媒体文件列表成功", gin.H{
+		"media":      mediaResponse,
+		"pagination": pagination,
+	})
+}
+
+// UploadMedia 上传媒体文件
+func (h *MediaHandler) UploadMedia(c *gin.Context) {
+	// 获取用户ID
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.UnauthorizedResponse(c, "未授权")
+		return
+	}
+
+	// 获取上传的文件
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequestResponse(c, "上传文件失败", err.Error())
+		return
+	}
+
+	uid := userID.(uint)
+	media, err := h.mediaService.Upload(c.Request.Context(), file, &uid)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidUpload) {
+			utils.BadRequestResponse(c, "上传媒体文件失败", err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "上传媒体文件失败: "+err.Error())
+		return
+	}
+
+	utils.CreatedResponse(c, "媒体文件上传成功", media.ToResponse(0))
+}
+
+// DeleteMedia 删除媒体文件
+func (h *MediaHandler) DeleteMedia(c *gin.Context) {
+	// 获取ID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的媒体文件ID", nil)
+		return
+	}
+
+	if err := h.mediaService.DeleteMedia(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, service.ErrMediaInUse) {
+			utils.ErrorResponse(c, http.StatusConflict, "媒体文件正在被文章或用户使用，无法删除", nil)
+			return
+		}
+		if errors.Is(err, service.ErrMediaNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "删除媒体文件失败: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "媒体文件删除成功", nil)
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler 站内通知处理器
+type NotificationHandler struct {
+	notificationService service.NotificationService
+}
+
+// NewNotificationHandler 创建站内通知处理器实例
+func NewNotificationHandler(notificationService service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+// GetNotifications 获取当前用户的通知列表，unread=true时只返回未读通知
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	page, limit := utils.GetPagination(c)
+
+	notifications, pagination, err := h.notificationService.GetNotifications(
+		c.Request.Context(),
+		userID,
+		c.Query("unread") == "true",
+		page,
+		limit,
+	)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取通知失败: "+err.Error())
+		return
+	}
+
+	unread, err := h.notificationService.CountUnread(c.Request.Context(), userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取通知失败: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "获取通知成功", gin.H{
+		"notifications": notifications,
+		"unread_count":  unread,
+		"pagination":    pagination,
+	})
+}
+
+// MarkRead 将指定通知标记为已读
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required,min=1,max=100"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "无效的请求数据", err.Error())
+		return
+	}
+
+	h.respondMarked(c, func(userID uint) (int64, error) {
+		return h.notificationService.MarkRead(c.Request.Context(), userID, req.IDs)
+	})
+}
+
+// MarkAllRead 将全部通知标记为已读
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	h.respondMarked(c, func(userID uint) (int64, error) {
+		return h.notificationService.MarkAllRead(c.Request.Context(), userID)
+	})
+}
+
+// respondMarked 执行标记已读操作并返回更新数量和剩余未读数量
+func (h *NotificationHandler) respondMarked(c *gin.Context, mark func(userID uint) (int64, error)) {
+	userID := c.GetUint("user_id")
+	updated, err := mark(userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "标记通知失败: "+err.Error())
+		return
+	}
+
+	unread, err := h.notificationService.CountUnread(c.Request.Context(), userID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "标记通知失败: "+err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, "通知已标记为已读", gin.H{
+		"updated":      updated,
+		"unread_count": unread,
+	})
+}
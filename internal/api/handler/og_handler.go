@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OGHandler Open Graph分享图处理器
+type OGHandler struct {
+	ogImageService service.OGImageService
+}
+
+// NewOGHandler 创建分享图处理器实例
+func NewOGHandler(ogImageService service.OGImageService) *OGHandler {
+	return &OGHandler{
+		ogImageService: ogImageService,
+	}
+}
+
+// GetArticleImage 获取文章分享图
+func (h *OGHandler) GetArticleImage(c *gin.Context) {
+	// 路由参数形如 my-article.png
+	file := c.Param("file")
+	if !strings.HasSuffix(file, ".png") {
+		utils.NotFoundResponse(c, "图片不存在")
+		return
+	}
+	slug := strings.TrimSuffix(file, ".png")
+
+	// 获取分享图
+	img, err := h.ogImageService.GetArticleImage(c.Request.Context(), slug)
+	if err != nil {
+		utils.NotFoundResponse(c, "图片不存在")
+		return
+	}
+	defer img.Content.Close()
+
+	// 返回图片
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("Content-Type", img.ContentType)
+	http.ServeContent(c.Writer, c.Request, "", img.ModTime, img.Content)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reactionActor 返回请求对应的表态访问者，已登录时按用户区分，否则按IP和User-Agent区分
+func reactionActor(c *gin.Context, reactionService service.ReactionService) service.ReactionActor {
+	var userID *uint
+	if id := c.GetUint("user_id"); id != 0 {
+		userID = &id
+	}
+	return reactionService.Actor(userID, c.ClientIP(), c.Request.UserAgent())
+}
+
+// toggleReaction 切换内容的表态并返回最新统计
+func toggleReaction(c *gin.Context, reactionService service.ReactionService, targetType, kind string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的ID", err.Error())
+		return
+	}
+
+	added, summary, err := reactionService.Toggle(
+		c.Request.Context(),
+		targetType,
+		uint(id),
+		kind,
+		reactionActor(c, reactionService),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrReactionTargetNotFound):
+			utils.NotFoundResponse(c, err.Error())
+		case errors.Is(err, service.ErrInvalidReaction):
+			utils.BadRequestResponse(c, err.Error(), nil)
+		default:
+			utils.InternalServerErrorResponse(c, "表态失败: "+err.Error())
+		}
+		return
+	}
+
+	message := "已取消"
+	if added {
+		message = "表态成功"
+	}
+	// 点赞数量来自内容的likes字段，与详情接口返回的点赞数一致
+	utils.SuccessResponse(c, message, gin.H{
+		"kind":         kind,
+		"added":        added,
+		"likes":        summary.Counts[domain.ReactionLike],
+		"reactions":    summary.Counts,
+		"my_reactions": summary.Mine,
+	})
+}
+
+// bindReactionKind 读取请求体中的表态类型
+func bindReactionKind(c *gin.Context) (string, bool) {
+	var req struct {
+		Kind string `json:"kind" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "无效的请求数据", err.Error())
+		return "", false
+	}
+	return req.Kind, true
+}
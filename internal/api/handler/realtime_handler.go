@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"Lin_studio/internal/realtime"
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RealtimeHandler 实时推送处理器
+type RealtimeHandler struct {
+	realtimeService service.RealtimeService
+}
+
+// NewRealtimeHandler 创建实时推送处理器实例
+func NewRealtimeHandler(realtimeService service.RealtimeService) *RealtimeHandler {
+	return &RealtimeHandler{
+		realtimeService: realtimeService,
+	}
+}
+
+// Stream 通过Server-Sent Events推送订阅主题的消息
+// 主题通过topics参数以逗号分隔传入，例如 ?topics=article:3,user:5
+// 断线重连时浏览器会自动带上Last-Event-ID请求头，也可以通过last_event_id参数指定
+// 错过的消息已不在补发缓冲区时先发送reset事件，客户端应重新加载数据
+func (h *RealtimeHandler) Stream(c *gin.Context) {
+	var topics []string
+	for _, topic := range strings.Split(c.Query("topics"), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			utils.BadRequestResponse(c, "无效的Last-Event-ID", err.Error())
+			return
+		}
+		lastID = id
+	}
+
+	var userID *uint
+	if id, exists := c.Get("user_id"); exists {
+		uid := id.(uint)
+		userID = &uid
+	}
+
+	sub, replay, complete, err := h.realtimeService.Connect(topics, userID, c.GetString("role"), lastID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRealtimeTopic):
+			utils.BadRequestResponse(c, err.Error(), nil)
+		case errors.Is(err, service.ErrRealtimeTopicForbidden):
+			utils.ForbiddenResponse(c, err.Error())
+		default:
+			utils.InternalServerErrorResponse(c, "订阅失败: "+err.Error())
+		}
+		return
+	}
+	defer h.realtimeService.Disconnect(sub)
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 禁止Nginx缓冲响应
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	if _, err := w.WriteString("retry: 3000\n\n"); err != nil {
+		return
+	}
+	if !complete {
+		if _, err := w.WriteString("event: reset\ndata: {}\n\n"); err != nil {
+			return
+		}
+	}
+	for _, msg := range replay {
+		if err := realtime.WriteEvent(w, msg); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(h.realtimeService.HeartbeatInterval())
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				// 消息积压过多被断开，客户端重连后补发
+				return
+			}
+			if err := realtime.WriteEvent(w, msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := realtime.WriteComment(w, "ping"); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/service"
+	"Lin_studio/internal/utils"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeriesHandler 系列处理器
+type SeriesHandler struct {
+	seriesService service.SeriesService
+}
+
+// NewSeriesHandler 创建系列处理器实例
+func NewSeriesHandler(seriesService service.SeriesService) *SeriesHandler {
+	return &SeriesHandler{
+		seriesService: seriesService,
+	}
+}
+
+// seriesRequest 创建和更新系列的请求体
+type seriesRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Slug        string `json:"slug"` // 为空时根据标题生成
+	Description string `json:"description"`
+	CoverImage  string `json:"cover_image"`
+	ArticleIDs  []uint `json:"article_ids"` // 按顺序排列的文章ID
+}
+
+// GetAllSeries 获取有已发布文章的系列
+func (h *SeriesHandler) GetAllSeries(c *gin.Context) {
+	list, err := h.seriesService.GetAllSeries(c.Request.Context())
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取系列列表失败: "+err.Error())
+		return
+	}
+
+	// 以最后修改的系列和各系列的文章数量作为缓存校验数据
+	var lastModified time.Time
+	parts := make([]interface{}, 0, len(list))
+	for _, series := range list {
+		if series.UpdatedAt.After(lastModified) {
+			lastModified = series.UpdatedAt
+		}
+		parts = append(parts, series.ID, series.Version, series.PartsCount, series.ReadTime)
+	}
+	utils.SetCacheValidator(c, lastModified, parts...)
+	utils.SuccessResponse(c, "获取系列列表成功", list)
+}
+
+// GetSeriesByID 根据ID获取系列和其中的文章，编辑者可以看到未发布的文章
+func (h *SeriesHandler) GetSeriesByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的系列ID", err.Error())
+		return
+	}
+
+	series, err := h.seriesService.GetSeriesByID(c.Request.Context(), uint(id), canSeeDrafts(c))
+	h.respondSeries(c, series, err)
+}
+
+// GetSeriesBySlug 根据Slug获取系列和其中的文章，编辑者可以看到未发布的文章
+func (h *SeriesHandler) GetSeriesBySlug(c *gin.Context) {
+	series, err := h.seriesService.GetSeriesBySlug(c.Request.Context(), c.Param("slug"), canSeeDrafts(c))
+	h.respondSeries(c, series, err)
+}
+
+// CreateSeries 创建系列
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var req seriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "无效的请求数据", err.Error())
+		return
+	}
+
+	series, err := h.seriesService.CreateSeries(
+		c.Request.Context(),
+		req.Title,
+		req.Slug,
+		req.Description,
+		req.CoverImage,
+		req.ArticleIDs,
+	)
+	if err != nil {
+		utils.BadRequestResponse(c, "创建系列失败", err.Error())
+		return
+	}
+
+	c.Header("ETag", utils.VersionETag(series.Version))
+	utils.CreatedResponse(c, "系列创建成功", gin.H{
+		"id":      series.ID,
+		"title":   series.Title,
+		"slug":    series.Slug,
+		"version": series.Version,
+	})
+}
+
+// UpdateSeries 更新系列，article_ids按顺序替换系列中的文章
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的系列ID", err.Error())
+		return
+	}
+
+	var req seriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequestResponse(c, "无效的请求数据", err.Error())
+		return
+	}
+
+	// 更新系列，携带If-Match时只更新该版本
+	series, err := h.seriesService.UpdateSeries(
+		c.Request.Context(),
+		uint(id),
+		c.GetUint("if_match_version"),
+		req.Title,
+		req.Slug,
+		req.Description,
+		req.CoverImage,
+		req.ArticleIDs,
+	)
+	if err != nil {
+		seriesWriteError(c, "更新系列失败", err)
+		return
+	}
+
+	c.Header("ETag", utils.VersionETag(series.Version))
+	utils.SuccessResponse(c, "系列更新成功", gin.H{
+		"id":      series.ID,
+		"title":   series.Title,
+		"slug":    series.Slug,
+		"version": series.Version,
+	})
+}
+
+// DeleteSeries 删除系列，其中的文章不会被删除
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.BadRequestResponse(c, "无效的系列ID", err.Error())
+		return
+	}
+
+	// 删除系列，携带If-Match时只删除该版本
+	if err := h.seriesService.DeleteSeries(c.Request.Context(), uint(id), c.GetUint("if_match_version")); err != nil {
+		seriesWriteError(c, "删除系列失败", err)
+		return
+	}
+
+	utils.SuccessResponse(c, "系列删除成功", nil)
+}
+
+// respondSeries 返回系列详情，以系列和其中各篇文章的修改时间作为缓存校验数据
+func (h *SeriesHandler) respondSeries(c *gin.Context, series *domain.SeriesResponse, err error) {
+	if err != nil {
+		if errors.Is(err, service.ErrSeriesNotFound) {
+			utils.NotFoundResponse(c, err.Error())
+			return
+		}
+		utils.InternalServerErrorResponse(c, "获取系列失败: "+err.Error())
+		return
+	}
+
+	// 文章修改不会改变系列的版本号，这里不使用版本号作为ETag，修改时按响应中的version携带If-Match
+	lastModified := series.UpdatedAt
+	parts := []interface{}{series.Version}
+	for _, part := range series.Parts {
+		if part.UpdatedAt.After(lastModified) {
+			lastModified = part.UpdatedAt
+		}
+		parts = append(parts, part.ID, part.Status)
+	}
+	utils.SetCacheValidator(c, lastModified, parts...)
+	utils.SuccessResponse(c, "获取系列成功", series)
+}
+
+// seriesWriteError 返回修改系列的错误响应
+func seriesWriteError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrSeriesNotFound):
+		utils.NotFoundResponse(c, err.Error())
+	case errors.Is(err, service.ErrVersionConflict):
+		utils.PreconditionFailedResponse(c, err.Error())
+	default:
+		utils.BadRequestResponse(c, message, err.Error())
+	}
+}
+
+// canSeeDrafts 当前用户是否可以看到未发布的文章
+func canSeeDrafts(c *gin.Context) bool {
+	role := c.GetString("role")
+	return role == "admin" || role == "editor"
+}
@@ -4,6 +4,7 @@ import (
 	"Lin_studio/internal/service"
 	"Lin_studio/internal/utils"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,6 +30,17 @@ func (h *TagHandler) GetAllTags(c *gin.Context) {
 		return
 	}
 
+	// 以最后修改的标签和各标签的统计数量作为缓存校验数据
+	var lastModified time.Time
+	parts := make([]interface{}, 0, len(tags))
+	for _, tag := range tags {
+		if tag.UpdatedAt.After(lastModified) {
+			lastModified = tag.UpdatedAt
+		}
+		parts = append(parts, tag.ID, tag.Version, tag.ArticleCount, tag.ProjectCount)
+	}
+	utils.SetCacheValidator(c, lastModified, parts...)
+
 	// 返回标签列表
 	utils.SuccessResponse(c, "获取标签列表成功", gin.H{
 		"tags": tags,
@@ -87,7 +99,9 @@ func (h *TagHandler) GetTagByID(c *gin.Context) {
 		return
 	}
 
-	// 返回标签
+	// 返回标签，ETag中的版本号用于修改时的If-Match，文章和项目数量不改变版本号
+	c.Header("ETag", utils.VersionETag(tag.Version))
+	utils.SetCacheValidator(c, tag.UpdatedAt, tag.ArticleCount, tag.ProjectCount)
 	utils.SuccessResponse(c, "获取标签成功", tag)
 }
 
@@ -103,6 +117,8 @@ func (h *TagHandler) GetTagBySlug(c *gin.Context) {
 		return
 	}
 
-	// 返回标签
+	// 返回标签，ETag中的版本号用于修改时的If-Match，文章和项目数量不改变版本号
+	c.Header("ETag", utils.VersionETag(tag.Version))
+	utils.SetCacheValidator(c, tag.UpdatedAt, tag.ArticleCount, tag.ProjectCount)
 	utils.SuccessResponse(c, "获取标签成功", tag)
 } 
\ No newline at end of file
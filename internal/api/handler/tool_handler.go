@@ -4,7 +4,9 @@ import (
 	"Lin_studio/internal/domain"
 	"Lin_studio/internal/service"
 	"Lin_studio/internal/utils"
+	"errors"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,12 +14,14 @@ import (
 // ToolHandler 工具处理器
 type ToolHandler struct {
 	toolService service.ToolService
+	viewTracker service.ViewTracker
 }
 
 // NewToolHandler 创建工具处理器实例
-func NewToolHandler(toolService service.ToolService) *ToolHandler {
+func NewToolHandler(toolService service.ToolService, viewTracker service.ViewTracker) *ToolHandler {
 	return &ToolHandler{
 		toolService: toolService,
+		viewTracker: viewTracker,
 	}
 }
 
@@ -62,11 +66,18 @@ func (h *ToolHandler) GetTools(c *gin.Context) {
 		return
 	}
 	
-	// 转换为响应格式
+	// 转换为响应格式，以最后修改的工具作为缓存校验数据
+	var lastModified time.Time
+	parts := []interface{}{pagination.Total}
 	toolsResponse := make([]interface{}, len(tools))
 	for i, tool := range tools {
 		toolsResponse[i] = tool.ToSimpleResponse()
+		if tool.UpdatedAt.After(lastModified) {
+			lastModified = tool.UpdatedAt
+		}
+		parts = append(parts, tool.ID, tool.Version)
 	}
+	utils.SetCacheValidator(c, lastModified, parts...)
 	
 	// 返回结果
 	utils.SuccessResponse(c, "获取工具列表成功", gin.H{
@@ -87,7 +98,12 @@ func (h *ToolHandler) GetToolBySlug(c *gin.Context) {
 		return
 	}
 	
-	// 返回工具
+	// 记录浏览，去重后定期批量写入
+	h.viewTracker.Track(service.ViewTargetTool, tool.ID, viewVisitor(c))
+	
+	// 返回工具，ETag中的版本号用于修改时的If-Match，浏览量不改变版本号
+	c.Header("ETag", utils.VersionETag(tool.Version))
+	utils.SetCacheValidator(c, tool.UpdatedAt, tool.Views)
 	utils.SuccessResponse(c, "获取工具成功", tool.ToResponse(true))
 }
 
@@ -194,10 +210,11 @@ func (h *ToolHandler) UpdateTool(c *gin.Context) {
 		return
 	}
 	
-	// 更新工具
+	// 更新工具，携带If-Match时只更新该版本
 	tool, err := h.toolService.UpdateTool(
 		c.Request.Context(),
 		uint(id),
+		c.GetUint("if_match_version"),
 		req.Name,
 		req.Description,
 		req.Icon,
@@ -208,15 +225,21 @@ func (h *ToolHandler) UpdateTool(c *gin.Context) {
 	)
 	
 	if err != nil {
+		if errors.Is(err, service.ErrVersionConflict) {
+			utils.PreconditionFailedResponse(c, err.Error())
+			return
+		}
 		utils.BadRequestResponse(c, "更新工具失败", err.Error())
 		return
 	}
 	
 	// 返回结果
+	c.Header("ETag", utils.VersionETag(tool.Version))
 	utils.SuccessResponse(c, "工具更新成功", gin.H{
-		"id":   tool.ID,
-		"name": tool.Name,
-		"slug": tool.Slug,
+		"id":      tool.ID,
+		"name":    tool.Name,
+		"slug":    tool.Slug,
+		"version": tool.Version,
 	})
 }
 
@@ -237,9 +260,13 @@ func (h *ToolHandler) DeleteTool(c *gin.Context) {
 		return
 	}
 	
-	// 删除工具
-	err = h.toolService.DeleteTool(c.Request.Context(), uint(id))
+	// 删除工具，携带If-Match时只删除该版本
+	err = h.toolService.DeleteTool(c.Request.Context(), uint(id), c.GetUint("if_match_version"))
 	if err != nil {
+		if errors.Is(err, service.ErrVersionConflict) {
+			utils.PreconditionFailedResponse(c, err.Error())
+			return
+		}
 		utils.BadRequestResponse(c, "删除工具失败", err.Error())
 		return
 	}
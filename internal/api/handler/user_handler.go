@@ -3,26 +3,29 @@ package handler
 import (
 	"Lin_studio/internal/service"
 	"Lin_studio/internal/utils"
+	"errors"
 
 	"github.com/gin-gonic/gin"
 )
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	userService service.UserService
+	userService         service.UserService
+	notificationService service.NotificationService
 }
 
 // NewUserHandler 创建用户处理器实例
-func NewUserHandler(userService service.UserService) *UserHandler {
+func NewUserHandler(userService service.UserService, notificationService service.NotificationService) *UserHandler {
 	return &UserHandler{
-		userService: userService,
+		userService:         userService,
+		notificationService: notificationService,
 	}
 }
 
 // GetProfile 获取当前用户信息
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	// 从上下文中获取用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		utils.UnauthorizedResponse(c, "未授权")
 		return
@@ -35,6 +38,14 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
+	// 附带未读通知数量
+	unread, err := h.notificationService.CountUnread(c.Request.Context(), user.ID)
+	if err != nil {
+		utils.InternalServerErrorResponse(c, "获取用户信息失败: "+err.Error())
+		return
+	}
+	user.UnreadNotifications = &unread
+
 	// 返回用户信息
 	utils.SuccessResponse(c, "获取用户信息成功", user)
 }
@@ -42,7 +53,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 // UpdateProfile 更新用户信息
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	// 从上下文中获取用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		utils.UnauthorizedResponse(c, "未授权")
 		return
@@ -81,7 +92,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 // UploadAvatar 上传用户头像
 func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	// 从上下文中获取用户ID
-	userID, exists := c.Get("userID")
+	userID, exists := c.Get("user_id")
 	if !exists {
 		utils.UnauthorizedResponse(c, "未授权")
 		return
@@ -97,6 +108,10 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	// 上传头像
 	avatarURL, err := h.userService.UploadAvatar(c.Request.Context(), userID.(uint), file)
 	if err != nil {
+		if errors.Is(err, utils.ErrInvalidUpload) {
+			utils.BadRequestResponse(c, "上传头像失败", err.Error())
+			return
+		}
 		utils.InternalServerErrorResponse(c, "上传头像失败: "+err.Error())
 		return
 	}
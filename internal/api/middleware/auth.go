@@ -72,4 +72,29 @@ func Optional() gin.HandlerFunc {
 		
 		c.Next()
 	}
-} 
\ No newline at end of file
+} 
+
+// TokenFromWebSocketProtocol 从Sec-WebSocket-Protocol请求头读取访问令牌并放入Authorization头，需放在JWTAuth之前
+// 浏览器建立WebSocket连接时无法设置Authorization，令牌放在子协议列表中bearer之后
+// 不通过查询参数传递令牌，避免令牌被写入访问日志
+func TokenFromWebSocketProtocol() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := websocketProtocolToken(c.GetHeader("Sec-WebSocket-Protocol")); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
+
+// websocketProtocolToken 返回子协议列表中紧跟在bearer之后的令牌
+func websocketProtocolToken(header string) string {
+	protocols := strings.Split(header, ",")
+	for i := 0; i+1 < len(protocols); i++ {
+		if strings.TrimSpace(protocols[i]) == utils.WebSocketTokenProtocol {
+			return strings.TrimSpace(protocols[i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTokenFromWebSocketProtocol 测试从子协议列表中读取访问令牌
+func TestTokenFromWebSocketProtocol(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/ws", TokenFromWebSocketProtocol(), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader("Authorization"))
+	})
+
+	get := func(headers map[string]string) string {
+		req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "Bearer a.b.c", get(map[string]string{"Sec-WebSocket-Protocol": "bearer, a.b.c"}))
+	// 已有Authorization时不覆盖
+	assert.Equal(t, "Bearer x", get(map[string]string{"Sec-WebSocket-Protocol": "bearer, a.b.c", "Authorization": "Bearer x"}))
+	// 没有bearer或bearer后没有令牌
+	assert.Empty(t, get(map[string]string{"Sec-WebSocket-Protocol": "chat, a.b.c"}))
+	assert.Empty(t, get(map[string]string{"Sec-WebSocket-Protocol": "bearer"}))
+}
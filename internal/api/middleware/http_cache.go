@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPCache 公开读取接口的HTTP缓存中间件，只处理GET和HEAD请求
+// 按路由组的策略设置Cache-Control和Vary；处理函数通过utils.SetCacheValidator记录内容的最后修改时间后，
+// 生成ETag和Last-Modified，处理函数设置了版本ETag时生成同时包含版本号和校验数据的ETag，满足If-None-Match或If-Modified-Since时返回304
+// 携带Authorization的请求可能包含个人数据，Cache-Control改为private, no-cache
+func HTTPCache(policy config.CachePolicy) gin.HandlerFunc {
+	fields := make([]string, 0, len(policy.Vary))
+	for _, field := range policy.Vary {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	vary := strings.Join(fields, ", ")
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		cacheControl := policy.CacheControl
+		if c.GetHeader("Authorization") != "" {
+			cacheControl = "private, no-cache"
+		}
+		if cacheControl != "" {
+			c.Header("Cache-Control", cacheControl)
+		}
+		if vary != "" {
+			c.Header("Vary", vary)
+		}
+
+		writer := &conditionalWriter{ResponseWriter: c.Writer, c: c}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+	}
+}
+
+// conditionalWriter 在写入响应体前检查条件请求，满足时改为304并丢弃响应体
+type conditionalWriter struct {
+	gin.ResponseWriter
+	c           *gin.Context
+	checked     bool
+	notModified bool
+}
+
+// Write 写入响应体
+func (w *conditionalWriter) Write(data []byte) (int, error) {
+	w.check()
+	if w.notModified {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写入字符串响应体
+func (w *conditionalWriter) WriteString(s string) (int, error) {
+	w.check()
+	if w.notModified {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// WriteHeaderNow 立即写入响应头
+func (w *conditionalWriter) WriteHeaderNow() {
+	w.check()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// check 第一次写入时设置校验头并判断是否返回304，只处理200响应
+func (w *conditionalWriter) check() {
+	if w.checked {
+		return
+	}
+	w.checked = true
+	if w.Status() != http.StatusOK {
+		return
+	}
+	validator, ok := utils.GetCacheValidator(w.c)
+	if !ok {
+		return
+	}
+
+	// 版本号不随点赞、评论等统计数量变化，不能单独用于If-None-Match
+	header := w.Header()
+	key := w.c.Request.URL.RequestURI()
+	etag := header.Get("ETag")
+	if version, ok := utils.ParseVersionETag(etag); ok {
+		etag = validator.VersionETag(version, key)
+		header.Set("ETag", etag)
+	} else if etag == "" {
+		etag = validator.ETag(key)
+		header.Set("ETag", etag)
+	}
+	lastModified := validator.LastModified.UTC().Truncate(time.Second)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if !notModified(w.c.Request, etag, lastModified) {
+		return
+	}
+	w.notModified = true
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(http.StatusNotModified)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// notModified 判断条件请求是否可以返回304，同时携带两个条件时以If-None-Match为准
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagListMatch(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !lastModified.After(since)
+	}
+	return false
+}
+
+// etagListMatch 按弱比较判断If-None-Match中是否有与etag相同的值
+func etagListMatch(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
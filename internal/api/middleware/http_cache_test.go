@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPCacheConditionalGet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	updatedAt := time.Date(2025, 3, 1, 8, 30, 15, 500, time.UTC)
+
+	r := gin.New()
+	r.Use(HTTPCache(config.CachePolicy{CacheControl: "public, max-age=60", Vary: []string{"Accept-Encoding", " Authorization"}}))
+	r.GET("/tags", func(c *gin.Context) {
+		utils.SetCacheValidator(c, updatedAt, 3)
+		utils.SuccessResponse(c, "ok", gin.H{"count": 3})
+	})
+	r.GET("/tags/1", func(c *gin.Context) {
+		c.Header("ETag", utils.VersionETag(2))
+		utils.SetCacheValidator(c, updatedAt)
+		utils.SuccessResponse(c, "ok", nil)
+	})
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("/tags", nil)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "public, max-age=60", first.Header().Get("Cache-Control"))
+	assert.Equal(t, "Accept-Encoding, Authorization", first.Header().Get("Vary"))
+	assert.Equal(t, "Sat, 01 Mar 2025 08:30:15 GMT", first.Header().Get("Last-Modified"))
+	etag := first.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// ETag相同时返回304且没有响应体
+	cached := get("/tags", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, cached.Code)
+	assert.Empty(t, cached.Body.String())
+	assert.Equal(t, etag, cached.Header().Get("ETag"))
+
+	// 查询参数不同时是不同的表示
+	assert.NotEqual(t, etag, get("/tags?render_html=true", nil).Header().Get("ETag"))
+
+	// 按最后修改时间判断
+	assert.Equal(t, http.StatusNotModified, get("/tags", map[string]string{"If-Modified-Since": "Sat, 01 Mar 2025 08:30:15 GMT"}).Code)
+	assert.Equal(t, http.StatusOK, get("/tags", map[string]string{"If-Modified-Since": "Sat, 01 Mar 2025 08:30:14 GMT"}).Code)
+
+	// 处理函数设置版本ETag时，ETag包含版本号和校验数据，If-None-Match按弱比较
+	detailETag := get("/tags/1", nil).Header().Get("ETag")
+	version, ok := utils.ParseVersionETag(detailETag)
+	assert.True(t, ok, detailETag)
+	assert.Equal(t, uint(2), version)
+	detail := get("/tags/1", map[string]string{"If-None-Match": "W/" + detailETag})
+	assert.Equal(t, http.StatusNotModified, detail.Code)
+	assert.Equal(t, detailETag, detail.Header().Get("ETag"))
+	// 只有版本号的ETag不满足If-None-Match
+	assert.Equal(t, http.StatusOK, get("/tags/1", map[string]string{"If-None-Match": `"v2"`}).Code)
+
+	// 登录用户的响应不允许共享缓存
+	assert.Equal(t, "private, no-cache", get("/tags", map[string]string{"Authorization": "Bearer x"}).Header().Get("Cache-Control"))
+}
+
+// TestHTTPCacheVersionETagCounters 测试版本号不变但统计数量变化时不返回304，If-Match仍按版本号匹配
+func TestHTTPCacheVersionETagCounters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	updatedAt := time.Date(2025, 3, 1, 8, 30, 15, 0, time.UTC)
+	likes := 1
+
+	r := gin.New()
+	r.Use(HTTPCache(config.CachePolicy{CacheControl: "public, max-age=60"}))
+	r.GET("/articles/go", func(c *gin.Context) {
+		c.Header("ETag", utils.VersionETag(3))
+		utils.SetCacheValidator(c, updatedAt, likes)
+		utils.SuccessResponse(c, "ok", gin.H{"likes": likes})
+	})
+	r.PUT("/articles/go", IfMatch(true), func(c *gin.Context) {
+		utils.SuccessResponse(c, "ok", c.GetUint("if_match_version"))
+	})
+
+	serve := func(method string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/articles/go", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	etag := serve(http.MethodGet, nil).Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, serve(http.MethodGet, map[string]string{"If-None-Match": etag}).Code)
+
+	// 点赞后版本号不变，旧的ETag不再满足If-None-Match
+	likes = 2
+	fresh := serve(http.MethodGet, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, fresh.Code)
+	assert.Contains(t, fresh.Body.String(), `"likes":2`)
+	assert.NotEqual(t, etag, fresh.Header().Get("ETag"))
+
+	// 旧的ETag仍可用于If-Match，版本号没有变化
+	updated := serve(http.MethodPut, map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusOK, updated.Code)
+	assert.Contains(t, updated.Body.String(), `"data":3`)
+}
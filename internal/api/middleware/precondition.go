@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"Lin_studio/internal/utils"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IfMatch 解析If-Match请求头中的版本号并放入上下文的if_match_version，处理函数据此检查版本
+// If-Match为*或未提供时不检查版本；required为true时未提供If-Match的请求返回428
+func IfMatch(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := strings.TrimSpace(c.GetHeader("If-Match"))
+		if header == "" {
+			if required {
+				utils.ErrorResponse(c, http.StatusPreconditionRequired, "请求需要携带If-Match请求头", nil)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if header == "*" {
+			c.Next()
+			return
+		}
+		if strings.Contains(header, ",") {
+			utils.BadRequestResponse(c, "If-Match只支持单个ETag", nil)
+			c.Abort()
+			return
+		}
+
+		version, ok := utils.ParseVersionETag(header)
+		if !ok {
+			// 不是由本服务生成的ETag，不可能与当前版本匹配
+			utils.PreconditionFailedResponse(c, "If-Match与当前版本不匹配")
+			c.Abort()
+			return
+		}
+		c.Set("if_match_version", version)
+		c.Next()
+	}
+}
@@ -20,6 +20,15 @@ func SetupRouter(
 	articleHandler *handler.ArticleHandler,
 	commentHandler *handler.CommentHandler,
 	toolHandler *handler.ToolHandler,
+	ogHandler *handler.OGHandler,
+	mediaHandler *handler.MediaHandler,
+	fileHandler *handler.FileHandler,
+	emailHandler *handler.EmailHandler,
+	notificationHandler *handler.NotificationHandler,
+	realtimeHandler *handler.RealtimeHandler,
+	editingHandler *handler.EditingHandler,
+	analyticsHandler *handler.AnalyticsHandler,
+	seriesHandler *handler.SeriesHandler,
 	// 其他处理器...
 ) *gin.Engine {
 	r := gin.Default()
@@ -42,9 +51,21 @@ func SetupRouter(
 		})
 	})
 
+	// Open Graph分享图
+	r.GET("/og/articles/:file", ogHandler.GetArticleImage)
+
+	// 上传文件
+	r.GET("/uploads/*filepath", fileHandler.ServeUpload)
+	r.HEAD("/uploads/*filepath", fileHandler.ServeUpload)
+
 	// API版本前缀
 	api := r.Group("/api/v1")
 
+	// 修改和删除时检查If-Match中的版本号
+	ifMatch := middleware.IfMatch(config.GetConfig().Concurrency.RequireIfMatch)
+	// 公开读取接口的缓存策略
+	cachePolicies := config.GetConfig().HTTPCache
+
 	// 身份验证路由
 	auth := api.Group("/auth")
 	{
@@ -66,10 +87,32 @@ func SetupRouter(
 		users.GET("/profile", middleware.JWTAuth(), userHandler.GetProfile)
 		users.PUT("/profile", middleware.JWTAuth(), userHandler.UpdateProfile)
 		users.POST("/avatar", middleware.JWTAuth(), userHandler.UploadAvatar)
+		users.GET("/email-preferences", middleware.JWTAuth(), emailHandler.GetPreferences)
+		users.PUT("/email-preferences", middleware.JWTAuth(), emailHandler.UpdatePreferences)
+	}
+
+	// 站内通知路由
+	notifications := api.Group("/notifications")
+	{
+		notifications.GET("", middleware.JWTAuth(), notificationHandler.GetNotifications)
+		notifications.POST("/read", middleware.JWTAuth(), notificationHandler.MarkRead)
+		notifications.POST("/read-all", middleware.JWTAuth(), notificationHandler.MarkAllRead)
+	}
+
+	// 实时推送，订阅用户和审核队列主题时需要登录
+	api.GET("/events", middleware.Optional(), realtimeHandler.Stream)
+
+	// 邮件退订和邮箱确认，链接中带有签名，不需要登录
+	email := api.Group("/email")
+	{
+		email.GET("/unsubscribe", emailHandler.ConfirmUnsubscribe)
+		email.POST("/unsubscribe", emailHandler.Unsubscribe)
+		email.GET("/confirm", emailHandler.ConfirmEmailPage)
+		email.POST("/confirm", emailHandler.ConfirmEmail)
 	}
 
 	// 分类路由
-	categories := api.Group("/categories")
+	categories := api.Group("/categories", middleware.HTTPCache(cachePolicies.Categories))
 	{
 		// 公开路由
 		categories.GET("", categoryHandler.GetAllCategories)
@@ -81,7 +124,7 @@ func SetupRouter(
 	}
 
 	// 标签路由
-	tags := api.Group("/tags")
+	tags := api.Group("/tags", middleware.HTTPCache(cachePolicies.Tags))
 	{
 		// 公开路由
 		tags.GET("", tagHandler.GetAllTags)
@@ -93,43 +136,71 @@ func SetupRouter(
 	}
 
 	// 文章路由
-	articles := api.Group("/articles")
+	articles := api.Group("/articles", middleware.HTTPCache(cachePolicies.Articles))
 	{
 		// 公开路由
 		articles.GET("", articleHandler.GetArticles)
-		articles.GET("/:slug", articleHandler.GetArticleBySlug)
+		articles.GET("/:slug", middleware.Optional(), articleHandler.GetArticleBySlug)
 		articles.GET("/featured", articleHandler.GetFeaturedArticles)
-		articles.POST("/like/:id", articleHandler.LikeArticle)
+		articles.GET("/:slug/related", articleHandler.GetRelatedArticles)
+		articles.POST("/like/:id", middleware.Optional(), articleHandler.LikeArticle)
+		articles.POST("/:id/reactions", middleware.Optional(), articleHandler.ReactToArticle)
 
 		// 需要认证的路由
 		articles.POST("", middleware.JWTAuth(), articleHandler.CreateArticle)
-		articles.PUT("/:id", middleware.JWTAuth(), articleHandler.UpdateArticle)
-		articles.DELETE("/:id", middleware.JWTAuth(), articleHandler.DeleteArticle)
+		articles.PUT("/:id", middleware.JWTAuth(), ifMatch, articleHandler.UpdateArticle)
+		articles.DELETE("/:id", middleware.JWTAuth(), ifMatch, articleHandler.DeleteArticle)
 		articles.POST("/upload-cover", middleware.JWTAuth(), articleHandler.UploadCoverImage)
+		// 协同编辑的WebSocket连接，浏览器无法设置请求头，令牌通过Sec-WebSocket-Protocol传入
+		articles.GET("/editing/:id", middleware.TokenFromWebSocketProtocol(), middleware.JWTAuth(), middleware.RequireEditor(), editingHandler.Connect)
+	}
+
+	// 媒体库路由
+	media := api.Group("/media")
+	{
+		// 需要认证的路由
+		media.GET("", middleware.JWTAuth(), mediaHandler.GetMediaList)
+		media.POST("", middleware.JWTAuth(), mediaHandler.UploadMedia)
+
+		// 需要管理员权限的路由
+		media.DELETE("/:id", middleware.JWTAuth(), middleware.RequireAdmin(), mediaHandler.DeleteMedia)
 	}
 
 	// 评论路由
 	comments := api.Group("/comments")
 	{
 		// 公开路由
-		comments.GET("", commentHandler.GetComments)
-		comments.GET("/:id", commentHandler.GetCommentByID)
-		comments.POST("/like/:id", commentHandler.LikeComment)
+		comments.GET("", middleware.Optional(), commentHandler.GetComments)
+		comments.GET("/tree", middleware.Optional(), commentHandler.GetCommentTree)
+		comments.GET("/form-token", commentHandler.GetFormToken)
+		comments.GET("/:id", middleware.Optional(), commentHandler.GetCommentByID)
+		comments.GET("/:id/replies", middleware.Optional(), commentHandler.GetReplies)
+		comments.POST("/like/:id", middleware.Optional(), commentHandler.LikeComment)
+		comments.POST("/:id/reactions", middleware.Optional(), commentHandler.ReactToComment)
 		
 		// 创建评论 - 可以是登录用户，也可以是匿名用户
-		comments.POST("", commentHandler.CreateComment)
+		comments.POST("", middleware.Optional(), commentHandler.CreateComment)
 		
 		// 需要认证的路由
 		comments.PUT("/:id", middleware.JWTAuth(), commentHandler.UpdateComment)
+		comments.GET("/:id/revisions", middleware.JWTAuth(), commentHandler.GetCommentRevisions)
 		comments.DELETE("/:id", middleware.JWTAuth(), commentHandler.DeleteComment)
 		
 		// 需要管理员权限的路由
 		comments.PUT("/:id/approve", middleware.JWTAuth(), middleware.RequireAdmin(), commentHandler.ApproveComment)
 		comments.PUT("/:id/spam", middleware.JWTAuth(), middleware.RequireAdmin(), commentHandler.MarkCommentAsSpam)
+
+		// 审核队列
+		moderation := comments.Group("/moderation", middleware.JWTAuth(), middleware.RequireAdmin())
+		{
+			moderation.GET("", commentHandler.GetModerationQueue)
+			moderation.POST("/bulk", commentHandler.BulkModerate)
+			moderation.GET("/logs", commentHandler.GetModerationLogs)
+		}
 	}
 
 	// 工具路由
-	tools := api.Group("/tools")
+	tools := api.Group("/tools", middleware.HTTPCache(cachePolicies.Tools))
 	{
 		// 公开路由
 		tools.GET("", toolHandler.GetTools)
@@ -138,8 +209,31 @@ func SetupRouter(
 		
 		// 需要管理员权限的路由
 		tools.POST("", middleware.JWTAuth(), middleware.RequireAdmin(), toolHandler.CreateTool)
-		tools.PUT("/:id", middleware.JWTAuth(), middleware.RequireAdmin(), toolHandler.UpdateTool)
-		tools.DELETE("/:id", middleware.JWTAuth(), middleware.RequireAdmin(), toolHandler.DeleteTool)
+		tools.PUT("/:id", middleware.JWTAuth(), middleware.RequireAdmin(), ifMatch, toolHandler.UpdateTool)
+		tools.DELETE("/:id", middleware.JWTAuth(), middleware.RequireAdmin(), ifMatch, toolHandler.DeleteTool)
+	}
+
+	// 系列路由，编辑者登录后可以看到未发布的文章
+	series := api.Group("/series", middleware.HTTPCache(cachePolicies.Series))
+	{
+		// 公开路由
+		series.GET("", seriesHandler.GetAllSeries)
+		series.GET("/:id", middleware.Optional(), seriesHandler.GetSeriesByID)
+		series.GET("/slug/:slug", middleware.Optional(), seriesHandler.GetSeriesBySlug)
+
+		// 需要编辑者权限的路由
+		series.POST("", middleware.JWTAuth(), middleware.RequireEditor(), seriesHandler.CreateSeries)
+		series.PUT("/:id", middleware.JWTAuth(), middleware.RequireEditor(), ifMatch, seriesHandler.UpdateSeries)
+		series.DELETE("/:id", middleware.JWTAuth(), middleware.RequireEditor(), ifMatch, seriesHandler.DeleteSeries)
+	}
+
+	// 内容统计路由，只有管理员可以查看
+	analytics := api.Group("/analytics", middleware.JWTAuth(), middleware.RequireAdmin())
+	{
+		analytics.GET("/series", analyticsHandler.GetSeries)
+		analytics.GET("/top", analyticsHandler.GetTopItems)
+		analytics.GET("/referrers", analyticsHandler.GetTopReferrers)
+		analytics.GET("/sources", analyticsHandler.GetSources)
 	}
 
 	return r
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"Lin_studio/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrMiss 缓存不存在或已过期
+var ErrMiss = errors.New("缓存不存在")
+
+// 实体标签，写入实体后按标签清除依赖它的缓存
+const (
+	TagArticle  = "article"
+	TagCategory = "category"
+	TagTag      = "tag"
+	TagSeries   = "series"
+	TagRelated  = "related" // 相关文章，后台重新计算后清除
+)
+
+// Cache 缓存接口，每条缓存可以关联多个标签，按标签批量清除
+type Cache interface {
+	// Get 读取缓存，不存在或已过期时返回ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入缓存并关联标签，ttl小于等于0时使用默认有效期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error
+	// Delete 删除缓存，不存在时不返回错误
+	Delete(ctx context.Context, keys ...string) error
+	// Invalidate 删除关联了任一标签的缓存
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// New 根据配置创建缓存实例
+func New(cfg config.CacheConfig) (Cache, error) {
+	switch cfg.Driver {
+	case "", "memory":
+		return NewMemoryCache(cfg.MaxEntries, cfg.DefaultTTL), nil
+	case "redis":
+		return NewRedisCache(cfg.Redis, cfg.DefaultTTL)
+	default:
+		return nil, fmt.Errorf("不支持的缓存驱动: %s", cfg.Driver)
+	}
+}
+
+// Remember 读取JSON编码的缓存，不存在时调用load加载并写入缓存
+// 缓存读写失败只记录日志，不影响返回load的结果
+func Remember[T any](ctx context.Context, c Cache, key string, ttl time.Duration, tags []string, load func() (T, error)) (T, error) {
+	if data, err := c.Get(ctx, key); err == nil {
+		var value T
+		if err := json.Unmarshal(data, &value); err == nil {
+			return value, nil
+		}
+		log.Printf("解析缓存 %s 失败: %v", key, err)
+	} else if !errors.Is(err, ErrMiss) {
+		log.Printf("读取缓存 %s 失败: %v", key, err)
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("编码缓存 %s 失败: %v", key, err)
+		return value, nil
+	}
+	if err := c.Set(ctx, key, data, ttl, tags...); err != nil {
+		log.Printf("写入缓存 %s 失败: %v", key, err)
+	}
+	return value, nil
+}
+
+// InvalidateLogged 按标签清除缓存，失败只记录日志，用于写入成功后的清理
+func InvalidateLogged(ctx context.Context, c Cache, tags ...string) {
+	if err := c.Invalidate(ctx, tags...); err != nil {
+		log.Printf("清除缓存标签 %v 失败: %v", tags, err)
+	}
+}
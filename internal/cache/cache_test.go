@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"Lin_studio/internal/config"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis 模拟Redis服务，只支持缓存用到的命令
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu      sync.Mutex
+	strings map[string]string
+	expires map[string]time.Time
+	sets    map[string]map[string]struct{}
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{
+		listener: listener,
+		password: password,
+		strings:  make(map[string]string),
+		expires:  make(map[string]time.Time),
+		sets:     make(map[string]map[string]struct{}),
+	}
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		if cmd == "AUTH" {
+			if args[1] != f.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+			continue
+		}
+		if !authed {
+			fmt.Fprint(conn, "-NOAUTH Authentication required\r\n")
+			continue
+		}
+		fmt.Fprint(conn, f.exec(cmd, args[1:]))
+	}
+}
+
+func (f *fakeRedis) exec(cmd string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch cmd {
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.strings[args[0]]
+		if !ok || time.Now().After(f.expires[args[0]]) {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		ms, _ := strconv.Atoi(args[3])
+		f.strings[args[0]] = args[1]
+		f.expires[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args {
+			if _, ok := f.strings[key]; ok {
+				n++
+			}
+			if _, ok := f.sets[key]; ok {
+				n++
+			}
+			delete(f.strings, key)
+			delete(f.sets, key)
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "SADD":
+		set, ok := f.sets[args[0]]
+		if !ok {
+			set = make(map[string]struct{})
+			f.sets[args[0]] = set
+		}
+		for _, member := range args[1:] {
+			set[member] = struct{}{}
+		}
+		return ":1\r\n"
+	case "SREM":
+		for _, member := range args[1:] {
+			delete(f.sets[args[0]], member)
+		}
+		return ":1\r\n"
+	case "SMEMBERS":
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%d\r\n", len(f.sets[args[0]]))
+		for member := range f.sets[args[0]] {
+			fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(member), member)
+		}
+		return b.String()
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+// testCacheTags 测试按标签清除，两种驱动的行为相同
+func testCacheTags(t *testing.T, c Cache) {
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "tags:all", []byte(`["go"]`), 0, TagTag, TagArticle))
+	require.NoError(t, c.Set(ctx, "categories:all", []byte(`["dev"]`), 0, TagCategory, TagArticle))
+
+	value, err := c.Get(ctx, "tags:all")
+	require.NoError(t, err)
+	assert.Equal(t, `["go"]`, string(value))
+
+	// 修改标签只清除标签列表
+	require.NoError(t, c.Invalidate(ctx, TagTag))
+	_, err = c.Get(ctx, "tags:all")
+	assert.ErrorIs(t, err, ErrMiss)
+	_, err = c.Get(ctx, "categories:all")
+	assert.NoError(t, err)
+
+	// 修改文章清除所有依赖文章的缓存
+	require.NoError(t, c.Invalidate(ctx, TagArticle))
+	_, err = c.Get(ctx, "categories:all")
+	assert.ErrorIs(t, err, ErrMiss)
+
+	require.NoError(t, c.Set(ctx, "featured", []byte(`[]`), 0))
+	require.NoError(t, c.Delete(ctx, "featured"))
+	_, err = c.Get(ctx, "featured")
+	assert.ErrorIs(t, err, ErrMiss)
+}
+
+func TestMemoryCache(t *testing.T) {
+	testCacheTags(t, NewMemoryCache(10, time.Minute))
+
+	// 过期和条数上限
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewMemoryCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	require.NoError(t, c.Set(ctx, "a", []byte("1"), 0, TagTag))
+	require.NoError(t, c.Set(ctx, "b", []byte("2"), 10*time.Second))
+	_, err := c.Get(ctx, "a") // a最近被使用，写入c时淘汰b
+	require.NoError(t, err)
+	require.NoError(t, c.Set(ctx, "c", []byte("3"), 0))
+	assert.Equal(t, 2, c.Len())
+	_, err = c.Get(ctx, "b")
+	assert.ErrorIs(t, err, ErrMiss)
+
+	now = now.Add(2 * time.Minute)
+	_, err = c.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrMiss)
+	assert.Empty(t, c.tags, "淘汰的记录应从标签索引中移除")
+}
+
+func TestRedisCache(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+	c, err := NewRedisCache(config.RedisConfig{Addr: server.listener.Addr().String(), Password: "secret", DB: 1, Prefix: "test:"}, time.Minute)
+	require.NoError(t, err)
+	defer c.Close()
+
+	testCacheTags(t, c)
+	assert.Empty(t, server.sets["test:tag:"+TagArticle], "清除后标签集合中不应保留已删除的键")
+
+	// 密码错误时返回错误回复
+	bad, err := NewRedisCache(config.RedisConfig{Addr: server.listener.Addr().String(), Password: "wrong"}, time.Minute)
+	require.NoError(t, err)
+	_, err = bad.Get(context.Background(), "tags:all")
+	var replyErr redisError
+	assert.True(t, errors.As(err, &replyErr))
+}
+
+func TestRemember(t *testing.T) {
+	c := NewMemoryCache(10, time.Minute)
+	ctx := context.Background()
+	loads := 0
+	load := func() ([]string, error) {
+		loads++
+		return []string{"go", "gin"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		value, err := Remember(ctx, c, "tags:all", 0, []string{TagTag}, load)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"go", "gin"}, value)
+	}
+	assert.Equal(t, 1, loads)
+
+	InvalidateLogged(ctx, c, TagTag)
+	_, err := Remember(ctx, c, "tags:all", 0, []string{TagTag}, load)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loads)
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryEntry 内存缓存中的一条记录
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	tags      []string
+}
+
+// MemoryCache 进程内缓存，超过条数上限时淘汰最久未使用的记录
+type MemoryCache struct {
+	maxEntries int
+	defaultTTL time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // 最近使用的记录在前
+	entries map[string]*list.Element
+	tags    map[string]map[string]struct{} // 标签到缓存键的索引
+}
+
+// NewMemoryCache 创建内存缓存实例，maxEntries小于等于0时不限制条数
+func NewMemoryCache(maxEntries int, defaultTTL time.Duration) *MemoryCache {
+	if defaultTTL <= 0 {
+		defaultTTL = 5 * time.Minute
+	}
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		defaultTTL: defaultTTL,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+	}
+}
+
+// Get 读取缓存
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !m.now().Before(entry.expiresAt) {
+		m.remove(elem)
+		return nil, ErrMiss
+	}
+	m.order.MoveToFront(elem)
+	return entry.value, nil
+}
+
+// Set 写入缓存
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if ttl <= 0 {
+		ttl = m.defaultTTL
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.entries[key]; ok {
+		m.remove(elem)
+	}
+	entry := &memoryEntry{
+		key:       key,
+		value:     value,
+		expiresAt: m.now().Add(ttl),
+		tags:      tags,
+	}
+	m.entries[key] = m.order.PushFront(entry)
+	for _, tag := range tags {
+		keys, ok := m.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			m.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return nil
+}
+
+// Delete 删除缓存
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if elem, ok := m.entries[key]; ok {
+			m.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Invalidate 删除关联了任一标签的缓存
+func (m *MemoryCache) Invalidate(ctx context.Context, tags ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			if elem, ok := m.entries[key]; ok {
+				m.remove(elem)
+			}
+		}
+		delete(m.tags, tag)
+	}
+	return nil
+}
+
+// Len 返回当前缓存条数，包含尚未清理的过期记录
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// remove 删除记录并更新标签索引，调用方需持有锁
+func (m *MemoryCache) remove(elem *list.Element) {
+	entry := m.order.Remove(elem).(*memoryEntry)
+	delete(m.entries, entry.key)
+	for _, tag := range entry.tags {
+		if keys, ok := m.tags[tag]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(m.tags, tag)
+			}
+		}
+	}
+}
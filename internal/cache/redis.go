@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"Lin_studio/internal/config"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// redisError Redis返回的错误回复，连接仍然可用
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisConn 一个Redis连接
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// RedisCache 使用RESP协议访问Redis兼容服务的缓存
+// 每个标签对应一个集合，保存关联的缓存键，按标签清除时删除集合中的所有键
+type RedisCache struct {
+	cfg        config.RedisConfig
+	defaultTTL time.Duration
+	pool       chan *redisConn
+}
+
+// NewRedisCache 创建Redis缓存实例，连接在第一次使用时建立
+func NewRedisCache(cfg config.RedisConfig, defaultTTL time.Duration) (*RedisCache, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("Redis缓存需要配置地址")
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 4
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Second
+	}
+	if defaultTTL <= 0 {
+		defaultTTL = 5 * time.Minute
+	}
+	return &RedisCache{
+		cfg:        cfg,
+		defaultTTL: defaultTTL,
+		pool:       make(chan *redisConn, cfg.PoolSize),
+	}, nil
+}
+
+// Get 读取缓存
+func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	replies, err := r.pipeline(ctx, []string{"GET", r.cfg.Prefix + key})
+	if err != nil {
+		return nil, err
+	}
+	if replies[0] == nil {
+		return nil, ErrMiss
+	}
+	value, ok := replies[0].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("redis: GET返回了意外的类型 %T", replies[0])
+	}
+	return value, nil
+}
+
+// Set 写入缓存并把键加入标签集合
+func (r *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags ...string) error {
+	if ttl <= 0 {
+		ttl = r.defaultTTL
+	}
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+
+	key = r.cfg.Prefix + key
+	cmds := [][]string{{"SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10)}}
+	for _, tag := range tags {
+		cmds = append(cmds, []string{"SADD", r.tagKey(tag), key})
+	}
+	_, err := r.pipeline(ctx, cmds...)
+	return err
+}
+
+// Delete 删除缓存
+func (r *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	cmd := []string{"DEL"}
+	for _, key := range keys {
+		cmd = append(cmd, r.cfg.Prefix+key)
+	}
+	_, err := r.pipeline(ctx, cmd)
+	return err
+}
+
+// Invalidate 删除标签集合中的所有缓存键
+// 只从集合中移除读到的键，清除期间新写入的缓存仍留在集合中，下次清除时会被删除
+func (r *RedisCache) Invalidate(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	cmds := make([][]string, 0, len(tags))
+	for _, tag := range tags {
+		cmds = append(cmds, []string{"SMEMBERS", r.tagKey(tag)})
+	}
+	replies, err := r.pipeline(ctx, cmds...)
+	if err != nil {
+		return err
+	}
+
+	del := []string{"DEL"}
+	cmds = cmds[:0]
+	for i, tag := range tags {
+		members, _ := replies[i].([]interface{})
+		if len(members) == 0 {
+			continue
+		}
+		srem := []string{"SREM", r.tagKey(tag)}
+		for _, member := range members {
+			if key, ok := member.([]byte); ok {
+				del = append(del, string(key))
+				srem = append(srem, string(key))
+			}
+		}
+		cmds = append(cmds, srem)
+	}
+	if len(del) == 1 {
+		return nil
+	}
+	_, err = r.pipeline(ctx, append([][]string{del}, cmds...)...)
+	return err
+}
+
+// tagKey 返回标签集合的键
+func (r *RedisCache) tagKey(tag string) string {
+	return r.cfg.Prefix + "tag:" + tag
+}
+
+// pipeline 依次发送命令后读取全部回复，任一命令返回错误回复时返回该错误
+func (r *RedisCache) pipeline(ctx context.Context, cmds ...[]string) ([]interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := conn.do(ctx, r.cfg.Timeout, cmds...)
+	r.put(conn, err)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redisError); ok {
+			return nil, replyErr
+		}
+	}
+	return replies, nil
+}
+
+// get 从连接池取出连接，池中没有空闲连接时新建
+func (r *RedisCache) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.cfg.Timeout}
+	c, err := dialer.DialContext(ctx, "tcp", r.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}
+
+	var cmds [][]string
+	if r.cfg.Password != "" {
+		cmds = append(cmds, []string{"AUTH", r.cfg.Password})
+	}
+	if r.cfg.DB > 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.Itoa(r.cfg.DB)})
+	}
+	if len(cmds) > 0 {
+		replies, err := conn.do(ctx, r.cfg.Timeout, cmds...)
+		if err == nil {
+			for _, reply := range replies {
+				if replyErr, ok := reply.(redisError); ok {
+					err = replyErr
+				}
+			}
+		}
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// put 归还连接，出现网络错误或连接池已满时关闭连接
+func (r *RedisCache) put(conn *redisConn, err error) {
+	if err != nil {
+		conn.conn.Close()
+		return
+	}
+	select {
+	case r.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// Close 关闭连接池中的连接
+func (r *RedisCache) Close() error {
+	for {
+		select {
+		case conn := <-r.pool:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do 发送命令并读取同样数量的回复
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, cmds ...[]string) ([]interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	for _, cmd := range cmds {
+		fmt.Fprintf(c.w, "*%d\r\n", len(cmd))
+		for _, arg := range cmd {
+			fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := readReply(c.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// readReply 读取一个RESP回复
+// 简单字符串返回string，错误返回redisError，整数返回int64，批量字符串返回[]byte，数组返回[]interface{}，空值返回nil
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: 无效的回复")
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return redisError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:size], nil
+	case '*':
+		count, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: 未知的回复类型 %q", kind)
+	}
+}
@@ -1,18 +1,36 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Upload   UploadConfig
-	CORS     CORSConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	JWT         JWTConfig
+	Upload      UploadConfig
+	CORS        CORSConfig
+	OG          OGConfig
+	Comment     CommentConfig
+	Spam        SpamConfig
+	Reaction    ReactionConfig
+	Mail        MailConfig
+	Realtime    RealtimeConfig
+	Editing     EditingConfig
+	Concurrency ConcurrencyConfig
+	HTTPCache   HTTPCacheConfig
+	Cache       CacheConfig
+	Views       ViewConfig
+	Analytics   AnalyticsConfig
 }
 
 // ServerConfig 服务器配置
@@ -45,6 +63,148 @@ type UploadConfig struct {
 	UploadDir    string
 	MaxSize      int64
 	AllowedTypes []string
+	MaxWidth     int      // 图片最大宽度(像素)
+	MaxHeight    int      // 图片最大高度(像素)
+	MaxPixels    int64    // 图片最大像素总数，防止解压炸弹
+	Driver       string   // 存储驱动: local 或 s3
+	PublicURL    string   // 文件公开访问地址前缀
+	S3           S3Config // S3兼容存储配置，Driver为s3时生效
+	VariantWidths []int   // 图片缩略版本的宽度列表
+	ImageWorkers  int     // 图片处理后台任务并发数
+	SignDrafts    bool          // 草稿文章使用的文件需要签名URL才能访问
+	SigningSecret string        // 文件URL签名密钥
+	SignedURLTTL  time.Duration // 签名URL有效期
+}
+
+// S3Config S3兼容对象存储配置
+type S3Config struct {
+	Endpoint  string // 例如 https://s3.amazonaws.com 或 http://minio:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // 使用路径风格访问(endpoint/bucket/key)，MinIO等通常需要开启
+}
+
+// OGConfig Open Graph分享图配置
+type OGConfig struct {
+	SiteName     string // 分享图右下角显示的站点名称
+	TemplatePath string // 背景模板图片路径，为空时使用默认渐变背景
+	FontPath     string // 标题字体(TTF/OTF)路径，中文标题需要配置支持CJK的字体
+}
+
+// CommentConfig 评论配置
+type CommentConfig struct {
+	MaxDepth       int // 评论最大嵌套层级，顶级评论为第0层
+	TreeDepth      int // 评论树默认展开的回复层级
+	RepliesPerNode int // 评论树中每条评论默认展开的回复数量
+	EditWindow     time.Duration // 发表后允许作者修改评论的时长，超过后只有管理员可以修改，0表示不限制
+	MentionURL     string        // 被@提及用户的个人主页地址，{username}会被替换为用户名
+}
+
+// SpamConfig 匿名评论反垃圾配置
+type SpamConfig struct {
+	TokenSecret   string        // 评论表单令牌签名密钥
+	MinSubmitTime time.Duration // 获取表单令牌后最短的提交间隔，过快提交视为机器人
+	TokenMaxAge   time.Duration // 表单令牌有效期
+	MaxLinks      int           // 评论中允许的链接数量，超出部分计分
+	Blocklist     []string      // 屏蔽词，匹配评论内容、昵称、邮箱和IP
+	ApproveScore  float64       // 分数低于该值时自动批准
+	RejectScore   float64       // 分数不低于该值时直接标记为垃圾信息
+	BayesMinTrain int64         // 垃圾和正常评论的训练样本都达到该数量后才启用贝叶斯分类
+}
+
+// ReactionConfig 表态配置
+type ReactionConfig struct {
+	VisitorSecret string // 匿名访问者指纹的哈希密钥，修改后匿名访问者的表态记录会失效
+}
+
+// RealtimeConfig 实时推送(SSE)配置
+type RealtimeConfig struct {
+	HeartbeatInterval time.Duration // 心跳间隔，应小于反向代理的空闲超时
+	ReplayBufferSize  int           // 保留的最近消息数量，客户端断线重连时据此补发
+	ClientBufferSize  int           // 单个连接的待发送消息数量，积压超过时断开连接由客户端重连补发
+}
+
+// EditingConfig 文章协同编辑配置
+type EditingConfig struct {
+	LockTTL        time.Duration // 软锁在持有者没有心跳后保留的时长
+	IdleTimeout    time.Duration // 连接在该时长内没有收到任何消息时断开
+	BlockConflicts bool          // 为true时拒绝被锁定或内容已过期的保存，否则只在响应头中提示
+}
+
+// MailConfig 邮件通知配置
+type MailConfig struct {
+	Host              string        // SMTP服务器地址，为空时只在日志中输出邮件
+	Port              int           // SMTP端口，465使用TLS直连，其他端口在服务器支持时使用STARTTLS
+	Username          string
+	Password          string
+	From              string        // 发件人，例如 Lin Studio <noreply@example.com>
+	SiteName          string        // 邮件中显示的站点名称
+	SiteURL           string        // 前端站点地址，用于生成评论链接
+	APIURL            string        // API服务地址，用于生成退订链接
+	Locale            string        // 默认邮件语言: zh 或 en
+	UnsubscribeSecret string        // 退订链接签名密钥
+	PollInterval      time.Duration // 后台任务检查待发送邮件的间隔
+	MaxAttempts       int           // 单封邮件最多尝试发送的次数
+	BatchSize         int           // 每次最多取出的待发送邮件数量
+}
+
+// ConcurrencyConfig 并发修改控制配置
+type ConcurrencyConfig struct {
+	RequireIfMatch bool // 为true时修改和删除文章、工具必须携带If-Match，否则只在携带时检查版本
+}
+
+// HTTPCacheConfig 公开读取接口的HTTP缓存策略，按路由组配置
+type HTTPCacheConfig struct {
+	Articles   CachePolicy
+	Categories CachePolicy
+	Tags       CachePolicy
+	Tools      CachePolicy
+	Series     CachePolicy
+}
+
+// CachePolicy 路由组的缓存策略
+type CachePolicy struct {
+	CacheControl string   // Cache-Control响应头，为空时不设置
+	Vary         []string // Vary响应头
+}
+
+// CacheConfig 应用缓存配置
+type CacheConfig struct {
+	Driver     string        // 缓存驱动: memory 或 redis
+	DefaultTTL time.Duration // 默认有效期
+	MaxEntries int           // 内存缓存的条数上限
+	Redis      RedisConfig   // Redis缓存配置，Driver为redis时生效
+}
+
+// RedisConfig Redis连接配置
+type RedisConfig struct {
+	Addr     string // 例如 127.0.0.1:6379
+	Password string
+	DB       int
+	Prefix   string        // 缓存键前缀，多个应用共用一个Redis时用于区分
+	PoolSize int           // 最多保留的空闲连接数
+	Timeout  time.Duration // 连接和单次请求的超时
+}
+
+// ViewConfig 浏览量统计配置
+type ViewConfig struct {
+	DedupeWindow  time.Duration // 同一访问者重复浏览同一内容只计一次的时间窗口
+	FlushInterval time.Duration // 累计的浏览量写入数据库的间隔
+	VisitorSecret string        // 匿名访问者指纹的哈希密钥
+	BotPatterns   []string      // 额外视为爬虫的User-Agent关键字
+	MaxVisitors   int           // 内存中最多保留的去重记录数
+}
+
+// AnalyticsConfig 内容统计配置
+type AnalyticsConfig struct {
+	Timezone               string        // 划分统计日期使用的时区，例如 Asia/Shanghai，为空时使用服务器时区
+	SiteURL                string        // 站点地址，来自本站的访问不计为外部来源
+	DailyRetentionDays     int           // 按日统计保留的天数，之前的数据汇总为按月统计，0表示不汇总
+	MonthlyRetentionMonths int           // 按月统计保留的月数，0表示永久保留
+	MaxRangeDays           int           // 按日查询时间序列的最大天数
+	MaintenanceInterval    time.Duration // 清理访问者记录和汇总统计的间隔
 }
 
 // CORSConfig CORS配置
@@ -57,6 +217,28 @@ type CORSConfig struct {
 	MaxAge             int      // 预检请求缓存时间(秒)
 }
 
+// Validate 检查启用的功能所需的配置，签名密钥不回退到JWT_SECRET或默认值
+func (c Config) Validate() error {
+	return errors.Join(c.Upload.Validate(), c.Mail.Validate())
+}
+
+// Validate 检查上传配置，开启草稿文件签名时必须设置签名密钥
+func (c UploadConfig) Validate() error {
+	if c.SignDrafts && c.SigningSecret == "" {
+		return errors.New("设置了UPLOAD_SIGN_DRAFTS=true时必须设置UPLOAD_SIGNING_SECRET")
+	}
+	return nil
+}
+
+// Validate 检查邮件通知配置，通过SMTP发送邮件时退订链接必须签名
+// 未设置SMTP_HOST时邮件只输出到日志，不需要签名密钥
+func (c MailConfig) Validate() error {
+	if c.Host != "" && c.UnsubscribeSecret == "" {
+		return errors.New("设置了SMTP_HOST时必须设置MAIL_UNSUBSCRIBE_SECRET")
+	}
+	return nil
+}
+
 // GetConfig 获取配置
 func GetConfig() Config {
 	return Config{
@@ -83,6 +265,24 @@ func GetConfig() Config {
 			UploadDir:    getEnv("UPLOAD_DIR", "./uploads"),
 			MaxSize:      10 * 1024 * 1024, // 10MB
 			AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"},
+			MaxWidth:     8000,
+			MaxHeight:    8000,
+			MaxPixels:    40 * 1000 * 1000, // 4000万像素
+			Driver:       getEnv("UPLOAD_DRIVER", "local"),
+			PublicURL:    getEnv("UPLOAD_PUBLIC_URL", "/uploads"),
+			S3: S3Config{
+				Endpoint:  getEnv("S3_ENDPOINT", ""),
+				Region:    getEnv("S3_REGION", "us-east-1"),
+				Bucket:    getEnv("S3_BUCKET", ""),
+				AccessKey: getEnv("S3_ACCESS_KEY", ""),
+				SecretKey: getEnv("S3_SECRET_KEY", ""),
+				PathStyle: getEnv("S3_PATH_STYLE", "true") == "true",
+			},
+			VariantWidths: getEnvAsIntSlice("IMAGE_VARIANT_WIDTHS", []int{320, 640, 1280}),
+			ImageWorkers:  2,
+			SignDrafts:    getEnv("UPLOAD_SIGN_DRAFTS", "false") == "true",
+			SigningSecret: getEnv("UPLOAD_SIGNING_SECRET", ""),
+			SignedURLTTL:  time.Hour,
 		},
 		CORS: CORSConfig{
 			// 默认允许的域名列表，可以通过CORS_ALLOWED_ORIGINS环境变量覆盖
@@ -98,13 +298,118 @@ func GetConfig() Config {
 			AllowedHeaders: []string{
 				"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token",
 				"Authorization", "accept", "origin", "Cache-Control", "X-Requested-With",
-				"Token", "Refresh-Token",
+				"Token", "Refresh-Token", "Last-Event-ID", "X-Edit-Session", "If-Match",
+				"If-None-Match", "If-Modified-Since",
 			},
 			ExposedHeaders: []string{
-				"Content-Length", "Authorization", "Token", "Refresh-Token",
+				"Content-Length", "Authorization", "Token", "Refresh-Token", "X-Edit-Conflict", "ETag", "Last-Modified",
 			},
 			MaxAge: 86400, // 24小时
 		},
+		OG: OGConfig{
+			SiteName:     getEnv("OG_SITE_NAME", "Lin Studio"),
+			TemplatePath: getEnv("OG_TEMPLATE_PATH", ""),
+			FontPath:     getEnv("OG_FONT_PATH", ""),
+		},
+		Spam: SpamConfig{
+			TokenSecret:   getSecretEnv("SPAM_TOKEN_SECRET"),
+			MinSubmitTime: time.Duration(getEnvAsInt("SPAM_MIN_SUBMIT_SECONDS", 3)) * time.Second,
+			TokenMaxAge:   24 * time.Hour,
+			MaxLinks:      getEnvAsInt("SPAM_MAX_LINKS", 2),
+			Blocklist:     getEnvAsSlice("SPAM_BLOCKLIST", nil),
+			ApproveScore:  getEnvAsFloat("SPAM_APPROVE_SCORE", -0.5),
+			RejectScore:   getEnvAsFloat("SPAM_REJECT_SCORE", 1),
+			BayesMinTrain: 10,
+		},
+		Reaction: ReactionConfig{
+			VisitorSecret: getSecretEnv("REACTION_VISITOR_SECRET"),
+		},
+		Mail: MailConfig{
+			Host:              getEnv("SMTP_HOST", ""),
+			Port:              getEnvAsInt("SMTP_PORT", 587),
+			Username:          getEnv("SMTP_USERNAME", ""),
+			Password:          getEnv("SMTP_PASSWORD", ""),
+			From:              getEnv("MAIL_FROM", "Lin Studio <noreply@localhost>"),
+			SiteName:          getEnv("OG_SITE_NAME", "Lin Studio"),
+			SiteURL:           strings.TrimRight(getEnv("SITE_URL", "http://localhost:3000"), "/"),
+			APIURL:            strings.TrimRight(getEnv("API_URL", "http://localhost:8080"), "/"),
+			Locale:            getEnv("MAIL_LOCALE", "zh"),
+			UnsubscribeSecret: getEnv("MAIL_UNSUBSCRIBE_SECRET", ""),
+			PollInterval:      time.Duration(getEnvAsInt("MAIL_POLL_SECONDS", 10)) * time.Second,
+			MaxAttempts:       getEnvAsInt("MAIL_MAX_ATTEMPTS", 8),
+			BatchSize:         20,
+		},
+		Realtime: RealtimeConfig{
+			HeartbeatInterval: time.Duration(getEnvAsInt("SSE_HEARTBEAT_SECONDS", 15)) * time.Second,
+			ReplayBufferSize:  getEnvAsInt("SSE_REPLAY_BUFFER", 1000),
+			ClientBufferSize:  64,
+		},
+		Editing: EditingConfig{
+			LockTTL:        time.Duration(getEnvAsInt("EDIT_LOCK_TTL_SECONDS", 120)) * time.Second,
+			IdleTimeout:    time.Duration(getEnvAsInt("EDIT_IDLE_TIMEOUT_SECONDS", 90)) * time.Second,
+			BlockConflicts: getEnv("EDIT_CONFLICT_MODE", "block") == "block",
+		},
+		Concurrency: ConcurrencyConfig{
+			RequireIfMatch: getEnv("REQUIRE_IF_MATCH", "false") == "true",
+		},
+		Cache: CacheConfig{
+			Driver:     getEnv("CACHE_DRIVER", "memory"),
+			DefaultTTL: time.Duration(getEnvAsInt("CACHE_TTL_SECONDS", 300)) * time.Second,
+			MaxEntries: getEnvAsInt("CACHE_MAX_ENTRIES", 1000),
+			Redis: RedisConfig{
+				Addr:     getEnv("REDIS_ADDR", "127.0.0.1:6379"),
+				Password: getEnv("REDIS_PASSWORD", ""),
+				DB:       getEnvAsInt("REDIS_DB", 0),
+				Prefix:   getEnv("CACHE_PREFIX", "lin_studio:"),
+				PoolSize: 8,
+				Timeout:  time.Second,
+			},
+		},
+		Views: ViewConfig{
+			DedupeWindow:  time.Duration(getEnvAsInt("VIEW_DEDUPE_MINUTES", 30)) * time.Minute,
+			FlushInterval: time.Duration(getEnvAsInt("VIEW_FLUSH_SECONDS", 10)) * time.Second,
+			VisitorSecret: getSecretEnv("VIEW_VISITOR_SECRET"),
+			BotPatterns:   getEnvAsSlice("VIEW_BOT_PATTERNS", nil),
+			MaxVisitors:   100000,
+		},
+		Analytics: AnalyticsConfig{
+			Timezone:               getEnv("ANALYTICS_TIMEZONE", ""),
+			SiteURL:                getEnv("SITE_URL", "http://localhost:3000"),
+			DailyRetentionDays:     getEnvAsInt("ANALYTICS_DAILY_RETENTION_DAYS", 180),
+			MonthlyRetentionMonths: getEnvAsInt("ANALYTICS_MONTHLY_RETENTION_MONTHS", 0),
+			MaxRangeDays:           366,
+			MaintenanceInterval:    time.Hour,
+		},
+		HTTPCache: HTTPCacheConfig{
+			// 文章包含访问者自己的表态，不允许共享缓存，每次使用前向服务器确认
+			Articles: CachePolicy{
+				CacheControl: getEnv("HTTP_CACHE_ARTICLES", "private, no-cache"),
+				Vary:         getEnvAsSlice("HTTP_CACHE_ARTICLES_VARY", []string{"Accept-Encoding", "Authorization"}),
+			},
+			Categories: CachePolicy{
+				CacheControl: getEnv("HTTP_CACHE_CATEGORIES", "public, max-age=300"),
+				Vary:         getEnvAsSlice("HTTP_CACHE_CATEGORIES_VARY", []string{"Accept-Encoding"}),
+			},
+			Tags: CachePolicy{
+				CacheControl: getEnv("HTTP_CACHE_TAGS", "public, max-age=300"),
+				Vary:         getEnvAsSlice("HTTP_CACHE_TAGS_VARY", []string{"Accept-Encoding"}),
+			},
+			Tools: CachePolicy{
+				CacheControl: getEnv("HTTP_CACHE_TOOLS", "public, max-age=60"),
+				Vary:         getEnvAsSlice("HTTP_CACHE_TOOLS_VARY", []string{"Accept-Encoding"}),
+			},
+			Series: CachePolicy{
+				CacheControl: getEnv("HTTP_CACHE_SERIES", "public, max-age=60"),
+				Vary:         getEnvAsSlice("HTTP_CACHE_SERIES_VARY", []string{"Accept-Encoding"}),
+			},
+		},
+		Comment: CommentConfig{
+			MaxDepth:       getEnvAsInt("COMMENT_MAX_DEPTH", 5),
+			TreeDepth:      getEnvAsInt("COMMENT_TREE_DEPTH", 3),
+			RepliesPerNode: getEnvAsInt("COMMENT_REPLIES_PER_NODE", 5),
+			EditWindow:     time.Duration(getEnvAsInt("COMMENT_EDIT_WINDOW_MINUTES", 15)) * time.Minute,
+			MentionURL:     getEnv("COMMENT_MENTION_URL", "/users/{username}"),
+		},
 	}
 }
 
@@ -116,10 +421,71 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// generatedSecrets 未设置的签名密钥，每个进程只生成一次，多次读取配置时保持一致
+var generatedSecrets sync.Map
+
+// 获取签名密钥环境变量，未设置时生成只在本次运行中有效的随机密钥并输出警告
+// 不使用固定的默认值或其他密钥，避免签名可以被伪造
+func getSecretEnv(key string) string {
+	if value := getEnv(key, ""); value != "" {
+		return value
+	}
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	secret, loaded := generatedSecrets.LoadOrStore(key, hex.EncodeToString(buf))
+	if !loaded {
+		log.Printf("警告: 未设置%s，已生成随机密钥，重启后之前的签名会失效", key)
+	}
+	return secret.(string)
+}
+
 // 获取环境变量并转换为字符串切片，以逗号分隔
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	if value, exists := os.LookupEnv(key); exists && value != "" {
 		return strings.Split(value, ",")
 	}
 	return defaultValue
-} 
+}
+
+// 获取环境变量并转换为整数，解析失败时返回默认值
+func getEnvAsInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+// 获取环境变量并转换为浮点数，解析失败时返回默认值
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return f
+}
+
+// 获取环境变量并转换为整数切片，以逗号分隔，解析失败时返回默认值
+func getEnvAsIntSlice(key string, defaultValue []int) []int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	var result []int
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			return defaultValue
+		}
+		result = append(result, n)
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package domain
+
+// 统计数据的时间粒度，超过保留期的按日统计会汇总为按月统计
+const (
+	AnalyticsPeriodDay   = "day"
+	AnalyticsPeriodMonth = "month"
+)
+
+// 统计指标
+const (
+	AnalyticsMetricViews    = "views"
+	AnalyticsMetricVisitors = "visitors"
+	AnalyticsMetricLikes    = "likes"
+	AnalyticsMetricComments = "comments"
+)
+
+// IsAnalyticsMetric 判断是否为支持的统计指标
+func IsAnalyticsMetric(metric string) bool {
+	switch metric {
+	case AnalyticsMetricViews, AnalyticsMetricVisitors, AnalyticsMetricLikes, AnalyticsMetricComments:
+		return true
+	}
+	return false
+}
+
+// AnalyticsStat 内容在一天或一个月内的统计
+// Date为 2006-01-02 格式，按月统计时为当月第一天；Visitors为每天独立访客数之和
+type AnalyticsStat struct {
+	ID       uint   `gorm:"primaryKey;column:id" json:"-"`
+	Period   string `gorm:"column:period;size:5;not null;uniqueIndex:idx_analytics_stat,priority:1" json:"period"`
+	Date     string `gorm:"column:date;size:10;not null;uniqueIndex:idx_analytics_stat,priority:2;index" json:"date"`
+	ItemType string `gorm:"column:item_type;size:20;not null;uniqueIndex:idx_analytics_stat,priority:3" json:"item_type"`
+	ItemID   uint   `gorm:"column:item_id;not null;uniqueIndex:idx_analytics_stat,priority:4" json:"item_id"`
+	Views    uint   `gorm:"column:views;not null" json:"views"`
+	Visitors uint   `gorm:"column:visitors;not null" json:"visitors"`
+	Likes    uint   `gorm:"column:likes;not null" json:"likes"`
+	Comments uint   `gorm:"column:comments;not null" json:"comments"`
+}
+
+// TableName 表名
+func (AnalyticsStat) TableName() string {
+	return "analytics_stats"
+}
+
+// AnalyticsVisitor 当天浏览过内容的访问者，用于统计独立访客，次日结束后删除
+// Visitor为访问者指纹加当天随机盐的哈希值，不保存IP等原始信息，盐删除后无法关联不同日期的访问者
+type AnalyticsVisitor struct {
+	ID       uint   `gorm:"primaryKey;column:id"`
+	Date     string `gorm:"column:date;size:10;not null;uniqueIndex:idx_analytics_visitor,priority:1"`
+	ItemType string `gorm:"column:item_type;size:20;not null;uniqueIndex:idx_analytics_visitor,priority:2"`
+	ItemID   uint   `gorm:"column:item_id;not null;uniqueIndex:idx_analytics_visitor,priority:3"`
+	Visitor  string `gorm:"column:visitor;size:64;not null;uniqueIndex:idx_analytics_visitor,priority:4"`
+}
+
+// TableName 表名
+func (AnalyticsVisitor) TableName() string {
+	return "analytics_visitors"
+}
+
+// AnalyticsReferrer 内容在一天或一个月内来自各来源域名的浏览量，Domain为空表示直接访问
+type AnalyticsReferrer struct {
+	ID       uint   `gorm:"primaryKey;column:id" json:"-"`
+	Period   string `gorm:"column:period;size:5;not null;uniqueIndex:idx_analytics_referrer,priority:1" json:"period"`
+	Date     string `gorm:"column:date;size:10;not null;uniqueIndex:idx_analytics_referrer,priority:2;index" json:"date"`
+	ItemType string `gorm:"column:item_type;size:20;not null;uniqueIndex:idx_analytics_referrer,priority:3" json:"item_type"`
+	ItemID   uint   `gorm:"column:item_id;not null;uniqueIndex:idx_analytics_referrer,priority:4" json:"item_id"`
+	Domain   string `gorm:"column:domain;size:255;not null;uniqueIndex:idx_analytics_referrer,priority:5" json:"domain"`
+	Views    uint   `gorm:"column:views;not null" json:"views"`
+}
+
+// TableName 表名
+func (AnalyticsReferrer) TableName() string {
+	return "analytics_referrers"
+}
+
+// AnalyticsSalt 每天随机生成的盐，用于计算访问者哈希，与访问者记录同时删除
+type AnalyticsSalt struct {
+	Date string `gorm:"primaryKey;column:date;size:10"`
+	Salt string `gorm:"column:salt;size:64;not null"`
+}
+
+// TableName 表名
+func (AnalyticsSalt) TableName() string {
+	return "analytics_salts"
+}
+
+// AnalyticsCampaign 内容在一天或一个月内来自各推广活动(UTM参数)的浏览量
+type AnalyticsCampaign struct {
+	ID       uint   `gorm:"primaryKey;column:id" json:"-"`
+	Period   string `gorm:"column:period;size:5;not null;uniqueIndex:idx_analytics_campaign,priority:1" json:"period"`
+	Date     string `gorm:"column:date;size:10;not null;uniqueIndex:idx_analytics_campaign,priority:2;index" json:"date"`
+	ItemType string `gorm:"column:item_type;size:20;not null;uniqueIndex:idx_analytics_campaign,priority:3" json:"item_type"`
+	ItemID   uint   `gorm:"column:item_id;not null;uniqueIndex:idx_analytics_campaign,priority:4" json:"item_id"`
+	Source   string `gorm:"column:source;size:100;not null;uniqueIndex:idx_analytics_campaign,priority:5" json:"source"`
+	Medium   string `gorm:"column:medium;size:100;not null;uniqueIndex:idx_analytics_campaign,priority:6" json:"medium"`
+	Campaign string `gorm:"column:campaign;size:100;not null;uniqueIndex:idx_analytics_campaign,priority:7" json:"campaign"`
+	Views    uint   `gorm:"column:views;not null" json:"views"`
+}
+
+// TableName 表名
+func (AnalyticsCampaign) TableName() string {
+	return "analytics_campaigns"
+}
+
+// AnalyticsPoint 时间序列中的一个点，Date为日期或月份(2006-01)
+type AnalyticsPoint struct {
+	Date     string `json:"date"`
+	Views    uint   `json:"views"`
+	Visitors uint   `json:"visitors"`
+	Likes    uint   `json:"likes"`
+	Comments uint   `json:"comments"`
+}
+
+// AnalyticsItem 一段时间内单个内容的统计合计
+type AnalyticsItem struct {
+	ItemType string `json:"item_type"`
+	ItemID   uint   `json:"item_id"`
+	Title    string `json:"title,omitempty"`
+	Slug     string `json:"slug,omitempty"`
+	Views    uint   `json:"views"`
+	Visitors uint   `json:"visitors"`
+	Likes    uint   `json:"likes"`
+	Comments uint   `json:"comments"`
+}
+
+// AnalyticsReferrerCount 一段时间内来源域名的浏览量合计
+type AnalyticsReferrerCount struct {
+	Domain string `json:"domain"`
+	Views  uint   `json:"views"`
+}
+
+// AnalyticsCampaignCount 一段时间内推广活动的浏览量合计
+type AnalyticsCampaignCount struct {
+	Source   string `json:"source"`
+	Medium   string `json:"medium"`
+	Campaign string `json:"campaign"`
+	Views    uint   `json:"views"`
+}
+
+// AnalyticsSources 一段时间内的访问来源，包括来源域名和推广活动
+type AnalyticsSources struct {
+	Referrers []AnalyticsReferrerCount `json:"referrers"`
+	Campaigns []AnalyticsCampaignCount `json:"campaigns"`
+}
@@ -20,16 +20,21 @@ type Article struct {
 	CategoryID    *uint     `json:"category_id,omitempty"`
 	Category      *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
 	CoverImage    string    `gorm:"size:255" json:"cover_image,omitempty"`
+	CoverImageSet *ImageSet `gorm:"-" json:"cover_image_set,omitempty"` // 封面图的响应式版本，由服务层加载
 	ReadTime      uint16    `gorm:"default:0" json:"read_time"`
 	Views         uint      `gorm:"default:0" json:"views"`
 	Likes         uint      `gorm:"default:0" json:"likes"`
 	CommentsCount uint      `gorm:"default:0" json:"comments_count"`
 	Status        string    `gorm:"type:enum('draft','published','archived');default:'draft'" json:"status"`
 	FeaturedOrder *uint8    `json:"featured_order,omitempty"`
+	Version       uint      `gorm:"not null;default:1" json:"version"` // 每次更新加1，用于乐观锁和ETag
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 	PublishedAt   sql.NullTime `json:"published_at,omitempty"`
 	Tags          []Tag     `gorm:"many2many:article_tags;" json:"tags,omitempty"`
+	Reactions     map[string]int64 `gorm:"-" json:"reactions,omitempty"`    // 各表态数量，由处理器加载
+	MyReactions   []string  `gorm:"-" json:"my_reactions,omitempty"` // 当前访问者的表态
+	Series        *ArticleSeriesNav `gorm:"-" json:"series,omitempty"` // 所属系列和上一篇、下一篇，由服务层加载
 }
 
 // TableName 指定表名
@@ -61,6 +66,7 @@ type ArticleResponse struct {
 	Category      *CategoryResponse `json:"category,omitempty"`
 	Tags          []SimpleTagResponse `json:"tags,omitempty"`
 	CoverImage    string           `json:"cover_image,omitempty"`
+	CoverImageSet *ImageSet        `json:"cover_image_set,omitempty"`
 	ReadTime      uint16           `json:"read_time"`
 	Views         uint             `json:"views"`
 	Likes         uint             `json:"likes"`
@@ -70,6 +76,9 @@ type ArticleResponse struct {
 	PublishedAt   *time.Time       `json:"published_at,omitempty"`
 	CreatedAt     time.Time        `json:"created_at"`
 	UpdatedAt     time.Time        `json:"updated_at"`
+	Reactions     map[string]int64 `json:"reactions,omitempty"`
+	MyReactions   []string         `json:"my_reactions,omitempty"`
+	Series        *ArticleSeriesNav `json:"series,omitempty"`
 }
 
 // ToResponse 将文章模型转换为响应数据
@@ -101,6 +110,7 @@ func (a *Article) ToResponse(includeContent bool) ArticleResponse {
 		Category:      categoryResponse,
 		Tags:          tags,
 		CoverImage:    a.CoverImage,
+		CoverImageSet: a.CoverImageSet,
 		ReadTime:      a.ReadTime,
 		Views:         a.Views,
 		Likes:         a.Likes,
@@ -110,6 +120,9 @@ func (a *Article) ToResponse(includeContent bool) ArticleResponse {
 		PublishedAt:   publishedAt,
 		CreatedAt:     a.CreatedAt,
 		UpdatedAt:     a.UpdatedAt,
+		Reactions:     a.Reactions,
+		MyReactions:   a.MyReactions,
+		Series:        a.Series,
 	}
 
 	// 如果作者信息可用
@@ -136,6 +149,7 @@ type ArticleListResponse struct {
 	Category      *SimpleCategoryResponse `json:"category,omitempty"`
 	Tags          []SimpleTagResponse `json:"tags,omitempty"`
 	CoverImage    string           `json:"cover_image,omitempty"`
+	CoverImageSet *ImageSet        `json:"cover_image_set,omitempty"`
 	ReadTime      uint16           `json:"read_time"`
 	Views         uint             `json:"views"`
 	Likes         uint             `json:"likes"`
@@ -176,6 +190,7 @@ func (a *Article) ToListResponse() ArticleListResponse {
 		Category:      categoryResponse,
 		Tags:          tags,
 		CoverImage:    a.CoverImage,
+		CoverImageSet: a.CoverImageSet,
 		ReadTime:      a.ReadTime,
 		Views:         a.Views,
 		Likes:         a.Likes,
@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// ArticleRelated 预先计算的相关文章，每篇已发布文章保存得分最高的若干篇
+type ArticleRelated struct {
+	ArticleID uint      `gorm:"primaryKey;column:article_id" json:"article_id"`
+	RelatedID uint      `gorm:"primaryKey;column:related_id" json:"related_id"`
+	Position  uint8     `gorm:"not null" json:"position"` // 从1开始，越小越相关
+	Score     float64   `gorm:"not null" json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 表名
+func (ArticleRelated) TableName() string {
+	return "article_related"
+}
@@ -13,6 +13,7 @@ type Category struct {
 	ParentID    *uint      `gorm:"column:parent_id" json:"parent_id"`
 	Parent      *Category  `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
 	Children    []Category `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	Version     uint       `gorm:"column:version;not null;default:1" json:"version"` // 每次更新加1，用于乐观锁和ETag
 	CreatedAt   time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time  `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
 	// 用于统计的虚拟字段
@@ -33,6 +34,7 @@ type CategoryResponse struct {
 	ParentID     *uint              `json:"parent_id"`
 	Children     []CategoryResponse `json:"children,omitempty"`
 	ArticleCount int64              `json:"article_count"`
+	Version      uint               `json:"version"`
 	CreatedAt    time.Time          `json:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at"`
 }
@@ -46,6 +48,7 @@ func (c *Category) ToResponse(includeChildren bool) CategoryResponse {
 		Description:  c.Description,
 		ParentID:     c.ParentID,
 		ArticleCount: c.ArticleCount,
+		Version:      c.Version,
 		CreatedAt:    c.CreatedAt,
 		UpdatedAt:    c.UpdatedAt,
 	}
@@ -1,27 +1,43 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 )
 
+// CommentPathMaxDepth 评论路径字段能容纳的最大层级，每层占用11个字符
+const CommentPathMaxDepth = 22
+
 // Comment 评论模型
 type Comment struct {
 	ID             uint      `gorm:"primaryKey;column:id" json:"id"`
 	Content        string    `gorm:"column:content;type:text;not null" json:"content"`
+	ContentHTML    string    `gorm:"column:content_html;type:text" json:"content_html"`
 	UserID         *uint     `gorm:"column:user_id" json:"user_id,omitempty"`
 	User           *User     `gorm:"-" json:"user,omitempty"`
 	AnonymousName  string    `gorm:"column:anonymous_name;size:100" json:"anonymous_name,omitempty"`
 	AnonymousEmail string    `gorm:"column:anonymous_email;size:100" json:"anonymous_email,omitempty"`
+	NotifyEmail    bool      `gorm:"column:notify_email;not null;default:false" json:"-"` // 匿名评论者是否勾选了接收邮件通知
 	ItemType       string    `gorm:"column:item_type;type:enum('article','project','tool');not null" json:"item_type"`
 	ItemID         uint      `gorm:"column:item_id;not null" json:"item_id"`
 	ParentID       *uint     `gorm:"column:parent_id" json:"parent_id,omitempty"`
+	Path           string    `gorm:"column:path;size:255;index" json:"-"`
+	Depth          int       `gorm:"column:depth;not null;default:0" json:"depth"`
 	Likes          uint      `gorm:"column:likes;default:0" json:"likes"`
 	Status         string    `gorm:"column:status;type:enum('pending','approved','spam','deleted');default:'pending'" json:"status"`
+	SpamScore      float64   `gorm:"column:spam_score;default:0" json:"spam_score"`
+	SpamReasons    string    `gorm:"column:spam_reasons;size:255" json:"spam_reasons,omitempty"`
+	SpamTrained    string    `gorm:"column:spam_trained;size:10" json:"-"` // 已作为哪类样本训练分类器: spam、ham或空
+	SpamTrainedTokens string `gorm:"column:spam_trained_tokens;type:text" json:"-"` // 训练时使用的词，以空格分隔，改判时按此撤销统计
+	EditCount      int       `gorm:"column:edit_count;not null;default:0" json:"edit_count"`
+	EditedAt       *time.Time `gorm:"column:edited_at" json:"edited_at,omitempty"` // 最后一次修改内容的时间
 	CreatedAt      time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt      time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
 	// 非数据库字段
 	Replies     []Comment `gorm:"-" json:"replies,omitempty"`
 	ReplyCount  int64     `gorm:"-" json:"reply_count,omitempty"`
+	Reactions   map[string]int64 `gorm:"-" json:"reactions,omitempty"`
+	MyReactions []string  `gorm:"-" json:"my_reactions,omitempty"`
 }
 
 // TableName 表名
@@ -29,20 +45,70 @@ func (Comment) TableName() string {
 	return "comments"
 }
 
+// CommentRevision 评论的历史版本，每次修改前保存原内容
+type CommentRevision struct {
+	ID        uint      `gorm:"primaryKey;column:id" json:"id"`
+	CommentID uint      `gorm:"column:comment_id;not null;index" json:"comment_id"`
+	Content   string    `gorm:"column:content;type:text;not null" json:"content"`
+	EditorID  *uint     `gorm:"column:editor_id" json:"editor_id,omitempty"` // 修改后内容的编辑者
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`       // 被修改的时间
+}
+
+// TableName 表名
+func (CommentRevision) TableName() string {
+	return "comment_revisions"
+}
+
+// CommentMention 评论中@提及的用户
+type CommentMention struct {
+	ID         uint       `gorm:"primaryKey;column:id" json:"id"`
+	CommentID  uint       `gorm:"column:comment_id;not null;uniqueIndex:idx_comment_mention,priority:1" json:"comment_id"`
+	UserID     uint       `gorm:"column:user_id;not null;uniqueIndex:idx_comment_mention,priority:2;index" json:"user_id"`
+	NotifiedAt *time.Time `gorm:"column:notified_at" json:"notified_at,omitempty"` // 评论通过审核后通知被提及用户的时间
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (CommentMention) TableName() string {
+	return "comment_mentions"
+}
+
+// CommentTarget 评论所属的内容
+type CommentTarget struct {
+	ItemType string `json:"item_type"`
+	ItemID   uint   `json:"item_id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Status   string `json:"status"`
+	Open     bool   `json:"open"` // 内容当前状态是否允许发表评论
+}
+
+// CommentPathSegment 返回评论ID在物化路径中对应的片段
+// 路径由祖先评论到自身的ID依次拼接而成，例如 0000000012/0000000045/，定长ID保证按前缀查询子树
+func CommentPathSegment(id uint) string {
+	return fmt.Sprintf("%010d/", id)
+}
+
 // CommentResponse 评论响应结构
 type CommentResponse struct {
 	ID         uint             `json:"id"`
 	Content    string           `json:"content"`
+	ContentHTML string          `json:"content_html"`
 	Author     *CommentAuthor   `json:"author,omitempty"`
 	ItemType   string           `json:"item_type"`
 	ItemID     uint             `json:"item_id"`
 	ParentID   *uint            `json:"parent_id,omitempty"`
+	Depth      int              `json:"depth"`
 	Likes      uint             `json:"likes"`
 	Status     string           `json:"status"`
+	Edited     bool             `json:"edited"`
+	EditedAt   *time.Time       `json:"edited_at,omitempty"`
 	CreatedAt  time.Time        `json:"created_at"`
 	UpdatedAt  time.Time        `json:"updated_at"`
 	Replies    []CommentResponse `json:"replies,omitempty"`
 	ReplyCount int64            `json:"reply_count,omitempty"`
+	Reactions  map[string]int64 `json:"reactions,omitempty"`
+	MyReactions []string        `json:"my_reactions,omitempty"`
 }
 
 // CommentAuthor 评论作者信息
@@ -74,6 +140,11 @@ func (c *Comment) ToResponse() CommentResponse {
 		}
 	}
 
+	// 已删除的占位评论不显示作者
+	if c.Status == "deleted" {
+		author = nil
+	}
+
 	// 处理回复
 	replies := make([]CommentResponse, 0)
 	if len(c.Replies) > 0 {
@@ -85,16 +156,42 @@ func (c *Comment) ToResponse() CommentResponse {
 	return CommentResponse{
 		ID:         c.ID,
 		Content:    c.Content,
+		ContentHTML: c.ContentHTML,
 		Author:     author,
 		ItemType:   c.ItemType,
 		ItemID:     c.ItemID,
 		ParentID:   c.ParentID,
+		Depth:      c.Depth,
 		Likes:      c.Likes,
 		Status:     c.Status,
+		Edited:     c.EditedAt != nil,
+		EditedAt:   c.EditedAt,
 		CreatedAt:  c.CreatedAt,
 		UpdatedAt:  c.UpdatedAt,
 		Replies:    replies,
 		ReplyCount: c.ReplyCount,
+		Reactions:  c.Reactions,
+		MyReactions: c.MyReactions,
+	}
+}
+
+// CollectIDs 收集评论及其所有已加载回复的ID
+func (c *Comment) CollectIDs(ids []uint) []uint {
+	ids = append(ids, c.ID)
+	for i := range c.Replies {
+		ids = c.Replies[i].CollectIDs(ids)
+	}
+	return ids
+}
+
+// ApplyReactions 为评论及其所有已加载回复填充表态统计
+func (c *Comment) ApplyReactions(summaries map[uint]ReactionSummary) {
+	if summary, ok := summaries[c.ID]; ok {
+		c.Reactions = summary.Counts
+		c.MyReactions = summary.Mine
+	}
+	for i := range c.Replies {
+		c.Replies[i].ApplyReactions(summaries)
 	}
 }
 
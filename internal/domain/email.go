@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// 邮件通知类型，用户可以分别退订
+const (
+	EmailKindReply      = "reply"      // 评论收到回复
+	EmailKindModeration = "moderation" // 评论通过审核
+	EmailKindAdmin      = "admin"      // 有新的待审核评论，只发送给管理员
+	EmailKindAll        = "all"        // 退订全部通知
+	EmailKindConfirm    = "confirm"    // 确认匿名评论者填写的邮箱，不能退订
+)
+
+// 待发送邮件状态
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed" // 超过最大重试次数
+)
+
+// EmailOutbox 待发送邮件，由后台任务发送，失败后按退避时间重试
+type EmailOutbox struct {
+	ID             uint       `gorm:"primaryKey;column:id" json:"id"`
+	Kind           string     `gorm:"column:kind;size:20;not null" json:"kind"`
+	Recipient      string     `gorm:"column:recipient;size:100;not null" json:"recipient"`
+	Subject        string     `gorm:"column:subject;size:255;not null" json:"subject"`
+	TextBody       string     `gorm:"column:text_body;type:text" json:"-"`
+	HTMLBody       string     `gorm:"column:html_body;type:mediumtext" json:"-"`
+	UnsubscribeURL string     `gorm:"column:unsubscribe_url;size:512" json:"-"`
+	Status         string     `gorm:"column:status;size:20;not null;default:'pending';index:idx_email_outbox_due,priority:1" json:"status"`
+	NextAttemptAt  time.Time  `gorm:"column:next_attempt_at;not null;index:idx_email_outbox_due,priority:2" json:"next_attempt_at"`
+	Attempts       int        `gorm:"column:attempts;not null;default:0" json:"attempts"`
+	LastError      string     `gorm:"column:last_error;size:500" json:"last_error,omitempty"`
+	SentAt         *time.Time `gorm:"column:sent_at" json:"sent_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (EmailOutbox) TableName() string {
+	return "email_outbox"
+}
+
+// EmailPreference 邮箱的通知偏好，注册用户和匿名评论者都按邮箱地址保存
+// 没有记录的邮箱默认接收所有通知
+type EmailPreference struct {
+	ID            uint       `gorm:"primaryKey;column:id" json:"-"`
+	Email         string     `gorm:"column:email;size:100;not null;uniqueIndex" json:"email"`
+	UserID        *uint      `gorm:"column:user_id;index" json:"-"`
+	Locale        string     `gorm:"column:locale;size:10" json:"locale"`
+	Reply         bool       `gorm:"column:reply;not null" json:"reply"`
+	Moderation    bool       `gorm:"column:moderation;not null" json:"moderation"`
+	Admin         bool       `gorm:"column:admin;not null" json:"admin"`
+	ConfirmedAt   *time.Time `gorm:"column:confirmed_at" json:"-"`    // 点击确认邮件中链接的时间，确认前不向匿名评论者发送通知
+	ConfirmSentAt *time.Time `gorm:"column:confirm_sent_at" json:"-"` // 最后一次发送确认邮件的时间
+	UpdatedAt     time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (EmailPreference) TableName() string {
+	return "email_preferences"
+}
+
+// NewEmailPreference 返回接收所有通知的默认偏好
+func NewEmailPreference(email string) *EmailPreference {
+	return &EmailPreference{
+		Email:      NormalizeEmail(email),
+		Reply:      true,
+		Moderation: true,
+		Admin:      true,
+	}
+}
+
+// Allows 判断是否接收某类通知
+func (p *EmailPreference) Allows(kind string) bool {
+	switch kind {
+	case EmailKindReply:
+		return p.Reply
+	case EmailKindModeration:
+		return p.Moderation
+	case EmailKindAdmin:
+		return p.Admin
+	}
+	return false
+}
+
+// Disable 退订某类通知，kind为EmailKindAll时退订全部
+func (p *EmailPreference) Disable(kind string) {
+	switch kind {
+	case EmailKindReply:
+		p.Reply = false
+	case EmailKindModeration:
+		p.Moderation = false
+	case EmailKindAdmin:
+		p.Admin = false
+	case EmailKindAll:
+		p.Reply, p.Moderation, p.Admin = false, false, false
+	}
+}
+
+// NormalizeEmail 统一邮箱地址的大小写和空白，用于比较和查找
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
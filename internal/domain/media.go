@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Media 上传的媒体文件
+type Media struct {
+	ID          uint          `gorm:"primaryKey;column:id" json:"id"`
+	Key         string        `gorm:"column:storage_key;size:255;uniqueIndex;not null" json:"key"`
+	URL         string        `gorm:"column:url;size:255;index;not null" json:"url"`
+	Hash        string        `gorm:"column:hash;size:64;index" json:"hash"` // 文件内容的SHA-256，用于命名和去重
+	Name        string        `gorm:"column:name;size:255" json:"name"`      // 上传时的原始文件名
+	UploaderID  *uint         `gorm:"column:uploader_id;index" json:"uploader_id,omitempty"`
+	ContentType string        `gorm:"column:content_type;size:50;not null" json:"content_type"`
+	Size        int64         `gorm:"column:size;not null" json:"size"`
+	Width       int           `gorm:"column:width" json:"width"`
+	Height      int           `gorm:"column:height" json:"height"`
+	Variants    MediaVariants `gorm:"column:variants;type:json" json:"variants,omitempty"`
+	Status      string        `gorm:"column:status;type:enum('pending','processing','ready','failed');default:'pending'" json:"status"`
+	Error       string        `gorm:"column:error;size:255" json:"error,omitempty"`
+	// LastUploadedAt 最近一次上传的时间，重复上传相同内容时更新，清理未引用文件的宽限期从此时开始计算
+	LastUploadedAt time.Time `gorm:"column:last_uploaded_at;index;default:CURRENT_TIMESTAMP" json:"last_uploaded_at"`
+	CreatedAt      time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName 表名
+func (Media) TableName() string {
+	return "media"
+}
+
+// MediaReference 媒体文件的引用关系，没有引用的文件会被清理
+type MediaReference struct {
+	ID        uint      `gorm:"primaryKey;column:id" json:"id"`
+	MediaID   uint      `gorm:"column:media_id;not null;uniqueIndex:idx_media_reference" json:"media_id"`
+	RefType   string    `gorm:"column:ref_type;size:20;not null;uniqueIndex:idx_media_reference;index:idx_media_reference_owner" json:"ref_type"`
+	RefID     uint      `gorm:"column:ref_id;not null;uniqueIndex:idx_media_reference;index:idx_media_reference_owner" json:"ref_id"`
+	CreatedAt time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName 表名
+func (MediaReference) TableName() string {
+	return "media_references"
+}
+
+// 媒体文件引用方类型
+const (
+	MediaRefArticle = "article"
+	MediaRefUser    = "user"
+	MediaRefProject = "project"
+	MediaRefSeries  = "series"
+)
+
+// MediaResponse 媒体文件响应数据
+type MediaResponse struct {
+	ID          uint      `json:"id"`
+	URL         string    `json:"url"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Status      string    `json:"status"`
+	ImageSet    *ImageSet `json:"image_set,omitempty"`
+	References  int64     `json:"references"`
+	UploaderID  *uint     `json:"uploader_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToResponse 将媒体文件转换为响应数据
+func (m *Media) ToResponse(references int64) MediaResponse {
+	return MediaResponse{
+		ID:          m.ID,
+		URL:         m.URL,
+		Name:        m.Name,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Width:       m.Width,
+		Height:      m.Height,
+		Status:      m.Status,
+		ImageSet:    m.ToImageSet(),
+		References:  references,
+		UploaderID:  m.UploaderID,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// MediaVariant 媒体文件的缩放/转码版本
+type MediaVariant struct {
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+// MediaVariants 自定义JSON变体数组类型
+type MediaVariants []MediaVariant
+
+// Scan 实现sql.Scanner接口
+func (j *MediaVariants) Scan(value interface{}) error {
+	if value == nil {
+		*j = make([]MediaVariant, 0)
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("类型断言错误")
+	}
+	if len(bytes) == 0 {
+		*j = make([]MediaVariant, 0)
+		return nil
+	}
+	return json.Unmarshal(bytes, j)
+}
+
+// Value 实现driver.Valuer接口
+func (j MediaVariants) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(j)
+}
+
+// ImageSet 响应式图片数据，前端可据此构建<picture>和srcset
+type ImageSet struct {
+	Src     string        `json:"src"`
+	Width   int           `json:"width"`
+	Height  int           `json:"height"`
+	Sources []ImageSource `json:"sources"`
+}
+
+// ImageSource 同一格式的一组候选图片
+type ImageSource struct {
+	Type   string `json:"type"`
+	Srcset string `json:"srcset"`
+}
+
+// ToImageSet 将媒体文件及其变体转换为响应式图片数据
+// 仅在变体生成完成后返回，否则返回nil
+func (m *Media) ToImageSet() *ImageSet {
+	if m.Status != "ready" {
+		return nil
+	}
+
+	// 按格式分组，原图作为其格式中的最大尺寸候选
+	groups := make(map[string][]MediaVariant)
+	groups[m.ContentType] = append(groups[m.ContentType], MediaVariant{
+		URL:         m.URL,
+		ContentType: m.ContentType,
+		Width:       m.Width,
+		Height:      m.Height,
+	})
+	for _, v := range m.Variants {
+		groups[v.ContentType] = append(groups[v.ContentType], v)
+	}
+
+	// WebP优先，浏览器会选择第一个支持的格式
+	types := make([]string, 0, len(groups))
+	for t := range groups {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if (types[i] == "image/webp") != (types[j] == "image/webp") {
+			return types[i] == "image/webp"
+		}
+		return types[i] < types[j]
+	})
+
+	set := &ImageSet{
+		Src:    m.URL,
+		Width:  m.Width,
+		Height: m.Height,
+	}
+	for _, t := range types {
+		variants := groups[t]
+		sort.Slice(variants, func(i, j int) bool { return variants[i].Width < variants[j].Width })
+
+		candidates := make([]string, 0, len(variants))
+		seen := make(map[int]bool)
+		for _, v := range variants {
+			if seen[v.Width] {
+				continue
+			}
+			seen[v.Width] = true
+			candidates = append(candidates, fmt.Sprintf("%s %dw", v.URL, v.Width))
+		}
+		set.Sources = append(set.Sources, ImageSource{
+			Type:   t,
+			Srcset: strings.Join(candidates, ", "),
+		})
+	}
+	return set
+}
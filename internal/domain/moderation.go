@@ -0,0 +1,52 @@
+package domain
+
+import "time"
+
+// 评论审核操作
+const (
+	ModerationActionApprove = "approve"
+	ModerationActionSpam    = "spam"
+	ModerationActionDelete  = "delete"
+)
+
+// ModerationLog 评论审核日志，记录管理员对评论状态的每次修改
+type ModerationLog struct {
+	ID            uint      `gorm:"primaryKey;column:id" json:"id"`
+	CommentID     uint      `gorm:"column:comment_id;not null;index" json:"comment_id"`
+	ModeratorID   uint      `gorm:"column:moderator_id;not null;index" json:"moderator_id"`
+	ModeratorName string    `gorm:"-" json:"moderator_name,omitempty"`
+	Action        string    `gorm:"column:action;size:20;not null" json:"action"`
+	FromStatus    string    `gorm:"column:from_status;size:20" json:"from_status"`
+	ToStatus      string    `gorm:"column:to_status;size:20" json:"to_status"`
+	CreatedAt     time.Time `gorm:"column:created_at;index" json:"created_at"`
+}
+
+// TableName 表名
+func (ModerationLog) TableName() string {
+	return "moderation_logs"
+}
+
+// CommentAuthorStats 评论作者的历史评论统计
+type CommentAuthorStats struct {
+	Approved int64 `json:"approved"`
+	Pending  int64 `json:"pending"`
+	Spam     int64 `json:"spam"`
+}
+
+// ModerationItem 审核队列中的评论及其上下文
+type ModerationItem struct {
+	Comment        CommentResponse        `json:"comment"`
+	AnonymousEmail string                 `json:"anonymous_email,omitempty"`
+	SpamScore      float64                `json:"spam_score"`
+	SpamReasons    string                 `json:"spam_reasons,omitempty"`
+	Target         *CommentTarget         `json:"target,omitempty"`
+	Parent         *SimpleCommentResponse `json:"parent,omitempty"`
+	AuthorHistory  CommentAuthorStats     `json:"author_history"`
+}
+
+// ModerationResult 批量审核结果
+type ModerationResult struct {
+	Processed int    `json:"processed"`
+	Unchanged int    `json:"unchanged"`
+	Failed    []uint `json:"failed,omitempty"`
+}
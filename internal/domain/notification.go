@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// 站内通知类型
+const (
+	NotificationCommentReply    = "comment_reply"    // 评论收到回复
+	NotificationCommentMention  = "comment_mention"  // 在评论中被@提及
+	NotificationArticleLike     = "article_like"     // 文章被点赞
+	NotificationCommentApproved = "comment_approved" // 评论通过审核
+	NotificationCommentRejected = "comment_rejected" // 评论被标记为垃圾信息
+)
+
+// Notification 注册用户的站内通知
+// DedupeKey不为空时同一用户相同键的通知只保存一条，避免重复点赞、同时被回复和提及时收到多条通知
+type Notification struct {
+	ID        uint       `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uint       `gorm:"column:user_id;not null;index:idx_notification_user,priority:1;uniqueIndex:idx_notification_dedupe,priority:1" json:"-"`
+	Type      string     `gorm:"column:type;size:30;not null" json:"type"`
+	ActorID   *uint      `gorm:"column:actor_id" json:"actor_id,omitempty"`
+	ActorName string     `gorm:"column:actor_name;size:100" json:"actor_name,omitempty"`
+	ItemType  string     `gorm:"column:item_type;size:20" json:"item_type"`
+	ItemID    uint       `gorm:"column:item_id" json:"item_id"`
+	CommentID *uint      `gorm:"column:comment_id" json:"comment_id,omitempty"`
+	Title     string     `gorm:"column:title;size:255" json:"title"`
+	Excerpt   string     `gorm:"column:excerpt;size:500" json:"excerpt,omitempty"`
+	Link      string     `gorm:"column:link;size:512" json:"link"`
+	DedupeKey *string    `gorm:"column:dedupe_key;size:120;uniqueIndex:idx_notification_dedupe,priority:2" json:"-"`
+	ReadAt    *time.Time `gorm:"column:read_at;index:idx_notification_user,priority:2" json:"read_at,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// IsRead 通知是否已读
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
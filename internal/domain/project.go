@@ -98,6 +98,10 @@ type JSONImages []ImageItem
 
 // Scan 实现sql.Scanner接口
 func (j *JSONImages) Scan(value interface{}) error {
+	if value == nil {
+		*j = make([]ImageItem, 0)
+		return nil
+	}
 	bytes, ok := value.([]byte)
 	if !ok {
 		return errors.New("类型断言错误")
@@ -0,0 +1,55 @@
+package domain
+
+import "time"
+
+// 可添加表态的内容类型
+const (
+	ReactionTargetComment = "comment"
+	ReactionTargetArticle = "article"
+)
+
+// ReactionLike 点赞表态，同时维护内容的likes计数
+const ReactionLike = "like"
+
+// ReactionKinds 支持的表态类型
+var ReactionKinds = []string{
+	ReactionLike, // 👍
+	"heart",      // ❤️
+	"laugh",      // 😄
+	"hooray",     // 🎉
+	"confused",   // 😕
+	"eyes",       // 👀
+}
+
+// IsReactionKind 判断是否为支持的表态类型
+func IsReactionKind(kind string) bool {
+	for _, k := range ReactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Reaction 表态记录，同一访问者对同一内容的每种表态只保留一条
+// Actor为 u:<用户ID> 或 v:<匿名访问者指纹>
+type Reaction struct {
+	ID         uint      `gorm:"primaryKey;column:id" json:"id"`
+	TargetType string    `gorm:"column:target_type;size:20;not null;uniqueIndex:idx_reaction_actor,priority:1;index:idx_reaction_target,priority:1" json:"target_type"`
+	TargetID   uint      `gorm:"column:target_id;not null;uniqueIndex:idx_reaction_actor,priority:2;index:idx_reaction_target,priority:2" json:"target_id"`
+	Kind       string    `gorm:"column:kind;size:20;not null;uniqueIndex:idx_reaction_actor,priority:3" json:"kind"`
+	Actor      string    `gorm:"column:actor;size:80;not null;uniqueIndex:idx_reaction_actor,priority:4" json:"-"`
+	UserID     *uint     `gorm:"column:user_id;index" json:"user_id,omitempty"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+// TableName 表名
+func (Reaction) TableName() string {
+	return "reactions"
+}
+
+// ReactionSummary 内容的表态统计及当前访问者的表态
+type ReactionSummary struct {
+	Counts map[string]int64 `json:"reactions"`
+	Mine   []string         `json:"my_reactions"`
+}
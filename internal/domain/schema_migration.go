@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// SchemaMigration 已执行的一次性数据迁移，启动时跳过已记录的迁移
+type SchemaMigration struct {
+	Name      string    `gorm:"primaryKey;size:100" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
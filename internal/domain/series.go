@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"time"
+)
+
+// Series 系列模型，多篇文章按顺序组成系列教程
+type Series struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Title       string    `gorm:"size:255;not null" json:"title"`
+	Slug        string    `gorm:"size:255;uniqueIndex;not null" json:"slug"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	CoverImage  string    `gorm:"size:255" json:"cover_image,omitempty"`
+	Version     uint      `gorm:"not null;default:1" json:"version"` // 每次更新加1，用于乐观锁和ETag
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (Series) TableName() string {
+	return "series"
+}
+
+// SeriesArticle 系列中的文章，一篇文章最多属于一个系列
+type SeriesArticle struct {
+	SeriesID  uint      `gorm:"primaryKey" json:"series_id"`
+	ArticleID uint      `gorm:"primaryKey;uniqueIndex" json:"article_id"`
+	Position  uint      `gorm:"not null" json:"position"` // 从1开始的顺序
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 表名
+func (SeriesArticle) TableName() string {
+	return "series_articles"
+}
+
+// SeriesPartResponse 系列中的一篇文章
+type SeriesPartResponse struct {
+	Position    int        `json:"position"` // 从1开始的顺序
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Excerpt     string     `json:"excerpt,omitempty"`
+	ReadTime    uint16     `json:"read_time"`
+	Status      string     `json:"status"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Progress    int        `json:"progress"` // 读完该篇后整个系列的阅读进度百分比
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// SeriesResponse 系列响应数据
+type SeriesResponse struct {
+	ID             uint                 `json:"id"`
+	Title          string               `json:"title"`
+	Slug           string               `json:"slug"`
+	Description    string               `json:"description,omitempty"`
+	CoverImage     string               `json:"cover_image,omitempty"`
+	PartsCount     int                  `json:"parts_count"`     // 返回的文章数量
+	PublishedCount int                  `json:"published_count"` // 已发布的文章数量
+	ReadTime       int                  `json:"read_time"`       // 返回的文章的阅读时间合计
+	Parts          []SeriesPartResponse `json:"parts,omitempty"`
+	Version        uint                 `json:"version"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+}
+
+// ToResponse 将系列和按顺序排列的文章转换为响应数据
+func (s *Series) ToResponse(parts []Article, includeParts bool) SeriesResponse {
+	response := SeriesResponse{
+		ID:          s.ID,
+		Title:       s.Title,
+		Slug:        s.Slug,
+		Description: s.Description,
+		CoverImage:  s.CoverImage,
+		PartsCount:  len(parts),
+		Version:     s.Version,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+
+	for i := range parts {
+		if parts[i].Status == "published" {
+			response.PublishedCount++
+		}
+		response.ReadTime += int(parts[i].ReadTime)
+		if !includeParts {
+			continue
+		}
+
+		var publishedAt *time.Time
+		if parts[i].PublishedAt.Valid {
+			publishedAt = &parts[i].PublishedAt.Time
+		}
+		response.Parts = append(response.Parts, SeriesPartResponse{
+			Position:    i + 1,
+			ID:          parts[i].ID,
+			Title:       parts[i].Title,
+			Slug:        parts[i].Slug,
+			Excerpt:     parts[i].Excerpt,
+			ReadTime:    parts[i].ReadTime,
+			Status:      parts[i].Status,
+			PublishedAt: publishedAt,
+			Progress:    (i + 1) * 100 / len(parts),
+			UpdatedAt:   parts[i].UpdatedAt,
+		})
+	}
+
+	return response
+}
+
+// SeriesLink 系列中相邻文章的链接
+type SeriesLink struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// ArticleSeriesNav 文章所属系列和系列中的上一篇、下一篇
+type ArticleSeriesNav struct {
+	ID       uint        `json:"id"`
+	Title    string      `json:"title"`
+	Slug     string      `json:"slug"`
+	Position int         `json:"position"` // 文章在系列中的顺序，从1开始
+	Total    int         `json:"total"`    // 系列的文章数量
+	Progress int         `json:"progress"` // 读完该篇后整个系列的阅读进度百分比
+	Prev     *SeriesLink `json:"prev,omitempty"`
+	Next     *SeriesLink `json:"next,omitempty"`
+}
+
+// NewArticleSeriesNav 根据系列中按顺序排列的文章生成文章的导航，文章不在其中时返回nil
+func NewArticleSeriesNav(series *Series, parts []Article, articleID uint) *ArticleSeriesNav {
+	for i := range parts {
+		if parts[i].ID != articleID {
+			continue
+		}
+		nav := &ArticleSeriesNav{
+			ID:       series.ID,
+			Title:    series.Title,
+			Slug:     series.Slug,
+			Position: i + 1,
+			Total:    len(parts),
+			Progress: (i + 1) * 100 / len(parts),
+		}
+		if i > 0 {
+			nav.Prev = &SeriesLink{ID: parts[i-1].ID, Title: parts[i-1].Title, Slug: parts[i-1].Slug}
+		}
+		if i+1 < len(parts) {
+			nav.Next = &SeriesLink{ID: parts[i+1].ID, Title: parts[i+1].Title, Slug: parts[i+1].Slug}
+		}
+		return nav
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// 评论作为分类器训练样本的类别
+const (
+	SpamClassSpam = "spam"
+	SpamClassHam  = "ham"
+)
+
+// SpamToken 贝叶斯分类器的词频统计，记录包含该词的垃圾评论和正常评论数量
+type SpamToken struct {
+	Token     string    `gorm:"primaryKey;column:token;size:64" json:"token"`
+	SpamCount int64     `gorm:"column:spam_count;not null;default:0" json:"spam_count"`
+	HamCount  int64     `gorm:"column:ham_count;not null;default:0" json:"ham_count"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (SpamToken) TableName() string {
+	return "spam_tokens"
+}
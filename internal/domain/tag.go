@@ -11,6 +11,7 @@ type Tag struct {
 	Slug        string    `gorm:"column:slug;size:50;uniqueIndex;not null" json:"slug"`
 	Description string    `gorm:"column:description;type:text" json:"description"`
 	Color       string    `gorm:"column:color;size:7" json:"color"`
+	Version     uint      `gorm:"column:version;not null;default:1" json:"version"` // 每次更新加1，用于乐观锁和ETag
 	CreatedAt   time.Time `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
 	// 用于统计的虚拟字段
@@ -32,6 +33,7 @@ type TagResponse struct {
 	Color        string    `json:"color"`
 	ArticleCount int64     `json:"article_count"`
 	ProjectCount int64     `json:"project_count"`
+	Version      uint      `json:"version"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -46,6 +48,7 @@ func (t *Tag) ToResponse() TagResponse {
 		Color:        t.Color,
 		ArticleCount: t.ArticleCount,
 		ProjectCount: t.ProjectCount,
+		Version:      t.Version,
 		CreatedAt:    t.CreatedAt,
 		UpdatedAt:    t.UpdatedAt,
 	}
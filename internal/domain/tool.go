@@ -20,6 +20,7 @@ type Tool struct {
 	Config      JSONConfig `gorm:"column:config;type:json" json:"config"`
 	Views       uint       `gorm:"column:views;default:0" json:"views"`
 	Status      string     `gorm:"column:status;type:enum('active','maintenance','deprecated');default:'active'" json:"status"`
+	Version     uint       `gorm:"column:version;not null;default:1" json:"version"` // 每次更新加1，用于乐观锁和ETag
 	CreatedAt   time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time  `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
@@ -66,6 +67,7 @@ type ToolResponse struct {
 	Config      JSONConfig          `json:"config,omitempty"`
 	Views       uint                `json:"views"`
 	Status      string              `json:"status"`
+	Version     uint                `json:"version"`
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 }
@@ -83,6 +85,7 @@ func (t *Tool) ToResponse(includeContent bool) ToolResponse {
 		Config:      t.Config,
 		Views:       t.Views,
 		Status:      t.Status,
+		Version:     t.Version,
 		CreatedAt:   t.CreatedAt,
 		UpdatedAt:   t.UpdatedAt,
 	}
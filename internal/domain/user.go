@@ -24,6 +24,8 @@ type User struct {
 	UpdatedAt    time.Time      `json:"updated_at"`
 	LastLogin    sql.NullTime   `json:"last_login,omitempty"`
 	Status       string         `gorm:"type:enum('active','suspended','deleted');default:'active'" json:"status"`
+	// UnreadNotifications 未读站内通知数量，只在获取当前用户信息时返回
+	UnreadNotifications *int64 `gorm:"-" json:"unread_notifications,omitempty"`
 }
 
 // TableName 指定表名
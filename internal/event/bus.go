@@ -0,0 +1,73 @@
+package event
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// TopicAll 订阅所有事件时使用的主题
+const TopicAll = "*"
+
+// Event 领域事件
+type Event interface {
+	// Topic 返回事件主题，例如 comment.mentioned
+	Topic() string
+}
+
+// Handler 事件处理函数
+type Handler func(ctx context.Context, e Event) error
+
+// Bus 进程内事件总线，服务发布领域事件，通知等功能订阅事件
+type Bus interface {
+	// Publish 发布事件，按订阅顺序依次调用处理函数
+	// 处理函数的错误只记录日志，不影响发布方和其他处理函数
+	Publish(ctx context.Context, e Event)
+	// Subscribe 订阅主题，topic为TopicAll时接收所有事件
+	Subscribe(topic string, handler Handler)
+}
+
+// bus 事件总线实现
+type bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus 创建事件总线
+func NewBus() Bus {
+	return &bus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Publish 发布事件
+func (b *bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[e.Topic()])+len(b.handlers[TopicAll]))
+	handlers = append(handlers, b.handlers[e.Topic()]...)
+	handlers = append(handlers, b.handlers[TopicAll]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		dispatch(ctx, e, handler)
+	}
+}
+
+// Subscribe 订阅主题
+func (b *bus) Subscribe(topic string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], handler)
+}
+
+// dispatch 调用单个处理函数，处理函数出错或panic时记录日志
+func dispatch(ctx context.Context, e Event, handler Handler) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("处理事件%s时发生panic: %v", e.Topic(), r)
+		}
+	}()
+	if err := handler(ctx, e); err != nil {
+		log.Printf("处理事件%s失败: %v", e.Topic(), err)
+	}
+}
@@ -0,0 +1,58 @@
+package event
+
+import "Lin_studio/internal/domain"
+
+// 评论相关事件主题
+const (
+	TopicCommentCreated   = "comment.created"
+	TopicCommentApproved  = "comment.approved"
+	TopicCommentMentioned = "comment.mentioned"
+	TopicCommentRejected  = "comment.rejected"
+)
+
+// CommentCreated 新评论已保存，被反垃圾检查拒绝的评论不发布
+type CommentCreated struct {
+	Comment *domain.Comment
+	Target  *domain.CommentTarget
+}
+
+// Topic 返回事件主题
+func (CommentCreated) Topic() string {
+	return TopicCommentCreated
+}
+
+// CommentApproved 管理员批准了评论，FromStatus为批准前的状态
+type CommentApproved struct {
+	Comment     *domain.Comment
+	FromStatus  string
+	ModeratorID uint
+}
+
+// Topic 返回事件主题
+func (CommentApproved) Topic() string {
+	return TopicCommentApproved
+}
+
+// CommentMentioned 评论通过审核后，其中@提及的用户需要收到通知
+// 每个用户对同一条评论只会收到一次，评论修改后新增的提及会再次发布
+type CommentMentioned struct {
+	Comment *domain.Comment
+	UserIDs []uint
+}
+
+// Topic 返回事件主题
+func (CommentMentioned) Topic() string {
+	return TopicCommentMentioned
+}
+
+// CommentRejected 管理员将评论标记为垃圾信息，FromStatus为标记前的状态
+type CommentRejected struct {
+	Comment     *domain.Comment
+	FromStatus  string
+	ModeratorID uint
+}
+
+// Topic 返回事件主题
+func (CommentRejected) Topic() string {
+	return TopicCommentRejected
+}
@@ -0,0 +1,16 @@
+package event
+
+import "Lin_studio/internal/domain"
+
+// TopicNotificationCreated 新站内通知事件主题
+const TopicNotificationCreated = "notification.created"
+
+// NotificationCreated 为用户保存了一条新的站内通知，被去重忽略的通知不发布
+type NotificationCreated struct {
+	Notification *domain.Notification
+}
+
+// Topic 返回事件主题
+func (NotificationCreated) Topic() string {
+	return TopicNotificationCreated
+}
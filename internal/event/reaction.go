@@ -0,0 +1,34 @@
+package event
+
+// 表态相关事件主题
+const (
+	TopicReactionAdded   = "reaction.added"
+	TopicReactionChanged = "reaction.changed"
+)
+
+// ReactionAdded 访问者对内容添加了表态，取消表态不发布
+// ActorKey为 u:<用户ID> 或 v:<匿名访问者指纹>，匿名访问者的UserID为nil
+type ReactionAdded struct {
+	TargetType string
+	TargetID   uint
+	Kind       string
+	ActorKey   string
+	UserID     *uint
+}
+
+// Topic 返回事件主题
+func (ReactionAdded) Topic() string {
+	return TopicReactionAdded
+}
+
+// ReactionChanged 内容的表态统计发生变化，添加和取消表态都会发布
+type ReactionChanged struct {
+	TargetType string
+	TargetID   uint
+	Counts     map[string]int64
+}
+
+// Topic 返回事件主题
+func (ReactionChanged) Topic() string {
+	return TopicReactionChanged
+}
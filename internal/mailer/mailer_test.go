@@ -0,0 +1,230 @@
+package mailer
+
+import (
+	"Lin_studio/internal/config"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "Lin Studio", Address: "noreply@example.com"}
+	to := &mail.Address{Address: "alice@example.com"}
+
+	data, err := buildMessage(from, to, &Message{
+		Subject:        "有人回复了你的评论",
+		Text:           "你好，" + strings.Repeat("很长的一行", 30),
+		HTML:           `<p><a href="https://example.com/articles/go#comment-1">查看</a></p>`,
+		UnsubscribeURL: "https://api.example.com/api/v1/email/unsubscribe?token=abc",
+	})
+	require.NoError(t, err)
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "有人回复了你的评论", subject)
+	assert.Equal(t, "<https://api.example.com/api/v1/email/unsubscribe?token=abc>", msg.Header.Get("List-Unsubscribe"))
+	assert.Equal(t, "List-Unsubscribe=One-Click", msg.Header.Get("List-Unsubscribe-Post"))
+	assert.True(t, strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	// multipart.Reader会自动解码quoted-printable
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+	assert.Equal(t, []string{"text/plain; charset=UTF-8", "text/html; charset=UTF-8"}, types)
+	assert.Equal(t, "你好，"+strings.Repeat("很长的一行", 30), bodies[0])
+	assert.Contains(t, bodies[1], `href="https://example.com/articles/go#comment-1"`)
+}
+
+func TestTemplatesRender(t *testing.T) {
+	templates, err := NewTemplates("zh")
+	require.NoError(t, err)
+
+	data := map[string]string{
+		"SiteName":       "Lin Studio",
+		"RecipientName":  "alice",
+		"AuthorName":     "<bob>",
+		"TargetTitle":    "Go并发",
+		"Excerpt":        "同意",
+		"ParentExcerpt":  "写得好",
+		"CommentURL":     "https://example.com/articles/go#comment-2",
+		"UnsubscribeURL": "https://api.example.com/api/v1/email/unsubscribe?kind=reply",
+	}
+
+	msg, err := templates.Render(TemplateCommentReply, "en", data)
+	require.NoError(t, err)
+	assert.Equal(t, `<bob> replied to your comment on "Go并发"`, msg.Subject)
+	assert.Contains(t, msg.Text, "https://example.com/articles/go#comment-2")
+	// HTML版本需要转义用户输入
+	assert.Contains(t, msg.HTML, "&lt;bob&gt;")
+	assert.NotContains(t, msg.HTML, "<bob>")
+
+	// 不支持的语言使用默认语言
+	fallback, err := templates.Render(TemplateCommentReply, "fr", data)
+	require.NoError(t, err)
+	zh, err := templates.Render(TemplateCommentReply, "zh", data)
+	require.NoError(t, err)
+	assert.Equal(t, zh.Subject, fallback.Subject)
+}
+
+// fakeSMTPServer 记录收到的命令和邮件内容的SMTP服务器
+type fakeSMTPServer struct {
+	listener net.Listener
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+// startFakeSMTPServer 在本地端口启动只处理一次会话的SMTP服务器，支持PLAIN认证
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		defer close(server.done)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		text := textproto.NewConn(conn)
+		reply := func(line string) { _ = text.PrintfLine("%s", line) }
+		reply("220 fake ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			server.commands = append(server.commands, line)
+			switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+			case "EHLO":
+				reply("250-fake")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				reply("235 2.7.0 Authentication successful")
+			case "MAIL", "RCPT":
+				reply("250 OK")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				lines, err := text.ReadDotLines()
+				if err != nil {
+					return
+				}
+				server.data = strings.Join(lines, "\n")
+				reply("250 OK: queued")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return server
+}
+
+// TestSMTPSenderSend 测试通过SMTP会话认证并发送邮件
+func TestSMTPSenderSend(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	host, port, err := net.SplitHostPort(server.listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	sender, err := New(config.MailConfig{
+		Host:     host,
+		Port:     portNumber,
+		Username: "noreply",
+		Password: "secret",
+		From:     "Lin Studio <noreply@example.com>",
+	})
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), &Message{
+		To:             "Alice <alice@example.com>",
+		Subject:        "新回复",
+		Text:           "你好",
+		UnsubscribeURL: "https://api.example.com/api/v1/email/unsubscribe?token=x",
+	})
+	require.NoError(t, err)
+	<-server.done
+
+	plain := base64.StdEncoding.EncodeToString([]byte("\x00noreply\x00secret"))
+	assert.Contains(t, server.commands, "AUTH PLAIN "+plain)
+	assert.Contains(t, server.commands, "MAIL FROM:<noreply@example.com>")
+	assert.Contains(t, server.commands, "RCPT TO:<alice@example.com>")
+	assert.Equal(t, "QUIT", server.commands[len(server.commands)-1])
+	assert.Contains(t, server.data, "To: \"Alice\" <alice@example.com>")
+	assert.Contains(t, server.data, "List-Unsubscribe-Post: List-Unsubscribe=One-Click")
+}
+
+// TestSMTPSenderRejected 测试服务器拒绝收件人时返回错误
+func TestSMTPSenderRejected(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		_ = text.PrintfLine("220 fake ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.SplitN(line, " ", 2)[0]) {
+			case "RCPT":
+				_ = text.PrintfLine("550 5.1.1 No such user")
+			case "QUIT":
+				_ = text.PrintfLine("221 Bye")
+				return
+			default:
+				_ = text.PrintfLine("250 OK")
+			}
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	sender, err := New(config.MailConfig{Host: "127.0.0.1", Port: portNumber, From: "noreply@example.com"})
+	require.NoError(t, err)
+
+	err = sender.Send(context.Background(), &Message{To: "nobody@example.com", Subject: "x", Text: "x"})
+	assert.ErrorContains(t, err, "No such user")
+}
@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// 邮件模板名称
+const (
+	TemplateCommentReply    = "comment_reply"
+	TemplateCommentApproved = "comment_approved"
+	TemplateCommentPending  = "comment_pending"
+	TemplateEmailConfirm    = "email_confirm"
+)
+
+// Locales 支持的邮件语言
+var Locales = []string{"zh", "en"}
+
+// template 同一封邮件的主题、纯文本和HTML模板
+type template struct {
+	text *texttemplate.Template // 包含subject和text两个模板
+	html *htmltemplate.Template
+}
+
+// Templates 邮件模板集合
+// 每个模板按语言对应两个文件: <名称>.<语言>.txt 定义subject和text，<名称>.<语言>.html 定义content并套用layout.html
+type Templates struct {
+	templates     map[string]*template
+	defaultLocale string
+}
+
+// NewTemplates 加载所有邮件模板，defaultLocale为找不到指定语言时使用的语言
+func NewTemplates(defaultLocale string) (*Templates, error) {
+	if !IsLocale(defaultLocale) {
+		defaultLocale = Locales[0]
+	}
+	t := &Templates{
+		templates:     make(map[string]*template),
+		defaultLocale: defaultLocale,
+	}
+
+	for _, name := range []string{TemplateCommentReply, TemplateCommentApproved, TemplateCommentPending, TemplateEmailConfirm} {
+		for _, locale := range Locales {
+			key := name + "." + locale
+			text, err := texttemplate.ParseFS(templateFS, "templates/"+key+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("加载邮件模板%s失败: %w", key, err)
+			}
+			html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", "templates/"+key+".html")
+			if err != nil {
+				return nil, fmt.Errorf("加载邮件模板%s失败: %w", key, err)
+			}
+			t.templates[key] = &template{text: text, html: html}
+		}
+	}
+	return t, nil
+}
+
+// Render 渲染邮件，返回的Message不包含收件人
+func (t *Templates) Render(name, locale string, data interface{}) (*Message, error) {
+	tmpl, ok := t.templates[name+"."+locale]
+	if !ok {
+		tmpl, ok = t.templates[name+"."+t.defaultLocale]
+	}
+	if !ok {
+		return nil, fmt.Errorf("邮件模板不存在: %s", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout.html", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// IsLocale 判断是否为支持的邮件语言
+func IsLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
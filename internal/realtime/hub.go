@@ -0,0 +1,151 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Message 推送给客户端的消息
+type Message struct {
+	ID    uint64 // 全局递增，作为SSE的id字段，客户端重连时通过Last-Event-ID带回
+	Topic string
+	Event string // SSE的event字段，例如 comment.created
+	Data  []byte // JSON数据
+}
+
+// Subscription 客户端连接的订阅
+// C在连接积压过多消息时被关闭，客户端应使用最后收到的消息ID重连补发
+type Subscription struct {
+	C      <-chan Message
+	ch     chan Message
+	topics map[string]bool
+	closed bool
+}
+
+// Hub 进程内发布订阅中心，按主题向客户端连接转发消息
+// 最近的消息保存在固定大小的环形缓冲区中，用于断线重连后补发
+type Hub struct {
+	mu         sync.Mutex
+	lastID     uint64
+	buffer     []Message
+	next       int // 下一条消息在buffer中的位置
+	size       int // buffer中已保存的消息数量
+	subs       map[*Subscription]struct{}
+	clientSize int
+}
+
+// NewHub 创建发布订阅中心，replaySize为补发缓冲区大小，clientSize为单个连接的消息积压上限
+func NewHub(replaySize, clientSize int) *Hub {
+	if replaySize <= 0 {
+		replaySize = 1
+	}
+	if clientSize <= 0 {
+		clientSize = 1
+	}
+	return &Hub{
+		// 以启动时间作为起始ID，服务重启后客户端带回的旧ID不会与新消息冲突
+		lastID:     uint64(time.Now().UnixMicro()),
+		buffer:     make([]Message, replaySize),
+		subs:       make(map[*Subscription]struct{}),
+		clientSize: clientSize,
+	}
+}
+
+// Publish 向主题发布消息，data序列化为JSON
+func (h *Hub) Publish(topic, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	msg := Message{ID: h.lastID, Topic: topic, Event: event, Data: payload}
+	h.buffer[h.next] = msg
+	h.next = (h.next + 1) % len(h.buffer)
+	if h.size < len(h.buffer) {
+		h.size++
+	}
+
+	for sub := range h.subs {
+		if !sub.topics[topic] {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			// 客户端处理过慢，断开后由客户端重连补发
+			h.close(sub)
+		}
+	}
+	return nil
+}
+
+// Subscribe 订阅主题
+// lastID大于0时返回缓冲区中该ID之后的消息，complete为false表示部分消息已不在缓冲区中，客户端需要重新加载数据
+func (h *Hub) Subscribe(topics []string, lastID uint64) (sub *Subscription, replay []Message, complete bool) {
+	ch := make(chan Message, h.clientSize)
+	sub = &Subscription{C: ch, ch: ch, topics: make(map[string]bool, len(topics))}
+	for _, topic := range topics {
+		sub.topics[topic] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	complete = true
+	if lastID > 0 {
+		replay, complete = h.since(lastID, sub.topics)
+	}
+	h.subs[sub] = struct{}{}
+	return sub, replay, complete
+}
+
+// since 返回缓冲区中ID大于lastID且属于指定主题的消息
+func (h *Hub) since(lastID uint64, topics map[string]bool) ([]Message, bool) {
+	if lastID > h.lastID {
+		// 来自其他进程或重启前的ID
+		return nil, false
+	}
+
+	oldest := h.lastID + 1
+	var replay []Message
+	start := (h.next - h.size + len(h.buffer)) % len(h.buffer)
+	for i := 0; i < h.size; i++ {
+		msg := h.buffer[(start+i)%len(h.buffer)]
+		if i == 0 {
+			oldest = msg.ID
+		}
+		if msg.ID > lastID && topics[msg.Topic] {
+			replay = append(replay, msg)
+		}
+	}
+	return replay, lastID+1 >= oldest
+}
+
+// Unsubscribe 取消订阅，连接断开时调用
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.close(sub)
+}
+
+// close 移除订阅并关闭消息通道，调用方需持有锁
+func (h *Hub) close(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subs, sub)
+	close(sub.ch)
+}
+
+// Subscribers 返回当前的订阅数量
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
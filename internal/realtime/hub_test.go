@@ -0,0 +1,67 @@
+package realtime
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublishAndReplay(t *testing.T) {
+	hub := NewHub(3, 8)
+
+	sub, replay, complete := hub.Subscribe([]string{"article:1"}, 0)
+	assert.Empty(t, replay)
+	assert.True(t, complete)
+
+	require.NoError(t, hub.Publish("article:1", "comment.created", map[string]int{"id": 1}))
+	require.NoError(t, hub.Publish("article:2", "comment.created", map[string]int{"id": 2}))
+
+	msg := <-sub.C
+	assert.Equal(t, "article:1", msg.Topic)
+	assert.JSONEq(t, `{"id":1}`, string(msg.Data))
+	assert.Empty(t, sub.C, "不应收到未订阅主题的消息")
+	hub.Unsubscribe(sub)
+	assert.Equal(t, 0, hub.Subscribers())
+
+	// 断线期间的消息在重连时补发
+	first := msg.ID
+	require.NoError(t, hub.Publish("article:1", "comment.created", map[string]int{"id": 3}))
+	_, replay, complete = hub.Subscribe([]string{"article:1"}, first)
+	assert.True(t, complete)
+	require.Len(t, replay, 1)
+	assert.JSONEq(t, `{"id":3}`, string(replay[0].Data))
+
+	// 缓冲区只保留最近3条，更早的消息无法补发
+	require.NoError(t, hub.Publish("article:1", "comment.created", map[string]int{"id": 4}))
+	require.NoError(t, hub.Publish("article:1", "comment.created", map[string]int{"id": 5}))
+	_, replay, complete = hub.Subscribe([]string{"article:1"}, first)
+	assert.False(t, complete)
+	assert.Len(t, replay, 3)
+
+	// 其他进程的ID视为无法补发
+	_, _, complete = hub.Subscribe([]string{"article:1"}, first+100)
+	assert.False(t, complete)
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub(10, 1)
+	sub, _, _ := hub.Subscribe([]string{"moderation"}, 0)
+
+	require.NoError(t, hub.Publish("moderation", "comment.pending", nil))
+	require.NoError(t, hub.Publish("moderation", "comment.pending", nil))
+
+	_, ok := <-sub.C
+	assert.True(t, ok)
+	_, ok = <-sub.C
+	assert.False(t, ok, "积压超过上限的连接应被断开")
+	assert.Equal(t, 0, hub.Subscribers())
+	hub.Unsubscribe(sub)
+}
+
+func TestWriteEvent(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteEvent(&buf, Message{ID: 42, Event: "comment.created", Data: []byte(`{"id":1}`)}))
+	assert.Equal(t, "id: 42\nevent: comment.created\ndata: {\"id\":1}\n\n", buf.String())
+}
@@ -0,0 +1,18 @@
+package realtime
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteEvent 按Server-Sent Events格式写出消息
+func WriteEvent(w io.Writer, msg Message) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Event, msg.Data)
+	return err
+}
+
+// WriteComment 写出SSE注释行，客户端会忽略，用作心跳保持连接
+func WriteComment(w io.Writer, text string) error {
+	_, err := fmt.Fprintf(w, ": %s\n\n", text)
+	return err
+}
@@ -0,0 +1,357 @@
+package repository
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnalyticsFilter 统计查询条件，From和To为 2006-01-02 格式的闭区间
+type AnalyticsFilter struct {
+	ItemType string // 为空时不限内容类型
+	ItemID   uint   // 为0时不限内容
+	From     string
+	To       string
+	Metric   string // 排行使用的指标
+	Limit    int
+}
+
+// AnalyticsCampaignKey 推广活动的UTM参数
+type AnalyticsCampaignKey struct {
+	Source   string
+	Medium   string
+	Campaign string
+}
+
+// AnalyticsViewBatch 同一天同一内容的一批浏览
+type AnalyticsViewBatch struct {
+	Date      string
+	ItemType  string
+	ItemID    uint
+	Views     uint
+	Visitors  []string                      // 访问者哈希，当天第一次出现的计入独立访客
+	Referrers map[string]uint               // 来源域名到浏览量的映射
+	Campaigns map[AnalyticsCampaignKey]uint // 推广活动到浏览量的映射
+}
+
+// AnalyticsRepository 统计仓储接口
+type AnalyticsRepository interface {
+	RecordViews(ctx context.Context, batches []AnalyticsViewBatch) error
+	AddCounts(ctx context.Context, stat *domain.AnalyticsStat) error
+	DailySeries(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsPoint, error)
+	MonthlySeries(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsPoint, error)
+	TopItems(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsItem, error)
+	TopReferrers(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsReferrerCount, error)
+	TopCampaigns(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsCampaignCount, error)
+	DailySalt(ctx context.Context, date, candidate string) (string, error)
+	DeleteVisitorsBefore(ctx context.Context, date string) (int64, error)
+	RollupBefore(ctx context.Context, date string) (int64, error)
+	DeleteMonthlyBefore(ctx context.Context, date string) (int64, error)
+}
+
+// AnalyticsRepositoryImpl 统计仓储实现
+type AnalyticsRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsRepository 创建统计仓储实例
+func NewAnalyticsRepository() AnalyticsRepository {
+	return &AnalyticsRepositoryImpl{
+		db: config.DB,
+	}
+}
+
+// statIncrements 写入统计时累加到已有记录
+var statIncrements = clause.OnConflict{
+	DoUpdates: clause.Assignments(map[string]interface{}{
+		"views":    gorm.Expr("views + VALUES(views)"),
+		"visitors": gorm.Expr("visitors + VALUES(visitors)"),
+		"likes":    gorm.Expr("likes + VALUES(likes)"),
+		"comments": gorm.Expr("comments + VALUES(comments)"),
+	}),
+}
+
+// viewIncrements 写入来源和推广活动统计时累加到已有记录
+var viewIncrements = clause.OnConflict{
+	DoUpdates: clause.Assignments(map[string]interface{}{
+		"views": gorm.Expr("views + VALUES(views)"),
+	}),
+}
+
+// RecordViews 在一个事务中写入浏览量、独立访客、来源和推广活动
+func (r *AnalyticsRepositoryImpl) RecordViews(ctx context.Context, batches []AnalyticsViewBatch) error {
+	if len(batches) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stats := make([]domain.AnalyticsStat, 0, len(batches))
+		var referrers []domain.AnalyticsReferrer
+		var campaigns []domain.AnalyticsCampaign
+		for _, batch := range batches {
+			stat := domain.AnalyticsStat{
+				Period:   domain.AnalyticsPeriodDay,
+				Date:     batch.Date,
+				ItemType: batch.ItemType,
+				ItemID:   batch.ItemID,
+				Views:    batch.Views,
+			}
+
+			// 已记录过的访问者被忽略，新增的行数即新的独立访客数
+			if len(batch.Visitors) > 0 {
+				visitors := make([]domain.AnalyticsVisitor, len(batch.Visitors))
+				for i, visitor := range batch.Visitors {
+					visitors[i] = domain.AnalyticsVisitor{
+						Date:     batch.Date,
+						ItemType: batch.ItemType,
+						ItemID:   batch.ItemID,
+						Visitor:  visitor,
+					}
+				}
+				result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&visitors)
+				if result.Error != nil {
+					return result.Error
+				}
+				stat.Visitors = uint(result.RowsAffected)
+			}
+			stats = append(stats, stat)
+
+			for domainName, views := range batch.Referrers {
+				referrers = append(referrers, domain.AnalyticsReferrer{
+					Period:   domain.AnalyticsPeriodDay,
+					Date:     batch.Date,
+					ItemType: batch.ItemType,
+					ItemID:   batch.ItemID,
+					Domain:   domainName,
+					Views:    views,
+				})
+			}
+			for key, views := range batch.Campaigns {
+				campaigns = append(campaigns, domain.AnalyticsCampaign{
+					Period:   domain.AnalyticsPeriodDay,
+					Date:     batch.Date,
+					ItemType: batch.ItemType,
+					ItemID:   batch.ItemID,
+					Source:   key.Source,
+					Medium:   key.Medium,
+					Campaign: key.Campaign,
+					Views:    views,
+				})
+			}
+		}
+
+		if err := tx.Clauses(statIncrements).Create(&stats).Error; err != nil {
+			return err
+		}
+		if len(referrers) > 0 {
+			if err := tx.Clauses(viewIncrements).Create(&referrers).Error; err != nil {
+				return err
+			}
+		}
+		if len(campaigns) > 0 {
+			return tx.Clauses(viewIncrements).Create(&campaigns).Error
+		}
+		return nil
+	})
+}
+
+// AddCounts 把统计累加到对应日期的记录
+func (r *AnalyticsRepositoryImpl) AddCounts(ctx context.Context, stat *domain.AnalyticsStat) error {
+	return r.db.WithContext(ctx).Clauses(statIncrements).Create(stat).Error
+}
+
+// DailySeries 按天合计统计，只包含按日统计的记录
+func (r *AnalyticsRepositoryImpl) DailySeries(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsPoint, error) {
+	var points []domain.AnalyticsPoint
+	err := r.filtered(r.db.WithContext(ctx).Model(&domain.AnalyticsStat{}), filter).
+		Where("period = ?", domain.AnalyticsPeriodDay).
+		Select("date, SUM(views) AS views, SUM(visitors) AS visitors, SUM(likes) AS likes, SUM(comments) AS comments").
+		Group("date").
+		Order("date").
+		Scan(&points).Error
+	return points, err
+}
+
+// MonthlySeries 按月合计统计，包含按日统计和已汇总的按月统计
+func (r *AnalyticsRepositoryImpl) MonthlySeries(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsPoint, error) {
+	var points []domain.AnalyticsPoint
+	err := r.filtered(r.db.WithContext(ctx).Model(&domain.AnalyticsStat{}), filter).
+		Select("LEFT(date, 7) AS date, SUM(views) AS views, SUM(visitors) AS visitors, SUM(likes) AS likes, SUM(comments) AS comments").
+		Group("LEFT(date, 7)").
+		Order("LEFT(date, 7)").
+		Scan(&points).Error
+	return points, err
+}
+
+// TopItems 按指标合计排序的内容，filter.Metric需为支持的统计指标
+func (r *AnalyticsRepositoryImpl) TopItems(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsItem, error) {
+	if !domain.IsAnalyticsMetric(filter.Metric) {
+		filter.Metric = domain.AnalyticsMetricViews
+	}
+
+	var items []domain.AnalyticsItem
+	err := r.filtered(r.db.WithContext(ctx).Model(&domain.AnalyticsStat{}), filter).
+		Select("item_type, item_id, SUM(views) AS views, SUM(visitors) AS visitors, SUM(likes) AS likes, SUM(comments) AS comments").
+		Group("item_type, item_id").
+		Order(filter.Metric + " DESC, item_id").
+		Limit(filter.Limit).
+		Scan(&items).Error
+	return items, err
+}
+
+// TopReferrers 按浏览量排序的来源域名
+func (r *AnalyticsRepositoryImpl) TopReferrers(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsReferrerCount, error) {
+	var referrers []domain.AnalyticsReferrerCount
+	err := r.filtered(r.db.WithContext(ctx).Model(&domain.AnalyticsReferrer{}), filter).
+		Select("domain, SUM(views) AS views").
+		Group("domain").
+		Order("views DESC, domain").
+		Limit(filter.Limit).
+		Scan(&referrers).Error
+	return referrers, err
+}
+
+// TopCampaigns 按浏览量排序的推广活动
+func (r *AnalyticsRepositoryImpl) TopCampaigns(ctx context.Context, filter AnalyticsFilter) ([]domain.AnalyticsCampaignCount, error) {
+	var campaigns []domain.AnalyticsCampaignCount
+	err := r.filtered(r.db.WithContext(ctx).Model(&domain.AnalyticsCampaign{}), filter).
+		Select("source, medium, campaign, SUM(views) AS views").
+		Group("source, medium, campaign").
+		Order("views DESC, source, medium, campaign").
+		Limit(filter.Limit).
+		Scan(&campaigns).Error
+	return campaigns, err
+}
+
+// DailySalt 返回指定日期的盐，还没有时保存candidate，多个进程同时写入时以先保存的为准
+func (r *AnalyticsRepositoryImpl) DailySalt(ctx context.Context, date, candidate string) (string, error) {
+	db := r.db.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.AnalyticsSalt{Date: date, Salt: candidate}).Error
+	if err != nil {
+		return "", err
+	}
+
+	var salt domain.AnalyticsSalt
+	if err := db.Where("date = ?", date).First(&salt).Error; err != nil {
+		return "", err
+	}
+	return salt.Salt, nil
+}
+
+// DeleteVisitorsBefore 删除指定日期之前的访问者记录和盐，这些日期的独立访客数已经确定
+func (r *AnalyticsRepositoryImpl) DeleteVisitorsBefore(ctx context.Context, date string) (int64, error) {
+	var removed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("date < ?", date).Delete(&domain.AnalyticsVisitor{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
+		return tx.Where("date < ?", date).Delete(&domain.AnalyticsSalt{}).Error
+	})
+	return removed, err
+}
+
+// RollupBefore 把指定日期之前的按日统计、来源和推广活动汇总为按月统计后删除，返回删除的按日记录数
+func (r *AnalyticsRepositoryImpl) RollupBefore(ctx context.Context, date string) (int64, error) {
+	var removed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stats []domain.AnalyticsStat
+		err := tx.Model(&domain.AnalyticsStat{}).
+			Where("period = ? AND date < ?", domain.AnalyticsPeriodDay, date).
+			Select("CONCAT(LEFT(date, 7), '-01') AS date, item_type, item_id, SUM(views) AS views, SUM(visitors) AS visitors, SUM(likes) AS likes, SUM(comments) AS comments").
+			Group("CONCAT(LEFT(date, 7), '-01'), item_type, item_id").
+			Scan(&stats).Error
+		if err != nil {
+			return err
+		}
+		if len(stats) > 0 {
+			for i := range stats {
+				stats[i].Period = domain.AnalyticsPeriodMonth
+			}
+			if err := tx.Clauses(statIncrements).CreateInBatches(&stats, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		var referrers []domain.AnalyticsReferrer
+		err = tx.Model(&domain.AnalyticsReferrer{}).
+			Where("period = ? AND date < ?", domain.AnalyticsPeriodDay, date).
+			Select("CONCAT(LEFT(date, 7), '-01') AS date, item_type, item_id, domain, SUM(views) AS views").
+			Group("CONCAT(LEFT(date, 7), '-01'), item_type, item_id, domain").
+			Scan(&referrers).Error
+		if err != nil {
+			return err
+		}
+		if len(referrers) > 0 {
+			for i := range referrers {
+				referrers[i].Period = domain.AnalyticsPeriodMonth
+			}
+			if err := tx.Clauses(viewIncrements).CreateInBatches(&referrers, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		var campaigns []domain.AnalyticsCampaign
+		err = tx.Model(&domain.AnalyticsCampaign{}).
+			Where("period = ? AND date < ?", domain.AnalyticsPeriodDay, date).
+			Select("CONCAT(LEFT(date, 7), '-01') AS date, item_type, item_id, source, medium, campaign, SUM(views) AS views").
+			Group("CONCAT(LEFT(date, 7), '-01'), item_type, item_id, source, medium, campaign").
+			Scan(&campaigns).Error
+		if err != nil {
+			return err
+		}
+		if len(campaigns) > 0 {
+			for i := range campaigns {
+				campaigns[i].Period = domain.AnalyticsPeriodMonth
+			}
+			if err := tx.Clauses(viewIncrements).CreateInBatches(&campaigns, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where("period = ? AND date < ?", domain.AnalyticsPeriodDay, date).Delete(&domain.AnalyticsStat{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
+		if err := tx.Where("period = ? AND date < ?", domain.AnalyticsPeriodDay, date).Delete(&domain.AnalyticsReferrer{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("period = ? AND date < ?", domain.AnalyticsPeriodDay, date).Delete(&domain.AnalyticsCampaign{}).Error
+	})
+	return removed, err
+}
+
+// DeleteMonthlyBefore 删除指定日期之前的按月统计、来源和推广活动
+func (r *AnalyticsRepositoryImpl) DeleteMonthlyBefore(ctx context.Context, date string) (int64, error) {
+	var removed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("period = ? AND date < ?", domain.AnalyticsPeriodMonth, date).Delete(&domain.AnalyticsStat{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
+		if err := tx.Where("period = ? AND date < ?", domain.AnalyticsPeriodMonth, date).Delete(&domain.AnalyticsReferrer{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("period = ? AND date < ?", domain.AnalyticsPeriodMonth, date).Delete(&domain.AnalyticsCampaign{}).Error
+	})
+	return removed, err
+}
+
+// filtered 添加日期范围和内容条件
+func (r *AnalyticsRepositoryImpl) filtered(query *gorm.DB, filter AnalyticsFilter) *gorm.DB {
+	query = query.Where("date BETWEEN ? AND ?", filter.From, filter.To)
+	if filter.ItemType != "" {
+		query = query.Where("item_type = ?", filter.ItemType)
+	}
+	if filter.ItemID > 0 {
+		query = query.Where("item_id = ?", filter.ItemID)
+	}
+	return query
+}
@@ -29,9 +29,11 @@ type ArticleRepository interface {
 	FindAll(ctx context.Context, filter ArticleFilter) ([]domain.Article, int64, error)
 	FindFeatured(ctx context.Context, limit int) ([]domain.Article, error)
 	Update(ctx context.Context, article *domain.Article) error
-	Delete(ctx context.Context, id uint) error
-	IncrementViews(ctx context.Context, id uint) error
-	IncrementLikes(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id, version uint) error
+	AddViews(ctx context.Context, counts map[uint]uint) error
+	FindPublishedWithTags(ctx context.Context) ([]domain.Article, error)
+	FindRelated(ctx context.Context, articleID uint, limit int) ([]domain.Article, error)
+	ReplaceRelated(ctx context.Context, related []domain.ArticleRelated) error
 }
 
 // ArticleRepositoryImpl 文章仓储实现
@@ -151,30 +153,53 @@ func (r *ArticleRepositoryImpl) FindFeatured(ctx context.Context, limit int) ([]
 	return articles, err
 }
 
-// Update 更新文章
+// Update 更新文章，版本号与读取时不一致时返回ErrVersionConflict
 func (r *ArticleRepositoryImpl) Update(ctx context.Context, article *domain.Article) error {
-	return r.db.WithContext(ctx).Save(article).Error
+	return updateVersioned(r.db.WithContext(ctx), article, &article.Version, "views", "likes", "comments_count")
 }
 
-// Delete 删除文章
-func (r *ArticleRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Article{}, id).Error
+// Delete 删除文章，version为0时不检查版本号
+func (r *ArticleRepositoryImpl) Delete(ctx context.Context, id, version uint) error {
+	return deleteVersioned(r.db.WithContext(ctx), &domain.Article{}, id, version)
 }
 
-// IncrementViews 增加文章浏览量
-func (r *ArticleRepositoryImpl) IncrementViews(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
-		Model(&domain.Article{}).
-		Where("id = ?", id).
-		UpdateColumn("views", gorm.Expr("views + ?", 1)).
-		Error
+// AddViews 批量增加文章浏览量，counts为文章ID到新增浏览量的映射
+func (r *ArticleRepositoryImpl) AddViews(ctx context.Context, counts map[uint]uint) error {
+	return addViews(r.db.WithContext(ctx), &domain.Article{}, counts)
 }
 
-// IncrementLikes 增加文章点赞数
-func (r *ArticleRepositoryImpl) IncrementLikes(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
-		Model(&domain.Article{}).
-		Where("id = ?", id).
-		UpdateColumn("likes", gorm.Expr("likes + ?", 1)).
-		Error
-} 
\ No newline at end of file
+// FindPublishedWithTags 查找所有已发布文章及其标签，用于计算相关文章
+func (r *ArticleRepositoryImpl) FindPublishedWithTags(ctx context.Context) ([]domain.Article, error) {
+	var articles []domain.Article
+	err := r.db.WithContext(ctx).
+		Select("id", "title", "excerpt", "content", "category_id", "status", "published_at").
+		Preload("Tags").
+		Where("status = ?", "published").
+		Find(&articles).Error
+	return articles, err
+}
+
+// FindRelated 按相关程度查找文章的已发布相关文章
+func (r *ArticleRepositoryImpl) FindRelated(ctx context.Context, articleID uint, limit int) ([]domain.Article, error) {
+	var articles []domain.Article
+	err := r.db.WithContext(ctx).
+		Joins("JOIN article_related ON article_related.related_id = articles.id").
+		Where("article_related.article_id = ? AND articles.status = ?", articleID, "published").
+		Order("article_related.position ASC").
+		Limit(limit).
+		Find(&articles).Error
+	return articles, err
+}
+
+// ReplaceRelated 用新计算的结果替换全部相关文章
+func (r *ArticleRepositoryImpl) ReplaceRelated(ctx context.Context, related []domain.ArticleRelated) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&domain.ArticleRelated{}).Error; err != nil {
+			return err
+		}
+		if len(related) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(related, 500).Error
+	})
+}
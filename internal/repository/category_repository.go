@@ -16,7 +16,7 @@ type CategoryRepository interface {
 	FindBySlug(ctx context.Context, slug string) (*domain.Category, error)
 	FindAll(ctx context.Context, parentID *uint) ([]domain.Category, error)
 	Update(ctx context.Context, category *domain.Category) error
-	Delete(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id, version uint) error
 	CountArticles(ctx context.Context, categoryID uint) (int64, error)
 }
 
@@ -78,14 +78,14 @@ func (r *CategoryRepositoryImpl) FindAll(ctx context.Context, parentID *uint) ([
 	return categories, err
 }
 
-// Update 更新分类
+// Update 更新分类，版本号与读取时不一致时返回ErrVersionConflict
 func (r *CategoryRepositoryImpl) Update(ctx context.Context, category *domain.Category) error {
-	return r.db.WithContext(ctx).Save(category).Error
+	return updateVersioned(r.db.WithContext(ctx), category, &category.Version)
 }
 
-// Delete 删除分类
-func (r *CategoryRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Category{}, id).Error
+// Delete 删除分类，version为0时不检查版本号
+func (r *CategoryRepositoryImpl) Delete(ctx context.Context, id, version uint) error {
+	return deleteVersioned(r.db.WithContext(ctx), &domain.Category{}, id, version)
 }
 
 // CountArticles 统计分类下的文章数量
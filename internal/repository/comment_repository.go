@@ -3,10 +3,14 @@ package repository
 import (
 	"Lin_studio/internal/config"
 	"Lin_studio/internal/domain"
+	"Lin_studio/internal/utils"
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CommentFilter 评论筛选条件
@@ -18,8 +22,30 @@ type CommentFilter struct {
 	ParentID *uint
 	UserID   *uint
 	Status   *string
+	OldestFirst bool // 按发布时间正序排列，用于回复列表
 }
 
+// ModerationFilter 审核队列筛选条件
+type ModerationFilter struct {
+	Page     int
+	Limit    int
+	Status   string // 默认为pending
+	ItemType string
+	ItemID   uint
+	MinScore *float64 // 只查询垃圾信息分数不低于该值的评论
+}
+
+// ModerationLogFilter 审核日志筛选条件
+type ModerationLogFilter struct {
+	Page        int
+	Limit       int
+	CommentID   *uint
+	ModeratorID *uint
+}
+
+// visibleCommentStatuses 评论树中显示的评论状态，作者删除的评论有回复时保留为已删除的占位评论
+var visibleCommentStatuses = []string{"approved", "deleted"}
+
 // CommentRepository 评论仓储接口
 type CommentRepository interface {
 	Create(ctx context.Context, comment *domain.Comment) error
@@ -27,10 +53,22 @@ type CommentRepository interface {
 	FindAll(ctx context.Context, filter CommentFilter) ([]domain.Comment, int64, error)
 	FindReplies(ctx context.Context, parentID uint) ([]domain.Comment, error)
 	CountReplies(ctx context.Context, parentID uint) (int64, error)
+	FindDescendants(ctx context.Context, paths []string, maxDepth, perParent int) ([]domain.Comment, error)
+	CountRepliesByParent(ctx context.Context, parentIDs []uint) (map[uint]int64, error)
+	FindTarget(ctx context.Context, itemType string, itemID uint) (*domain.CommentTarget, error)
+	FindTargets(ctx context.Context, itemType string, itemIDs []uint) (map[uint]domain.CommentTarget, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]domain.Comment, error)
+	FindForModeration(ctx context.Context, filter ModerationFilter) ([]domain.Comment, int64, error)
+	CountAuthorHistory(ctx context.Context, userIDs []uint, emails []string) (map[uint]domain.CommentAuthorStats, map[string]domain.CommentAuthorStats, error)
+	Moderate(ctx context.Context, id uint, action string, moderatorID uint) (*domain.ModerationLog, error)
+	FindModerationLogs(ctx context.Context, filter ModerationLogFilter) ([]domain.ModerationLog, int64, error)
 	Update(ctx context.Context, comment *domain.Comment) error
+	Edit(ctx context.Context, id uint, content, contentHTML string, editorID uint, status string) (*domain.Comment, error)
+	FindRevisions(ctx context.Context, commentID uint) ([]domain.CommentRevision, error)
+	SyncMentions(ctx context.Context, commentID uint, userIDs []uint) error
+	ClaimMentions(ctx context.Context, commentID uint) ([]uint, error)
 	UpdateStatus(ctx context.Context, id uint, status string) error
 	Delete(ctx context.Context, id uint) error
-	IncrementLikes(ctx context.Context, id uint) error
 }
 
 // CommentRepositoryImpl 评论仓储实现
@@ -45,9 +83,36 @@ func NewCommentRepository() CommentRepository {
 	}
 }
 
-// Create 创建评论
+// Create 创建评论，并根据父评论生成物化路径和层级
+// 已批准的评论同时增加所属内容的评论数量
 func (r *CommentRepositoryImpl) Create(ctx context.Context, comment *domain.Comment) error {
-	return r.db.WithContext(ctx).Create(comment).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		prefix := ""
+		comment.Depth = 0
+		if comment.ParentID != nil {
+			var parent domain.Comment
+			if err := tx.Select("id", "path", "depth").Where("id = ?", *comment.ParentID).First(&parent).Error; err != nil {
+				return err
+			}
+			prefix = parent.Path
+			comment.Depth = parent.Depth + 1
+		}
+
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+
+		// 路径包含自身ID，只能在插入后生成
+		comment.Path = prefix + domain.CommentPathSegment(comment.ID)
+		if err := tx.Model(comment).UpdateColumn("path", comment.Path).Error; err != nil {
+			return err
+		}
+
+		if comment.Status == "approved" {
+			return adjustCommentCount(tx, comment.ItemType, comment.ItemID, 1)
+		}
+		return nil
+	})
 }
 
 // FindByID 根据ID查找评论
@@ -93,8 +158,8 @@ func (r *CommentRepositoryImpl) FindAll(ctx context.Context, filter CommentFilte
 	if filter.Status != nil {
 		query = query.Where("status = ?", *filter.Status)
 	} else {
-		// 默认只查询已批准的评论
-		query = query.Where("status = ?", "approved")
+		// 默认只查询已批准的评论和保留了回复的已删除评论
+		query = query.Where("status IN ?", visibleCommentStatuses)
 	}
 	
 	// 计算总数
@@ -104,7 +169,11 @@ func (r *CommentRepositoryImpl) FindAll(ctx context.Context, filter CommentFilte
 	}
 	
 	// 排序和分页
-	query = query.Order("created_at DESC")
+	if filter.OldestFirst {
+		query = query.Order("created_at ASC, id ASC")
+	} else {
+		query = query.Order("created_at DESC")
+	}
 	
 	// 计算分页
 	offset := (filter.Page - 1) * filter.Limit
@@ -120,7 +189,7 @@ func (r *CommentRepositoryImpl) FindAll(ctx context.Context, filter CommentFilte
 func (r *CommentRepositoryImpl) FindReplies(ctx context.Context, parentID uint) ([]domain.Comment, error) {
 	var replies []domain.Comment
 	err := r.db.WithContext(ctx).
-		Where("parent_id = ? AND status = ?", parentID, "approved").
+		Where("parent_id = ? AND status IN ?", parentID, visibleCommentStatuses).
 		Order("created_at ASC").
 		Find(&replies).Error
 	return replies, err
@@ -131,34 +200,576 @@ func (r *CommentRepositoryImpl) CountReplies(ctx context.Context, parentID uint)
 	var count int64
 	err := r.db.WithContext(ctx).
 		Model(&domain.Comment{}).
-		Where("parent_id = ? AND status = ?", parentID, "approved").
+		Where("parent_id = ? AND status IN ?", parentID, visibleCommentStatuses).
 		Count(&count).Error
 	return count, err
 }
 
+// FindDescendants 查找指定路径下的已批准回复和已删除的占位评论
+// maxDepth为回复的最大层级，perParent限制每条评论最多返回的直接回复数量
+func (r *CommentRepositoryImpl) FindDescendants(ctx context.Context, paths []string, maxDepth, perParent int) ([]domain.Comment, error) {
+	var comments []domain.Comment
+	if len(paths) == 0 {
+		return comments, nil
+	}
+
+	// 路径前缀匹配子树，_%排除评论自身
+	subtree := r.db.Where("path LIKE ?", paths[0]+"_%")
+	for _, p := range paths[1:] {
+		subtree = subtree.Or("path LIKE ?", p+"_%")
+	}
+
+	// 按父评论分组编号，每组只保留最早的perParent条
+	inner := r.db.WithContext(ctx).
+		Model(&domain.Comment{}).
+		Select("comments.*, ROW_NUMBER() OVER (PARTITION BY parent_id ORDER BY created_at ASC, id ASC) AS row_num").
+		Where(subtree).
+		Where("depth <= ? AND status IN ?", maxDepth, visibleCommentStatuses)
+
+	err := r.db.WithContext(ctx).
+		Table("(?) AS t", inner).
+		Where("t.row_num <= ?", perParent).
+		Order("t.depth ASC, t.created_at ASC, t.id ASC").
+		Find(&comments).Error
+	return comments, err
+}
+
+// CountRepliesByParent 批量统计评论的已批准回复和已删除的占位评论数量
+func (r *CommentRepositoryImpl) CountRepliesByParent(ctx context.Context, parentIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ParentID uint
+		Count    int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&domain.Comment{}).
+		Select("parent_id, COUNT(*) AS count").
+		Where("parent_id IN ? AND status IN ?", parentIDs, visibleCommentStatuses).
+		Group("parent_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.ParentID] = row.Count
+	}
+	return counts, nil
+}
+
+// backfillCommentPaths 为物化路径上线前创建的评论补全路径和层级
+// 先处理顶级评论，再逐层处理父评论已有路径的回复，直到没有可更新的记录
+func backfillCommentPaths(db *gorm.DB) error {
+	err := db.Exec(`UPDATE comments SET path = CONCAT(LPAD(id, 10, '0'), '/'), depth = 0
+		WHERE parent_id IS NULL AND (path IS NULL OR path = '')`).Error
+	if err != nil {
+		return err
+	}
+
+	for {
+		result := db.Exec(`UPDATE comments c JOIN comments p ON c.parent_id = p.id
+			SET c.path = CONCAT(p.path, LPAD(c.id, 10, '0'), '/'), c.depth = p.depth + 1
+			WHERE (c.path IS NULL OR c.path = '') AND p.path <> ''`)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+	}
+}
+
+// backfillCommentHTML 为支持Markdown之前的评论生成HTML，已有评论中的@提及不生成链接
+func backfillCommentHTML(db *gorm.DB) error {
+	var comments []domain.Comment
+	result := db.Select("id", "content").
+		Where("content_html IS NULL OR content_html = ''").
+		Where("content <> ''").
+		FindInBatches(&comments, 200, func(tx *gorm.DB, batch int) error {
+			for _, comment := range comments {
+				html, err := utils.RenderCommentMarkdown(comment.Content, nil)
+				if err != nil {
+					return err
+				}
+				err = tx.Model(&domain.Comment{}).
+					Where("id = ?", comment.ID).
+					UpdateColumn("content_html", html).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	return result.Error
+}
+
+// FindTarget 查找评论所属的内容，不存在时返回nil
+func (r *CommentRepositoryImpl) FindTarget(ctx context.Context, itemType string, itemID uint) (*domain.CommentTarget, error) {
+	return findCommentTarget(r.db.WithContext(ctx), itemType, itemID)
+}
+
+// FindTargets 批量查找同一类型的评论内容
+func (r *CommentRepositoryImpl) FindTargets(ctx context.Context, itemType string, itemIDs []uint) (map[uint]domain.CommentTarget, error) {
+	return findCommentTargets(r.db.WithContext(ctx), itemType, itemIDs)
+}
+
+// FindByIDs 根据ID批量查找评论
+func (r *CommentRepositoryImpl) FindByIDs(ctx context.Context, ids []uint) ([]domain.Comment, error) {
+	var comments []domain.Comment
+	if len(ids) == 0 {
+		return comments, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&comments).Error
+	return comments, err
+}
+
+// FindForModeration 查找审核队列中的评论，包含所有层级，按提交时间先后排列
+func (r *CommentRepositoryImpl) FindForModeration(ctx context.Context, filter ModerationFilter) ([]domain.Comment, int64, error) {
+	var comments []domain.Comment
+	var total int64
+
+	status := filter.Status
+	if status == "" {
+		status = "pending"
+	}
+	query := r.db.WithContext(ctx).Model(&domain.Comment{}).Where("status = ?", status)
+
+	if filter.ItemType != "" {
+		query = query.Where("item_type = ?", filter.ItemType)
+	}
+
+	if filter.ItemID > 0 {
+		query = query.Where("item_id = ?", filter.ItemID)
+	}
+
+	if filter.MinScore != nil {
+		query = query.Where("spam_score >= ?", *filter.MinScore)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Order("created_at ASC, id ASC").
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&comments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comments, total, nil
+}
+
+// CountAuthorHistory 统计评论作者各状态的历史评论数量
+// 注册用户按用户ID统计，匿名用户按邮箱统计
+func (r *CommentRepositoryImpl) CountAuthorHistory(ctx context.Context, userIDs []uint, emails []string) (map[uint]domain.CommentAuthorStats, map[string]domain.CommentAuthorStats, error) {
+	const columns = `COUNT(CASE WHEN status = 'approved' THEN 1 END) AS approved,
+		COUNT(CASE WHEN status = 'pending' THEN 1 END) AS pending,
+		COUNT(CASE WHEN status = 'spam' THEN 1 END) AS spam`
+
+	byUser := make(map[uint]domain.CommentAuthorStats, len(userIDs))
+	if len(userIDs) > 0 {
+		var rows []struct {
+			UserID uint
+			domain.CommentAuthorStats
+		}
+		err := r.db.WithContext(ctx).
+			Model(&domain.Comment{}).
+			Select("user_id, "+columns).
+			Where("user_id IN ?", userIDs).
+			Group("user_id").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, row := range rows {
+			byUser[row.UserID] = row.CommentAuthorStats
+		}
+	}
+
+	byEmail := make(map[string]domain.CommentAuthorStats, len(emails))
+	if len(emails) > 0 {
+		var rows []struct {
+			AnonymousEmail string
+			domain.CommentAuthorStats
+		}
+		err := r.db.WithContext(ctx).
+			Model(&domain.Comment{}).
+			Select("anonymous_email, "+columns).
+			Where("user_id IS NULL AND anonymous_email IN ?", emails).
+			Group("anonymous_email").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, row := range rows {
+			byEmail[row.AnonymousEmail] = row.CommentAuthorStats
+		}
+	}
+
+	return byUser, byEmail, nil
+}
+
+// FindModerationLogs 分页查找审核日志，最新的在前
+func (r *CommentRepositoryImpl) FindModerationLogs(ctx context.Context, filter ModerationLogFilter) ([]domain.ModerationLog, int64, error) {
+	var logs []domain.ModerationLog
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.ModerationLog{})
+
+	if filter.CommentID != nil {
+		query = query.Where("comment_id = ?", *filter.CommentID)
+	}
+
+	if filter.ModeratorID != nil {
+		query = query.Where("moderator_id = ?", *filter.ModeratorID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
 // Update 更新评论
 func (r *CommentRepositoryImpl) Update(ctx context.Context, comment *domain.Comment) error {
 	return r.db.WithContext(ctx).Save(comment).Error
 }
 
-// UpdateStatus 更新评论状态
+// Edit 修改评论内容并保存修改前的版本，status不为空时同时更新评论状态
+// 返回修改后的评论，内容没有变化时不做修改
+func (r *CommentRepositoryImpl) Edit(ctx context.Context, id uint, content, contentHTML string, editorID uint, status string) (*domain.Comment, error) {
+	var comment domain.Comment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).
+			First(&comment).Error
+		if err != nil {
+			return err
+		}
+		if comment.Content == content {
+			return nil
+		}
+
+		revision := &domain.CommentRevision{
+			CommentID: id,
+			Content:   comment.Content,
+			EditorID:  &editorID,
+		}
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = tx.Model(&domain.Comment{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"content":      content,
+				"content_html": contentHTML,
+				"edited_at":    now,
+				"edit_count":   gorm.Expr("edit_count + 1"),
+			}).Error
+		if err != nil {
+			return err
+		}
+		comment.Content = content
+		comment.ContentHTML = contentHTML
+		comment.EditedAt = &now
+		comment.EditCount++
+
+		if status == "" || status == comment.Status {
+			return nil
+		}
+		if _, err := changeCommentStatus(tx, id, status); err != nil {
+			return err
+		}
+		comment.Status = status
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// FindRevisions 查找评论的历史版本，最新的在前
+func (r *CommentRepositoryImpl) FindRevisions(ctx context.Context, commentID uint) ([]domain.CommentRevision, error) {
+	var revisions []domain.CommentRevision
+	err := r.db.WithContext(ctx).
+		Where("comment_id = ?", commentID).
+		Order("id DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// SyncMentions 更新评论提及的用户，保留仍被提及用户的通知状态
+func (r *CommentRepositoryImpl) SyncMentions(ctx context.Context, commentID uint, userIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("comment_id = ?", commentID)
+		if len(userIDs) > 0 {
+			query = query.Where("user_id NOT IN ?", userIDs)
+		}
+		if err := query.Delete(&domain.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+
+		mentions := make([]domain.CommentMention, len(userIDs))
+		for i, userID := range userIDs {
+			mentions[i] = domain.CommentMention{CommentID: commentID, UserID: userID}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&mentions).Error
+	})
+}
+
+// ClaimMentions 返回尚未通知的被提及用户并标记为已通知，保证每个用户只通知一次
+func (r *CommentRepositoryImpl) ClaimMentions(ctx context.Context, commentID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.CommentMention{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("comment_id = ? AND notified_at IS NULL", commentID).
+			Pluck("user_id", &userIDs).Error
+		if err != nil || len(userIDs) == 0 {
+			return err
+		}
+		return tx.Model(&domain.CommentMention{}).
+			Where("comment_id = ? AND user_id IN ?", commentID, userIDs).
+			Update("notified_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// UpdateStatus 更新评论状态，评论进入或离开已批准状态时同步所属内容的评论数量
 func (r *CommentRepositoryImpl) UpdateStatus(ctx context.Context, id uint, status string) error {
-	return r.db.WithContext(ctx).
-		Model(&domain.Comment{}).
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := changeCommentStatus(tx, id, status)
+		return err
+	})
+}
+
+// Delete 作者删除自己的评论，已批准的评论同时减少所属内容的评论数量
+// 有回复时只清空内容并标记为已删除，保留路径使其他用户的回复留在原位；没有回复时直接删除
+func (r *CommentRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tombstoneComment(tx, id)
+	})
+}
+
+// Moderate 执行审核操作并在同一事务中记录审核日志，评论状态没有变化时返回nil
+func (r *CommentRepositoryImpl) Moderate(ctx context.Context, id uint, action string, moderatorID uint) (*domain.ModerationLog, error) {
+	var entry *domain.ModerationLog
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var from, to string
+		var err error
+		switch action {
+		case domain.ModerationActionApprove:
+			to = "approved"
+			from, err = changeCommentStatus(tx, id, to)
+		case domain.ModerationActionSpam:
+			to = "spam"
+			from, err = changeCommentStatus(tx, id, to)
+		case domain.ModerationActionDelete:
+			to = "deleted"
+			from, err = deleteComment(tx, id)
+		default:
+			return fmt.Errorf("无效的审核操作: %s", action)
+		}
+		if err != nil || from == "" || from == to {
+			return err
+		}
+
+		entry = &domain.ModerationLog{
+			CommentID:   id,
+			ModeratorID: moderatorID,
+			Action:      action,
+			FromStatus:  from,
+			ToStatus:    to,
+		}
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// changeCommentStatus 在事务中更新评论状态并同步评论数量，返回原状态
+// 标记为垃圾评论时，其下已批准和待审核的回复一并标记，不再计入评论数量
+func changeCommentStatus(tx *gorm.DB, id uint, status string) (string, error) {
+	comment, err := lockComment(tx, id)
+	if err != nil {
+		return "", err
+	}
+	if comment.Status == status {
+		return comment.Status, nil
+	}
+
+	err = tx.Model(&domain.Comment{}).
 		Where("id = ?", id).
 		Update("status", status).Error
+	if err != nil {
+		return "", err
+	}
+
+	delta := 0
+	switch {
+	case status == "approved":
+		delta = 1
+	case comment.Status == "approved":
+		delta = -1
+	}
+	if status == "spam" {
+		replies := commentReplies(tx, comment).Where("status IN ?", []string{"approved", "pending"})
+		approved, err := countApproved(commentReplies(tx, comment))
+		if err != nil {
+			return "", err
+		}
+		if err := replies.Update("status", status).Error; err != nil {
+			return "", err
+		}
+		delta -= int(approved)
+	}
+	return comment.Status, adjustCommentCount(tx, comment.ItemType, comment.ItemID, delta)
 }
 
-// Delete 删除评论
-func (r *CommentRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Comment{}, id).Error
+// deleteComment 在事务中删除评论和它的全部回复并同步评论数量，返回原状态，评论不存在时返回空字符串
+// 评论的表态、修改记录和提及一并删除，作为反垃圾训练样本的评论撤销词频统计
+func deleteComment(tx *gorm.DB, id uint) (string, error) {
+	comment, err := lockComment(tx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	approved, err := countApproved(commentReplies(tx, comment))
+	if err != nil {
+		return "", err
+	}
+	if comment.Status == "approved" {
+		approved++
+	}
+	var ids []uint
+	if err := commentReplies(tx, comment).Pluck("id", &ids).Error; err != nil {
+		return "", err
+	}
+	ids = append(ids, comment.ID)
+
+	if err := untrainComments(tx, ids); err != nil {
+		return "", err
+	}
+	if err := deleteCommentData(tx, ids); err != nil {
+		return "", err
+	}
+	if err := tx.Where("id IN ?", ids).Delete(&domain.Comment{}).Error; err != nil {
+		return "", err
+	}
+	return comment.Status, adjustCommentCount(tx, comment.ItemType, comment.ItemID, -int(approved))
 }
 
-// IncrementLikes 增加评论点赞数
-func (r *CommentRepositoryImpl) IncrementLikes(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
-		Model(&domain.Comment{}).
+// tombstoneComment 在事务中将有回复的评论标记为已删除并清空内容，没有回复时直接删除
+// 已删除的评论保留训练记录，反垃圾样本数量和词频统计保持一致
+func tombstoneComment(tx *gorm.DB, id uint) error {
+	comment, err := lockComment(tx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if comment.Status == "deleted" {
+		return nil
+	}
+
+	var replies int64
+	if err := commentReplies(tx, comment).Count(&replies).Error; err != nil {
+		return err
+	}
+	if replies == 0 {
+		_, err := deleteComment(tx, id)
+		return err
+	}
+
+	err = tx.Model(&domain.Comment{}).
 		Where("id = ?", id).
-		UpdateColumn("likes", gorm.Expr("likes + ?", 1)).
-		Error
-} 
\ No newline at end of file
+		Updates(map[string]interface{}{
+			"status":       "deleted",
+			"content":      "",
+			"content_html": "",
+		}).Error
+	if err != nil {
+		return err
+	}
+	if err := deleteCommentData(tx, []uint{id}); err != nil {
+		return err
+	}
+	if comment.Status == "approved" {
+		return adjustCommentCount(tx, comment.ItemType, comment.ItemID, -1)
+	}
+	return nil
+}
+
+// deleteCommentData 在事务中删除评论的表态、修改记录和提及
+func deleteCommentData(tx *gorm.DB, ids []uint) error {
+	err := tx.Where("target_type = ? AND target_id IN ?", domain.ReactionTargetComment, ids).
+		Delete(&domain.Reaction{}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("comment_id IN ?", ids).Delete(&domain.CommentRevision{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("comment_id IN ?", ids).Delete(&domain.CommentMention{}).Error
+}
+
+// lockComment 在事务中锁定并查找评论
+func lockComment(tx *gorm.DB, id uint) (*domain.Comment, error) {
+	var comment domain.Comment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "item_type", "item_id", "status", "path").
+		Where("id = ?", id).
+		First(&comment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// commentReplies 按物化路径查找评论的全部回复，不包含评论本身；没有路径的旧评论视为没有回复
+func commentReplies(tx *gorm.DB, comment *domain.Comment) *gorm.DB {
+	query := tx.Model(&domain.Comment{}).Where("item_type = ? AND item_id = ?", comment.ItemType, comment.ItemID)
+	if comment.Path == "" {
+		return query.Where("1 = 0")
+	}
+	return query.Where("path LIKE ? AND id <> ?", comment.Path+"%", comment.ID)
+}
+
+// countApproved 锁定并统计查询范围内已批准的评论数量
+func countApproved(query *gorm.DB) (int64, error) {
+	var count int64
+	err := query.Clauses(clause.Locking{Strength: "UPDATE"}).Where("status = ?", "approved").Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"Lin_studio/internal/domain"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// CommentTargetType 可评论内容类型在数据库中的映射
+type CommentTargetType struct {
+	Table        string   // 内容所在的数据表
+	TitleColumn  string   // 标题字段，用于审核时展示评论所在的内容
+	OpenStatuses []string // 允许发表评论的内容状态
+	CountColumn  string   // 已批准评论数量字段，为空时不维护计数
+}
+
+// commentTargetTypes 已注册的可评论内容类型，键为评论的item_type
+// 新增类型时还需要同步扩展comments.item_type的枚举值
+var commentTargetTypes = map[string]CommentTargetType{
+	"article": {
+		Table:        "articles",
+		TitleColumn:  "title",
+		OpenStatuses: []string{"published"},
+		CountColumn:  "comments_count",
+	},
+	"project": {
+		Table:        "projects",
+		TitleColumn:  "title",
+		OpenStatuses: []string{"planning", "in-progress", "completed"},
+		CountColumn:  "comments_count",
+	},
+	"tool": {
+		Table:        "tools",
+		TitleColumn:  "name",
+		OpenStatuses: []string{"active", "maintenance"},
+	},
+}
+
+// RegisterCommentTargetType 注册可评论内容类型，已存在时覆盖
+func RegisterCommentTargetType(itemType string, target CommentTargetType) {
+	commentTargetTypes[itemType] = target
+}
+
+// LookupCommentTargetType 查找可评论内容类型
+func LookupCommentTargetType(itemType string) (CommentTargetType, bool) {
+	target, ok := commentTargetTypes[itemType]
+	return target, ok
+}
+
+// findCommentTarget 查找评论所属的内容，不存在时返回nil
+func findCommentTarget(db *gorm.DB, itemType string, itemID uint) (*domain.CommentTarget, error) {
+	targets, err := findCommentTargets(db, itemType, []uint{itemID})
+	if err != nil {
+		return nil, err
+	}
+	target, ok := targets[itemID]
+	if !ok {
+		return nil, nil
+	}
+	return &target, nil
+}
+
+// findCommentTargets 批量查找同一类型的评论内容，键为内容ID，不存在的内容不在结果中
+func findCommentTargets(db *gorm.DB, itemType string, itemIDs []uint) (map[uint]domain.CommentTarget, error) {
+	targetType, ok := LookupCommentTargetType(itemType)
+	if !ok {
+		return nil, fmt.Errorf("未注册的评论内容类型: %s", itemType)
+	}
+
+	targets := make(map[uint]domain.CommentTarget, len(itemIDs))
+	if len(itemIDs) == 0 {
+		return targets, nil
+	}
+
+	var rows []struct {
+		ID     uint
+		Title  string
+		Slug   string
+		Status string
+	}
+	err := db.Table(targetType.Table).
+		Select("id", targetType.TitleColumn+" AS title", "slug", "status").
+		Where("id IN ?", itemIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		target := domain.CommentTarget{
+			ItemType: itemType,
+			ItemID:   row.ID,
+			Title:    row.Title,
+			Slug:     row.Slug,
+			Status:   row.Status,
+		}
+		for _, status := range targetType.OpenStatuses {
+			if status == target.Status {
+				target.Open = true
+				break
+			}
+		}
+		targets[row.ID] = target
+	}
+	return targets, nil
+}
+
+// adjustCommentCount 调整内容的已批准评论数量，delta为正数时增加，负数时减少且不低于0
+func adjustCommentCount(tx *gorm.DB, itemType string, itemID uint, delta int) error {
+	targetType, ok := LookupCommentTargetType(itemType)
+	if !ok || targetType.CountColumn == "" || delta == 0 {
+		return nil
+	}
+
+	column := targetType.CountColumn
+	expr := gorm.Expr(column+" + ?", delta)
+	if delta < 0 {
+		// 计数字段为无符号整数，避免减到负数时报错
+		expr = gorm.Expr("GREATEST("+column+", ?) - ?", -delta, -delta)
+	}
+	return tx.Table(targetType.Table).Where("id = ?", itemID).UpdateColumn(column, expr).Error
+}
+
+// recountComments 按已批准评论重新计算所有内容的评论数量
+func recountComments(db *gorm.DB) error {
+	for itemType, targetType := range commentTargetTypes {
+		if targetType.CountColumn == "" {
+			continue
+		}
+		err := db.Exec(fmt.Sprintf(
+			"UPDATE %s SET %s = (SELECT COUNT(*) FROM comments WHERE comments.item_type = ? AND comments.item_id = %s.id AND comments.status = 'approved')",
+			targetType.Table, targetType.CountColumn, targetType.Table,
+		), itemType).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
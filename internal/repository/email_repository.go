@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EmailRepository 邮件发件箱和通知偏好仓储接口
+type EmailRepository interface {
+	Enqueue(ctx context.Context, email *domain.EmailOutbox) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.EmailOutbox, error)
+	MarkSent(ctx context.Context, id uint) error
+	MarkFailed(ctx context.Context, id uint, lastError string, nextAttempt *time.Time) error
+	FindPreference(ctx context.Context, email string) (*domain.EmailPreference, error)
+	SavePreference(ctx context.Context, preference *domain.EmailPreference) error
+}
+
+// EmailRepositoryImpl 邮件发件箱和通知偏好仓储实现
+type EmailRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewEmailRepository 创建邮件仓储实例
+func NewEmailRepository() EmailRepository {
+	return &EmailRepositoryImpl{
+		db: config.DB,
+	}
+}
+
+// Enqueue 将邮件写入发件箱，等待后台任务发送
+func (r *EmailRepositoryImpl) Enqueue(ctx context.Context, email *domain.EmailOutbox) error {
+	email.Status = domain.EmailStatusPending
+	if email.NextAttemptAt.IsZero() {
+		email.NextAttemptAt = time.Now()
+	}
+	return r.db.WithContext(ctx).Create(email).Error
+}
+
+// ClaimDue 取出到期的待发送邮件，并把下次尝试时间推迟lease
+// 进程在发送过程中退出时，邮件会在lease之后被重新取出；多个实例同时运行时跳过已被锁定的记录
+func (r *EmailRepositoryImpl) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.EmailOutbox, error) {
+	var emails []domain.EmailOutbox
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.EmailStatusPending, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(limit).
+			Find(&emails).Error
+		if err != nil || len(emails) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(emails))
+		for i := range emails {
+			ids[i] = emails[i].ID
+			emails[i].Attempts++
+		}
+		return tx.Model(&domain.EmailOutbox{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": now.Add(lease),
+			}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+// MarkSent 标记邮件已发送
+func (r *EmailRepositoryImpl) MarkSent(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.EmailOutbox{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":     domain.EmailStatusSent,
+			"sent_at":    time.Now(),
+			"last_error": "",
+		}).Error
+}
+
+// MarkFailed 记录发送失败，nextAttempt为nil时不再重试
+func (r *EmailRepositoryImpl) MarkFailed(ctx context.Context, id uint, lastError string, nextAttempt *time.Time) error {
+	updates := map[string]interface{}{
+		"last_error": lastError,
+	}
+	if nextAttempt != nil {
+		updates["next_attempt_at"] = *nextAttempt
+	} else {
+		updates["status"] = domain.EmailStatusFailed
+	}
+	return r.db.WithContext(ctx).
+		Model(&domain.EmailOutbox{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+// FindPreference 根据邮箱查找通知偏好，不存在时返回nil
+func (r *EmailRepositoryImpl) FindPreference(ctx context.Context, email string) (*domain.EmailPreference, error) {
+	var preference domain.EmailPreference
+	err := r.db.WithContext(ctx).Where("email = ?", domain.NormalizeEmail(email)).First(&preference).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &preference, nil
+}
+
+// SavePreference 保存通知偏好，同一邮箱已有记录时覆盖
+func (r *EmailRepositoryImpl) SavePreference(ctx context.Context, preference *domain.EmailPreference) error {
+	preference.Email = domain.NormalizeEmail(preference.Email)
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "locale", "reply", "moderation", "admin", "confirmed_at", "confirm_sent_at", "updated_at"}),
+		}).
+		Create(preference).Error
+}
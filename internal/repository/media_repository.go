@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MediaFilter 媒体文件筛选条件
+type MediaFilter struct {
+	Page        int
+	Limit       int
+	ContentType *string
+	UploaderID  *uint
+	Unused      bool // 只查询没有被引用的文件
+}
+
+// MediaRepository 媒体文件仓储接口
+type MediaRepository interface {
+	Create(ctx context.Context, media *domain.Media) error
+	FindByID(ctx context.Context, id uint) (*domain.Media, error)
+	FindByHash(ctx context.Context, hash string) (*domain.Media, error)
+	FindByURL(ctx context.Context, url string) (*domain.Media, error)
+	FindByURLs(ctx context.Context, urls []string) ([]domain.Media, error)
+	FindByKeys(ctx context.Context, keys []string) ([]domain.Media, error)
+	FindByHashes(ctx context.Context, hashes []string) ([]domain.Media, error)
+	FindByStatus(ctx context.Context, statuses ...string) ([]domain.Media, error)
+	FindAll(ctx context.Context, filter MediaFilter) ([]domain.Media, int64, error)
+	FindUnreferenced(ctx context.Context, before time.Time) ([]domain.Media, error)
+	TouchUploaded(ctx context.Context, id uint) error
+	Update(ctx context.Context, media *domain.Media) error
+	UpdateStatus(ctx context.Context, id uint, status, message string) error
+	Delete(ctx context.Context, id uint) error
+	CountReferences(ctx context.Context, mediaIDs []uint) (map[uint]int64, error)
+	CountReferencesByVisibility(ctx context.Context, mediaID uint) (public, private int64, err error)
+	ReplaceReferences(ctx context.Context, refType string, refID uint, mediaIDs []uint) error
+}
+
+// MediaRepositoryImpl 媒体文件仓储实现
+type MediaRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewMediaRepository 创建媒体文件仓储实例
+func NewMediaRepository() MediaRepository {
+	return &MediaRepositoryImpl{
+		db: config.DB,
+	}
+}
+
+// Create 创建媒体文件记录
+func (r *MediaRepositoryImpl) Create(ctx context.Context, media *domain.Media) error {
+	return r.db.WithContext(ctx).Create(media).Error
+}
+
+// FindByID 根据ID查找媒体文件
+func (r *MediaRepositoryImpl) FindByID(ctx context.Context, id uint) (*domain.Media, error) {
+	var media domain.Media
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&media).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
+// FindByHash 根据内容哈希查找媒体文件
+func (r *MediaRepositoryImpl) FindByHash(ctx context.Context, hash string) (*domain.Media, error) {
+	var media domain.Media
+	err := r.db.WithContext(ctx).Where("hash = ?", hash).Order("id ASC").First(&media).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
+// FindByURL 根据公开地址查找媒体文件
+func (r *MediaRepositoryImpl) FindByURL(ctx context.Context, url string) (*domain.Media, error) {
+	var media domain.Media
+	err := r.db.WithContext(ctx).Where("url = ?", url).First(&media).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
+// FindByURLs 根据公开地址批量查找媒体文件
+func (r *MediaRepositoryImpl) FindByURLs(ctx context.Context, urls []string) ([]domain.Media, error) {
+	var media []domain.Media
+	if len(urls) == 0 {
+		return media, nil
+	}
+	err := r.db.WithContext(ctx).Where("url IN ?", urls).Find(&media).Error
+	return media, err
+}
+
+// FindByKeys 根据存储键批量查找媒体文件
+func (r *MediaRepositoryImpl) FindByKeys(ctx context.Context, keys []string) ([]domain.Media, error) {
+	var media []domain.Media
+	if len(keys) == 0 {
+		return media, nil
+	}
+	err := r.db.WithContext(ctx).Where("storage_key IN ?", keys).Find(&media).Error
+	return media, err
+}
+
+// FindByHashes 根据内容哈希批量查找媒体文件
+func (r *MediaRepositoryImpl) FindByHashes(ctx context.Context, hashes []string) ([]domain.Media, error) {
+	var media []domain.Media
+	if len(hashes) == 0 {
+		return media, nil
+	}
+	err := r.db.WithContext(ctx).Where("hash IN ?", hashes).Find(&media).Error
+	return media, err
+}
+
+// FindByStatus 查找指定状态的媒体文件
+func (r *MediaRepositoryImpl) FindByStatus(ctx context.Context, statuses ...string) ([]domain.Media, error) {
+	var media []domain.Media
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", statuses).
+		Order("id ASC").
+		Find(&media).Error
+	return media, err
+}
+
+// FindAll 分页查找媒体文件
+func (r *MediaRepositoryImpl) FindAll(ctx context.Context, filter MediaFilter) ([]domain.Media, int64, error) {
+	var media []domain.Media
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Media{})
+
+	// 应用过滤条件
+	if filter.ContentType != nil {
+		query = query.Where("content_type = ?", *filter.ContentType)
+	}
+
+	if filter.UploaderID != nil {
+		query = query.Where("uploader_id = ?", *filter.UploaderID)
+	}
+
+	if filter.Unused {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM media_references WHERE media_references.media_id = media.id)")
+	}
+
+	// 计算总数
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// 分页
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&media).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return media, total, nil
+}
+
+// FindUnreferenced 查找最近一次上传在指定时间之前且没有被引用的媒体文件
+func (r *MediaRepositoryImpl) FindUnreferenced(ctx context.Context, before time.Time) ([]domain.Media, error) {
+	var media []domain.Media
+	err := r.db.WithContext(ctx).
+		Where("last_uploaded_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM media_references WHERE media_references.media_id = media.id)").
+		Order("id ASC").
+		Find(&media).Error
+	return media, err
+}
+
+// TouchUploaded 将最近一次上传时间更新为当前时间
+func (r *MediaRepositoryImpl) TouchUploaded(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Media{}).
+		Where("id = ?", id).
+		UpdateColumn("last_uploaded_at", time.Now()).Error
+}
+
+// Update 更新媒体文件
+func (r *MediaRepositoryImpl) Update(ctx context.Context, media *domain.Media) error {
+	return r.db.WithContext(ctx).Save(media).Error
+}
+
+// UpdateStatus 更新媒体文件处理状态
+func (r *MediaRepositoryImpl) UpdateStatus(ctx context.Context, id uint, status, message string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Media{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status": status,
+			"error":  message,
+		}).Error
+}
+
+// Delete 删除媒体文件记录及其引用关系
+func (r *MediaRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("media_id = ?", id).Delete(&domain.MediaReference{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&domain.Media{}, id).Error
+	})
+}
+
+// CountReferences 统计媒体文件的引用次数
+func (r *MediaRepositoryImpl) CountReferences(ctx context.Context, mediaIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(mediaIDs))
+	if len(mediaIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		MediaID uint
+		Count   int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&domain.MediaReference{}).
+		Select("media_id, COUNT(*) AS count").
+		Where("media_id IN ?", mediaIDs).
+		Group("media_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.MediaID] = row.Count
+	}
+	return counts, nil
+}
+
+// CountReferencesByVisibility 统计媒体文件的公开引用和非公开引用次数
+// 未发布文章的引用为非公开引用，已发布文章、用户和项目的引用为公开引用
+func (r *MediaRepositoryImpl) CountReferencesByVisibility(ctx context.Context, mediaID uint) (public, private int64, err error) {
+	var row struct {
+		Public  int64
+		Private int64
+	}
+	err = r.db.WithContext(ctx).
+		Table("media_references").
+		Select(`COALESCE(SUM(CASE WHEN media_references.ref_type = ? AND (articles.status IS NULL OR articles.status <> 'published') THEN 1 ELSE 0 END), 0) AS private,
+			COALESCE(SUM(CASE WHEN media_references.ref_type <> ? OR articles.status = 'published' THEN 1 ELSE 0 END), 0) AS public`,
+			domain.MediaRefArticle, domain.MediaRefArticle).
+		Joins("LEFT JOIN articles ON media_references.ref_type = ? AND articles.id = media_references.ref_id", domain.MediaRefArticle).
+		Where("media_references.media_id = ?", mediaID).
+		Scan(&row).Error
+	return row.Public, row.Private, err
+}
+
+// ReplaceReferences 用新的媒体文件列表替换引用方的全部引用
+func (r *MediaRepositoryImpl) ReplaceReferences(ctx context.Context, refType string, refID uint, mediaIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ref_type = ? AND ref_id = ?", refType, refID).Delete(&domain.MediaReference{}).Error; err != nil {
+			return err
+		}
+		if len(mediaIDs) == 0 {
+			return nil
+		}
+
+		refs := make([]domain.MediaReference, 0, len(mediaIDs))
+		seen := make(map[uint]bool, len(mediaIDs))
+		for _, id := range mediaIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			refs = append(refs, domain.MediaReference{
+				MediaID: id,
+				RefType: refType,
+				RefID:   refID,
+			})
+		}
+		return tx.Create(&refs).Error
+	})
+}
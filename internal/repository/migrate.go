@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"Lin_studio/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// AutoMigrate 创建或更新新增功能所需的数据表
+// 原有的表结构由初始化SQL维护，这里只登记后续新增的模型和原有表新增的字段
+func AutoMigrate(db *gorm.DB) error {
+	err := db.AutoMigrate(
+		&domain.Media{},
+		&domain.MediaReference{},
+		&domain.SpamToken{},
+		&domain.ModerationLog{},
+		&domain.Reaction{},
+		&domain.CommentRevision{},
+		&domain.CommentMention{},
+		&domain.EmailOutbox{},
+		&domain.EmailPreference{},
+		&domain.Notification{},
+		&domain.AnalyticsStat{},
+		&domain.AnalyticsVisitor{},
+		&domain.AnalyticsReferrer{},
+		&domain.AnalyticsSalt{},
+		&domain.AnalyticsCampaign{},
+		&domain.ArticleRelated{},
+		&domain.Series{},
+		&domain.SeriesArticle{},
+		&domain.SchemaMigration{},
+	)
+	if err != nil {
+		return err
+	}
+	if err := addVersionColumns(db); err != nil {
+		return err
+	}
+	if err := addCommentColumns(db); err != nil {
+		return err
+	}
+	if err := backfillCommentPaths(db); err != nil {
+		return err
+	}
+	if err := backfillCommentHTML(db); err != nil {
+		return err
+	}
+	// 评论计数在此之前未随评论状态维护，按已批准评论校正一次
+	return runOnce(db, "recount_comments", recountComments)
+}
+
+// runOnce 执行一次性数据迁移并记录，已记录的迁移不再执行
+func runOnce(db *gorm.DB, name string, migrate func(db *gorm.DB) error) error {
+	var count int64
+	if err := db.Model(&domain.SchemaMigration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := migrate(db); err != nil {
+		return err
+	}
+	return db.Create(&domain.SchemaMigration{Name: name}).Error
+}
+
+// addVersionColumns 为原有的可编辑内容表添加乐观锁版本号，已有记录的版本号为1
+// 这些表的其他字段由初始化SQL维护，只添加缺少的列，不对整表执行AutoMigrate
+func addVersionColumns(db *gorm.DB) error {
+	models := []interface{}{&domain.Article{}, &domain.Tool{}, &domain.Category{}, &domain.Tag{}}
+	for _, model := range models {
+		if db.Migrator().HasColumn(model, "Version") {
+			continue
+		}
+		if err := db.Migrator().AddColumn(model, "Version"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addCommentColumns 为评论表添加楼中楼、Markdown、反垃圾、修改记录和邮件通知所需的字段
+// 评论表的其他字段由初始化SQL维护，只添加缺少的列和路径索引
+func addCommentColumns(db *gorm.DB) error {
+	migrator := db.Migrator()
+	fields := []string{"Path", "Depth", "ContentHTML", "SpamScore", "SpamReasons", "SpamTrained", "SpamTrainedTokens", "EditCount", "EditedAt", "NotifyEmail"}
+	for _, field := range fields {
+		if migrator.HasColumn(&domain.Comment{}, field) {
+			continue
+		}
+		if err := migrator.AddColumn(&domain.Comment{}, field); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&domain.Comment{}, "Path") {
+		return migrator.CreateIndex(&domain.Comment{}, "Path")
+	}
+	return nil
+}
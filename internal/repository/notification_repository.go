@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationFilter 站内通知查询条件
+type NotificationFilter struct {
+	UserID     uint
+	UnreadOnly bool
+	Page       int
+	Limit      int
+}
+
+// NotificationRepository 站内通知仓储接口
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *domain.Notification) (bool, error)
+	FindByUser(ctx context.Context, filter NotificationFilter) ([]domain.Notification, int64, error)
+	CountUnread(ctx context.Context, userID uint) (int64, error)
+	MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error)
+	MarkAllRead(ctx context.Context, userID uint) (int64, error)
+}
+
+// NotificationRepositoryImpl 站内通知仓储实现
+type NotificationRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewNotificationRepository 创建站内通知仓储实例
+func NewNotificationRepository() NotificationRepository {
+	return &NotificationRepositoryImpl{
+		db: config.DB,
+	}
+}
+
+// Create 保存通知，与已有通知的DedupeKey相同时跳过，返回是否保存了新通知
+func (r *NotificationRepositoryImpl) Create(ctx context.Context, notification *domain.Notification) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(notification)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindByUser 分页查询用户的通知，最新的在前
+func (r *NotificationRepositoryImpl) FindByUser(ctx context.Context, filter NotificationFilter) ([]domain.Notification, int64, error) {
+	var notifications []domain.Notification
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.Notification{}).Where("user_id = ?", filter.UserID)
+	if filter.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("id DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&notifications).Error
+	return notifications, total, err
+}
+
+// CountUnread 统计用户的未读通知数量
+func (r *NotificationRepositoryImpl) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将用户的指定通知标记为已读，返回实际更新的数量
+// 只更新属于该用户的通知，其他用户的通知ID会被忽略
+func (r *NotificationRepositoryImpl) MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).
+		Model(&domain.Notification{}).
+		Where("user_id = ? AND id IN ? AND read_at IS NULL", userID, ids).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// MarkAllRead 将用户的全部通知标记为已读，返回实际更新的数量
+func (r *NotificationRepositoryImpl) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// reactionTarget 可表态内容在数据库中的映射
+type reactionTarget struct {
+	table         string // 内容所在的数据表
+	visibleStatus string // 允许表态的内容状态
+}
+
+// reactionTargets 可表态的内容类型
+var reactionTargets = map[string]reactionTarget{
+	domain.ReactionTargetComment: {table: "comments", visibleStatus: "approved"},
+	domain.ReactionTargetArticle: {table: "articles", visibleStatus: "published"},
+}
+
+// ReactionRepository 表态仓储接口
+type ReactionRepository interface {
+	TargetExists(ctx context.Context, targetType string, targetID uint) (bool, error)
+	Toggle(ctx context.Context, reaction *domain.Reaction) (added bool, err error)
+	CountByTargets(ctx context.Context, targetType string, targetIDs []uint) (map[uint]map[string]int64, error)
+	FindActorKinds(ctx context.Context, targetType string, targetIDs []uint, actor string) (map[uint][]string, error)
+}
+
+// ReactionRepositoryImpl 表态仓储实现
+type ReactionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewReactionRepository 创建表态仓储实例
+func NewReactionRepository() ReactionRepository {
+	return &ReactionRepositoryImpl{
+		db: config.DB,
+	}
+}
+
+// TargetExists 判断内容是否存在且处于允许表态的状态
+func (r *ReactionRepositoryImpl) TargetExists(ctx context.Context, targetType string, targetID uint) (bool, error) {
+	target, ok := reactionTargets[targetType]
+	if !ok {
+		return false, fmt.Errorf("不支持表态的内容类型: %s", targetType)
+	}
+
+	var count int64
+	err := r.db.WithContext(ctx).
+		Table(target.table).
+		Where("id = ? AND status = ?", targetID, target.visibleStatus).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Toggle 切换表态，已存在时取消，不存在时添加
+// 点赞表态在同一事务中同步内容的likes计数
+func (r *ReactionRepositoryImpl) Toggle(ctx context.Context, reaction *domain.Reaction) (bool, error) {
+	target, ok := reactionTargets[reaction.TargetType]
+	if !ok {
+		return false, fmt.Errorf("不支持表态的内容类型: %s", reaction.TargetType)
+	}
+
+	added := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing domain.Reaction
+		err := tx.Where("target_type = ? AND target_id = ? AND kind = ? AND actor = ?",
+			reaction.TargetType, reaction.TargetID, reaction.Kind, reaction.Actor).
+			First(&existing).Error
+
+		delta := 0
+		switch {
+		case err == nil:
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+			delta = -1
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(reaction).Error; err != nil {
+				// 并发的重复请求由唯一索引拦截，此时表态已由另一个请求添加
+				var count int64
+				tx.Model(&domain.Reaction{}).
+					Where("target_type = ? AND target_id = ? AND kind = ? AND actor = ?",
+						reaction.TargetType, reaction.TargetID, reaction.Kind, reaction.Actor).
+					Count(&count)
+				if count > 0 {
+					added = true
+					return nil
+				}
+				return err
+			}
+			added = true
+			delta = 1
+		default:
+			return err
+		}
+
+		if reaction.Kind != domain.ReactionLike {
+			return nil
+		}
+		expr := gorm.Expr("likes + 1")
+		if delta < 0 {
+			expr = gorm.Expr("GREATEST(likes, 1) - 1")
+		}
+		return tx.Table(target.table).Where("id = ?", reaction.TargetID).UpdateColumn("likes", expr).Error
+	})
+	return added, err
+}
+
+// CountByTargets 批量统计内容的各表态数量
+// 点赞数量使用内容的likes字段，包含表态功能上线前没有表态记录的点赞，与内容详情中的点赞数一致
+func (r *ReactionRepositoryImpl) CountByTargets(ctx context.Context, targetType string, targetIDs []uint) (map[uint]map[string]int64, error) {
+	counts := make(map[uint]map[string]int64, len(targetIDs))
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+	target, ok := reactionTargets[targetType]
+	if !ok {
+		return nil, fmt.Errorf("不支持表态的内容类型: %s", targetType)
+	}
+
+	var rows []struct {
+		TargetID uint
+		Kind     string
+		Count    int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&domain.Reaction{}).
+		Select("target_id, kind, COUNT(*) AS count").
+		Where("target_type = ? AND target_id IN ?", targetType, targetIDs).
+		Group("target_id, kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if row.Kind == domain.ReactionLike {
+			continue
+		}
+		if counts[row.TargetID] == nil {
+			counts[row.TargetID] = make(map[string]int64)
+		}
+		counts[row.TargetID][row.Kind] = row.Count
+	}
+
+	var likes []struct {
+		ID    uint
+		Likes int64
+	}
+	err = r.db.WithContext(ctx).
+		Table(target.table).
+		Select("id, likes").
+		Where("id IN ? AND likes > 0", targetIDs).
+		Scan(&likes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range likes {
+		if counts[row.ID] == nil {
+			counts[row.ID] = make(map[string]int64)
+		}
+		counts[row.ID][domain.ReactionLike] = row.Likes
+	}
+	return counts, nil
+}
+
+// FindActorKinds 批量查找访问者对内容的表态类型
+func (r *ReactionRepositoryImpl) FindActorKinds(ctx context.Context, targetType string, targetIDs []uint, actor string) (map[uint][]string, error) {
+	kinds := make(map[uint][]string)
+	if len(targetIDs) == 0 || actor == "" {
+		return kinds, nil
+	}
+
+	var rows []domain.Reaction
+	err := r.db.WithContext(ctx).
+		Select("target_id", "kind").
+		Where("target_type = ? AND target_id IN ? AND actor = ?", targetType, targetIDs, actor).
+		Order("id ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		kinds[row.TargetID] = append(kinds[row.TargetID], row.Kind)
+	}
+	return kinds, nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// SeriesRepository 系列仓储接口
+type SeriesRepository interface {
+	Create(ctx context.Context, series *domain.Series, articleIDs []uint) error
+	FindByID(ctx context.Context, id uint) (*domain.Series, error)
+	FindBySlug(ctx context.Context, slug string) (*domain.Series, error)
+	FindByArticleID(ctx context.Context, articleID uint) (*domain.Series, error)
+	FindAll(ctx context.Context) ([]domain.Series, error)
+	FindParts(ctx context.Context, seriesID uint) ([]domain.Article, error)
+	FindArticleSeriesIDs(ctx context.Context, articleIDs []uint) (map[uint]uint, error)
+	Update(ctx context.Context, series *domain.Series, articleIDs []uint) error
+	Delete(ctx context.Context, id, version uint) error
+}
+
+// SeriesRepositoryImpl 系列仓储实现
+type SeriesRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewSeriesRepository 创建系列仓储实例
+func NewSeriesRepository() SeriesRepository {
+	return &SeriesRepositoryImpl{
+		db: config.DB,
+	}
+}
+
+// Create 创建系列并按articleIDs的顺序添加文章
+func (r *SeriesRepositoryImpl) Create(ctx context.Context, series *domain.Series, articleIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(series).Error; err != nil {
+			return err
+		}
+		return replaceSeriesArticles(tx, series.ID, articleIDs)
+	})
+}
+
+// FindByID 根据ID查找系列
+func (r *SeriesRepositoryImpl) FindByID(ctx context.Context, id uint) (*domain.Series, error) {
+	var series domain.Series
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&series).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// FindBySlug 根据Slug查找系列
+func (r *SeriesRepositoryImpl) FindBySlug(ctx context.Context, slug string) (*domain.Series, error) {
+	var series domain.Series
+	err := r.db.WithContext(ctx).Where("slug = ?", slug).First(&series).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// FindByArticleID 查找文章所属的系列，不属于任何系列时返回nil
+func (r *SeriesRepositoryImpl) FindByArticleID(ctx context.Context, articleID uint) (*domain.Series, error) {
+	var series domain.Series
+	err := r.db.WithContext(ctx).
+		Joins("JOIN series_articles ON series_articles.series_id = series.id").
+		Where("series_articles.article_id = ?", articleID).
+		First(&series).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// FindAll 查找所有系列，最近更新的在前
+func (r *SeriesRepositoryImpl) FindAll(ctx context.Context) ([]domain.Series, error) {
+	var series []domain.Series
+	err := r.db.WithContext(ctx).Order("updated_at DESC").Find(&series).Error
+	return series, err
+}
+
+// FindParts 按顺序查找系列中的文章，包括未发布的文章，不加载正文
+func (r *SeriesRepositoryImpl) FindParts(ctx context.Context, seriesID uint) ([]domain.Article, error) {
+	var articles []domain.Article
+	err := r.db.WithContext(ctx).
+		Select("articles.id", "articles.title", "articles.slug", "articles.excerpt", "articles.read_time",
+			"articles.status", "articles.published_at", "articles.updated_at").
+		Joins("JOIN series_articles ON series_articles.article_id = articles.id").
+		Where("series_articles.series_id = ?", seriesID).
+		Order("series_articles.position ASC").
+		Find(&articles).Error
+	return articles, err
+}
+
+// FindArticleSeriesIDs 查找文章所属的系列，返回文章ID到系列ID的映射，不属于任何系列的文章不在其中
+func (r *SeriesRepositoryImpl) FindArticleSeriesIDs(ctx context.Context, articleIDs []uint) (map[uint]uint, error) {
+	result := make(map[uint]uint)
+	if len(articleIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []domain.SeriesArticle
+	err := r.db.WithContext(ctx).Where("article_id IN ?", articleIDs).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ArticleID] = row.SeriesID
+	}
+	return result, nil
+}
+
+// Update 更新系列并按articleIDs的顺序替换其中的文章，版本号与读取时不一致时返回ErrVersionConflict
+func (r *SeriesRepositoryImpl) Update(ctx context.Context, series *domain.Series, articleIDs []uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateVersioned(tx, series, &series.Version); err != nil {
+			return err
+		}
+		return replaceSeriesArticles(tx, series.ID, articleIDs)
+	})
+}
+
+// Delete 删除系列，文章本身不删除，version为0时不检查版本号
+func (r *SeriesRepositoryImpl) Delete(ctx context.Context, id, version uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteVersioned(tx, &domain.Series{}, id, version); err != nil {
+			return err
+		}
+		return tx.Where("series_id = ?", id).Delete(&domain.SeriesArticle{}).Error
+	})
+}
+
+// replaceSeriesArticles 按articleIDs的顺序替换系列中的文章
+func replaceSeriesArticles(tx *gorm.DB, seriesID uint, articleIDs []uint) error {
+	if err := tx.Where("series_id = ?", seriesID).Delete(&domain.SeriesArticle{}).Error; err != nil {
+		return err
+	}
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	rows := make([]domain.SeriesArticle, len(articleIDs))
+	for i, articleID := range articleIDs {
+		rows[i] = domain.SeriesArticle{SeriesID: seriesID, ArticleID: articleID, Position: uint(i + 1)}
+	}
+	return tx.Create(&rows).Error
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SpamRepository 反垃圾分类器仓储接口
+type SpamRepository interface {
+	FindTokens(ctx context.Context, tokens []string) (map[string]domain.SpamToken, error)
+	CountSamples(ctx context.Context) (spam, ham int64, err error)
+	Train(ctx context.Context, commentID uint, tokens []string, class string) error
+}
+
+// SpamRepositoryImpl 反垃圾分类器仓储实现
+type SpamRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewSpamRepository 创建反垃圾分类器仓储实例
+func NewSpamRepository() SpamRepository {
+	return &SpamRepositoryImpl{
+		db: config.DB,
+	}
+}
+
+// FindTokens 批量查找词频统计，未出现过的词不在结果中
+func (r *SpamRepositoryImpl) FindTokens(ctx context.Context, tokens []string) (map[string]domain.SpamToken, error) {
+	result := make(map[string]domain.SpamToken, len(tokens))
+	if len(tokens) == 0 {
+		return result, nil
+	}
+
+	var rows []domain.SpamToken
+	if err := r.db.WithContext(ctx).Where("token IN ?", tokens).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.Token] = row
+	}
+	return result, nil
+}
+
+// CountSamples 统计已训练的垃圾评论和正常评论数量
+func (r *SpamRepositoryImpl) CountSamples(ctx context.Context) (spam, ham int64, err error) {
+	var row struct {
+		Spam int64
+		Ham  int64
+	}
+	err = r.db.WithContext(ctx).
+		Model(&domain.Comment{}).
+		Select(`COALESCE(SUM(CASE WHEN spam_trained = ? THEN 1 ELSE 0 END), 0) AS spam,
+			COALESCE(SUM(CASE WHEN spam_trained = ? THEN 1 ELSE 0 END), 0) AS ham`,
+			domain.SpamClassSpam, domain.SpamClassHam).
+		Where("spam_trained IN ?", []string{domain.SpamClassSpam, domain.SpamClassHam}).
+		Scan(&row).Error
+	return row.Spam, row.Ham, err
+}
+
+// Train 将评论作为指定类别的样本训练分类器
+// 评论已作为其他类别训练过时按当时训练的词撤销原来的统计，评论内容修改后也能准确撤销
+// 重复训练同一类别不会重复计数
+func (r *SpamRepositoryImpl) Train(ctx context.Context, commentID uint, tokens []string, class string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var comment domain.Comment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "spam_trained", "spam_trained_tokens").
+			Where("id = ?", commentID).
+			First(&comment).Error
+		if err != nil {
+			return err
+		}
+		if comment.SpamTrained == class {
+			return nil
+		}
+
+		// 撤销原类别的统计，记录训练词之前训练的评论只能使用当前内容的词
+		if comment.SpamTrained != "" {
+			trained := tokens
+			if comment.SpamTrainedTokens != "" {
+				trained = strings.Fields(comment.SpamTrainedTokens)
+			}
+			if err := untrainSpamTokens(tx, comment.SpamTrained, trained); err != nil {
+				return err
+			}
+		}
+
+		// 增加新类别的统计
+		if len(tokens) > 0 {
+			column := spamCountColumn(class)
+			now := time.Now()
+			rows := make([]domain.SpamToken, 0, len(tokens))
+			for _, token := range tokens {
+				row := domain.SpamToken{Token: token, UpdatedAt: now}
+				if class == domain.SpamClassSpam {
+					row.SpamCount = 1
+				} else {
+					row.HamCount = 1
+				}
+				rows = append(rows, row)
+			}
+			err := tx.Clauses(clause.OnConflict{
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					column:       gorm.Expr(column + " + 1"),
+					"updated_at": now,
+				}),
+			}).Create(&rows).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&domain.Comment{}).
+			Where("id = ?", commentID).
+			UpdateColumns(map[string]interface{}{
+				"spam_trained":        class,
+				"spam_trained_tokens": strings.Join(tokens, " "),
+			}).Error
+	})
+}
+
+// untrainComments 在事务中撤销评论作为训练样本时增加的词频统计，用于删除评论前
+// 没有记录训练词的旧评论无法准确撤销，直接跳过
+func untrainComments(tx *gorm.DB, commentIDs []uint) error {
+	var comments []domain.Comment
+	err := tx.Select("id", "spam_trained", "spam_trained_tokens").
+		Where("id IN ? AND spam_trained IN ?", commentIDs, []string{domain.SpamClassSpam, domain.SpamClassHam}).
+		Find(&comments).Error
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if err := untrainSpamTokens(tx, comment.SpamTrained, strings.Fields(comment.SpamTrainedTokens)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untrainSpamTokens 在事务中将词在指定类别下的计数减一
+func untrainSpamTokens(tx *gorm.DB, class string, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	column := spamCountColumn(class)
+	return tx.Model(&domain.SpamToken{}).
+		Where("token IN ?", tokens).
+		UpdateColumn(column, gorm.Expr("GREATEST("+column+", 1) - 1")).Error
+}
+
+// spamCountColumn 返回类别对应的计数字段
+func spamCountColumn(class string) string {
+	if class == domain.SpamClassSpam {
+		return "spam_count"
+	}
+	return "ham_count"
+}
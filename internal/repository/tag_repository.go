@@ -16,7 +16,7 @@ type TagRepository interface {
 	FindBySlug(ctx context.Context, slug string) (*domain.Tag, error)
 	FindAll(ctx context.Context) ([]domain.Tag, error)
 	Update(ctx context.Context, tag *domain.Tag) error
-	Delete(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id, version uint) error
 	CountArticles(ctx context.Context, tagID uint) (int64, error)
 	CountProjects(ctx context.Context, tagID uint) (int64, error)
 	FindByArticleID(ctx context.Context, articleID uint) ([]domain.Tag, error)
@@ -75,14 +75,14 @@ func (r *TagRepositoryImpl) FindAll(ctx context.Context) ([]domain.Tag, error) {
 	return tags, err
 }
 
-// Update 更新标签
+// Update 更新标签，版本号与读取时不一致时返回ErrVersionConflict
 func (r *TagRepositoryImpl) Update(ctx context.Context, tag *domain.Tag) error {
-	return r.db.WithContext(ctx).Save(tag).Error
+	return updateVersioned(r.db.WithContext(ctx), tag, &tag.Version)
 }
 
-// Delete 删除标签
-func (r *TagRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Tag{}, id).Error
+// Delete 删除标签，version为0时不检查版本号
+func (r *TagRepositoryImpl) Delete(ctx context.Context, id, version uint) error {
+	return deleteVersioned(r.db.WithContext(ctx), &domain.Tag{}, id, version)
 }
 
 // CountArticles 统计标签下的文章数量
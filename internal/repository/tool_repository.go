@@ -26,8 +26,8 @@ type ToolRepository interface {
 	FindBySlug(ctx context.Context, slug string) (*domain.Tool, error)
 	FindAll(ctx context.Context, filter ToolFilter) ([]domain.Tool, int64, error)
 	Update(ctx context.Context, tool *domain.Tool) error
-	Delete(ctx context.Context, id uint) error
-	IncrementViews(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id, version uint) error
+	AddViews(ctx context.Context, counts map[uint]uint) error
 }
 
 // ToolRepositoryImpl 工具仓储实现
@@ -112,21 +112,17 @@ func (r *ToolRepositoryImpl) FindAll(ctx context.Context, filter ToolFilter) ([]
 	return tools, total, err
 }
 
-// Update 更新工具
+// Update 更新工具，版本号与读取时不一致时返回ErrVersionConflict
 func (r *ToolRepositoryImpl) Update(ctx context.Context, tool *domain.Tool) error {
-	return r.db.WithContext(ctx).Save(tool).Error
+	return updateVersioned(r.db.WithContext(ctx), tool, &tool.Version, "views")
 }
 
-// Delete 删除工具
-func (r *ToolRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&domain.Tool{}, id).Error
+// Delete 删除工具，version为0时不检查版本号
+func (r *ToolRepositoryImpl) Delete(ctx context.Context, id, version uint) error {
+	return deleteVersioned(r.db.WithContext(ctx), &domain.Tool{}, id, version)
 }
 
-// IncrementViews 增加工具浏览量
-func (r *ToolRepositoryImpl) IncrementViews(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
-		Model(&domain.Tool{}).
-		Where("id = ?", id).
-		UpdateColumn("views", gorm.Expr("views + ?", 1)).
-		Error
+// AddViews 批量增加工具浏览量，counts为工具ID到新增浏览量的映射
+func (r *ToolRepositoryImpl) AddViews(ctx context.Context, counts map[uint]uint) error {
+	return addViews(r.db.WithContext(ctx), &domain.Tool{}, counts)
 } 
\ No newline at end of file
@@ -12,6 +12,8 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id uint) (*domain.User, error)
 	GetByID(id uint) (*domain.User, error)
 	GetByUsername(username string) (*domain.User, error)
+	FindByUsernames(ctx context.Context, usernames []string) ([]domain.User, error)
+	FindByRole(ctx context.Context, role string) ([]domain.User, error)
 	GetByEmail(email string) (*domain.User, error)
 	Create(user *domain.User) error
 	Update(user *domain.User) error
@@ -56,6 +58,23 @@ func (r *userRepository) GetByUsername(username string) (*domain.User, error) {
 	return &user, nil
 }
 
+// FindByUsernames 根据用户名批量获取用户
+func (r *userRepository) FindByUsernames(ctx context.Context, usernames []string) ([]domain.User, error) {
+	var users []domain.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("username IN ?", usernames).Find(&users).Error
+	return users, err
+}
+
+// FindByRole 获取指定角色的所有用户
+func (r *userRepository) FindByRole(ctx context.Context, role string) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).Where("role = ?", role).Find(&users).Error
+	return users, err
+}
+
 // GetByEmail 根据邮箱获取用户
 func (r *userRepository) GetByEmail(email string) (*domain.User, error) {
 	var user domain.User
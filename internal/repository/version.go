@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict 记录已被其他请求修改或删除
+var ErrVersionConflict = errors.New("数据已被其他人修改，请刷新后重试")
+
+// updateVersioned 以读取时的版本号为条件更新整行并将版本号加1
+// 版本号不一致时不做修改，返回ErrVersionConflict；omit中的列(例如浏览量等计数)不会被覆盖
+func updateVersioned(db *gorm.DB, value interface{}, version *uint, omit ...string) error {
+	expected := *version
+	*version = expected + 1
+
+	columns := append([]string{"created_at", clause.Associations}, omit...)
+	result := db.Model(value).
+		Where("version = ?", expected).
+		Select("*").
+		Omit(columns...).
+		Updates(value)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrVersionConflict
+	}
+	if result.Error != nil {
+		*version = expected
+		return result.Error
+	}
+	return nil
+}
+
+// deleteVersioned 删除记录，version大于0时只在版本号一致时删除，否则返回ErrVersionConflict
+func deleteVersioned(db *gorm.DB, model interface{}, id, version uint) error {
+	if version == 0 {
+		return db.Delete(model, id).Error
+	}
+
+	result := db.Where("version = ?", version).Delete(model, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// addViews 用一条UPDATE语句为多条记录增加浏览量，不修改updated_at
+func addViews(db *gorm.DB, model interface{}, counts map[uint]uint) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(counts))
+	args := make([]interface{}, 0, len(counts)*2)
+	var expr strings.Builder
+	expr.WriteString("views + CASE id")
+	for id, n := range counts {
+		expr.WriteString(" WHEN ? THEN ?")
+		args = append(args, id, n)
+		ids = append(ids, id)
+	}
+	expr.WriteString(" ELSE 0 END")
+
+	return db.Model(model).
+		Where("id IN ?", ids).
+		UpdateColumn("views", gorm.Expr(expr.String(), args...)).
+		Error
+}
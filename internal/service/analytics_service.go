@@ -0,0 +1,508 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/utils"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// analyticsDateLayout 统计日期格式
+const analyticsDateLayout = "2006-01-02"
+
+// ErrInvalidAnalyticsQuery 统计查询条件无效
+var ErrInvalidAnalyticsQuery = errors.New("无效的统计查询条件")
+
+// ViewCampaign 浏览时携带的UTM参数
+type ViewCampaign struct {
+	Source   string // utm_source
+	Medium   string // utm_medium
+	Campaign string // utm_campaign
+}
+
+// ViewHit 一次计入浏览量的浏览
+type ViewHit struct {
+	ItemType string
+	ItemID   uint
+	Time     time.Time
+	Visitor  string // 访问者指纹，写入前再用当天的盐计算哈希
+	Referrer string // 来源地址，只保存域名
+	Campaign ViewCampaign
+}
+
+// ViewRecorder 浏览记录的写入接口，由浏览量统计定期批量调用
+type ViewRecorder interface {
+	RecordViews(ctx context.Context, hits []ViewHit) error
+}
+
+// AnalyticsQuery 统计查询条件，日期为 2006-01-02 格式，为空时查询最近30天
+type AnalyticsQuery struct {
+	ItemType string
+	ItemID   uint
+	From     string
+	To       string
+	Period   string // day 或 month，只用于时间序列
+	Metric   string // 排行使用的指标
+	Limit    int
+}
+
+// AnalyticsService 内容统计服务接口，按天记录浏览量、独立访客、点赞、评论、来源域名和推广活动
+type AnalyticsService interface {
+	ViewRecorder
+	Subscribe(bus event.Bus)
+	GetSeries(ctx context.Context, query AnalyticsQuery) ([]domain.AnalyticsPoint, error)
+	GetTopItems(ctx context.Context, query AnalyticsQuery) ([]domain.AnalyticsItem, error)
+	GetTopReferrers(ctx context.Context, query AnalyticsQuery) ([]domain.AnalyticsReferrerCount, error)
+	GetSources(ctx context.Context, query AnalyticsQuery) (*domain.AnalyticsSources, error)
+	Maintain(ctx context.Context) error
+	Start()
+	Stop()
+}
+
+// AnalyticsServiceImpl 内容统计服务实现
+type AnalyticsServiceImpl struct {
+	analyticsRepo repository.AnalyticsRepository
+	articleRepo   repository.ArticleRepository
+	toolRepo      repository.ToolRepository
+	cfg           config.AnalyticsConfig
+	location      *time.Location
+	siteDomain    string
+	now           func() time.Time
+
+	saltMu sync.Mutex
+	salts  map[string]string // 日期到当天盐的缓存
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAnalyticsService 创建内容统计服务实例，时区无效时使用服务器时区
+func NewAnalyticsService(
+	analyticsRepo repository.AnalyticsRepository,
+	articleRepo repository.ArticleRepository,
+	toolRepo repository.ToolRepository,
+	cfg config.AnalyticsConfig,
+) AnalyticsService {
+	location := time.Local
+	if cfg.Timezone != "" {
+		if loc, err := time.LoadLocation(cfg.Timezone); err == nil {
+			location = loc
+		} else {
+			log.Printf("统计时区 %s 无效，使用服务器时区: %v", cfg.Timezone, err)
+		}
+	}
+	if cfg.MaxRangeDays <= 0 {
+		cfg.MaxRangeDays = 366
+	}
+	if cfg.MaintenanceInterval <= 0 {
+		cfg.MaintenanceInterval = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AnalyticsServiceImpl{
+		analyticsRepo: analyticsRepo,
+		articleRepo:   articleRepo,
+		toolRepo:      toolRepo,
+		cfg:           cfg,
+		location:      location,
+		siteDomain:    utils.ReferrerDomain(cfg.SiteURL),
+		now:           time.Now,
+		salts:         make(map[string]string),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Subscribe 订阅点赞和评论事件
+func (s *AnalyticsServiceImpl) Subscribe(bus event.Bus) {
+	bus.Subscribe(event.TopicReactionAdded, s.onReactionAdded)
+	bus.Subscribe(event.TopicCommentCreated, s.onCommentCreated)
+	bus.Subscribe(event.TopicCommentApproved, s.onCommentApproved)
+}
+
+// onReactionAdded 记录文章的点赞，取消点赞不从统计中扣除
+func (s *AnalyticsServiceImpl) onReactionAdded(ctx context.Context, e event.Event) error {
+	added := e.(event.ReactionAdded)
+	if added.Kind != domain.ReactionLike || added.TargetType != domain.ReactionTargetArticle {
+		return nil
+	}
+	return s.analyticsRepo.AddCounts(ctx, &domain.AnalyticsStat{
+		Period:   domain.AnalyticsPeriodDay,
+		Date:     s.today(),
+		ItemType: added.TargetType,
+		ItemID:   added.TargetID,
+		Likes:    1,
+	})
+}
+
+// onCommentCreated 记录直接发布的评论
+func (s *AnalyticsServiceImpl) onCommentCreated(ctx context.Context, e event.Event) error {
+	created := e.(event.CommentCreated)
+	if created.Comment.Status != "approved" {
+		return nil
+	}
+	return s.addComment(ctx, created.Comment)
+}
+
+// onCommentApproved 记录通过审核的评论，计入通过审核的日期
+func (s *AnalyticsServiceImpl) onCommentApproved(ctx context.Context, e event.Event) error {
+	approved := e.(event.CommentApproved)
+	if approved.FromStatus == "approved" {
+		return nil
+	}
+	return s.addComment(ctx, approved.Comment)
+}
+
+// addComment 为评论所属的内容增加当天的评论数
+func (s *AnalyticsServiceImpl) addComment(ctx context.Context, comment *domain.Comment) error {
+	return s.analyticsRepo.AddCounts(ctx, &domain.AnalyticsStat{
+		Period:   domain.AnalyticsPeriodDay,
+		Date:     s.today(),
+		ItemType: comment.ItemType,
+		ItemID:   comment.ItemID,
+		Comments: 1,
+	})
+}
+
+// RecordViews 按日期和内容合并浏览记录后写入
+// 访问者指纹用当天的盐计算哈希后保存，来源只保存归一化的域名，不保存IP和完整地址
+func (s *AnalyticsServiceImpl) RecordViews(ctx context.Context, hits []ViewHit) error {
+	type batchKey struct {
+		date     string
+		itemType string
+		itemID   uint
+	}
+
+	batches := make(map[batchKey]*repository.AnalyticsViewBatch)
+	seen := make(map[batchKey]map[string]bool)
+	var order []batchKey
+	for _, hit := range hits {
+		key := batchKey{hit.Time.In(s.location).Format(analyticsDateLayout), hit.ItemType, hit.ItemID}
+		batch, ok := batches[key]
+		if !ok {
+			batch = &repository.AnalyticsViewBatch{
+				Date:      key.date,
+				ItemType:  hit.ItemType,
+				ItemID:    hit.ItemID,
+				Referrers: make(map[string]uint),
+				Campaigns: make(map[repository.AnalyticsCampaignKey]uint),
+			}
+			batches[key] = batch
+			seen[key] = make(map[string]bool)
+			order = append(order, key)
+		}
+
+		batch.Views++
+		if hit.Visitor != "" {
+			visitor, err := s.visitorHash(ctx, key.date, hit.Visitor)
+			if err != nil {
+				return err
+			}
+			if !seen[key][visitor] {
+				seen[key][visitor] = true
+				batch.Visitors = append(batch.Visitors, visitor)
+			}
+		}
+		batch.Referrers[s.referrerDomain(hit.Referrer)]++
+		if campaign, ok := normalizeCampaign(hit.Campaign); ok {
+			batch.Campaigns[campaign]++
+		}
+	}
+
+	list := make([]repository.AnalyticsViewBatch, len(order))
+	for i, key := range order {
+		list[i] = *batches[key]
+	}
+	return s.analyticsRepo.RecordViews(ctx, list)
+}
+
+// visitorHash 用指定日期的盐计算访问者哈希，同一访问者在不同日期的哈希无法关联
+func (s *AnalyticsServiceImpl) visitorHash(ctx context.Context, date, fingerprint string) (string, error) {
+	salt, err := s.dailySalt(ctx, date)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(salt + "\n" + fingerprint))
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// dailySalt 返回指定日期的盐，第一次使用时随机生成并保存，多个进程共用同一个盐
+func (s *AnalyticsServiceImpl) dailySalt(ctx context.Context, date string) (string, error) {
+	s.saltMu.Lock()
+	defer s.saltMu.Unlock()
+
+	if salt, ok := s.salts[date]; ok {
+		return salt, nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	salt, err := s.analyticsRepo.DailySalt(ctx, date, hex.EncodeToString(buf))
+	if err != nil {
+		return "", err
+	}
+
+	// 只缓存最近几天的盐，已删除的盐不会留在内存中
+	if len(s.salts) >= 2 {
+		s.salts = make(map[string]string)
+	}
+	s.salts[date] = salt
+	return salt, nil
+}
+
+// normalizeCampaign 归一化UTM参数，没有utm_source时不计入推广活动
+func normalizeCampaign(campaign ViewCampaign) (repository.AnalyticsCampaignKey, bool) {
+	key := repository.AnalyticsCampaignKey{
+		Source:   utils.NormalizeCampaignValue(campaign.Source),
+		Medium:   utils.NormalizeCampaignValue(campaign.Medium),
+		Campaign: utils.NormalizeCampaignValue(campaign.Campaign),
+	}
+	return key, key.Source != ""
+}
+
+// referrerDomain 返回外部来源的域名，直接访问和站内跳转返回空字符串
+func (s *AnalyticsServiceImpl) referrerDomain(referrer string) string {
+	domainName := utils.ReferrerDomain(referrer)
+	if domainName == s.siteDomain {
+		return ""
+	}
+	return domainName
+}
+
+// GetSeries 获取时间序列，没有数据的日期或月份补零
+// 按日查询只包含保留期内的按日统计，更早的数据需要按月查询
+func (s *AnalyticsServiceImpl) GetSeries(ctx context.Context, query AnalyticsQuery) ([]domain.AnalyticsPoint, error) {
+	filter, from, to, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Period == "" || query.Period == domain.AnalyticsPeriodDay {
+		if to.Sub(from) >= time.Duration(s.cfg.MaxRangeDays)*24*time.Hour {
+			return nil, ErrInvalidAnalyticsQuery
+		}
+		points, err := s.analyticsRepo.DailySeries(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return fillSeries(points, from, to, analyticsDateLayout, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }), nil
+	}
+	if query.Period != domain.AnalyticsPeriodMonth {
+		return nil, ErrInvalidAnalyticsQuery
+	}
+
+	// 按月统计的日期为当月第一天，查询范围扩展到整月
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	filter.From = from.Format(analyticsDateLayout)
+	points, err := s.analyticsRepo.MonthlySeries(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return fillSeries(points, from, to, "2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }), nil
+}
+
+// GetTopItems 获取指标合计最高的内容，并补充标题和Slug
+func (s *AnalyticsServiceImpl) GetTopItems(ctx context.Context, query AnalyticsQuery) ([]domain.AnalyticsItem, error) {
+	if query.Metric == "" {
+		query.Metric = domain.AnalyticsMetricViews
+	}
+	if !domain.IsAnalyticsMetric(query.Metric) {
+		return nil, ErrInvalidAnalyticsQuery
+	}
+	filter, _, _, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.analyticsRepo.TopItems(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		s.loadItemTitle(ctx, &items[i])
+	}
+	return items, nil
+}
+
+// GetTopReferrers 获取浏览量最高的来源域名，域名为空表示直接访问
+func (s *AnalyticsServiceImpl) GetTopReferrers(ctx context.Context, query AnalyticsQuery) ([]domain.AnalyticsReferrerCount, error) {
+	filter, _, _, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.analyticsRepo.TopReferrers(ctx, filter)
+}
+
+// GetSources 获取来源域名和推广活动的浏览量，通常按单篇文章查询
+func (s *AnalyticsServiceImpl) GetSources(ctx context.Context, query AnalyticsQuery) (*domain.AnalyticsSources, error) {
+	filter, _, _, err := s.filter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	referrers, err := s.analyticsRepo.TopReferrers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	campaigns, err := s.analyticsRepo.TopCampaigns(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.AnalyticsSources{Referrers: referrers, Campaigns: campaigns}, nil
+}
+
+// Maintain 删除访问者记录和盐，汇总超过保留期的按日统计，删除超过保留期的按月统计
+// 访问者记录和盐保留到次日结束，跨过零点后才写入的前一天浏览仍能正确去重
+func (s *AnalyticsServiceImpl) Maintain(ctx context.Context) error {
+	now := s.now().In(s.location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, s.location)
+
+	yesterday := today.AddDate(0, 0, -1).Format(analyticsDateLayout)
+	if _, err := s.analyticsRepo.DeleteVisitorsBefore(ctx, yesterday); err != nil {
+		return err
+	}
+
+	if s.cfg.DailyRetentionDays > 0 {
+		cutoff := today.AddDate(0, 0, -s.cfg.DailyRetentionDays).Format(analyticsDateLayout)
+		n, err := s.analyticsRepo.RollupBefore(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("已将%s之前的%d条按日统计汇总为按月统计", cutoff, n)
+		}
+	}
+
+	if s.cfg.MonthlyRetentionMonths > 0 {
+		month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, s.location)
+		cutoff := month.AddDate(0, -s.cfg.MonthlyRetentionMonths, 0).Format(analyticsDateLayout)
+		n, err := s.analyticsRepo.DeleteMonthlyBefore(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("已删除%s之前的%d条按月统计", cutoff, n)
+		}
+	}
+	return nil
+}
+
+// Start 启动定期维护的后台协程，启动时先执行一次
+func (s *AnalyticsServiceImpl) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.MaintenanceInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.Maintain(s.ctx); err != nil {
+				log.Printf("维护统计数据失败: %v", err)
+			}
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台协程
+func (s *AnalyticsServiceImpl) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// today 返回统计时区的当天日期
+func (s *AnalyticsServiceImpl) today() string {
+	return s.now().In(s.location).Format(analyticsDateLayout)
+}
+
+// filter 校验查询条件并转换为仓储查询条件，同时返回起止日期
+func (s *AnalyticsServiceImpl) filter(query AnalyticsQuery) (repository.AnalyticsFilter, time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if query.To == "" {
+		now := s.now().In(s.location)
+		to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, s.location)
+	} else if to, err = time.ParseInLocation(analyticsDateLayout, query.To, s.location); err != nil {
+		return repository.AnalyticsFilter{}, from, to, ErrInvalidAnalyticsQuery
+	}
+	if query.From == "" {
+		from = to.AddDate(0, 0, -29)
+	} else if from, err = time.ParseInLocation(analyticsDateLayout, query.From, s.location); err != nil {
+		return repository.AnalyticsFilter{}, from, to, ErrInvalidAnalyticsQuery
+	}
+	if from.After(to) {
+		return repository.AnalyticsFilter{}, from, to, ErrInvalidAnalyticsQuery
+	}
+
+	switch query.ItemType {
+	case "", ViewTargetArticle, ViewTargetTool:
+	default:
+		return repository.AnalyticsFilter{}, from, to, ErrInvalidAnalyticsQuery
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	return repository.AnalyticsFilter{
+		ItemType: query.ItemType,
+		ItemID:   query.ItemID,
+		From:     from.Format(analyticsDateLayout),
+		To:       to.Format(analyticsDateLayout),
+		Metric:   query.Metric,
+		Limit:    limit,
+	}, from, to, nil
+}
+
+// loadItemTitle 补充内容的标题和Slug，内容已删除时保持为空
+func (s *AnalyticsServiceImpl) loadItemTitle(ctx context.Context, item *domain.AnalyticsItem) {
+	switch item.ItemType {
+	case ViewTargetArticle:
+		if article, err := s.articleRepo.FindByID(ctx, item.ItemID); err == nil && article != nil {
+			item.Title, item.Slug = article.Title, article.Slug
+		}
+	case ViewTargetTool:
+		if tool, err := s.toolRepo.FindByID(ctx, item.ItemID); err == nil && tool != nil {
+			item.Title, item.Slug = tool.Name, tool.Slug
+		}
+	}
+}
+
+// fillSeries 按步长生成from到to的完整序列，用查询结果填充有数据的点
+func fillSeries(points []domain.AnalyticsPoint, from, to time.Time, layout string, next func(time.Time) time.Time) []domain.AnalyticsPoint {
+	byDate := make(map[string]domain.AnalyticsPoint, len(points))
+	for _, point := range points {
+		byDate[point.Date] = point
+	}
+
+	var series []domain.AnalyticsPoint
+	for t := from; !t.After(to); t = next(t) {
+		date := t.Format(layout)
+		point, ok := byDate[date]
+		if !ok {
+			point = domain.AnalyticsPoint{Date: date}
+		}
+		series = append(series, point)
+	}
+	return series
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/repository"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockAnalyticsRepository 模拟统计仓储
+type MockAnalyticsRepository struct {
+	mock.Mock
+}
+
+func (m *MockAnalyticsRepository) RecordViews(ctx context.Context, batches []repository.AnalyticsViewBatch) error {
+	args := m.Called(ctx, batches)
+	return args.Error(0)
+}
+
+func (m *MockAnalyticsRepository) AddCounts(ctx context.Context, stat *domain.AnalyticsStat) error {
+	args := m.Called(ctx, stat)
+	return args.Error(0)
+}
+
+func (m *MockAnalyticsRepository) DailySeries(ctx context.Context, filter repository.AnalyticsFilter) ([]domain.AnalyticsPoint, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.AnalyticsPoint), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) MonthlySeries(ctx context.Context, filter repository.AnalyticsFilter) ([]domain.AnalyticsPoint, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.AnalyticsPoint), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) TopItems(ctx context.Context, filter repository.AnalyticsFilter) ([]domain.AnalyticsItem, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.AnalyticsItem), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) TopReferrers(ctx context.Context, filter repository.AnalyticsFilter) ([]domain.AnalyticsReferrerCount, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.AnalyticsReferrerCount), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) TopCampaigns(ctx context.Context, filter repository.AnalyticsFilter) ([]domain.AnalyticsCampaignCount, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.AnalyticsCampaignCount), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) DailySalt(ctx context.Context, date, candidate string) (string, error) {
+	args := m.Called(ctx, date, candidate)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) DeleteVisitorsBefore(ctx context.Context, date string) (int64, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) RollupBefore(ctx context.Context, date string) (int64, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAnalyticsRepository) DeleteMonthlyBefore(ctx context.Context, date string) (int64, error) {
+	args := m.Called(ctx, date)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// newTestAnalyticsService 创建使用UTC+8时区、当前时间固定的统计服务
+func newTestAnalyticsService(analyticsRepo *MockAnalyticsRepository, cfg config.AnalyticsConfig) *AnalyticsServiceImpl {
+	s := NewAnalyticsService(analyticsRepo, new(MockArticleRepository), new(MockToolRepository), cfg).(*AnalyticsServiceImpl)
+	s.location = time.FixedZone("UTC+8", 8*3600)
+	s.now = func() time.Time { return time.Date(2025, 3, 10, 12, 0, 0, 0, s.location) }
+	return s
+}
+
+// saltedVisitor 返回用盐计算的访问者哈希
+func saltedVisitor(salt, fingerprint string) string {
+	sum := sha256.Sum256([]byte(salt + "\n" + fingerprint))
+	return hex.EncodeToString(sum[:16])
+}
+
+// TestAnalyticsRecordViews 测试浏览记录按统计时区的日期和内容合并，访问者按当天的盐计算哈希
+func TestAnalyticsRecordViews(t *testing.T) {
+	analyticsRepo := new(MockAnalyticsRepository)
+	s := newTestAnalyticsService(analyticsRepo, config.AnalyticsConfig{SiteURL: "https://www.example.com"})
+
+	// UTC 3月9日16:30 在UTC+8已是3月10日
+	lateUTC := time.Date(2025, 3, 9, 16, 30, 0, 0, time.UTC)
+	earlyUTC := time.Date(2025, 3, 9, 15, 0, 0, 0, time.UTC)
+	hits := []ViewHit{
+		{ItemType: ViewTargetArticle, ItemID: 1, Time: lateUTC, Visitor: "v:a", Referrer: "https://www.Google.com/search?q=go"},
+		{ItemType: ViewTargetArticle, ItemID: 1, Time: lateUTC, Visitor: "v:a", Referrer: "https://example.com/articles", Campaign: ViewCampaign{Source: " Newsletter ", Medium: "Email", Campaign: "March"}},
+		{ItemType: ViewTargetArticle, ItemID: 1, Time: lateUTC, Campaign: ViewCampaign{Source: "newsletter", Medium: "email", Campaign: "march"}},
+		{ItemType: ViewTargetArticle, ItemID: 1, Time: lateUTC, Campaign: ViewCampaign{Medium: "email"}},
+		{ItemType: ViewTargetArticle, ItemID: 1, Time: lateUTC, Visitor: "u:7"},
+		{ItemType: ViewTargetArticle, ItemID: 1, Time: earlyUTC, Visitor: "v:a", Referrer: "android-app://com.slack"},
+	}
+
+	// 盐由仓储保存，以已保存的为准
+	analyticsRepo.On("DailySalt", mock.Anything, "2025-03-10", mock.Anything).Return("salt-10", nil).Once()
+	analyticsRepo.On("DailySalt", mock.Anything, "2025-03-09", mock.Anything).Return("salt-09", nil).Once()
+	analyticsRepo.On("RecordViews", mock.Anything, []repository.AnalyticsViewBatch{
+		{
+			Date:      "2025-03-10",
+			ItemType:  ViewTargetArticle,
+			ItemID:    1,
+			Views:     5,
+			Visitors:  []string{saltedVisitor("salt-10", "v:a"), saltedVisitor("salt-10", "u:7")},
+			Referrers: map[string]uint{"google.com": 1, "": 4},
+			Campaigns: map[repository.AnalyticsCampaignKey]uint{{Source: "newsletter", Medium: "email", Campaign: "march"}: 2},
+		},
+		{
+			Date:      "2025-03-09",
+			ItemType:  ViewTargetArticle,
+			ItemID:    1,
+			Views:     1,
+			Visitors:  []string{saltedVisitor("salt-09", "v:a")},
+			Referrers: map[string]uint{"": 1},
+			Campaigns: map[repository.AnalyticsCampaignKey]uint{},
+		},
+	}).Return(nil).Once()
+
+	require.NoError(t, s.RecordViews(context.Background(), hits))
+	assert.NotEqual(t, saltedVisitor("salt-10", "v:a"), saltedVisitor("salt-09", "v:a"))
+
+	// 同一天的盐只读取一次
+	analyticsRepo.On("RecordViews", mock.Anything, mock.Anything).Return(nil).Once()
+	require.NoError(t, s.RecordViews(context.Background(), hits[:1]))
+	analyticsRepo.AssertExpectations(t)
+}
+
+// TestAnalyticsSeries 测试时间序列补零和查询条件校验
+func TestAnalyticsSeries(t *testing.T) {
+	analyticsRepo := new(MockAnalyticsRepository)
+	s := newTestAnalyticsService(analyticsRepo, config.AnalyticsConfig{MaxRangeDays: 31})
+	ctx := context.Background()
+
+	analyticsRepo.On("DailySeries", mock.Anything, repository.AnalyticsFilter{
+		ItemType: ViewTargetArticle, ItemID: 1, From: "2025-03-08", To: "2025-03-10", Limit: 10,
+	}).Return([]domain.AnalyticsPoint{{Date: "2025-03-09", Views: 5, Visitors: 3}}, nil).Once()
+
+	points, err := s.GetSeries(ctx, AnalyticsQuery{ItemType: ViewTargetArticle, ItemID: 1, From: "2025-03-08"})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.AnalyticsPoint{
+		{Date: "2025-03-08"},
+		{Date: "2025-03-09", Views: 5, Visitors: 3},
+		{Date: "2025-03-10"},
+	}, points)
+
+	// 按月查询时起始日期扩展到月初
+	analyticsRepo.On("MonthlySeries", mock.Anything, repository.AnalyticsFilter{
+		From: "2025-01-01", To: "2025-03-10", Limit: 10,
+	}).Return([]domain.AnalyticsPoint{{Date: "2025-02", Views: 40}}, nil).Once()
+
+	points, err = s.GetSeries(ctx, AnalyticsQuery{From: "2025-01-15", Period: domain.AnalyticsPeriodMonth})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.AnalyticsPoint{{Date: "2025-01"}, {Date: "2025-02", Views: 40}, {Date: "2025-03"}}, points)
+
+	invalid := []AnalyticsQuery{
+		{From: "2025-03-11"}, // 起始日期晚于结束日期
+		{From: "2025/03/01"}, // 日期格式错误
+		{From: "2025-01-01"}, // 超过按日查询的最大天数
+		{ItemType: "user"},   // 不支持的内容类型
+		{Period: "week"},     // 不支持的粒度
+	}
+	for _, query := range invalid {
+		_, err := s.GetSeries(ctx, query)
+		assert.ErrorIs(t, err, ErrInvalidAnalyticsQuery, "%+v", query)
+	}
+	_, err = s.GetTopItems(ctx, AnalyticsQuery{Metric: "shares"})
+	assert.ErrorIs(t, err, ErrInvalidAnalyticsQuery)
+
+	analyticsRepo.AssertExpectations(t)
+}
+
+// TestAnalyticsEventsAndMaintain 测试点赞和评论事件的记录以及数据维护
+func TestAnalyticsEventsAndMaintain(t *testing.T) {
+	analyticsRepo := new(MockAnalyticsRepository)
+	s := newTestAnalyticsService(analyticsRepo, config.AnalyticsConfig{DailyRetentionDays: 90, MonthlyRetentionMonths: 24})
+	bus := event.NewBus()
+	s.Subscribe(bus)
+	ctx := context.Background()
+
+	analyticsRepo.On("AddCounts", mock.Anything, &domain.AnalyticsStat{
+		Period: domain.AnalyticsPeriodDay, Date: "2025-03-10", ItemType: "article", ItemID: 3, Likes: 1,
+	}).Return(nil).Once()
+	analyticsRepo.On("AddCounts", mock.Anything, &domain.AnalyticsStat{
+		Period: domain.AnalyticsPeriodDay, Date: "2025-03-10", ItemType: "tool", ItemID: 4, Comments: 1,
+	}).Return(nil).Twice()
+
+	bus.Publish(ctx, event.ReactionAdded{TargetType: "article", TargetID: 3, Kind: domain.ReactionLike})
+	bus.Publish(ctx, event.ReactionAdded{TargetType: "article", TargetID: 3, Kind: "heart"})
+	bus.Publish(ctx, event.ReactionAdded{TargetType: "comment", TargetID: 9, Kind: domain.ReactionLike})
+	bus.Publish(ctx, event.CommentCreated{Comment: &domain.Comment{ItemType: "tool", ItemID: 4, Status: "approved"}})
+	bus.Publish(ctx, event.CommentCreated{Comment: &domain.Comment{ItemType: "tool", ItemID: 4, Status: "pending"}})
+	bus.Publish(ctx, event.CommentApproved{Comment: &domain.Comment{ItemType: "tool", ItemID: 4}, FromStatus: "pending"})
+
+	analyticsRepo.On("DeleteVisitorsBefore", mock.Anything, "2025-03-09").Return(int64(12), nil).Once()
+	analyticsRepo.On("RollupBefore", mock.Anything, "2024-12-10").Return(int64(0), nil).Once()
+	analyticsRepo.On("DeleteMonthlyBefore", mock.Anything, "2023-03-01").Return(int64(0), nil).Once()
+	require.NoError(t, s.Maintain(ctx))
+
+	analyticsRepo.AssertExpectations(t)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"Lin_studio/internal/cache"
 	"Lin_studio/internal/domain"
 	"Lin_studio/internal/repository"
 	"Lin_studio/internal/utils"
@@ -8,25 +9,33 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"html"
+	"log"
 	"mime/multipart"
-	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	// ErrArticleNotFound 文章不存在
+	ErrArticleNotFound = errors.New("文章不存在")
+	// ErrVersionConflict 内容已被其他人修改，请求中的版本号已过期，与repository.ErrVersionConflict相同
+	ErrVersionConflict = repository.ErrVersionConflict
+)
+
 // ArticleService 文章服务接口
 type ArticleService interface {
 	GetArticles(ctx context.Context, page, limit int, categoryID, tagID, authorID *uint, status, search, sort *string, renderHTML bool) ([]domain.Article, domain.PaginationData, error)
 	GetArticleByID(ctx context.Context, id uint, renderHTML bool) (*domain.Article, error)
 	GetArticleBySlug(ctx context.Context, slug string, renderHTML bool) (*domain.Article, error)
 	CreateArticle(ctx context.Context, title, excerpt, content string, authorID, categoryID uint, tagIDs []uint, coverImage, status string) (*domain.Article, error)
-	UpdateArticle(ctx context.Context, id uint, title, excerpt, content string, categoryID uint, tagIDs []uint, coverImage, status string) (*domain.Article, error)
-	DeleteArticle(ctx context.Context, id uint) error
-	UploadCoverImage(ctx context.Context, file *multipart.FileHeader) (string, error)
+	UpdateArticle(ctx context.Context, id, version uint, title, excerpt, content string, categoryID uint, tagIDs []uint, coverImage, status string) (*domain.Article, error)
+	DeleteArticle(ctx context.Context, id, version uint) error
+	UploadCoverImage(ctx context.Context, file *multipart.FileHeader, uploaderID uint) (string, error)
 	GetFeaturedArticles(ctx context.Context, limit int, renderHTML bool) ([]domain.Article, error)
-	ViewArticle(ctx context.Context, id uint) error
-	LikeArticle(ctx context.Context, id uint) error
+	RelatedArticles(ctx context.Context, id uint, n int) ([]domain.Article, error)
+	RebuildRelatedArticles(ctx context.Context) error
 }
 
 // ArticleServiceImpl 文章服务实现
@@ -35,6 +44,14 @@ type ArticleServiceImpl struct {
 	tagRepo     repository.TagRepository
 	userRepo    repository.UserRepository
 	categoryRepo repository.CategoryRepository
+	seriesRepo   repository.SeriesRepository
+	mediaService MediaService
+	cache        cache.Cache
+	relatedMu    sync.Mutex // 串行化相关文章的重新计算
+	// 已发布文章变化后延迟在后台重新计算相关文章
+	relatedDelay time.Duration
+	timerMu      sync.Mutex
+	relatedTimer *time.Timer
 }
 
 // NewArticleService 创建文章服务实例
@@ -43,12 +60,19 @@ func NewArticleService(
 	tagRepo repository.TagRepository,
 	userRepo repository.UserRepository,
 	categoryRepo repository.CategoryRepository,
+	seriesRepo repository.SeriesRepository,
+	mediaService MediaService,
+	appCache cache.Cache,
 ) ArticleService {
 	return &ArticleServiceImpl{
 		articleRepo: articleRepo,
 		tagRepo:     tagRepo,
 		userRepo:    userRepo,
 		categoryRepo: categoryRepo,
+		seriesRepo:   seriesRepo,
+		mediaService: mediaService,
+		cache:        appCache,
+		relatedDelay: relatedRebuildDelay,
 	}
 }
 
@@ -117,6 +141,10 @@ func (s *ArticleServiceImpl) GetArticles(
 		}
 	}
 
+	// 加载封面图的响应式版本，最后为草稿的文件签名
+	s.loadCoverImageSets(ctx, articles)
+	s.signDraftMediaList(articles)
+
 	return articles, pagination, nil
 }
 
@@ -128,7 +156,7 @@ func (s *ArticleServiceImpl) GetArticleByID(ctx context.Context, id uint, render
 	}
 
 	if article == nil {
-		return nil, errors.New("文章不存在")
+		return nil, ErrArticleNotFound
 	}
 
 	// 加载相关数据
@@ -142,6 +170,9 @@ func (s *ArticleServiceImpl) GetArticleByID(ctx context.Context, id uint, render
 		}
 	}
 
+	// 渲染完成后再签名，避免重新渲染的HTML覆盖已签名的图片地址
+	s.signDraftMedia(article)
+
 	return article, nil
 }
 
@@ -153,7 +184,7 @@ func (s *ArticleServiceImpl) GetArticleBySlug(ctx context.Context, slug string,
 	}
 
 	if article == nil {
-		return nil, errors.New("文章不存在")
+		return nil, ErrArticleNotFound
 	}
 
 	// 加载相关数据
@@ -167,6 +198,9 @@ func (s *ArticleServiceImpl) GetArticleBySlug(ctx context.Context, slug string,
 		}
 	}
 
+	// 渲染完成后再签名，避免重新渲染的HTML覆盖已签名的图片地址
+	s.signDraftMedia(article)
+
 	return article, nil
 }
 
@@ -210,6 +244,9 @@ func (s *ArticleServiceImpl) CreateArticle(
 		catID = &categoryID
 	}
 
+	// 去掉草稿预览时附带的签名参数
+	coverImage = utils.StripURLSignature(coverImage)
+
 	// 生成slug
 	slug := s.generateSlug(title)
 
@@ -240,6 +277,7 @@ func (s *ArticleServiceImpl) CreateArticle(
 		CoverImage:  coverImage,
 		Status:      status,
 		PublishedAt: publishedAt,
+		Version:     1,
 	}
 
 	// 保存文章
@@ -259,13 +297,22 @@ func (s *ArticleServiceImpl) CreateArticle(
 		}
 	}
 
+	// 记录文章引用的媒体文件
+	s.syncMediaReferences(ctx, article)
+	if article.Status == "published" {
+		s.refreshRelatedArticles()
+	}
+	s.signDraftMedia(article)
+	cache.InvalidateLogged(ctx, s.cache, cache.TagArticle)
+
 	return article, nil
 }
 
 // UpdateArticle 更新文章
+// version为客户端读取时的版本号，大于0且与当前版本不一致时返回ErrVersionConflict
 func (s *ArticleServiceImpl) UpdateArticle(
 	ctx context.Context,
-	id uint,
+	id, version uint,
 	title, excerpt, content string,
 	categoryID uint,
 	tagIDs []uint,
@@ -277,8 +324,12 @@ func (s *ArticleServiceImpl) UpdateArticle(
 		return nil, err
 	}
 	if article == nil {
-		return nil, errors.New("文章不存在")
+		return nil, ErrArticleNotFound
+	}
+	if version > 0 && article.Version != version {
+		return nil, ErrVersionConflict
 	}
+	wasPublished := article.Status == "published"
 
 	// 检查标题是否为空
 	if strings.TrimSpace(title) == "" {
@@ -332,12 +383,13 @@ func (s *ArticleServiceImpl) UpdateArticle(
 	article.ContentHTML = contentHTML // 更新渲染后的HTML内容
 	article.CategoryID = catID
 	if coverImage != "" {
-		article.CoverImage = coverImage
+		// 去掉草稿预览时附带的签名参数
+		article.CoverImage = utils.StripURLSignature(coverImage)
 	}
 	article.Status = status
 	article.PublishedAt = publishedAt
 
-	// 保存更新
+	// 保存更新，读取后被其他请求修改过时返回ErrVersionConflict
 	err = s.articleRepo.Update(ctx, article)
 	if err != nil {
 		return nil, err
@@ -361,61 +413,70 @@ func (s *ArticleServiceImpl) UpdateArticle(
 		}
 	}
 
+	// 记录文章引用的媒体文件，不再使用的文件会被清理
+	s.syncMediaReferences(ctx, article)
+	if wasPublished || article.Status == "published" {
+		s.refreshRelatedArticles()
+	}
+	s.signDraftMedia(article)
+	cache.InvalidateLogged(ctx, s.cache, cache.TagArticle)
+
 	return article, nil
 }
 
-// DeleteArticle 删除文章
-func (s *ArticleServiceImpl) DeleteArticle(ctx context.Context, id uint) error {
+// DeleteArticle 删除文章，version大于0时只删除该版本
+func (s *ArticleServiceImpl) DeleteArticle(ctx context.Context, id, version uint) error {
 	// 检查文章是否存在
 	article, err := s.articleRepo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if article == nil {
-		return errors.New("文章不存在")
+		return ErrArticleNotFound
 	}
 
 	// 删除文章
-	return s.articleRepo.Delete(ctx, id)
-}
-
-// UploadCoverImage 上传文章封面图片
-func (s *ArticleServiceImpl) UploadCoverImage(ctx context.Context, file *multipart.FileHeader) (string, error) {
-	// 确保上传目录存在
-	uploadDir := "./uploads/covers"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return "", err
+	if version > 0 && article.Version != version {
+		return ErrVersionConflict
 	}
-
-	// 创建文件名
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("cover_%d%s", time.Now().Unix(), ext)
-	filePath := filepath.Join(uploadDir, filename)
-
-	// 保存文件
-	src, err := file.Open()
-	if err != nil {
-		return "", err
+	if err := s.articleRepo.Delete(ctx, id, version); err != nil {
+		return err
+	}
+	if article.Status == "published" {
+		s.refreshRelatedArticles()
 	}
-	defer src.Close()
+	cache.InvalidateLogged(ctx, s.cache, cache.TagArticle)
 
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return "", err
+	// 释放文章引用的媒体文件
+	if err := s.mediaService.SyncReferences(ctx, domain.MediaRefArticle, id); err != nil {
+		log.Printf("清除文章 %d 的媒体引用失败: %v", id, err)
 	}
-	defer dst.Close()
+	return nil
+}
 
-	// 将源文件内容复制到目标文件
-	if _, err = dst.ReadFrom(src); err != nil {
+// UploadCoverImage 上传文章封面图片
+func (s *ArticleServiceImpl) UploadCoverImage(ctx context.Context, file *multipart.FileHeader, uploaderID uint) (string, error) {
+	// 保存到媒体库，相同图片只保存一份
+	media, err := s.mediaService.Upload(ctx, file, &uploaderID)
+	if err != nil {
 		return "", err
 	}
 
 	// 返回文件URL
-	return fmt.Sprintf("/uploads/covers/%s", filename), nil
+	return media.URL, nil
 }
 
-// GetFeaturedArticles 获取精选文章
+// GetFeaturedArticles 获取精选文章，结果在文章或分类修改前缓存
 func (s *ArticleServiceImpl) GetFeaturedArticles(ctx context.Context, limit int, renderHTML bool) ([]domain.Article, error) {
+	key := fmt.Sprintf("articles:featured:%d:%t", limit, renderHTML)
+	tags := []string{cache.TagArticle, cache.TagCategory}
+	return cache.Remember(ctx, s.cache, key, 0, tags, func() ([]domain.Article, error) {
+		return s.loadFeaturedArticles(ctx, limit, renderHTML)
+	})
+}
+
+// loadFeaturedArticles 从数据库加载精选文章
+func (s *ArticleServiceImpl) loadFeaturedArticles(ctx context.Context, limit int, renderHTML bool) ([]domain.Article, error) {
 	articles, err := s.articleRepo.FindFeatured(ctx, limit)
 	if err != nil {
 		return nil, err
@@ -445,17 +506,81 @@ func (s *ArticleServiceImpl) GetFeaturedArticles(ctx context.Context, limit int,
 		}
 	}
 
+	// 加载封面图的响应式版本，最后为草稿的文件签名
+	s.loadCoverImageSets(ctx, articles)
+	s.signDraftMediaList(articles)
+
 	return articles, nil
 }
 
-// ViewArticle 增加文章浏览量
-func (s *ArticleServiceImpl) ViewArticle(ctx context.Context, id uint) error {
-	return s.articleRepo.IncrementViews(ctx, id)
+// RelatedArticles 获取与文章最相关的n篇已发布文章，n最多为10
+// 结果在发布、更新或删除文章时预先计算，查询结果在文章或分类修改前缓存
+func (s *ArticleServiceImpl) RelatedArticles(ctx context.Context, id uint, n int) ([]domain.Article, error) {
+	if n < 1 || n > relatedArticlesLimit {
+		n = relatedArticlesLimit
+	}
+	key := fmt.Sprintf("articles:related:%d:%d", id, n)
+	tags := []string{cache.TagArticle, cache.TagCategory, cache.TagRelated}
+	return cache.Remember(ctx, s.cache, key, 0, tags, func() ([]domain.Article, error) {
+		articles, err := s.articleRepo.FindRelated(ctx, id, n)
+		if err != nil {
+			return nil, err
+		}
+
+		// 只加载作者和分类，不渲染内容
+		for i := range articles {
+			author, err := s.userRepo.FindByID(ctx, articles[i].AuthorID)
+			if err == nil && author != nil {
+				articles[i].Author = author
+			}
+
+			if articles[i].CategoryID != nil {
+				category, err := s.categoryRepo.FindByID(ctx, *articles[i].CategoryID)
+				if err == nil && category != nil {
+					articles[i].Category = category
+				}
+			}
+		}
+		s.loadCoverImageSets(ctx, articles)
+		s.signDraftMediaList(articles)
+
+		return articles, nil
+	})
+}
+
+// RebuildRelatedArticles 重新计算所有已发布文章的相关文章
+func (s *ArticleServiceImpl) RebuildRelatedArticles(ctx context.Context) error {
+	s.relatedMu.Lock()
+	defer s.relatedMu.Unlock()
+
+	articles, err := s.articleRepo.FindPublishedWithTags(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.articleRepo.ReplaceRelated(ctx, computeRelatedArticles(articles, time.Now())); err != nil {
+		return err
+	}
+	cache.InvalidateLogged(ctx, s.cache, cache.TagRelated)
+	return nil
 }
 
-// LikeArticle 点赞文章
-func (s *ArticleServiceImpl) LikeArticle(ctx context.Context, id uint) error {
-	return s.articleRepo.IncrementLikes(ctx, id)
+// refreshRelatedArticles 已发布文章变化后安排在后台重新计算相关文章，不阻塞文章保存
+// 等待期间的多次变化合并为一次计算
+func (s *ArticleServiceImpl) refreshRelatedArticles() {
+	s.timerMu.Lock()
+	defer s.timerMu.Unlock()
+	if s.relatedTimer != nil {
+		return
+	}
+	s.relatedTimer = time.AfterFunc(s.relatedDelay, func() {
+		s.timerMu.Lock()
+		s.relatedTimer = nil
+		s.timerMu.Unlock()
+
+		if err := s.RebuildRelatedArticles(context.Background()); err != nil {
+			log.Printf("计算相关文章失败: %v", err)
+		}
+	})
 }
 
 // loadArticleRelations 加载文章关联数据
@@ -479,6 +604,86 @@ func (s *ArticleServiceImpl) loadArticleRelations(ctx context.Context, article *
 	if err == nil {
 		article.Tags = tags
 	}
+
+	// 加载封面图的响应式版本
+	if article.CoverImage != "" {
+		sets := s.mediaService.GetImageSets(ctx, []string{article.CoverImage})
+		article.CoverImageSet = sets[article.CoverImage]
+	}
+
+	// 加载所属系列和上一篇、下一篇
+	s.loadSeriesNav(ctx, article)
+}
+
+// loadSeriesNav 加载文章所属系列的导航，上一篇、下一篇只在已发布的文章中查找，失败时不返回系列
+func (s *ArticleServiceImpl) loadSeriesNav(ctx context.Context, article *domain.Article) {
+	series, err := s.seriesRepo.FindByArticleID(ctx, article.ID)
+	if err != nil {
+		log.Printf("获取文章 %d 所属系列失败: %v", article.ID, err)
+		return
+	}
+	if series == nil {
+		return
+	}
+
+	parts, err := s.seriesRepo.FindParts(ctx, series.ID)
+	if err != nil {
+		log.Printf("获取系列 %d 的文章失败: %v", series.ID, err)
+		return
+	}
+	article.Series = domain.NewArticleSeriesNav(series, publishedParts(parts, article.ID), article.ID)
+}
+
+// loadCoverImageSets 批量加载文章封面图的响应式版本
+func (s *ArticleServiceImpl) loadCoverImageSets(ctx context.Context, articles []domain.Article) {
+	urls := make([]string, 0, len(articles))
+	for i := range articles {
+		if articles[i].CoverImage != "" {
+			urls = append(urls, articles[i].CoverImage)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	sets := s.mediaService.GetImageSets(ctx, urls)
+	for i := range articles {
+		articles[i].CoverImageSet = sets[articles[i].CoverImage]
+	}
+}
+
+// signDraftMediaList 为列表中的草稿签名，需要在渲染HTML和加载封面图之后调用
+func (s *ArticleServiceImpl) signDraftMediaList(articles []domain.Article) {
+	for i := range articles {
+		s.signDraftMedia(&articles[i])
+	}
+}
+
+// signDraftMedia 未发布文章的文件只能通过签名URL访问，返回前为封面和正文HTML中的图片签名
+// 只修改返回给调用方的数据，Markdown原文保持不变，避免签名被编辑器回写
+func (s *ArticleServiceImpl) signDraftMedia(article *domain.Article) {
+	if article.Status == "published" {
+		return
+	}
+
+	for _, url := range ArticleMediaURLs(article) {
+		signed := s.mediaService.SignURL(url)
+		if signed == url {
+			continue
+		}
+		if article.ContentHTML != "" {
+			article.ContentHTML = strings.ReplaceAll(article.ContentHTML, `"`+url+`"`, `"`+html.EscapeString(signed)+`"`)
+		}
+	}
+	article.CoverImage = s.mediaService.SignURL(article.CoverImage)
+	article.CoverImageSet = s.mediaService.SignImageSet(article.CoverImageSet)
+}
+
+// syncMediaReferences 同步文章引用的媒体文件，失败不影响文章保存
+func (s *ArticleServiceImpl) syncMediaReferences(ctx context.Context, article *domain.Article) {
+	if err := s.mediaService.SyncReferences(ctx, domain.MediaRefArticle, article.ID, ArticleMediaURLs(article)...); err != nil {
+		log.Printf("同步文章 %d 的媒体引用失败: %v", article.ID, err)
+	}
 }
 
 // generateSlug 生成文章别名
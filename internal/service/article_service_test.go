@@ -0,0 +1,133 @@
+package service
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"context"
+	"html"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTagRepository 模拟标签仓储
+type MockTagRepository struct {
+	mock.Mock
+}
+
+func (m *MockTagRepository) Create(ctx context.Context, tag *domain.Tag) error {
+	args := m.Called(ctx, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) FindByID(ctx context.Context, id uint) (*domain.Tag, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) FindBySlug(ctx context.Context, slug string) (*domain.Tag, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) FindAll(ctx context.Context) ([]domain.Tag, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) Update(ctx context.Context, tag *domain.Tag) error {
+	args := m.Called(ctx, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Delete(ctx context.Context, id, version uint) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) CountArticles(ctx context.Context, tagID uint) (int64, error) {
+	args := m.Called(ctx, tagID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTagRepository) CountProjects(ctx context.Context, tagID uint) (int64, error) {
+	args := m.Called(ctx, tagID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTagRepository) FindByArticleID(ctx context.Context, articleID uint) ([]domain.Tag, error) {
+	args := m.Called(ctx, articleID)
+	return args.Get(0).([]domain.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) AddArticleTag(ctx context.Context, articleID, tagID uint) error {
+	args := m.Called(ctx, articleID, tagID)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) RemoveArticleTag(ctx context.Context, articleID, tagID uint) error {
+	args := m.Called(ctx, articleID, tagID)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) ClearArticleTags(ctx context.Context, articleID uint) error {
+	args := m.Called(ctx, articleID)
+	return args.Error(0)
+}
+
+// TestGetDraftArticleSignsRenderedHTML 测试读取草稿并重新渲染HTML时，正文图片和封面使用签名URL
+func TestGetDraftArticleSignsRenderedHTML(t *testing.T) {
+	ctx := context.Background()
+	articleRepo := new(MockArticleRepository)
+	tagRepo := new(MockTagRepository)
+	userRepo := new(MockUserRepository)
+	seriesRepo := new(MockSeriesRepository)
+	signer := utils.NewURLSigner("secret", time.Hour)
+	mediaService := NewMediaService(new(MockMediaRepository), storage.NewLocalStorage(t.TempDir(), "/uploads"), nil, nil, signer)
+	s := NewArticleService(articleRepo, tagRepo, userRepo, nil, seriesRepo, mediaService, nil)
+
+	draft := &domain.Article{
+		ID:       1,
+		Slug:     "draft",
+		AuthorID: 2,
+		Status:   "draft",
+		Content:  "![图](/uploads/media/ab/photo.png)",
+	}
+	articleRepo.On("FindBySlug", mock.Anything, "draft").Return(draft, nil)
+	articleRepo.On("FindAll", mock.Anything, mock.Anything).Return([]domain.Article{*draft}, int64(1), nil)
+	userRepo.On("FindByID", mock.Anything, uint(2)).Return(nil, nil)
+	tagRepo.On("FindByArticleID", mock.Anything, uint(1)).Return([]domain.Tag{}, nil)
+	seriesRepo.On("FindByArticleID", mock.Anything, uint(1)).Return(nil, nil)
+
+	srcPattern := regexp.MustCompile(`src="([^"]+)"`)
+	assertSigned := func(contentHTML string) {
+		match := srcPattern.FindStringSubmatch(contentHTML)
+		require.Len(t, match, 2, contentHTML)
+		signed, err := url.Parse(html.UnescapeString(match[1]))
+		require.NoError(t, err)
+		assert.Equal(t, "/uploads/media/ab/photo.png", signed.Path)
+		assert.True(t, signer.Verify(signed.Path, signed.Query().Get("expires"), signed.Query().Get("signature")), match[1])
+	}
+
+	article, err := s.GetArticleBySlug(ctx, "draft", true)
+	require.NoError(t, err)
+	assertSigned(article.ContentHTML)
+
+	// 列表中没有封面的草稿也需要签名
+	articles, _, err := s.GetArticles(ctx, 1, 10, nil, nil, nil, nil, nil, nil, true)
+	require.NoError(t, err)
+	require.Len(t, articles, 1)
+	assertSigned(articles[0].ContentHTML)
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"Lin_studio/internal/cache"
 	"Lin_studio/internal/domain"
 	"Lin_studio/internal/repository"
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -19,17 +21,31 @@ type CategoryService interface {
 // CategoryServiceImpl 分类服务实现
 type CategoryServiceImpl struct {
 	categoryRepo repository.CategoryRepository
+	cache        cache.Cache
 }
 
 // NewCategoryService 创建分类服务实例
-func NewCategoryService(categoryRepo repository.CategoryRepository) CategoryService {
+func NewCategoryService(categoryRepo repository.CategoryRepository, appCache cache.Cache) CategoryService {
 	return &CategoryServiceImpl{
 		categoryRepo: categoryRepo,
+		cache:        appCache,
 	}
 }
 
-// GetAllCategories 获取所有分类
+// GetAllCategories 获取所有分类，文章数量随文章变化，结果在分类或文章修改前缓存
 func (s *CategoryServiceImpl) GetAllCategories(ctx context.Context, parentID *uint) ([]domain.CategoryResponse, error) {
+	key := "categories:all"
+	if parentID != nil {
+		key = fmt.Sprintf("categories:parent:%d", *parentID)
+	}
+	tags := []string{cache.TagCategory, cache.TagArticle}
+	return cache.Remember(ctx, s.cache, key, 0, tags, func() ([]domain.CategoryResponse, error) {
+		return s.loadAllCategories(ctx, parentID)
+	})
+}
+
+// loadAllCategories 从数据库加载分类和文章数量
+func (s *CategoryServiceImpl) loadAllCategories(ctx context.Context, parentID *uint) ([]domain.CategoryResponse, error) {
 	categories, err := s.categoryRepo.FindAll(ctx, parentID)
 	if err != nil {
 		return nil, err
@@ -49,6 +65,9 @@ func (s *CategoryServiceImpl) GetAllCategories(ctx context.Context, parentID *ui
 			Description:  category.Description,
 			ParentID:     category.ParentID,
 			ArticleCount: articleCount,
+			Version:      category.Version,
+			CreatedAt:    category.CreatedAt,
+			UpdatedAt:    category.UpdatedAt,
 		})
 	}
 	
@@ -93,12 +112,14 @@ func (s *CategoryServiceImpl) CreateCategory(ctx context.Context, name, descript
 		Slug:        slug,
 		Description: description,
 		ParentID:    parentID,
+		Version:     1,
 	}
 	
 	err = s.categoryRepo.Create(ctx, category)
 	if err != nil {
 		return nil, err
 	}
+	cache.InvalidateLogged(ctx, s.cache, cache.TagCategory)
 	
 	return category, nil
 }
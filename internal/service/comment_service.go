@@ -1,39 +1,103 @@
 package service
 
 import (
+	"Lin_studio/internal/config"
 	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
 	"Lin_studio/internal/repository"
+	"Lin_studio/internal/utils"
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"net/url"
 	"strings"
+	"time"
 )
 
+var (
+	// ErrCommentNotFound 评论不存在
+	ErrCommentNotFound = errors.New("评论不存在")
+	// ErrCommentTooDeep 回复层级超过限制
+	ErrCommentTooDeep = errors.New("回复层级过深，请回复上层评论")
+	// ErrCommentTargetNotFound 评论的内容不存在
+	ErrCommentTargetNotFound = errors.New("评论的内容不存在")
+	// ErrCommentTargetClosed 内容当前状态不允许评论
+	ErrCommentTargetClosed = errors.New("该内容暂不允许评论")
+	// ErrCommentRejected 评论被反垃圾检查拒绝
+	ErrCommentRejected = errors.New("评论被判定为垃圾信息")
+	// ErrInvalidModerationAction 无效的审核操作
+	ErrInvalidModerationAction = errors.New("无效的审核操作")
+	// ErrCommentEditForbidden 无权修改或查看评论的修改记录
+	ErrCommentEditForbidden = errors.New("无权修改此评论")
+	// ErrCommentEditExpired 评论已超过允许作者修改的时间
+	ErrCommentEditExpired = errors.New("评论已超过可修改的时间")
+)
+
+// maxBulkModeration 批量审核每次最多处理的评论数量
+const maxBulkModeration = 500
+
+// maxCommentMentions 每条评论最多提及的用户数量，超出的@用户名按普通文本显示
+const maxCommentMentions = 10
+
 // CommentService 评论服务接口
 type CommentService interface {
 	GetComments(ctx context.Context, itemType string, itemID uint, parentID *uint, page, limit int, status *string) ([]domain.Comment, domain.PaginationData, error)
+	GetCommentTree(ctx context.Context, itemType string, itemID uint, page, limit, depth int) ([]domain.Comment, domain.PaginationData, error)
+	GetReplies(ctx context.Context, parentID uint, page, limit, depth int) ([]domain.Comment, domain.PaginationData, error)
+	MaxDepth() int
 	GetCommentByID(ctx context.Context, id uint) (*domain.Comment, error)
-	CreateComment(ctx context.Context, content, itemType string, itemID uint, userID *uint, anonymousName, anonymousEmail string, parentID *uint) (*domain.Comment, error)
+	CreateComment(ctx context.Context, content, itemType string, itemID uint, userID *uint, anonymousName, anonymousEmail string, notifyEmail bool, parentID *uint, client CommentClient) (*domain.Comment, error)
+	IssueFormToken() string
 	UpdateComment(ctx context.Context, id uint, content string, userID uint) (*domain.Comment, error)
+	GetCommentRevisions(ctx context.Context, id uint, userID uint, isAdmin bool) ([]domain.CommentRevision, error)
 	DeleteComment(ctx context.Context, id uint, userID uint, isAdmin bool) error
-	LikeComment(ctx context.Context, id uint) error
-	ApproveComment(ctx context.Context, id uint) error
-	MarkCommentAsSpam(ctx context.Context, id uint) error
+	ApproveComment(ctx context.Context, id uint, moderatorID uint) error
+	MarkCommentAsSpam(ctx context.Context, id uint, moderatorID uint) error
+	GetModerationQueue(ctx context.Context, status, itemType string, itemID uint, minScore *float64, page, limit int) ([]domain.ModerationItem, domain.PaginationData, error)
+	BulkModerate(ctx context.Context, action string, ids []uint, filter *ModerationCriteria, moderatorID uint) (*domain.ModerationResult, error)
+	GetModerationLogs(ctx context.Context, commentID, moderatorID *uint, page, limit int) ([]domain.ModerationLog, domain.PaginationData, error)
+}
+
+// CommentClient 提交评论的客户端信息，用于反垃圾检查
+type CommentClient struct {
+	IP        string
+	UserAgent string
+	Honeypot  string // 表单隐藏字段的值
+	FormToken string // 评论表单令牌
+}
+
+// ModerationCriteria 批量审核的评论筛选条件
+type ModerationCriteria struct {
+	Status   string // 默认为pending
+	ItemType string
+	ItemID   uint
+	MinScore *float64
 }
 
 // CommentServiceImpl 评论服务实现
 type CommentServiceImpl struct {
 	commentRepo repository.CommentRepository
 	userRepo    repository.UserRepository
+	spamFilter  SpamFilter
+	events      event.Bus
+	cfg         config.CommentConfig
 }
 
-// NewCommentService 创建评论服务实例
+// NewCommentService 创建评论服务实例，spamFilter为nil时不检查匿名评论，events为nil时不发布事件
 func NewCommentService(
 	commentRepo repository.CommentRepository,
 	userRepo repository.UserRepository,
+	spamFilter SpamFilter,
+	events event.Bus,
+	cfg config.CommentConfig,
 ) CommentService {
 	return &CommentServiceImpl{
 		commentRepo: commentRepo,
 		userRepo:    userRepo,
+		spamFilter:  spamFilter,
+		events:      events,
+		cfg:         cfg,
 	}
 }
 
@@ -106,6 +170,181 @@ func (s *CommentServiceImpl) GetComments(
 	return comments, pagination, nil
 }
 
+// GetCommentTree 获取评论树
+// 顶级评论分页返回，每条评论展开depth层回复，每层最多展开配置数量的回复，其余回复通过GetReplies加载
+// depth<=0时使用配置的默认层级
+func (s *CommentServiceImpl) GetCommentTree(
+	ctx context.Context,
+	itemType string,
+	itemID uint,
+	page, limit, depth int,
+) ([]domain.Comment, domain.PaginationData, error) {
+	filter := repository.CommentFilter{
+		Page:     page,
+		Limit:    limit,
+		ItemType: itemType,
+		ItemID:   itemID,
+	}
+
+	comments, total, err := s.commentRepo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	if err := s.buildTree(ctx, comments, depth); err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	pagination := domain.PaginationData{
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (int(total) + limit - 1) / limit,
+	}
+	return comments, pagination, nil
+}
+
+// GetReplies 分页获取评论的直接回复，用于加载更多回复
+// 每条回复同样展开depth层子回复，depth<=0时使用配置的默认层级
+func (s *CommentServiceImpl) GetReplies(
+	ctx context.Context,
+	parentID uint,
+	page, limit, depth int,
+) ([]domain.Comment, domain.PaginationData, error) {
+	parent, err := s.commentRepo.FindByID(ctx, parentID)
+	if err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+	if parent == nil || (parent.Status != "approved" && parent.Status != "deleted") {
+		return nil, domain.PaginationData{}, ErrCommentNotFound
+	}
+
+	filter := repository.CommentFilter{
+		Page:        page,
+		Limit:       limit,
+		ParentID:    &parentID,
+		OldestFirst: true,
+	}
+
+	replies, total, err := s.commentRepo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	if err := s.buildTree(ctx, replies, depth); err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	pagination := domain.PaginationData{
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (int(total) + limit - 1) / limit,
+	}
+	return replies, pagination, nil
+}
+
+// MaxDepth 返回允许的最大回复层级
+func (s *CommentServiceImpl) MaxDepth() int {
+	depth := s.cfg.MaxDepth
+	if depth < 0 {
+		return 0
+	}
+	if depth > domain.CommentPathMaxDepth {
+		return domain.CommentPathMaxDepth
+	}
+	return depth
+}
+
+// buildTree 为同一层级的评论加载depth层回复、回复数量和作者信息
+func (s *CommentServiceImpl) buildTree(ctx context.Context, comments []domain.Comment, depth int) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	if depth <= 0 {
+		depth = s.cfg.TreeDepth
+	}
+	perParent := s.cfg.RepliesPerNode
+	if perParent <= 0 {
+		perParent = 5
+	}
+
+	// 查询子树中的回复
+	maxDepth := comments[0].Depth + depth
+	if maxDepth > s.MaxDepth() {
+		maxDepth = s.MaxDepth()
+	}
+	paths := make([]string, 0, len(comments))
+	for _, c := range comments {
+		if c.Path != "" {
+			paths = append(paths, c.Path)
+		}
+	}
+	var descendants []domain.Comment
+	if depth > 0 && maxDepth > comments[0].Depth {
+		var err error
+		descendants, err = s.commentRepo.FindDescendants(ctx, paths, maxDepth, perParent)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 回复总数用于判断是否还有未展开的回复
+	ids := make([]uint, 0, len(comments)+len(descendants))
+	for _, c := range comments {
+		ids = append(ids, c.ID)
+	}
+	for _, c := range descendants {
+		ids = append(ids, c.ID)
+	}
+	counts, err := s.commentRepo.CountRepliesByParent(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	// 加载作者信息，同一用户只查询一次
+	users := make(map[uint]*domain.User)
+	loadUser := func(c *domain.Comment) {
+		c.ReplyCount = counts[c.ID]
+		if c.UserID == nil {
+			return
+		}
+		user, ok := users[*c.UserID]
+		if !ok {
+			if u, err := s.userRepo.FindByID(ctx, *c.UserID); err == nil {
+				user = u
+			}
+			users[*c.UserID] = user
+		}
+		if user != nil {
+			c.User = user
+		}
+	}
+
+	// 按父评论分组，descendants已按层级和时间排序
+	children := make(map[uint][]*domain.Comment)
+	for i := range descendants {
+		loadUser(&descendants[i])
+		if descendants[i].ParentID != nil {
+			children[*descendants[i].ParentID] = append(children[*descendants[i].ParentID], &descendants[i])
+		}
+	}
+
+	// 自底向上组装，父评论未展开的回复不会出现在结果中
+	var attach func(c *domain.Comment)
+	attach = func(c *domain.Comment) {
+		for _, child := range children[c.ID] {
+			attach(child)
+			c.Replies = append(c.Replies, *child)
+		}
+	}
+	for i := range comments {
+		loadUser(&comments[i])
+		attach(&comments[i])
+	}
+	return nil
+}
+
 // GetCommentByID 根据ID获取评论
 func (s *CommentServiceImpl) GetCommentByID(ctx context.Context, id uint) (*domain.Comment, error) {
 	comment, err := s.commentRepo.FindByID(ctx, id)
@@ -114,7 +353,7 @@ func (s *CommentServiceImpl) GetCommentByID(ctx context.Context, id uint) (*doma
 	}
 
 	if comment == nil {
-		return nil, errors.New("评论不存在")
+		return nil, ErrCommentNotFound
 	}
 
 	// 加载用户信息
@@ -145,13 +384,17 @@ func (s *CommentServiceImpl) GetCommentByID(ctx context.Context, id uint) (*doma
 }
 
 // CreateComment 创建评论
+// 匿名评论经过反垃圾检查，按分数自动批准、等待审核或标记为垃圾信息
+// notifyEmail为匿名评论者是否勾选了接收邮件通知，确认邮箱后才会发送
 func (s *CommentServiceImpl) CreateComment(
 	ctx context.Context,
 	content, itemType string,
 	itemID uint,
 	userID *uint,
 	anonymousName, anonymousEmail string,
+	notifyEmail bool,
 	parentID *uint,
+	client CommentClient,
 ) (*domain.Comment, error) {
 	// 检查内容是否为空
 	if strings.TrimSpace(content) == "" {
@@ -159,7 +402,7 @@ func (s *CommentServiceImpl) CreateComment(
 	}
 
 	// 检查内容类型是否有效
-	if itemType != "article" && itemType != "project" && itemType != "tool" {
+	if _, ok := repository.LookupCommentTargetType(itemType); !ok {
 		return nil, errors.New("无效的内容类型")
 	}
 
@@ -168,15 +411,33 @@ func (s *CommentServiceImpl) CreateComment(
 		return nil, errors.New("需要提供用户身份或匿名信息")
 	}
 
-	// 检查父评论是否存在
+	// 检查评论的内容是否存在且允许评论
+	target, err := s.commentRepo.FindTarget(ctx, itemType, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrCommentTargetNotFound
+	}
+	if !target.Open {
+		return nil, ErrCommentTargetClosed
+	}
+
+	// 检查父评论是否存在且属于同一内容
 	if parentID != nil {
 		parent, err := s.commentRepo.FindByID(ctx, *parentID)
 		if err != nil {
 			return nil, err
 		}
-		if parent == nil {
+		if parent == nil || parent.Status == "spam" || parent.Status == "deleted" {
 			return nil, errors.New("父评论不存在")
 		}
+		if parent.ItemType != itemType || parent.ItemID != itemID {
+			return nil, errors.New("父评论不属于该内容")
+		}
+		if parent.Depth+1 > s.MaxDepth() {
+			return nil, ErrCommentTooDeep
+		}
 	}
 
 	// 创建评论
@@ -185,6 +446,7 @@ func (s *CommentServiceImpl) CreateComment(
 		UserID:         userID,
 		AnonymousName:  anonymousName,
 		AnonymousEmail: anonymousEmail,
+		NotifyEmail:    userID == nil && notifyEmail,
 		ItemType:       itemType,
 		ItemID:         itemID,
 		ParentID:       parentID,
@@ -201,24 +463,149 @@ func (s *CommentServiceImpl) CreateComment(
 			return nil, errors.New("用户不存在")
 		}
 		
-		// 如果是管理员或者普通用户，自动批准评论
-		if user.Role == "admin" || user.Role == "editor" {
+		// 管理员和编辑的评论自动批准
+		if isTrustedCommenter(user) {
 			comment.Status = "approved"
 		}
 		
 		comment.User = user
 	}
 
+	// 匿名评论的反垃圾检查
+	var verdict string
+	if userID == nil && s.spamFilter != nil {
+		result, err := s.spamFilter.Check(ctx, &SpamInput{
+			Content:        content,
+			AnonymousName:  anonymousName,
+			AnonymousEmail: anonymousEmail,
+			IP:             client.IP,
+			UserAgent:      client.UserAgent,
+			Honeypot:       client.Honeypot,
+			FormToken:      client.FormToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.Discard {
+			return nil, ErrCommentRejected
+		}
+
+		verdict = result.Verdict
+		comment.SpamScore = result.Score
+		comment.SpamReasons = truncateUTF8(strings.Join(result.Reasons, "; "), 255)
+		switch verdict {
+		case SpamVerdictApprove:
+			comment.Status = "approved"
+		case SpamVerdictReject:
+			// 保存为垃圾信息供管理员复核，误判的评论批准后会作为正常样本训练分类器
+			comment.Status = "spam"
+		}
+	}
+
+	// 渲染Markdown并解析@提及
+	html, mentioned, err := s.renderContent(ctx, content, userID)
+	if err != nil {
+		return nil, err
+	}
+	comment.ContentHTML = html
+
 	// 保存评论
-	err := s.commentRepo.Create(ctx, comment)
+	err = s.commentRepo.Create(ctx, comment)
 	if err != nil {
 		return nil, err
 	}
 
+	if verdict == SpamVerdictReject {
+		return nil, ErrCommentRejected
+	}
+	s.publish(ctx, event.CommentCreated{Comment: comment, Target: target})
+
+	if len(mentioned) > 0 {
+		if err := s.commentRepo.SyncMentions(ctx, comment.ID, mentioned); err != nil {
+			log.Printf("保存评论提及失败，评论ID=%d: %v", comment.ID, err)
+		} else if comment.Status == "approved" {
+			s.notifyMentions(ctx, comment)
+		}
+	}
 	return comment, nil
 }
 
-// UpdateComment 更新评论
+// renderContent 将评论内容渲染为HTML，返回被提及的用户ID
+// 只有存在的用户会生成个人主页链接，作者提及自己时不通知
+func (s *CommentServiceImpl) renderContent(ctx context.Context, content string, authorID *uint) (string, []uint, error) {
+	usernames := utils.ExtractMentions(content)
+	if len(usernames) > maxCommentMentions {
+		usernames = usernames[:maxCommentMentions]
+	}
+
+	var users []domain.User
+	if len(usernames) > 0 {
+		var err error
+		users, err = s.userRepo.FindByUsernames(ctx, usernames)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	links := make(map[string]string, len(users))
+	var userIDs []uint
+	for _, user := range users {
+		links[strings.ToLower(user.Username)] = strings.ReplaceAll(s.cfg.MentionURL, "{username}", url.PathEscape(user.Username))
+		if authorID == nil || *authorID != user.ID {
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+
+	html, err := utils.RenderCommentMarkdown(content, links)
+	if err != nil {
+		return "", nil, err
+	}
+	return html, userIDs, nil
+}
+
+// publish 发布领域事件，未配置事件总线时忽略
+func (s *CommentServiceImpl) publish(ctx context.Context, e event.Event) {
+	if s.events != nil {
+		s.events.Publish(ctx, e)
+	}
+}
+
+// notifyMentions 评论通过审核后发布提及事件，已通知过的用户不再重复通知
+func (s *CommentServiceImpl) notifyMentions(ctx context.Context, comment *domain.Comment) {
+	if s.events == nil {
+		return
+	}
+	userIDs, err := s.commentRepo.ClaimMentions(ctx, comment.ID)
+	if err != nil {
+		log.Printf("获取评论提及失败，评论ID=%d: %v", comment.ID, err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+	s.events.Publish(ctx, event.CommentMentioned{Comment: comment, UserIDs: userIDs})
+}
+
+// IssueFormToken 签发评论表单令牌，未启用反垃圾检查时返回空字符串
+func (s *CommentServiceImpl) IssueFormToken() string {
+	if s.spamFilter == nil {
+		return ""
+	}
+	return s.spamFilter.IssueToken()
+}
+
+// trainSpamFilter 使用管理员审核结果训练反垃圾分类器，训练失败不影响审核操作
+func (s *CommentServiceImpl) trainSpamFilter(ctx context.Context, comment *domain.Comment, class string) {
+	if s.spamFilter == nil {
+		return
+	}
+	if err := s.spamFilter.Train(ctx, comment, class); err != nil {
+		log.Printf("训练反垃圾分类器失败，评论ID=%d: %v", comment.ID, err)
+	}
+}
+
+// UpdateComment 更新评论，修改前的内容保存为历史版本
+// 作者只能在发表后的修改时限内修改，管理员不受限制；普通用户修改已批准的评论后需要重新审核
 func (s *CommentServiceImpl) UpdateComment(
 	ctx context.Context,
 	id uint,
@@ -235,95 +622,386 @@ func (s *CommentServiceImpl) UpdateComment(
 	if err != nil {
 		return nil, err
 	}
-	if comment == nil {
-		return nil, errors.New("评论不存在")
+	if comment == nil || comment.Status == "deleted" {
+		return nil, ErrCommentNotFound
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
 	}
 
 	// 检查用户权限
-	if comment.UserID == nil || *comment.UserID != userID {
-		// 获取用户信息，检查是否为管理员
-		user, err := s.userRepo.FindByID(ctx, userID)
-		if err != nil {
-			return nil, err
-		}
-		if user == nil || user.Role != "admin" {
-			return nil, errors.New("无权修改此评论")
-		}
+	isAdmin := user.Role == "admin"
+	isAuthor := comment.UserID != nil && *comment.UserID == userID
+	if !isAdmin && !isAuthor {
+		return nil, ErrCommentEditForbidden
+	}
+	if !isAdmin && s.cfg.EditWindow > 0 && time.Since(comment.CreatedAt) > s.cfg.EditWindow {
+		return nil, ErrCommentEditExpired
 	}
 
-	// 更新评论内容
-	comment.Content = content
+	if comment.Content == content {
+		return comment, nil
+	}
+
+	// 普通用户修改后的内容未经审核，重新进入审核队列
+	status := ""
+	if comment.Status == "approved" && !isTrustedCommenter(user) {
+		status = "pending"
+	}
 
-	// 保存更新
-	err = s.commentRepo.Update(ctx, comment)
+	// 由管理员修改时同样不通知评论作者本人
+	html, mentioned, err := s.renderContent(ctx, content, comment.UserID)
 	if err != nil {
 		return nil, err
 	}
 
+	author := comment.User
+	comment, err = s.commentRepo.Edit(ctx, id, content, html, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	comment.User = author
+
+	if err := s.commentRepo.SyncMentions(ctx, comment.ID, mentioned); err != nil {
+		log.Printf("保存评论提及失败，评论ID=%d: %v", comment.ID, err)
+	} else if comment.Status == "approved" {
+		s.notifyMentions(ctx, comment)
+	}
 	return comment, nil
 }
 
-// DeleteComment 删除评论
-func (s *CommentServiceImpl) DeleteComment(ctx context.Context, id uint, userID uint, isAdmin bool) error {
-	// 获取评论
+// GetCommentRevisions 获取评论的修改记录，只有作者和管理员可以查看
+func (s *CommentServiceImpl) GetCommentRevisions(ctx context.Context, id uint, userID uint, isAdmin bool) ([]domain.CommentRevision, error) {
 	comment, err := s.commentRepo.FindByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if comment == nil {
-		return errors.New("评论不存在")
+		return nil, ErrCommentNotFound
 	}
-
-	// 检查权限
 	if !isAdmin && (comment.UserID == nil || *comment.UserID != userID) {
-		return errors.New("无权删除此评论")
+		return nil, ErrCommentEditForbidden
 	}
+	return s.commentRepo.FindRevisions(ctx, id)
+}
 
-	// 删除评论
-	return s.commentRepo.Delete(ctx, id)
+// isTrustedCommenter 判断用户的评论是否无需审核
+func isTrustedCommenter(user *domain.User) bool {
+	return user.Role == "admin" || user.Role == "editor"
 }
 
-// LikeComment 点赞评论
-func (s *CommentServiceImpl) LikeComment(ctx context.Context, id uint) error {
-	// 检查评论是否存在
+// DeleteComment 删除评论
+// 作者删除自己的评论时其他用户的回复保留在原位；管理员删除他人的评论时连同回复一起删除并记录审核日志
+func (s *CommentServiceImpl) DeleteComment(ctx context.Context, id uint, userID uint, isAdmin bool) error {
+	// 获取评论
 	comment, err := s.commentRepo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if comment == nil {
-		return errors.New("评论不存在")
+		return ErrCommentNotFound
 	}
 
-	// 增加点赞数
-	return s.commentRepo.IncrementLikes(ctx, id)
+	// 检查权限
+	isAuthor := comment.UserID != nil && *comment.UserID == userID
+	if !isAdmin && !isAuthor {
+		return errors.New("无权删除此评论")
+	}
+
+	if isAuthor {
+		return s.commentRepo.Delete(ctx, id)
+	}
+	_, err = s.moderate(ctx, comment, domain.ModerationActionDelete, userID)
+	return err
 }
 
 // ApproveComment 批准评论
-func (s *CommentServiceImpl) ApproveComment(ctx context.Context, id uint) error {
+func (s *CommentServiceImpl) ApproveComment(ctx context.Context, id uint, moderatorID uint) error {
 	// 检查评论是否存在
 	comment, err := s.commentRepo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if comment == nil {
-		return errors.New("评论不存在")
+		return ErrCommentNotFound
 	}
 
 	// 更新评论状态
-	return s.commentRepo.UpdateStatus(ctx, id, "approved")
+	_, err = s.moderate(ctx, comment, domain.ModerationActionApprove, moderatorID)
+	return err
 }
 
 // MarkCommentAsSpam 标记评论为垃圾信息
-func (s *CommentServiceImpl) MarkCommentAsSpam(ctx context.Context, id uint) error {
+func (s *CommentServiceImpl) MarkCommentAsSpam(ctx context.Context, id uint, moderatorID uint) error {
 	// 检查评论是否存在
 	comment, err := s.commentRepo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if comment == nil {
-		return errors.New("评论不存在")
+		return ErrCommentNotFound
 	}
 
 	// 更新评论状态
-	return s.commentRepo.UpdateStatus(ctx, id, "spam")
-} 
\ No newline at end of file
+	_, err = s.moderate(ctx, comment, domain.ModerationActionSpam, moderatorID)
+	return err
+}
+
+// GetModerationQueue 获取审核队列，附带评论所在内容、父评论、作者历史和垃圾信息分数
+func (s *CommentServiceImpl) GetModerationQueue(
+	ctx context.Context,
+	status, itemType string,
+	itemID uint,
+	minScore *float64,
+	page, limit int,
+) ([]domain.ModerationItem, domain.PaginationData, error) {
+	filter := repository.ModerationFilter{
+		Page:     page,
+		Limit:    limit,
+		Status:   status,
+		ItemType: itemType,
+		ItemID:   itemID,
+		MinScore: minScore,
+	}
+
+	comments, total, err := s.commentRepo.FindForModeration(ctx, filter)
+	if err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	items, err := s.buildModerationItems(ctx, comments)
+	if err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	pagination := domain.PaginationData{
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (int(total) + limit - 1) / limit,
+	}
+	return items, pagination, nil
+}
+
+// buildModerationItems 批量加载审核队列中评论的上下文
+func (s *CommentServiceImpl) buildModerationItems(ctx context.Context, comments []domain.Comment) ([]domain.ModerationItem, error) {
+	// 收集需要批量查询的内容、父评论和作者
+	itemIDs := make(map[string][]uint)
+	var parentIDs, userIDs []uint
+	var emails []string
+	for _, c := range comments {
+		itemIDs[c.ItemType] = append(itemIDs[c.ItemType], c.ItemID)
+		if c.ParentID != nil {
+			parentIDs = append(parentIDs, *c.ParentID)
+		}
+		if c.UserID != nil {
+			userIDs = append(userIDs, *c.UserID)
+		} else if c.AnonymousEmail != "" {
+			emails = append(emails, c.AnonymousEmail)
+		}
+	}
+
+	targets := make(map[string]map[uint]domain.CommentTarget, len(itemIDs))
+	for itemType, ids := range itemIDs {
+		found, err := s.commentRepo.FindTargets(ctx, itemType, ids)
+		if err != nil {
+			return nil, err
+		}
+		targets[itemType] = found
+	}
+
+	parentList, err := s.commentRepo.FindByIDs(ctx, parentIDs)
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]*domain.Comment, len(parentList))
+	for i := range parentList {
+		parents[parentList[i].ID] = &parentList[i]
+	}
+
+	byUser, byEmail, err := s.commentRepo.CountAuthorHistory(ctx, userIDs, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[uint]*domain.User)
+	loadUser := func(c *domain.Comment) {
+		if c.UserID == nil {
+			return
+		}
+		user, ok := users[*c.UserID]
+		if !ok {
+			if u, err := s.userRepo.FindByID(ctx, *c.UserID); err == nil {
+				user = u
+			}
+			users[*c.UserID] = user
+		}
+		if user != nil {
+			c.User = user
+		}
+	}
+
+	items := make([]domain.ModerationItem, len(comments))
+	for i := range comments {
+		c := &comments[i]
+		loadUser(c)
+
+		item := domain.ModerationItem{
+			Comment:        c.ToResponse(),
+			AnonymousEmail: c.AnonymousEmail,
+			SpamScore:      c.SpamScore,
+			SpamReasons:    c.SpamReasons,
+		}
+		if target, ok := targets[c.ItemType][c.ItemID]; ok {
+			item.Target = &target
+		}
+		if c.ParentID != nil {
+			if parent, ok := parents[*c.ParentID]; ok {
+				loadUser(parent)
+				simple := parent.ToSimpleResponse()
+				item.Parent = &simple
+			}
+		}
+		if c.UserID != nil {
+			item.AuthorHistory = byUser[*c.UserID]
+		} else {
+			item.AuthorHistory = byEmail[c.AnonymousEmail]
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// BulkModerate 批量审核评论
+// 指定ids时处理这些评论，否则处理filter筛选出的评论，每次最多处理maxBulkModeration条
+func (s *CommentServiceImpl) BulkModerate(
+	ctx context.Context,
+	action string,
+	ids []uint,
+	filter *ModerationCriteria,
+	moderatorID uint,
+) (*domain.ModerationResult, error) {
+	switch action {
+	case domain.ModerationActionApprove, domain.ModerationActionSpam, domain.ModerationActionDelete:
+	default:
+		return nil, ErrInvalidModerationAction
+	}
+
+	var comments []domain.Comment
+	var err error
+	switch {
+	case len(ids) > 0:
+		if len(ids) > maxBulkModeration {
+			return nil, fmt.Errorf("每次最多处理%d条评论", maxBulkModeration)
+		}
+		comments, err = s.commentRepo.FindByIDs(ctx, ids)
+	case filter != nil:
+		comments, _, err = s.commentRepo.FindForModeration(ctx, repository.ModerationFilter{
+			Page:     1,
+			Limit:    maxBulkModeration,
+			Status:   filter.Status,
+			ItemType: filter.ItemType,
+			ItemID:   filter.ItemID,
+			MinScore: filter.MinScore,
+		})
+	default:
+		return nil, errors.New("需要指定评论ID或筛选条件")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.ModerationResult{}
+	found := make(map[uint]bool, len(comments))
+	for i := range comments {
+		found[comments[i].ID] = true
+		entry, err := s.moderate(ctx, &comments[i], action, moderatorID)
+		switch {
+		case err != nil:
+			log.Printf("批量审核评论失败，评论ID=%d: %v", comments[i].ID, err)
+			result.Failed = append(result.Failed, comments[i].ID)
+		case entry == nil:
+			result.Unchanged++
+		default:
+			result.Processed++
+		}
+	}
+	// 不存在的评论视为处理失败
+	for _, id := range ids {
+		if !found[id] {
+			result.Failed = append(result.Failed, id)
+		}
+	}
+	return result, nil
+}
+
+// GetModerationLogs 分页获取审核日志
+func (s *CommentServiceImpl) GetModerationLogs(
+	ctx context.Context,
+	commentID, moderatorID *uint,
+	page, limit int,
+) ([]domain.ModerationLog, domain.PaginationData, error) {
+	filter := repository.ModerationLogFilter{
+		Page:        page,
+		Limit:       limit,
+		CommentID:   commentID,
+		ModeratorID: moderatorID,
+	}
+
+	logs, total, err := s.commentRepo.FindModerationLogs(ctx, filter)
+	if err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	// 加载审核人用户名
+	names := make(map[uint]string)
+	for i := range logs {
+		name, ok := names[logs[i].ModeratorID]
+		if !ok {
+			if user, err := s.userRepo.FindByID(ctx, logs[i].ModeratorID); err == nil && user != nil {
+				name = user.Username
+			}
+			names[logs[i].ModeratorID] = name
+		}
+		logs[i].ModeratorName = name
+	}
+
+	pagination := domain.PaginationData{
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (int(total) + limit - 1) / limit,
+	}
+	return logs, pagination, nil
+}
+
+// moderate 执行审核操作，批准和标记垃圾信息的结果同时用于训练反垃圾分类器
+// 评论状态没有变化时返回nil
+func (s *CommentServiceImpl) moderate(ctx context.Context, comment *domain.Comment, action string, moderatorID uint) (*domain.ModerationLog, error) {
+	entry, err := s.commentRepo.Moderate(ctx, comment.ID, action, moderatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch action {
+	case domain.ModerationActionApprove:
+		s.trainSpamFilter(ctx, comment, domain.SpamClassHam)
+		if entry != nil {
+			comment.Status = entry.ToStatus
+			s.publish(ctx, event.CommentApproved{Comment: comment, FromStatus: entry.FromStatus, ModeratorID: moderatorID})
+			s.notifyMentions(ctx, comment)
+		}
+	case domain.ModerationActionSpam:
+		s.trainSpamFilter(ctx, comment, domain.SpamClassSpam)
+		if entry != nil {
+			comment.Status = entry.ToStatus
+			s.publish(ctx, event.CommentRejected{Comment: comment, FromStatus: entry.FromStatus, ModeratorID: moderatorID})
+		}
+	}
+	return entry, nil
+}
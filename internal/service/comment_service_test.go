@@ -1,7 +1,9 @@
 package service
 
 import (
+	"Lin_studio/internal/config"
 	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
 	"Lin_studio/internal/repository"
 	"context"
 	"testing"
@@ -9,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockCommentRepository 模拟评论仓储
@@ -47,11 +50,85 @@ func (m *MockCommentRepository) CountReplies(ctx context.Context, parentID uint)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockCommentRepository) FindDescendants(ctx context.Context, paths []string, maxDepth, perParent int) ([]domain.Comment, error) {
+	args := m.Called(ctx, paths, maxDepth, perParent)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepository) CountRepliesByParent(ctx context.Context, parentIDs []uint) (map[uint]int64, error) {
+	args := m.Called(ctx, parentIDs)
+	return args.Get(0).(map[uint]int64), args.Error(1)
+}
+
+func (m *MockCommentRepository) FindTarget(ctx context.Context, itemType string, itemID uint) (*domain.CommentTarget, error) {
+	args := m.Called(ctx, itemType, itemID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.CommentTarget), args.Error(1)
+}
+
+func (m *MockCommentRepository) FindTargets(ctx context.Context, itemType string, itemIDs []uint) (map[uint]domain.CommentTarget, error) {
+	args := m.Called(ctx, itemType, itemIDs)
+	return args.Get(0).(map[uint]domain.CommentTarget), args.Error(1)
+}
+
+func (m *MockCommentRepository) FindByIDs(ctx context.Context, ids []uint) ([]domain.Comment, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]domain.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepository) FindForModeration(ctx context.Context, filter repository.ModerationFilter) ([]domain.Comment, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.Comment), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockCommentRepository) CountAuthorHistory(ctx context.Context, userIDs []uint, emails []string) (map[uint]domain.CommentAuthorStats, map[string]domain.CommentAuthorStats, error) {
+	args := m.Called(ctx, userIDs, emails)
+	return args.Get(0).(map[uint]domain.CommentAuthorStats), args.Get(1).(map[string]domain.CommentAuthorStats), args.Error(2)
+}
+
+func (m *MockCommentRepository) Moderate(ctx context.Context, id uint, action string, moderatorID uint) (*domain.ModerationLog, error) {
+	args := m.Called(ctx, id, action, moderatorID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ModerationLog), args.Error(1)
+}
+
+func (m *MockCommentRepository) FindModerationLogs(ctx context.Context, filter repository.ModerationLogFilter) ([]domain.ModerationLog, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.ModerationLog), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockCommentRepository) Update(ctx context.Context, comment *domain.Comment) error {
 	args := m.Called(ctx, comment)
 	return args.Error(0)
 }
 
+func (m *MockCommentRepository) Edit(ctx context.Context, id uint, content, contentHTML string, editorID uint, status string) (*domain.Comment, error) {
+	args := m.Called(ctx, id, content, contentHTML, editorID, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepository) FindRevisions(ctx context.Context, commentID uint) ([]domain.CommentRevision, error) {
+	args := m.Called(ctx, commentID)
+	return args.Get(0).([]domain.CommentRevision), args.Error(1)
+}
+
+func (m *MockCommentRepository) SyncMentions(ctx context.Context, commentID uint, userIDs []uint) error {
+	args := m.Called(ctx, commentID, userIDs)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepository) ClaimMentions(ctx context.Context, commentID uint) ([]uint, error) {
+	args := m.Called(ctx, commentID)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
 func (m *MockCommentRepository) UpdateStatus(ctx context.Context, id uint, status string) error {
 	args := m.Called(ctx, id, status)
 	return args.Error(0)
@@ -62,9 +139,13 @@ func (m *MockCommentRepository) Delete(ctx context.Context, id uint) error {
 	return args.Error(0)
 }
 
-func (m *MockCommentRepository) IncrementLikes(ctx context.Context, id uint) error {
-	args := m.Called(ctx, id)
-	return args.Error(0)
+// testCommentConfig 测试使用的评论配置
+var testCommentConfig = config.CommentConfig{
+	MaxDepth:       2,
+	TreeDepth:      3,
+	RepliesPerNode: 2,
+	EditWindow:     15 * time.Minute,
+	MentionURL:     "/users/{username}",
 }
 
 // MockUserRepository 模拟用户仓储
@@ -80,7 +161,59 @@ func (m *MockUserRepository) FindByID(ctx context.Context, id uint) (*domain.Use
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
-// 更多方法实现...
+func (m *MockUserRepository) GetByID(id uint) (*domain.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByUsername(username string) (*domain.User, error) {
+	args := m.Called(username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindByUsernames(ctx context.Context, usernames []string) ([]domain.User, error) {
+	args := m.Called(ctx, usernames)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindByRole(ctx context.Context, role string) ([]domain.User, error) {
+	args := m.Called(ctx, role)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByEmail(email string) (*domain.User, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Create(user *domain.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Update(user *domain.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateLastLogin(user *domain.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Delete(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
 
 // TestCreateComment 测试创建评论
 func TestCreateComment(t *testing.T) {
@@ -96,10 +229,16 @@ func TestCreateComment(t *testing.T) {
 		Role:     "user",
 	}, nil)
 
+	mockCommentRepo.On("FindTarget", mock.Anything, "article", uint(1)).Return(&domain.CommentTarget{
+		ItemType: "article",
+		ItemID:   1,
+		Status:   "published",
+		Open:     true,
+	}, nil)
 	mockCommentRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Comment")).Return(nil)
 
 	// 创建服务实例
-	commentService := NewCommentService(mockCommentRepo, mockUserRepo)
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
 
 	// 测试创建评论
 	ctx := context.Background()
@@ -111,7 +250,9 @@ func TestCreateComment(t *testing.T) {
 		&userID,
 		"",
 		"",
+		false,
 		nil,
+		CommentClient{},
 	)
 
 	// 断言
@@ -142,10 +283,16 @@ func TestCreateCommentByAdmin(t *testing.T) {
 		Role:     "admin",
 	}, nil)
 
+	mockCommentRepo.On("FindTarget", mock.Anything, "article", uint(1)).Return(&domain.CommentTarget{
+		ItemType: "article",
+		ItemID:   1,
+		Status:   "published",
+		Open:     true,
+	}, nil)
 	mockCommentRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Comment")).Return(nil)
 
 	// 创建服务实例
-	commentService := NewCommentService(mockCommentRepo, mockUserRepo)
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
 
 	// 测试创建评论
 	ctx := context.Background()
@@ -157,7 +304,9 @@ func TestCreateCommentByAdmin(t *testing.T) {
 		&userID,
 		"",
 		"",
+		false,
 		nil,
+		CommentClient{},
 	)
 
 	// 断言
@@ -203,7 +352,7 @@ func TestGetComments(t *testing.T) {
 	mockCommentRepo.On("CountReplies", mock.Anything, uint(2)).Return(int64(0), nil)
 
 	// 创建服务实例
-	commentService := NewCommentService(mockCommentRepo, mockUserRepo)
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
 
 	// 测试获取评论
 	ctx := context.Background()
@@ -219,4 +368,308 @@ func TestGetComments(t *testing.T) {
 	mockCommentRepo.AssertExpectations(t)
 }
 
-// 更多测试用例... 
\ No newline at end of file
+// TestCreateCommentTooDeep 测试回复层级超过限制时拒绝创建
+func TestCreateCommentTooDeep(t *testing.T) {
+	mockCommentRepo := new(MockCommentRepository)
+	mockUserRepo := new(MockUserRepository)
+
+	parentID := uint(3)
+	mockCommentRepo.On("FindTarget", mock.Anything, "article", uint(1)).Return(&domain.CommentTarget{
+		ItemType: "article",
+		ItemID:   1,
+		Status:   "published",
+		Open:     true,
+	}, nil)
+	mockCommentRepo.On("FindByID", mock.Anything, parentID).Return(&domain.Comment{
+		ID:       parentID,
+		ItemType: "article",
+		ItemID:   1,
+		Path:     "0000000001/0000000002/0000000003/",
+		Depth:    2,
+		Status:   "approved",
+	}, nil)
+
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
+
+	comment, err := commentService.CreateComment(
+		context.Background(),
+		"第四层回复",
+		"article",
+		uint(1),
+		nil,
+		"访客",
+		"guest@example.com",
+		false,
+		&parentID,
+		CommentClient{},
+	)
+
+	assert.ErrorIs(t, err, ErrCommentTooDeep)
+	assert.Nil(t, comment)
+	mockCommentRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// TestCreateCommentInvalidTarget 测试评论不可评论的内容或其他内容下的父评论
+func TestCreateCommentInvalidTarget(t *testing.T) {
+	mockCommentRepo := new(MockCommentRepository)
+	mockUserRepo := new(MockUserRepository)
+
+	mockCommentRepo.On("FindTarget", mock.Anything, "article", uint(1)).Return(&domain.CommentTarget{
+		ItemType: "article",
+		ItemID:   1,
+		Status:   "draft",
+		Open:     false,
+	}, nil)
+	mockCommentRepo.On("FindTarget", mock.Anything, "article", uint(2)).Return(nil, nil)
+	mockCommentRepo.On("FindTarget", mock.Anything, "article", uint(3)).Return(&domain.CommentTarget{
+		ItemType: "article",
+		ItemID:   3,
+		Status:   "published",
+		Open:     true,
+	}, nil)
+	parentID := uint(10)
+	mockCommentRepo.On("FindByID", mock.Anything, parentID).Return(&domain.Comment{
+		ID:       parentID,
+		ItemType: "project",
+		ItemID:   3,
+		Status:   "approved",
+	}, nil)
+
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
+	ctx := context.Background()
+
+	// 草稿文章不允许评论
+	_, err := commentService.CreateComment(ctx, "评论", "article", 1, nil, "访客", "guest@example.com", false, nil, CommentClient{})
+	assert.ErrorIs(t, err, ErrCommentTargetClosed)
+
+	// 文章不存在
+	_, err = commentService.CreateComment(ctx, "评论", "article", 2, nil, "访客", "guest@example.com", false, nil, CommentClient{})
+	assert.ErrorIs(t, err, ErrCommentTargetNotFound)
+
+	// 父评论属于其他内容
+	_, err = commentService.CreateComment(ctx, "回复", "article", 3, nil, "访客", "guest@example.com", false, &parentID, CommentClient{})
+	assert.EqualError(t, err, "父评论不属于该内容")
+
+	mockCommentRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// TestBulkModerate 测试批量审核统计处理结果并训练反垃圾分类器
+func TestBulkModerate(t *testing.T) {
+	mockCommentRepo := new(MockCommentRepository)
+	mockUserRepo := new(MockUserRepository)
+	spamRepo := new(MockSpamRepository)
+
+	moderatorID := uint(9)
+	mockCommentRepo.On("FindByIDs", mock.Anything, []uint{1, 2, 3}).Return([]domain.Comment{
+		{ID: 1, Content: "spam one", Status: "pending"},
+		{ID: 2, Content: "spam two", Status: "spam"},
+	}, nil)
+	mockCommentRepo.On("Moderate", mock.Anything, uint(1), domain.ModerationActionSpam, moderatorID).
+		Return(&domain.ModerationLog{CommentID: 1, FromStatus: "pending", ToStatus: "spam"}, nil)
+	// 已经是垃圾信息，状态没有变化
+	mockCommentRepo.On("Moderate", mock.Anything, uint(2), domain.ModerationActionSpam, moderatorID).
+		Return(nil, nil)
+	spamRepo.On("Train", mock.Anything, mock.Anything, mock.Anything, domain.SpamClassSpam).Return(nil)
+
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, NewSpamFilter(spamRepo, testSpamConfig), nil, testCommentConfig)
+
+	result, err := commentService.BulkModerate(context.Background(), domain.ModerationActionSpam, []uint{1, 2, 3}, nil, moderatorID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Processed)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Equal(t, []uint{3}, result.Failed)
+	spamRepo.AssertCalled(t, "Train", mock.Anything, uint(1), []string{"spam", "one"}, domain.SpamClassSpam)
+
+	_, err = commentService.BulkModerate(context.Background(), "pin", []uint{1}, nil, moderatorID)
+	assert.ErrorIs(t, err, ErrInvalidModerationAction)
+}
+
+// TestGetCommentTree 测试按物化路径组装多层评论树
+func TestGetCommentTree(t *testing.T) {
+	mockCommentRepo := new(MockCommentRepository)
+	mockUserRepo := new(MockUserRepository)
+
+	id := func(v uint) *uint { return &v }
+	roots := []domain.Comment{
+		{ID: 1, Content: "顶级评论1", Path: "0000000001/", Depth: 0, Status: "approved"},
+		{ID: 2, Content: "顶级评论2", Path: "0000000002/", Depth: 0, Status: "approved"},
+	}
+	descendants := []domain.Comment{
+		{ID: 3, ParentID: id(1), Path: "0000000001/0000000003/", Depth: 1, Status: "approved"},
+		{ID: 4, ParentID: id(1), Path: "0000000001/0000000004/", Depth: 1, Status: "approved"},
+		{ID: 5, ParentID: id(3), Path: "0000000001/0000000003/0000000005/", Depth: 2, Status: "approved"},
+	}
+
+	mockCommentRepo.On("FindAll", mock.Anything, mock.AnythingOfType("repository.CommentFilter")).
+		Return(roots, int64(2), nil)
+	// 层级受MaxDepth限制，每条评论最多展开RepliesPerNode条回复
+	mockCommentRepo.On("FindDescendants", mock.Anything, []string{"0000000001/", "0000000002/"}, 2, 2).
+		Return(descendants, nil)
+	mockCommentRepo.On("CountRepliesByParent", mock.Anything, []uint{1, 2, 3, 4, 5}).
+		Return(map[uint]int64{1: 3, 3: 1}, nil)
+
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
+
+	comments, pagination, err := commentService.GetCommentTree(context.Background(), "article", 1, 1, 10, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), pagination.Total)
+	assert.Len(t, comments, 2)
+
+	// 评论1有3条回复，只展开了2条
+	assert.Equal(t, int64(3), comments[0].ReplyCount)
+	assert.Len(t, comments[0].Replies, 2)
+	assert.Equal(t, uint(3), comments[0].Replies[0].ID)
+	assert.Equal(t, uint(4), comments[0].Replies[1].ID)
+
+	// 第二层回复挂在对应的父评论下
+	assert.Len(t, comments[0].Replies[0].Replies, 1)
+	assert.Equal(t, uint(5), comments[0].Replies[0].Replies[0].ID)
+	assert.Empty(t, comments[1].Replies)
+
+	mockCommentRepo.AssertExpectations(t)
+}
+
+// TestDeleteCommentKeepsReplies 测试作者删除评论时保留其他用户的回复，管理员删除时连同回复一起删除
+func TestDeleteCommentKeepsReplies(t *testing.T) {
+	mockCommentRepo := new(MockCommentRepository)
+	mockUserRepo := new(MockUserRepository)
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
+	ctx := context.Background()
+
+	id := func(v uint) *uint { return &v }
+	comment := &domain.Comment{ID: 1, UserID: id(7), Content: "顶级评论", ItemType: "article", ItemID: 1, Path: "0000000001/", Status: "approved"}
+	mockCommentRepo.On("FindByID", mock.Anything, uint(1)).Return(comment, nil)
+
+	// 作者删除：只清空自己的评论，不执行审核删除
+	mockCommentRepo.On("Delete", mock.Anything, uint(1)).Return(nil).Once()
+	require.NoError(t, commentService.DeleteComment(ctx, 1, 7, false))
+	mockCommentRepo.AssertNotCalled(t, "Moderate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// 其他用户的回复仍挂在已删除的占位评论下，占位评论不显示内容和作者
+	tombstone := domain.Comment{ID: 1, UserID: id(7), ItemType: "article", ItemID: 1, Path: "0000000001/", Status: "deleted"}
+	reply := domain.Comment{ID: 2, UserID: id(8), ParentID: id(1), Content: "回复", Path: "0000000001/0000000002/", Depth: 1, Status: "approved"}
+	mockCommentRepo.On("FindAll", mock.Anything, mock.AnythingOfType("repository.CommentFilter")).
+		Return([]domain.Comment{tombstone}, int64(1), nil)
+	mockCommentRepo.On("FindDescendants", mock.Anything, []string{"0000000001/"}, 2, 2).
+		Return([]domain.Comment{reply}, nil)
+	mockCommentRepo.On("CountRepliesByParent", mock.Anything, []uint{1, 2}).
+		Return(map[uint]int64{1: 1}, nil)
+	mockUserRepo.On("FindByID", mock.Anything, uint(7)).Return(&domain.User{ID: 7, Username: "alice"}, nil)
+	mockUserRepo.On("FindByID", mock.Anything, uint(8)).Return(&domain.User{ID: 8, Username: "bob"}, nil)
+
+	comments, _, err := commentService.GetCommentTree(ctx, "article", 1, 1, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	response := comments[0].ToResponse()
+	assert.Equal(t, "deleted", response.Status)
+	assert.Empty(t, response.Content)
+	assert.Nil(t, response.Author)
+	require.Len(t, response.Replies, 1)
+	assert.Equal(t, "bob", response.Replies[0].Author.Username)
+
+	// 管理员删除他人的评论时通过审核操作删除整个子树
+	mockCommentRepo.On("Moderate", mock.Anything, uint(1), domain.ModerationActionDelete, uint(9)).
+		Return(&domain.ModerationLog{CommentID: 1, FromStatus: "approved", ToStatus: "deleted"}, nil).Once()
+	require.NoError(t, commentService.DeleteComment(ctx, 1, 9, true))
+	mockCommentRepo.AssertNumberOfCalls(t, "Delete", 1)
+	mockCommentRepo.AssertExpectations(t)
+}
+
+// 更多测试用例... 
+
+// TestUpdateCommentPolicy 测试评论修改时限和修改后重新审核
+func TestUpdateCommentPolicy(t *testing.T) {
+	mockCommentRepo := new(MockCommentRepository)
+	mockUserRepo := new(MockUserRepository)
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, nil, testCommentConfig)
+	ctx := context.Background()
+
+	authorID, otherID, adminID := uint(1), uint(2), uint(3)
+	mockUserRepo.On("FindByID", mock.Anything, authorID).Return(&domain.User{ID: authorID, Role: "user"}, nil)
+	mockUserRepo.On("FindByID", mock.Anything, otherID).Return(&domain.User{ID: otherID, Role: "user"}, nil)
+	mockUserRepo.On("FindByID", mock.Anything, adminID).Return(&domain.User{ID: adminID, Role: "admin"}, nil)
+
+	recent := &domain.Comment{ID: 1, Content: "原内容", UserID: &authorID, Status: "approved", CreatedAt: time.Now().Add(-time.Minute)}
+	expired := &domain.Comment{ID: 2, Content: "原内容", UserID: &authorID, Status: "approved", CreatedAt: time.Now().Add(-time.Hour)}
+	mockCommentRepo.On("FindByID", mock.Anything, uint(1)).Return(recent, nil)
+	mockCommentRepo.On("FindByID", mock.Anything, uint(2)).Return(expired, nil)
+
+	// 其他用户不能修改
+	_, err := commentService.UpdateComment(ctx, 1, "新内容", otherID)
+	assert.ErrorIs(t, err, ErrCommentEditForbidden)
+
+	// 超过修改时限后作者不能修改，管理员可以修改且不需要重新审核
+	_, err = commentService.UpdateComment(ctx, 2, "新内容", authorID)
+	assert.ErrorIs(t, err, ErrCommentEditExpired)
+
+	mockCommentRepo.On("SyncMentions", mock.Anything, mock.Anything, []uint(nil)).Return(nil)
+	mockCommentRepo.On("Edit", mock.Anything, uint(2), "新内容", mock.Anything, adminID, "").
+		Return(&domain.Comment{ID: 2, Content: "新内容", Status: "approved"}, nil)
+	comment, err := commentService.UpdateComment(ctx, 2, "新内容", adminID)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", comment.Status)
+
+	// 普通用户修改已批准的评论后重新审核
+	mockCommentRepo.On("Edit", mock.Anything, uint(1), "新内容", mock.Anything, authorID, "pending").
+		Return(&domain.Comment{ID: 1, Content: "新内容", Status: "pending"}, nil)
+	comment, err = commentService.UpdateComment(ctx, 1, "新内容", authorID)
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", comment.Status)
+
+	mockCommentRepo.AssertExpectations(t)
+}
+
+// TestCreateCommentMentions 测试评论中的@提及生成链接并通知被提及的用户
+func TestCreateCommentMentions(t *testing.T) {
+	mockCommentRepo := new(MockCommentRepository)
+	mockUserRepo := new(MockUserRepository)
+	bus := event.NewBus()
+	var published []event.CommentMentioned
+	bus.Subscribe(event.TopicCommentMentioned, func(ctx context.Context, e event.Event) error {
+		published = append(published, e.(event.CommentMentioned))
+		return nil
+	})
+
+	adminID := uint(1)
+	mockUserRepo.On("FindByID", mock.Anything, adminID).Return(&domain.User{ID: adminID, Username: "admin", Role: "admin"}, nil)
+	mockUserRepo.On("FindByUsernames", mock.Anything, []string{"Alice", "admin", "nobody"}).Return([]domain.User{
+		{ID: 5, Username: "alice"},
+		{ID: adminID, Username: "admin"},
+	}, nil)
+	mockCommentRepo.On("FindTarget", mock.Anything, "article", uint(1)).Return(&domain.CommentTarget{
+		ItemType: "article",
+		ItemID:   1,
+		Open:     true,
+	}, nil)
+	mockCommentRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Comment")).Return(nil)
+	mockCommentRepo.On("SyncMentions", mock.Anything, uint(1), []uint{5}).Return(nil)
+	mockCommentRepo.On("ClaimMentions", mock.Anything, uint(1)).Return([]uint{5}, nil)
+
+	commentService := NewCommentService(mockCommentRepo, mockUserRepo, nil, bus, testCommentConfig)
+	comment, err := commentService.CreateComment(
+		context.Background(),
+		"感谢 @Alice 和 @admin，@nobody 不存在，`@code` 不算提及",
+		"article",
+		1,
+		&adminID,
+		"",
+		"",
+		false,
+		nil,
+		CommentClient{},
+	)
+
+	assert.NoError(t, err)
+	assert.Contains(t, comment.ContentHTML, `<a href="/users/alice" class="mention">@Alice</a>`)
+	assert.Contains(t, comment.ContentHTML, "@nobody 不存在")
+	assert.Contains(t, comment.ContentHTML, "<code>@code</code>")
+	// 作者提及自己不通知
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, []uint{5}, published[0].UserIDs)
+	}
+
+	mockUserRepo.AssertExpectations(t)
+	mockCommentRepo.AssertExpectations(t)
+}
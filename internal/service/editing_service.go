@@ -0,0 +1,351 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrArticleLocked 文章的软锁由其他编辑者持有
+	ErrArticleLocked = errors.New("文章正在被其他编辑者编辑")
+	// ErrStaleEdit 编辑者打开文章后其他人已保存过修改
+	ErrStaleEdit = errors.New("文章已被其他编辑者修改，请先同步最新内容")
+)
+
+// 保存冲突类型，在仅提示模式下通过响应头返回
+const (
+	EditConflictLocked = "locked"
+	EditConflictStale  = "stale"
+)
+
+// 编辑者发送的指令
+const (
+	EditCommandLock   = "lock"   // 申请软锁
+	EditCommandUnlock = "unlock" // 释放软锁
+	EditCommandSync   = "sync"   // 已重新加载最新内容
+	EditCommandPing   = "ping"   // 心跳，持有软锁时同时续期
+)
+
+// editSessionBuffer 单个连接待发送消息的上限，超过时断开连接
+const editSessionBuffer = 16
+
+// EditCommand 编辑者通过WebSocket发送的指令
+type EditCommand struct {
+	Type string `json:"type"`
+}
+
+// Editor 正在编辑文章的用户，同一用户打开多个页面时每个页面是一个编辑者
+type Editor struct {
+	SessionID string    `json:"session_id"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	JoinedAt  time.Time `json:"joined_at"`
+	Stale     bool      `json:"stale"` // 打开文章后其他人已保存过修改
+}
+
+// EditLock 文章的软锁，持有者断开或超过有效期没有心跳后自动释放
+type EditLock struct {
+	SessionID string    `json:"session_id"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Since     time.Time `json:"since"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EditMessage 推送给编辑者的消息
+// type为 welcome(连接建立，包含自己的session_id)、presence(编辑者和软锁变化)、
+// saved(其他编辑者保存了文章)、lock_denied(软锁被他人持有) 或 pong
+type EditMessage struct {
+	Type      string    `json:"type"`
+	ArticleID uint      `json:"article_id"`
+	SessionID string    `json:"session_id,omitempty"`
+	Editors   []Editor  `json:"editors,omitempty"`
+	Lock      *EditLock `json:"lock,omitempty"`
+	SavedBy   *Editor   `json:"saved_by,omitempty"`
+}
+
+// EditSession 编辑者的连接
+// C在连接积压过多消息时被关闭，连接应随之断开
+type EditSession struct {
+	C         <-chan EditMessage
+	ch        chan EditMessage
+	editor    Editor
+	articleID uint
+	closed    bool
+}
+
+// ID 返回连接ID，保存文章时通过X-Edit-Session请求头带回
+func (s *EditSession) ID() string {
+	return s.editor.SessionID
+}
+
+// editRoom 同一篇文章的所有编辑者
+type editRoom struct {
+	sessions map[string]*EditSession
+	lock     *EditLock
+}
+
+// EditingService 文章协同编辑服务接口，记录谁在编辑哪篇文章，广播在线状态和软锁，并检查过期的保存
+type EditingService interface {
+	Join(ctx context.Context, articleID, userID uint, username string) (*EditSession, error)
+	Leave(session *EditSession)
+	Handle(session *EditSession, cmd EditCommand)
+	CheckSave(articleID, userID uint, sessionID string) (string, error)
+	Saved(articleID, userID uint, sessionID string)
+	IdleTimeout() time.Duration
+}
+
+// EditingServiceImpl 文章协同编辑服务实现，状态只保存在当前进程内存中
+type EditingServiceImpl struct {
+	articleRepo repository.ArticleRepository
+	cfg         config.EditingConfig
+	now         func() time.Time
+
+	mu    sync.Mutex
+	rooms map[uint]*editRoom
+}
+
+// NewEditingService 创建文章协同编辑服务实例
+func NewEditingService(articleRepo repository.ArticleRepository, cfg config.EditingConfig) EditingService {
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = 2 * time.Minute
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 90 * time.Second
+	}
+	return &EditingServiceImpl{
+		articleRepo: articleRepo,
+		cfg:         cfg,
+		now:         time.Now,
+		rooms:       make(map[uint]*editRoom),
+	}
+}
+
+// IdleTimeout 返回连接的空闲超时
+func (s *EditingServiceImpl) IdleTimeout() time.Duration {
+	return s.cfg.IdleTimeout
+}
+
+// Join 加入文章的编辑，向其他编辑者广播在线状态
+func (s *EditingServiceImpl) Join(ctx context.Context, articleID, userID uint, username string) (*EditSession, error) {
+	article, err := s.articleRepo.FindByID(ctx, articleID)
+	if err != nil {
+		return nil, err
+	}
+	if article == nil {
+		return nil, ErrArticleNotFound
+	}
+
+	ch := make(chan EditMessage, editSessionBuffer)
+	session := &EditSession{
+		C:  ch,
+		ch: ch,
+		editor: Editor{
+			SessionID: newEditSessionID(),
+			UserID:    userID,
+			Username:  username,
+			JoinedAt:  s.now(),
+		},
+		articleID: articleID,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[articleID]
+	if !ok {
+		room = &editRoom{sessions: make(map[string]*EditSession)}
+		s.rooms[articleID] = room
+	}
+	room.sessions[session.ID()] = session
+	s.send(session, EditMessage{Type: "welcome", ArticleID: articleID, SessionID: session.ID()})
+	s.broadcastPresence(articleID, room)
+	return session, nil
+}
+
+// Leave 离开文章的编辑，持有的软锁随之释放
+func (s *EditingServiceImpl) Leave(session *EditSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeSession(session)
+	room, ok := s.rooms[session.articleID]
+	if !ok {
+		return
+	}
+	delete(room.sessions, session.ID())
+	if room.lock != nil && room.lock.SessionID == session.ID() {
+		room.lock = nil
+	}
+	if len(room.sessions) == 0 {
+		delete(s.rooms, session.articleID)
+		return
+	}
+	s.broadcastPresence(session.articleID, room)
+}
+
+// Handle 处理编辑者发送的指令
+func (s *EditingServiceImpl) Handle(session *EditSession, cmd EditCommand) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[session.articleID]
+	if !ok || room.sessions[session.ID()] != session {
+		return
+	}
+	now := s.now()
+	lock := room.activeLock(now)
+
+	switch cmd.Type {
+	case EditCommandLock:
+		if lock != nil && lock.SessionID != session.ID() {
+			copied := *lock
+			s.send(session, EditMessage{Type: "lock_denied", ArticleID: session.articleID, Lock: &copied})
+			return
+		}
+		since := now
+		if lock != nil {
+			since = lock.Since
+		}
+		room.lock = &EditLock{
+			SessionID: session.ID(),
+			UserID:    session.editor.UserID,
+			Username:  session.editor.Username,
+			Since:     since,
+			ExpiresAt: now.Add(s.cfg.LockTTL),
+		}
+		s.broadcastPresence(session.articleID, room)
+	case EditCommandUnlock:
+		if lock != nil && lock.SessionID == session.ID() {
+			room.lock = nil
+			s.broadcastPresence(session.articleID, room)
+		}
+	case EditCommandSync:
+		if session.editor.Stale {
+			session.editor.Stale = false
+			s.broadcastPresence(session.articleID, room)
+		}
+	case EditCommandPing:
+		if lock != nil && lock.SessionID == session.ID() {
+			lock.ExpiresAt = now.Add(s.cfg.LockTTL)
+		}
+		s.send(session, EditMessage{Type: "pong", ArticleID: session.articleID})
+	}
+}
+
+// CheckSave 检查保存是否与其他编辑者冲突，返回冲突类型
+// 阻止模式下有冲突时返回ErrArticleLocked或ErrStaleEdit，仅提示模式下只返回冲突类型
+// 同一用户在其他页面持有的软锁不算冲突；sessionID为空的请求(例如脚本调用)只检查软锁
+func (s *EditingServiceImpl) CheckSave(articleID, userID uint, sessionID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[articleID]
+	if !ok {
+		return "", nil
+	}
+
+	conflict := ""
+	if lock := room.activeLock(s.now()); lock != nil && lock.UserID != userID {
+		conflict = EditConflictLocked
+	} else if session, ok := room.sessions[sessionID]; ok && session.editor.Stale {
+		conflict = EditConflictStale
+	}
+
+	if conflict == "" || !s.cfg.BlockConflicts {
+		return conflict, nil
+	}
+	if conflict == EditConflictLocked {
+		return conflict, ErrArticleLocked
+	}
+	return conflict, ErrStaleEdit
+}
+
+// Saved 文章保存成功后调用，其他编辑者的内容标记为过期并收到通知
+func (s *EditingServiceImpl) Saved(articleID, userID uint, sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	room, ok := s.rooms[articleID]
+	if !ok {
+		return
+	}
+
+	savedBy := &Editor{UserID: userID, SessionID: sessionID}
+	for id, session := range room.sessions {
+		if id == sessionID {
+			session.editor.Stale = false
+			savedBy.Username = session.editor.Username
+			continue
+		}
+		session.editor.Stale = true
+	}
+	for _, session := range room.sessions {
+		if session.ID() != sessionID {
+			s.send(session, EditMessage{Type: "saved", ArticleID: articleID, SavedBy: savedBy})
+		}
+	}
+	s.broadcastPresence(articleID, room)
+}
+
+// activeLock 返回未过期的软锁
+func (r *editRoom) activeLock(now time.Time) *EditLock {
+	if r.lock != nil && now.After(r.lock.ExpiresAt) {
+		r.lock = nil
+	}
+	return r.lock
+}
+
+// broadcastPresence 向文章的所有编辑者广播编辑者列表和软锁，调用方需持有锁
+func (s *EditingServiceImpl) broadcastPresence(articleID uint, room *editRoom) {
+	editors := make([]Editor, 0, len(room.sessions))
+	for _, session := range room.sessions {
+		editors = append(editors, session.editor)
+	}
+	sort.Slice(editors, func(i, j int) bool {
+		return editors[i].JoinedAt.Before(editors[j].JoinedAt)
+	})
+
+	var lock *EditLock
+	if active := room.activeLock(s.now()); active != nil {
+		copied := *active
+		lock = &copied
+	}
+	for _, session := range room.sessions {
+		s.send(session, EditMessage{Type: "presence", ArticleID: articleID, Editors: editors, Lock: lock})
+	}
+}
+
+// send 向连接发送消息，积压过多时关闭连接，调用方需持有锁
+func (s *EditingServiceImpl) send(session *EditSession, msg EditMessage) {
+	if session.closed {
+		return
+	}
+	select {
+	case session.ch <- msg:
+	default:
+		s.closeSession(session)
+	}
+}
+
+// closeSession 关闭连接的消息通道，调用方需持有锁
+func (s *EditingServiceImpl) closeSession(session *EditSession) {
+	if !session.closed {
+		session.closed = true
+		close(session.ch)
+	}
+}
+
+// newEditSessionID 生成随机的连接ID
+func newEditSessionID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// drainEditMessages 取出连接中已收到的全部消息
+func drainEditMessages(session *EditSession) []EditMessage {
+	var messages []EditMessage
+	for {
+		select {
+		case msg, ok := <-session.C:
+			if !ok {
+				return messages
+			}
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+// TestEditingLocksAndStaleSaves 测试软锁和过期保存检查
+func TestEditingLocksAndStaleSaves(t *testing.T) {
+	articleRepo := new(MockArticleRepository)
+	articleRepo.On("FindByID", mock.Anything, uint(1)).Return(&domain.Article{ID: 1}, nil)
+	articleRepo.On("FindByID", mock.Anything, uint(2)).Return(nil, nil)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewEditingService(articleRepo, config.EditingConfig{LockTTL: time.Minute, BlockConflicts: true}).(*EditingServiceImpl)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := svc.Join(ctx, 2, 1, "alice")
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+
+	alice, err := svc.Join(ctx, 1, 1, "alice")
+	require.NoError(t, err)
+	bob, err := svc.Join(ctx, 1, 2, "bob")
+	require.NoError(t, err)
+
+	messages := drainEditMessages(alice)
+	assert.Equal(t, "welcome", messages[0].Type)
+	assert.Equal(t, alice.ID(), messages[0].SessionID)
+	assert.Len(t, messages[len(messages)-1].Editors, 2)
+	drainEditMessages(bob)
+
+	// alice持有软锁时bob无法加锁，也不能保存
+	svc.Handle(alice, EditCommand{Type: EditCommandLock})
+	svc.Handle(bob, EditCommand{Type: EditCommandLock})
+	denied := drainEditMessages(bob)
+	assert.Equal(t, "lock_denied", denied[len(denied)-1].Type)
+	assert.Equal(t, "alice", denied[len(denied)-1].Lock.Username)
+
+	conflict, err := svc.CheckSave(1, 2, bob.ID())
+	assert.Equal(t, EditConflictLocked, conflict)
+	assert.ErrorIs(t, err, ErrArticleLocked)
+	_, err = svc.CheckSave(1, 1, alice.ID())
+	assert.NoError(t, err)
+
+	// alice保存后bob的内容过期，同步后才能保存
+	svc.Saved(1, 1, alice.ID())
+	saved := drainEditMessages(bob)
+	assert.Equal(t, "saved", saved[0].Type)
+	assert.Equal(t, "alice", saved[0].SavedBy.Username)
+
+	// 软锁超过有效期没有心跳后释放
+	now = now.Add(2 * time.Minute)
+	conflict, err = svc.CheckSave(1, 2, bob.ID())
+	assert.Equal(t, EditConflictStale, conflict)
+	assert.ErrorIs(t, err, ErrStaleEdit)
+
+	svc.Handle(bob, EditCommand{Type: EditCommandSync})
+	conflict, err = svc.CheckSave(1, 2, bob.ID())
+	assert.Empty(t, conflict)
+	assert.NoError(t, err)
+
+	// 仅提示模式下返回冲突类型但不阻止保存
+	svc.cfg.BlockConflicts = false
+	svc.Handle(bob, EditCommand{Type: EditCommandLock})
+	conflict, err = svc.CheckSave(1, 1, alice.ID())
+	assert.Equal(t, EditConflictLocked, conflict)
+	assert.NoError(t, err)
+
+	// 持有者离开后软锁释放，最后一个编辑者离开后清理文章状态
+	svc.Leave(bob)
+	drainEditMessages(bob)
+	_, ok := <-bob.C
+	assert.False(t, ok)
+	conflict, _ = svc.CheckSave(1, 1, alice.ID())
+	assert.Empty(t, conflict)
+	svc.Leave(alice)
+	assert.Empty(t, svc.rooms)
+}
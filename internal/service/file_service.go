@@ -0,0 +1,136 @@
+package service
+
+import (
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrFileForbidden 文件需要有效的签名URL才能访问
+var ErrFileForbidden = errors.New("文件链接已过期或签名无效")
+
+// ServedFile 待输出的上传文件
+type ServedFile struct {
+	Content     io.ReadSeekCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string
+	Immutable   bool          // 内容哈希命名的文件内容不会变化，可以长期缓存
+	Private     bool          // 通过签名URL访问的非公开文件，不允许共享缓存
+	MaxAge      time.Duration // 非公开文件的缓存时长，不超过签名剩余有效期
+}
+
+// FileService 上传文件访问服务接口
+type FileService interface {
+	Open(ctx context.Context, key, requestPath, expires, signature string) (*ServedFile, error)
+}
+
+// FileServiceImpl 上传文件访问服务实现
+type FileServiceImpl struct {
+	storage   storage.Storage
+	mediaRepo repository.MediaRepository
+	signer    *utils.URLSigner
+}
+
+// NewFileService 创建上传文件访问服务实例，signer为nil时不限制访问
+func NewFileService(storage storage.Storage, mediaRepo repository.MediaRepository, signer *utils.URLSigner) FileService {
+	return &FileServiceImpl{
+		storage:   storage,
+		mediaRepo: mediaRepo,
+		signer:    signer,
+	}
+}
+
+// Open 校验访问权限并打开文件
+// requestPath为请求路径，expires和signature为签名URL中的参数
+func (s *FileServiceImpl) Open(ctx context.Context, key, requestPath, expires, signature string) (*ServedFile, error) {
+	private, err := s.isPrivate(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if private && !s.signer.Verify(requestPath, expires, signature) {
+		return nil, ErrFileForbidden
+	}
+
+	obj, err := s.storage.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := obj.ContentType
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+
+	hash := mediaHashFromKey(key)
+	file := &ServedFile{
+		Content:     storage.NewReadSeeker(ctx, s.storage, key, obj.Size),
+		Size:        obj.Size,
+		ContentType: contentType,
+		ModTime:     obj.ModTime,
+		ETag:        fileETag(key, hash, obj),
+		Immutable:   hash != "",
+		Private:     private,
+	}
+	if private {
+		file.MaxAge = s.signer.ExpiresIn(expires)
+	}
+	return file, nil
+}
+
+// isPrivate 判断文件是否只被未发布的文章引用
+// 未登记到媒体库或尚未被引用的文件视为公开，保证刚上传的文件可以在编辑器中预览
+func (s *FileServiceImpl) isPrivate(ctx context.Context, key string) (bool, error) {
+	if s.signer == nil {
+		return false, nil
+	}
+
+	// 缩略图与原图共享内容哈希，按哈希找到原图的引用关系
+	var mediaID uint
+	if hash := mediaHashFromKey(key); hash != "" {
+		media, err := s.mediaRepo.FindByHash(ctx, hash)
+		if err != nil {
+			return false, err
+		}
+		if media == nil {
+			return false, nil
+		}
+		mediaID = media.ID
+	} else {
+		media, err := s.mediaRepo.FindByKeys(ctx, []string{key})
+		if err != nil {
+			return false, err
+		}
+		if len(media) == 0 {
+			return false, nil
+		}
+		mediaID = media[0].ID
+	}
+
+	public, private, err := s.mediaRepo.CountReferencesByVisibility(ctx, mediaID)
+	if err != nil {
+		return false, err
+	}
+	return private > 0 && public == 0, nil
+}
+
+// fileETag 生成强ETag
+// 内容哈希命名的文件直接使用文件名，其他文件由存储键、大小和修改时间计算
+func fileETag(key, hash string, obj *storage.Object) string {
+	if hash != "" {
+		return `"` + strings.TrimSuffix(path.Base(key), path.Ext(key)) + `"`
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", key, obj.Size, obj.ModTime.UnixNano())))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
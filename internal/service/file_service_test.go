@@ -0,0 +1,67 @@
+package service
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFileServiceRangeAndSignature 测试Range读取、强ETag以及草稿文件的签名校验
+func TestFileServiceRangeAndSignature(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir(), "/uploads")
+	hash := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	key := "media/01/" + hash + ".png"
+	content := "0123456789abcdefghij"
+	require.NoError(t, store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "image/png"))
+
+	mockRepo := new(MockMediaRepository)
+	mockRepo.On("FindByHash", mock.Anything, hash).Return(&domain.Media{ID: 3, Key: key}, nil)
+	// 只被草稿文章引用
+	mockRepo.On("CountReferencesByVisibility", mock.Anything, uint(3)).Return(int64(0), int64(1), nil)
+
+	signer := utils.NewURLSigner("secret", time.Hour)
+	fileService := NewFileService(store, mockRepo, signer)
+	path := "/uploads/" + key
+
+	// 没有签名时拒绝访问
+	_, err := fileService.Open(ctx, key, path, "", "")
+	assert.ErrorIs(t, err, ErrFileForbidden)
+
+	// 使用签名URL访问，并按Range读取
+	signed, err := url.Parse(signer.Sign(path))
+	require.NoError(t, err)
+	file, err := fileService.Open(ctx, key, signed.Path, signed.Query().Get("expires"), signed.Query().Get("signature"))
+	require.NoError(t, err)
+	defer file.Content.Close()
+
+	assert.True(t, file.Private)
+	assert.True(t, file.Immutable)
+	assert.Equal(t, `"`+hash+`"`, file.ETag)
+	assert.Greater(t, file.MaxAge, time.Duration(0))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Range", "bytes=5-9")
+	rec := httptest.NewRecorder()
+	rec.Header().Set("ETag", file.ETag)
+	http.ServeContent(rec, req, "", file.ModTime, file.Content)
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	body, _ := io.ReadAll(rec.Body)
+	assert.Equal(t, "56789", string(body))
+
+	// 签名被篡改
+	_, err = fileService.Open(ctx, key, path, signed.Query().Get("expires"), strings.Repeat("0", 64))
+	assert.ErrorIs(t, err, ErrFileForbidden)
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册GIF解码器，GIF原图取首帧生成缩略图
+	"image/jpeg"
+	"image/png"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+)
+
+// ImageProcessor 图片后台处理接口，负责生成缩略版本和WebP版本
+type ImageProcessor interface {
+	Enqueue(mediaID uint)
+	Process(ctx context.Context, mediaID uint) error
+	Start()
+	Stop()
+}
+
+// ImageProcessorImpl 图片后台处理实现
+type ImageProcessorImpl struct {
+	mediaRepo repository.MediaRepository
+	storage   storage.Storage
+	widths    []int
+	workers   int
+
+	queue  chan uint
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewImageProcessor 创建图片后台处理实例
+func NewImageProcessor(mediaRepo repository.MediaRepository, storage storage.Storage, cfg config.UploadConfig) ImageProcessor {
+	widths := make([]int, 0, len(cfg.VariantWidths))
+	for _, w := range cfg.VariantWidths {
+		if w > 0 {
+			widths = append(widths, w)
+		}
+	}
+	sort.Ints(widths)
+
+	workers := cfg.ImageWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ImageProcessorImpl{
+		mediaRepo: mediaRepo,
+		storage:   storage,
+		widths:    widths,
+		workers:   workers,
+		queue:     make(chan uint, 256),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Enqueue 将媒体文件加入处理队列
+// 队列已满或处理器已停止时直接返回，记录保持pending状态，下次启动时会重新处理
+func (p *ImageProcessorImpl) Enqueue(mediaID uint) {
+	select {
+	case <-p.ctx.Done():
+	case p.queue <- mediaID:
+	default:
+		log.Printf("图片处理队列已满，媒体文件 %d 将在下次启动时处理", mediaID)
+	}
+}
+
+// Start 启动后台处理协程，并重新入队上次未完成的任务
+func (p *ImageProcessorImpl) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		unfinished, err := p.mediaRepo.FindByStatus(p.ctx, "pending", "processing")
+		if err != nil {
+			log.Printf("加载未完成的图片处理任务失败: %v", err)
+			return
+		}
+		for _, media := range unfinished {
+			select {
+			case <-p.ctx.Done():
+				return
+			case p.queue <- media.ID:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台处理并等待正在执行的任务结束
+func (p *ImageProcessorImpl) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// worker 从队列中取出任务并处理
+func (p *ImageProcessorImpl) worker() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case id := <-p.queue:
+			if err := p.Process(p.ctx, id); err != nil {
+				log.Printf("处理媒体文件 %d 失败: %v", id, err)
+			}
+		}
+	}
+}
+
+// Process 为媒体文件生成各尺寸的缩略版本和WebP版本
+func (p *ImageProcessorImpl) Process(ctx context.Context, mediaID uint) error {
+	media, err := p.mediaRepo.FindByID(ctx, mediaID)
+	if err != nil {
+		return err
+	}
+	if media == nil || media.Status == "ready" {
+		return nil
+	}
+
+	if err := p.mediaRepo.UpdateStatus(ctx, media.ID, "processing", ""); err != nil {
+		return err
+	}
+
+	variants, err := p.generateVariants(ctx, media)
+	if err != nil {
+		// 进程退出导致的中断保留processing状态，下次启动时重试
+		if ctx.Err() != nil {
+			return err
+		}
+		message := err.Error()
+		if len(message) > 255 {
+			message = message[:255]
+		}
+		if updateErr := p.mediaRepo.UpdateStatus(ctx, media.ID, "failed", message); updateErr != nil {
+			log.Printf("更新媒体文件 %d 状态失败: %v", media.ID, updateErr)
+		}
+		return err
+	}
+
+	media.Variants = variants
+	media.Status = "ready"
+	media.Error = ""
+	return p.mediaRepo.Update(ctx, media)
+}
+
+// generateVariants 读取原图并生成全部变体
+func (p *ImageProcessorImpl) generateVariants(ctx context.Context, media *domain.Media) (domain.MediaVariants, error) {
+	obj, err := p.storage.Open(ctx, media.Key)
+	if err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("解码原图失败: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return nil, errors.New("图片尺寸无效")
+	}
+
+	// 只生成比原图小的尺寸，原图本身作为最大尺寸的候选
+	targets := make([]int, 0, len(p.widths)+1)
+	for _, w := range p.widths {
+		if w < width {
+			targets = append(targets, w)
+		}
+	}
+	targets = append(targets, width)
+
+	base := strings.TrimSuffix(media.Key, path.Ext(media.Key))
+	variants := make(domain.MediaVariants, 0, len(targets)*2)
+	for _, w := range targets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		h := height * w / width
+		if h < 1 {
+			h = 1
+		}
+		resized := src
+		if w != width {
+			dst := image.NewRGBA(image.Rect(0, 0, w, h))
+			draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+			resized = dst
+		}
+
+		// 原格式的缩略版本，原图尺寸无需重复保存
+		var nativeSize int64
+		if w != width {
+			data, contentType, ext, err := encodeNative(resized, media.ContentType)
+			if err != nil {
+				return nil, err
+			}
+			variant, err := p.putVariant(ctx, fmt.Sprintf("variants/%s_w%d%s", base, w, ext), data, contentType, w, h)
+			if err != nil {
+				return nil, err
+			}
+			variants = append(variants, variant)
+			nativeSize = variant.Size
+		} else {
+			nativeSize = media.Size
+		}
+
+		// WebP版本为无损编码，只有比同尺寸原格式更小时才保留
+		var buf bytes.Buffer
+		if err := nativewebp.Encode(&buf, resized, nil); err != nil {
+			return nil, fmt.Errorf("编码WebP失败: %w", err)
+		}
+		if int64(buf.Len()) < nativeSize {
+			variant, err := p.putVariant(ctx, fmt.Sprintf("variants/%s_w%d.webp", base, w), buf.Bytes(), "image/webp", w, h)
+			if err != nil {
+				return nil, err
+			}
+			variants = append(variants, variant)
+		}
+	}
+
+	return variants, nil
+}
+
+// putVariant 保存变体文件
+func (p *ImageProcessorImpl) putVariant(ctx context.Context, key string, data []byte, contentType string, width, height int) (domain.MediaVariant, error) {
+	if err := p.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return domain.MediaVariant{}, err
+	}
+	return domain.MediaVariant{
+		Key:         key,
+		URL:         p.storage.URL(key),
+		ContentType: contentType,
+		Width:       width,
+		Height:      height,
+		Size:        int64(len(data)),
+	}, nil
+}
+
+// encodeNative 按原图格式编码缩略版本，GIF缩略图只保留首帧并转为PNG
+func encodeNative(img image.Image, contentType string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/jpeg":
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/jpeg", ".jpg", nil
+	case "image/png", "image/gif":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/png", ".png", nil
+	default:
+		return nil, "", "", fmt.Errorf("不支持的图片格式: %s", contentType)
+	}
+}
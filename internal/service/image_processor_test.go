@@ -0,0 +1,204 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/storage"
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockMediaRepository 模拟媒体文件仓储
+type MockMediaRepository struct {
+	mock.Mock
+}
+
+func (m *MockMediaRepository) Create(ctx context.Context, media *domain.Media) error {
+	args := m.Called(ctx, media)
+	media.ID = 1 // 模拟数据库分配ID
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) FindByID(ctx context.Context, id uint) (*domain.Media, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByHash(ctx context.Context, hash string) (*domain.Media, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByURL(ctx context.Context, url string) (*domain.Media, error) {
+	args := m.Called(ctx, url)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByURLs(ctx context.Context, urls []string) ([]domain.Media, error) {
+	args := m.Called(ctx, urls)
+	return args.Get(0).([]domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByKeys(ctx context.Context, keys []string) ([]domain.Media, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).([]domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByHashes(ctx context.Context, hashes []string) ([]domain.Media, error) {
+	args := m.Called(ctx, hashes)
+	return args.Get(0).([]domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) FindByStatus(ctx context.Context, statuses ...string) ([]domain.Media, error) {
+	args := m.Called(ctx, statuses)
+	return args.Get(0).([]domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) FindAll(ctx context.Context, filter repository.MediaFilter) ([]domain.Media, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.Media), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockMediaRepository) FindUnreferenced(ctx context.Context, before time.Time) ([]domain.Media, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).([]domain.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) TouchUploaded(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) Update(ctx context.Context, media *domain.Media) error {
+	args := m.Called(ctx, media)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) UpdateStatus(ctx context.Context, id uint, status, message string) error {
+	args := m.Called(ctx, id, status, message)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) Delete(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) CountReferences(ctx context.Context, mediaIDs []uint) (map[uint]int64, error) {
+	args := m.Called(ctx, mediaIDs)
+	return args.Get(0).(map[uint]int64), args.Error(1)
+}
+
+func (m *MockMediaRepository) CountReferencesByVisibility(ctx context.Context, mediaID uint) (int64, int64, error) {
+	args := m.Called(ctx, mediaID)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockMediaRepository) ReplaceReferences(ctx context.Context, refType string, refID uint, mediaIDs []uint) error {
+	args := m.Called(ctx, refType, refID, mediaIDs)
+	return args.Error(0)
+}
+
+// TestProcessImageVariants 测试为原图生成各尺寸变体及响应式图片数据
+func TestProcessImageVariants(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir(), "/uploads")
+
+	// 纯色图片压缩率高，WebP版本一定会被保留
+	src := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			src.Set(x, y, color.RGBA{uint8(x / 8), 80, 160, 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+	require.NoError(t, store.Put(ctx, "covers/cover_1.png", bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/png"))
+
+	media := &domain.Media{
+		ID:          1,
+		Key:         "covers/cover_1.png",
+		URL:         store.URL("covers/cover_1.png"),
+		ContentType: "image/png",
+		Size:        int64(buf.Len()),
+		Width:       800,
+		Height:      400,
+		Status:      "pending",
+	}
+
+	mockRepo := new(MockMediaRepository)
+	mockRepo.On("FindByID", mock.Anything, uint(1)).Return(media, nil)
+	mockRepo.On("UpdateStatus", mock.Anything, uint(1), "processing", "").Return(nil)
+	mockRepo.On("Update", mock.Anything, media).Return(nil)
+
+	processor := NewImageProcessor(mockRepo, store, config.UploadConfig{
+		VariantWidths: []int{320, 640, 1280},
+	})
+	require.NoError(t, processor.Process(ctx, 1))
+	mockRepo.AssertExpectations(t)
+
+	assert.Equal(t, "ready", media.Status)
+
+	// 1280大于原图宽度，不应生成
+	widths := map[string][]int{}
+	for _, v := range media.Variants {
+		widths[v.ContentType] = append(widths[v.ContentType], v.Width)
+		exists, err := store.Exists(ctx, v.Key)
+		require.NoError(t, err)
+		assert.True(t, exists, v.Key)
+	}
+	assert.Equal(t, []int{320, 640}, widths["image/png"])
+	assert.Equal(t, []int{320, 640, 800}, widths["image/webp"])
+	assert.Equal(t, 160, media.Variants[0].Height)
+
+	set := media.ToImageSet()
+	require.NotNil(t, set)
+	assert.Equal(t, "/uploads/covers/cover_1.png", set.Src)
+	require.Len(t, set.Sources, 2)
+	assert.Equal(t, "image/webp", set.Sources[0].Type)
+	assert.Equal(t, "/uploads/variants/covers/cover_1_w320.webp 320w, /uploads/variants/covers/cover_1_w640.webp 640w, /uploads/variants/covers/cover_1_w800.webp 800w", set.Sources[0].Srcset)
+	assert.Equal(t, "image/png", set.Sources[1].Type)
+	assert.Contains(t, set.Sources[1].Srcset, "/uploads/covers/cover_1.png 800w")
+}
+
+// TestSyncArticleMediaReferences 测试从封面和正文中解析出媒体文件引用
+func TestSyncArticleMediaReferences(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir(), "/uploads")
+	hash := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	article := &domain.Article{
+		ID:         7,
+		CoverImage: "/uploads/covers/cover_1.png",
+		Content: "正文 ![示意图](/uploads/variants/media/01/" + hash + "_w640.webp \"标题\")\n" +
+			"<img src='https://cdn.example.com/other.png'>",
+	}
+
+	mockRepo := new(MockMediaRepository)
+	mockRepo.On("FindByHashes", mock.Anything, []string{hash}).Return([]domain.Media{{ID: 2}}, nil)
+	mockRepo.On("FindByKeys", mock.Anything, []string{"covers/cover_1.png"}).Return([]domain.Media{{ID: 1}}, nil)
+	mockRepo.On("ReplaceReferences", mock.Anything, domain.MediaRefArticle, uint(7), []uint{2, 1}).Return(nil)
+
+	mediaService := NewMediaService(mockRepo, store, nil, nil, nil)
+	require.NoError(t, mediaService.SyncReferences(ctx, domain.MediaRefArticle, article.ID, ArticleMediaURLs(article)...))
+	mockRepo.AssertExpectations(t)
+}
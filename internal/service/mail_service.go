@@ -0,0 +1,510 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/mailer"
+	"Lin_studio/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidUnsubscribeToken 退订链接无效
+	ErrInvalidUnsubscribeToken = errors.New("退订链接无效或已损坏")
+	// ErrUnsubscribeDisabled 未配置退订签名密钥，无法校验退订链接
+	ErrUnsubscribeDisabled = errors.New("未配置退订链接签名密钥，请在个人设置中修改通知偏好")
+	// ErrInvalidConfirmToken 邮箱确认链接无效
+	ErrInvalidConfirmToken = errors.New("邮箱确认链接无效或已损坏")
+	// ErrInvalidMailLocale 不支持的邮件语言
+	ErrInvalidMailLocale = errors.New("不支持的邮件语言")
+)
+
+// mailExcerptLength 邮件中评论摘要的最大字符数
+const mailExcerptLength = 300
+
+// emailConfirmInterval 同一邮箱两次发送确认邮件的最小间隔，避免利用匿名评论向他人邮箱反复发信
+const emailConfirmInterval = 24 * time.Hour
+
+// EmailPreferenceUpdate 通知偏好的修改，为nil的字段保持不变
+type EmailPreferenceUpdate struct {
+	Locale     *string `json:"locale"`
+	Reply      *bool   `json:"reply"`
+	Moderation *bool   `json:"moderation"`
+	Admin      *bool   `json:"admin"`
+}
+
+// MailService 邮件通知服务接口，订阅评论事件并把通知邮件写入发件箱
+type MailService interface {
+	Subscribe(bus event.Bus)
+	GetPreference(ctx context.Context, userID uint) (*domain.EmailPreference, error)
+	UpdatePreference(ctx context.Context, userID uint, update EmailPreferenceUpdate) (*domain.EmailPreference, error)
+	VerifyUnsubscribe(email, kind, token string) error
+	Unsubscribe(ctx context.Context, email, kind, token string) error
+	UnsubscribeURL(email, kind string) string
+	VerifyConfirmation(email, token string) error
+	ConfirmEmail(ctx context.Context, email, token string) error
+}
+
+// MailServiceImpl 邮件通知服务实现
+type MailServiceImpl struct {
+	emailRepo   repository.EmailRepository
+	userRepo    repository.UserRepository
+	commentRepo repository.CommentRepository
+	templates   *mailer.Templates
+	cfg         config.MailConfig
+}
+
+// NewMailService 创建邮件通知服务实例
+func NewMailService(
+	emailRepo repository.EmailRepository,
+	userRepo repository.UserRepository,
+	commentRepo repository.CommentRepository,
+	templates *mailer.Templates,
+	cfg config.MailConfig,
+) MailService {
+	return &MailServiceImpl{
+		emailRepo:   emailRepo,
+		userRepo:    userRepo,
+		commentRepo: commentRepo,
+		templates:   templates,
+		cfg:         cfg,
+	}
+}
+
+// mailData 邮件模板使用的数据
+type mailData struct {
+	SiteName       string
+	SiteURL        string
+	RecipientName  string
+	AuthorName     string
+	TargetTitle    string
+	Excerpt        string
+	ParentExcerpt  string
+	SpamReasons    string
+	CommentURL     string
+	ModerationURL  string
+	ConfirmURL     string
+	UnsubscribeURL string
+}
+
+// Subscribe 订阅需要发送邮件的事件
+func (s *MailServiceImpl) Subscribe(bus event.Bus) {
+	bus.Subscribe(event.TopicCommentCreated, s.onCommentCreated)
+	bus.Subscribe(event.TopicCommentApproved, s.onCommentApproved)
+}
+
+// onCommentCreated 待审核的评论通知管理员，直接发布的回复通知被回复的人
+// 匿名评论者勾选了接收通知时发送邮箱确认邮件
+func (s *MailServiceImpl) onCommentCreated(ctx context.Context, e event.Event) error {
+	created := e.(event.CommentCreated)
+	if created.Comment.UserID == nil && created.Comment.NotifyEmail {
+		if err := s.requestConfirmation(ctx, created.Comment, created.Target); err != nil {
+			return err
+		}
+	}
+	switch created.Comment.Status {
+	case "pending":
+		return s.notifyModerators(ctx, created.Comment, created.Target)
+	case "approved":
+		return s.notifyReply(ctx, created.Comment, created.Target)
+	}
+	return nil
+}
+
+// onCommentApproved 评论从待审核或垃圾信息状态被批准后，通知作者和被回复的人
+func (s *MailServiceImpl) onCommentApproved(ctx context.Context, e event.Event) error {
+	approved := e.(event.CommentApproved)
+	if approved.FromStatus != "pending" && approved.FromStatus != "spam" {
+		return nil
+	}
+
+	comment := approved.Comment
+	target, err := s.commentRepo.FindTarget(ctx, comment.ItemType, comment.ItemID)
+	if err != nil || target == nil {
+		return err
+	}
+
+	email, name, err := s.recipient(ctx, comment)
+	if err != nil {
+		return err
+	}
+	// 管理员批准自己的评论时不通知
+	if email != "" && (comment.UserID == nil || *comment.UserID != approved.ModeratorID) {
+		err := s.enqueue(ctx, domain.EmailKindModeration, mailer.TemplateCommentApproved, email, name, mailData{
+			TargetTitle: target.Title,
+			Excerpt:     mailExcerpt(comment.Content),
+			CommentURL:  s.commentURL(target, comment.ID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.notifyReply(ctx, comment, target)
+}
+
+// notifyReply 通知父评论的作者收到了回复，回复自己的评论时不通知
+func (s *MailServiceImpl) notifyReply(ctx context.Context, comment *domain.Comment, target *domain.CommentTarget) error {
+	if comment.ParentID == nil {
+		return nil
+	}
+	parent, err := s.commentRepo.FindByID(ctx, *comment.ParentID)
+	if err != nil || parent == nil {
+		return err
+	}
+
+	to, recipientName, err := s.recipient(ctx, parent)
+	if err != nil || to == "" {
+		return err
+	}
+	from, authorName, err := s.author(ctx, comment)
+	if err != nil {
+		return err
+	}
+	if to == from {
+		return nil
+	}
+
+	return s.enqueue(ctx, domain.EmailKindReply, mailer.TemplateCommentReply, to, recipientName, mailData{
+		AuthorName:    authorName,
+		TargetTitle:   target.Title,
+		Excerpt:       mailExcerpt(comment.Content),
+		ParentExcerpt: mailExcerpt(parent.Content),
+		CommentURL:    s.commentURL(target, comment.ID),
+	})
+}
+
+// notifyModerators 通知所有管理员有新的待审核评论
+func (s *MailServiceImpl) notifyModerators(ctx context.Context, comment *domain.Comment, target *domain.CommentTarget) error {
+	admins, err := s.userRepo.FindByRole(ctx, "admin")
+	if err != nil {
+		return err
+	}
+	_, authorName, err := s.author(ctx, comment)
+	if err != nil {
+		return err
+	}
+
+	for _, admin := range admins {
+		err := s.enqueue(ctx, domain.EmailKindAdmin, mailer.TemplateCommentPending, admin.Email, admin.Username, mailData{
+			AuthorName:    authorName,
+			TargetTitle:   target.Title,
+			Excerpt:       mailExcerpt(comment.Content),
+			SpamReasons:   comment.SpamReasons,
+			CommentURL:    s.commentURL(target, comment.ID),
+			ModerationURL: s.cfg.SiteURL + "/admin/comments?status=pending",
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// author 返回评论作者的邮箱和名称，匿名评论使用填写的邮箱
+func (s *MailServiceImpl) author(ctx context.Context, comment *domain.Comment) (string, string, error) {
+	if comment.UserID == nil {
+		return domain.NormalizeEmail(comment.AnonymousEmail), comment.AnonymousName, nil
+	}
+
+	user := comment.User
+	if user == nil {
+		var err error
+		user, err = s.userRepo.FindByID(ctx, *comment.UserID)
+		if err != nil || user == nil {
+			return "", "", err
+		}
+	}
+	return domain.NormalizeEmail(user.Email), user.Username, nil
+}
+
+// recipient 返回接收评论相关通知的邮箱和名称
+// 匿名评论者填写的邮箱未经验证，只有勾选了接收通知并点击过确认邮件中的链接才发送，否则返回空邮箱
+func (s *MailServiceImpl) recipient(ctx context.Context, comment *domain.Comment) (string, string, error) {
+	email, name, err := s.author(ctx, comment)
+	if err != nil || email == "" || comment.UserID != nil {
+		return email, name, err
+	}
+	if !comment.NotifyEmail {
+		return "", name, nil
+	}
+
+	preference, err := s.emailRepo.FindPreference(ctx, email)
+	if err != nil {
+		return "", "", err
+	}
+	if preference == nil || preference.ConfirmedAt == nil {
+		return "", name, nil
+	}
+	return email, name, nil
+}
+
+// requestConfirmation 向匿名评论者填写的邮箱发送确认邮件
+// 已确认、已退订全部评论通知或最近发送过确认邮件的邮箱跳过，未配置签名密钥时无法生成确认链接，也跳过
+func (s *MailServiceImpl) requestConfirmation(ctx context.Context, comment *domain.Comment, target *domain.CommentTarget) error {
+	email := domain.NormalizeEmail(comment.AnonymousEmail)
+	if email == "" || s.cfg.UnsubscribeSecret == "" {
+		return nil
+	}
+
+	preference, err := s.emailRepo.FindPreference(ctx, email)
+	if err != nil {
+		return err
+	}
+	if preference == nil {
+		preference = domain.NewEmailPreference(email)
+	}
+	if preference.ConfirmedAt != nil || (!preference.Reply && !preference.Moderation) {
+		return nil
+	}
+	now := time.Now()
+	if preference.ConfirmSentAt != nil && now.Sub(*preference.ConfirmSentAt) < emailConfirmInterval {
+		return nil
+	}
+
+	preference.ConfirmSentAt = &now
+	if err := s.emailRepo.SavePreference(ctx, preference); err != nil {
+		return err
+	}
+	return s.render(ctx, domain.EmailKindConfirm, mailer.TemplateEmailConfirm, email, comment.AnonymousName, s.locale(preference), mailData{
+		TargetTitle:    target.Title,
+		Excerpt:        mailExcerpt(comment.Content),
+		ConfirmURL:     s.confirmURL(email),
+		UnsubscribeURL: s.UnsubscribeURL(email, domain.EmailKindAll),
+	})
+}
+
+// enqueue 按收件人的偏好渲染邮件并写入发件箱，已退订该类通知时跳过
+func (s *MailServiceImpl) enqueue(ctx context.Context, kind, template, to, recipientName string, data mailData) error {
+	preference, err := s.emailRepo.FindPreference(ctx, to)
+	if err != nil {
+		return err
+	}
+	if preference != nil && !preference.Allows(kind) {
+		return nil
+	}
+
+	data.UnsubscribeURL = s.UnsubscribeURL(to, kind)
+	return s.render(ctx, kind, template, to, recipientName, s.locale(preference), data)
+}
+
+// locale 返回收件人偏好的邮件语言，没有设置时使用默认语言
+func (s *MailServiceImpl) locale(preference *domain.EmailPreference) string {
+	if preference != nil && preference.Locale != "" {
+		return preference.Locale
+	}
+	return s.cfg.Locale
+}
+
+// render 渲染邮件并写入发件箱
+func (s *MailServiceImpl) render(ctx context.Context, kind, template, to, recipientName, locale string, data mailData) error {
+	data.SiteName = s.cfg.SiteName
+	data.SiteURL = s.cfg.SiteURL
+	data.RecipientName = recipientName
+	msg, err := s.templates.Render(template, locale, data)
+	if err != nil {
+		return err
+	}
+
+	return s.emailRepo.Enqueue(ctx, &domain.EmailOutbox{
+		Kind:           kind,
+		Recipient:      to,
+		Subject:        truncateUTF8(msg.Subject, 255),
+		TextBody:       msg.Text,
+		HTMLBody:       msg.HTML,
+		UnsubscribeURL: data.UnsubscribeURL,
+	})
+}
+
+// commentURL 返回评论在前端页面中的地址
+func (s *MailServiceImpl) commentURL(target *domain.CommentTarget, commentID uint) string {
+	return s.cfg.SiteURL + commentPath(target, commentID)
+}
+
+// commentPath 返回评论在前端页面中的路径，例如 /articles/go-concurrency#comment-12
+func commentPath(target *domain.CommentTarget, commentID uint) string {
+	key := target.Slug
+	if key == "" {
+		key = fmt.Sprint(target.ItemID)
+	}
+	return fmt.Sprintf("/%ss/%s#comment-%d", target.ItemType, url.PathEscape(key), commentID)
+}
+
+// GetPreference 获取用户的邮件通知偏好，没有保存过时返回默认偏好
+func (s *MailServiceImpl) GetPreference(ctx context.Context, userID uint) (*domain.EmailPreference, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	preference, err := s.emailRepo.FindPreference(ctx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if preference == nil {
+		preference = domain.NewEmailPreference(user.Email)
+		preference.Locale = s.cfg.Locale
+	}
+	preference.UserID = &user.ID
+	return preference, nil
+}
+
+// UpdatePreference 修改用户的邮件通知偏好
+func (s *MailServiceImpl) UpdatePreference(ctx context.Context, userID uint, update EmailPreferenceUpdate) (*domain.EmailPreference, error) {
+	preference, err := s.GetPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Locale != nil {
+		if !mailer.IsLocale(*update.Locale) {
+			return nil, ErrInvalidMailLocale
+		}
+		preference.Locale = *update.Locale
+	}
+	if update.Reply != nil {
+		preference.Reply = *update.Reply
+	}
+	if update.Moderation != nil {
+		preference.Moderation = *update.Moderation
+	}
+	if update.Admin != nil {
+		preference.Admin = *update.Admin
+	}
+
+	if err := s.emailRepo.SavePreference(ctx, preference); err != nil {
+		return nil, err
+	}
+	return preference, nil
+}
+
+// VerifyUnsubscribe 校验退订链接，不修改通知偏好
+// 未配置签名密钥时任何人都能伪造链接，此时返回ErrUnsubscribeDisabled
+func (s *MailServiceImpl) VerifyUnsubscribe(email, kind, token string) error {
+	if s.cfg.UnsubscribeSecret == "" {
+		return ErrUnsubscribeDisabled
+	}
+	switch kind {
+	case domain.EmailKindReply, domain.EmailKindModeration, domain.EmailKindAdmin, domain.EmailKindAll:
+	default:
+		return ErrInvalidUnsubscribeToken
+	}
+	email = domain.NormalizeEmail(email)
+	if email == "" || !hmac.Equal([]byte(token), []byte(s.unsubscribeToken(email, kind))) {
+		return ErrInvalidUnsubscribeToken
+	}
+	return nil
+}
+
+// Unsubscribe 通过邮件中的链接退订某类通知，不需要登录
+func (s *MailServiceImpl) Unsubscribe(ctx context.Context, email, kind, token string) error {
+	if err := s.VerifyUnsubscribe(email, kind, token); err != nil {
+		return err
+	}
+	email = domain.NormalizeEmail(email)
+
+	preference, err := s.emailRepo.FindPreference(ctx, email)
+	if err != nil {
+		return err
+	}
+	if preference == nil {
+		preference = domain.NewEmailPreference(email)
+	}
+	preference.Disable(kind)
+	return s.emailRepo.SavePreference(ctx, preference)
+}
+
+// UnsubscribeURL 返回一键退订链接，未配置签名密钥时返回空字符串，邮件中不附带退订链接
+func (s *MailServiceImpl) UnsubscribeURL(email, kind string) string {
+	if s.cfg.UnsubscribeSecret == "" {
+		return ""
+	}
+	query := url.Values{}
+	query.Set("email", domain.NormalizeEmail(email))
+	query.Set("kind", kind)
+	query.Set("token", s.unsubscribeToken(email, kind))
+	return s.cfg.APIURL + "/api/v1/email/unsubscribe?" + query.Encode()
+}
+
+// unsubscribeToken 计算退订链接签名
+func (s *MailServiceImpl) unsubscribeToken(email, kind string) string {
+	return s.sign("unsubscribe\n" + domain.NormalizeEmail(email) + "\n" + kind)
+}
+
+// VerifyConfirmation 校验邮箱确认链接，不修改通知偏好
+// 未配置签名密钥时不会发送确认邮件，任何链接都无效
+func (s *MailServiceImpl) VerifyConfirmation(email, token string) error {
+	email = domain.NormalizeEmail(email)
+	if s.cfg.UnsubscribeSecret == "" || email == "" || !hmac.Equal([]byte(token), []byte(s.confirmToken(email))) {
+		return ErrInvalidConfirmToken
+	}
+	return nil
+}
+
+// ConfirmEmail 通过确认邮件中的链接确认匿名评论者的邮箱，之后勾选了接收通知的匿名评论会收到回复和审核通知
+func (s *MailServiceImpl) ConfirmEmail(ctx context.Context, email, token string) error {
+	if err := s.VerifyConfirmation(email, token); err != nil {
+		return err
+	}
+	email = domain.NormalizeEmail(email)
+
+	preference, err := s.emailRepo.FindPreference(ctx, email)
+	if err != nil {
+		return err
+	}
+	if preference == nil {
+		preference = domain.NewEmailPreference(email)
+	}
+	if preference.ConfirmedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	preference.ConfirmedAt = &now
+	return s.emailRepo.SavePreference(ctx, preference)
+}
+
+// confirmURL 返回邮箱确认链接
+func (s *MailServiceImpl) confirmURL(email string) string {
+	query := url.Values{}
+	query.Set("email", domain.NormalizeEmail(email))
+	query.Set("token", s.confirmToken(email))
+	return s.cfg.APIURL + "/api/v1/email/confirm?" + query.Encode()
+}
+
+// confirmToken 计算邮箱确认链接签名
+func (s *MailServiceImpl) confirmToken(email string) string {
+	return s.sign("confirm\n" + domain.NormalizeEmail(email))
+}
+
+// sign 使用退订签名密钥计算链接签名，不同用途的链接使用不同的前缀
+func (s *MailServiceImpl) sign(message string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.UnsubscribeSecret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// mailExcerpt 截取评论摘要
+func mailExcerpt(content string) string {
+	return textExcerpt(content, mailExcerptLength)
+}
+
+// textExcerpt 截取最多length个字符的摘要，超出时以省略号结尾
+func textExcerpt(content string, length int) string {
+	content = strings.TrimSpace(content)
+	if utf8.RuneCountInString(content) <= length {
+		return content
+	}
+	return string([]rune(content)[:length]) + "…"
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/mailer"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockEmailRepository 模拟邮件仓储
+type MockEmailRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailRepository) Enqueue(ctx context.Context, email *domain.EmailOutbox) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockEmailRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]domain.EmailOutbox, error) {
+	args := m.Called(ctx, limit, lease)
+	return args.Get(0).([]domain.EmailOutbox), args.Error(1)
+}
+
+func (m *MockEmailRepository) MarkSent(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockEmailRepository) MarkFailed(ctx context.Context, id uint, lastError string, nextAttempt *time.Time) error {
+	args := m.Called(ctx, id, lastError, nextAttempt)
+	return args.Error(0)
+}
+
+func (m *MockEmailRepository) FindPreference(ctx context.Context, email string) (*domain.EmailPreference, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.EmailPreference), args.Error(1)
+}
+
+func (m *MockEmailRepository) SavePreference(ctx context.Context, preference *domain.EmailPreference) error {
+	args := m.Called(ctx, preference)
+	return args.Error(0)
+}
+
+var testMailConfig = config.MailConfig{
+	SiteName:          "Lin Studio",
+	SiteURL:           "https://example.com",
+	APIURL:            "https://api.example.com",
+	Locale:            "zh",
+	UnsubscribeSecret: "test-secret",
+	BatchSize:         20,
+	MaxAttempts:       3,
+}
+
+func newTestMailService(t *testing.T) (MailService, *MockEmailRepository, *MockUserRepository, *MockCommentRepository) {
+	templates, err := mailer.NewTemplates("zh")
+	require.NoError(t, err)
+	emailRepo := new(MockEmailRepository)
+	userRepo := new(MockUserRepository)
+	commentRepo := new(MockCommentRepository)
+	return NewMailService(emailRepo, userRepo, commentRepo, templates, testMailConfig), emailRepo, userRepo, commentRepo
+}
+
+// TestMailServiceReplyNotification 测试回复通知按偏好写入发件箱
+func TestMailServiceReplyNotification(t *testing.T) {
+	mailService, emailRepo, userRepo, commentRepo := newTestMailService(t)
+	bus := event.NewBus()
+	mailService.Subscribe(bus)
+	ctx := context.Background()
+
+	parentID := uint(1)
+	bobID := uint(8)
+	target := &domain.CommentTarget{ItemType: "article", ItemID: 3, Slug: "go-concurrency", Title: "Go并发"}
+	commentRepo.On("FindByID", mock.Anything, parentID).Return(&domain.Comment{
+		ID:             parentID,
+		Content:        "写得好",
+		AnonymousName:  "Alice",
+		AnonymousEmail: "Alice@Example.com",
+		NotifyEmail:    true,
+	}, nil)
+	userRepo.On("FindByID", mock.Anything, bobID).Return(&domain.User{ID: bobID, Username: "bob", Email: "bob@example.com"}, nil)
+	confirmedAt := time.Now()
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(&domain.EmailPreference{
+		Email: "alice@example.com", Locale: "en", Reply: true, ConfirmedAt: &confirmedAt,
+	}, nil).Twice()
+
+	var queued *domain.EmailOutbox
+	emailRepo.On("Enqueue", mock.Anything, mock.AnythingOfType("*domain.EmailOutbox")).Run(func(args mock.Arguments) {
+		queued = args.Get(1).(*domain.EmailOutbox)
+	}).Return(nil).Once()
+
+	reply := &domain.Comment{ID: 2, UserID: &bobID, ParentID: &parentID, Content: "同意", Status: "approved"}
+	bus.Publish(ctx, event.CommentCreated{Comment: reply, Target: target})
+
+	require.NotNil(t, queued)
+	assert.Equal(t, domain.EmailKindReply, queued.Kind)
+	assert.Equal(t, "alice@example.com", queued.Recipient)
+	assert.Equal(t, `bob replied to your comment on "Go并发"`, queued.Subject)
+	assert.Contains(t, queued.TextBody, "https://example.com/articles/go-concurrency#comment-2")
+
+	// 生成的退订链接可以通过验证
+	link, err := url.Parse(queued.UnsubscribeURL)
+	require.NoError(t, err)
+	query := link.Query()
+	// 只校验链接时不修改通知偏好
+	require.NoError(t, mailService.VerifyUnsubscribe(query.Get("email"), query.Get("kind"), query.Get("token")))
+	emailRepo.AssertNotCalled(t, "SavePreference", mock.Anything, mock.Anything)
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(nil, nil).Once()
+	emailRepo.On("SavePreference", mock.Anything, mock.MatchedBy(func(p *domain.EmailPreference) bool {
+		return p.Email == "alice@example.com" && !p.Reply
+	})).Return(nil).Once()
+	require.NoError(t, mailService.Unsubscribe(ctx, query.Get("email"), query.Get("kind"), query.Get("token")))
+
+	// 退订后不再写入发件箱
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(&domain.EmailPreference{
+		Email: "alice@example.com", Reply: false, ConfirmedAt: &confirmedAt,
+	}, nil).Twice()
+	bus.Publish(ctx, event.CommentCreated{Comment: reply, Target: target})
+	emailRepo.AssertNumberOfCalls(t, "Enqueue", 1)
+
+	// 篡改过的链接被拒绝
+	err = mailService.Unsubscribe(ctx, "alice@example.com", domain.EmailKindAll, query.Get("token"))
+	assert.True(t, errors.Is(err, ErrInvalidUnsubscribeToken))
+}
+
+// confirmLinkPattern 匹配确认邮件中的确认链接
+var confirmLinkPattern = regexp.MustCompile(`https://api\.example\.com/api/v1/email/confirm\?\S+`)
+
+// TestAnonymousEmailConfirmation 测试匿名评论者勾选接收通知并确认邮箱后才会收到回复通知
+func TestAnonymousEmailConfirmation(t *testing.T) {
+	mailService, emailRepo, userRepo, commentRepo := newTestMailService(t)
+	bus := event.NewBus()
+	mailService.Subscribe(bus)
+	ctx := context.Background()
+
+	bobID := uint(8)
+	target := &domain.CommentTarget{ItemType: "article", ItemID: 3, Slug: "go-concurrency", Title: "Go并发"}
+	userRepo.On("FindByID", mock.Anything, bobID).Return(&domain.User{ID: bobID, Username: "bob", Email: "bob@example.com"}, nil)
+
+	// 没有勾选接收通知的匿名评论不发送确认邮件，收到回复时也不通知
+	silentID := uint(1)
+	silent := &domain.Comment{ID: silentID, Content: "路过", AnonymousName: "Eve", AnonymousEmail: "eve@example.com", Status: "approved"}
+	commentRepo.On("FindByID", mock.Anything, silentID).Return(silent, nil)
+	bus.Publish(ctx, event.CommentCreated{Comment: silent, Target: target})
+	bus.Publish(ctx, event.CommentCreated{Comment: &domain.Comment{ID: 2, UserID: &bobID, ParentID: &silentID, Content: "欢迎", Status: "approved"}, Target: target})
+	emailRepo.AssertNotCalled(t, "FindPreference", mock.Anything, "eve@example.com")
+	emailRepo.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything)
+
+	// 勾选接收通知后发送确认邮件
+	parentID := uint(3)
+	parent := &domain.Comment{ID: parentID, Content: "写得好", AnonymousName: "Alice", AnonymousEmail: "alice@example.com", NotifyEmail: true, Status: "approved"}
+	commentRepo.On("FindByID", mock.Anything, parentID).Return(parent, nil)
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(nil, nil).Once()
+	emailRepo.On("SavePreference", mock.Anything, mock.MatchedBy(func(p *domain.EmailPreference) bool {
+		return p.ConfirmSentAt != nil && p.ConfirmedAt == nil
+	})).Return(nil).Once()
+	var queued *domain.EmailOutbox
+	emailRepo.On("Enqueue", mock.Anything, mock.AnythingOfType("*domain.EmailOutbox")).Run(func(args mock.Arguments) {
+		queued = args.Get(1).(*domain.EmailOutbox)
+	}).Return(nil).Once()
+	bus.Publish(ctx, event.CommentCreated{Comment: parent, Target: target})
+	require.NotNil(t, queued)
+	assert.Equal(t, domain.EmailKindConfirm, queued.Kind)
+	assert.Equal(t, "alice@example.com", queued.Recipient)
+
+	// 24小时内不重复发送确认邮件
+	sentAt := time.Now()
+	pending := &domain.EmailPreference{Email: "alice@example.com", Reply: true, Moderation: true, ConfirmSentAt: &sentAt}
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(pending, nil).Once()
+	bus.Publish(ctx, event.CommentCreated{Comment: parent, Target: target})
+
+	// 确认前收到回复不通知
+	reply := &domain.Comment{ID: 4, UserID: &bobID, ParentID: &parentID, Content: "同意", Status: "approved"}
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(pending, nil).Once()
+	bus.Publish(ctx, event.CommentCreated{Comment: reply, Target: target})
+	emailRepo.AssertNumberOfCalls(t, "Enqueue", 1)
+
+	// 篡改过的确认链接被拒绝
+	link, err := url.Parse(confirmLinkPattern.FindString(queued.TextBody))
+	require.NoError(t, err)
+	query := link.Query()
+	assert.ErrorIs(t, mailService.VerifyConfirmation("eve@example.com", query.Get("token")), ErrInvalidConfirmToken)
+
+	// 点击确认链接后收到回复通知
+	require.NoError(t, mailService.VerifyConfirmation(query.Get("email"), query.Get("token")))
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(pending, nil).Once()
+	emailRepo.On("SavePreference", mock.Anything, mock.MatchedBy(func(p *domain.EmailPreference) bool {
+		return p.ConfirmedAt != nil
+	})).Return(nil).Once()
+	require.NoError(t, mailService.ConfirmEmail(ctx, query.Get("email"), query.Get("token")))
+
+	emailRepo.On("FindPreference", mock.Anything, "alice@example.com").Return(pending, nil).Twice()
+	emailRepo.On("Enqueue", mock.Anything, mock.AnythingOfType("*domain.EmailOutbox")).Run(func(args mock.Arguments) {
+		queued = args.Get(1).(*domain.EmailOutbox)
+	}).Return(nil).Once()
+	bus.Publish(ctx, event.CommentCreated{Comment: reply, Target: target})
+	assert.Equal(t, domain.EmailKindReply, queued.Kind)
+	emailRepo.AssertExpectations(t)
+}
+
+// TestUnsubscribeWithoutSecret 测试未配置签名密钥时不生成退订链接，也不接受任何退订请求
+func TestUnsubscribeWithoutSecret(t *testing.T) {
+	templates, err := mailer.NewTemplates("zh")
+	require.NoError(t, err)
+	cfg := testMailConfig
+	cfg.UnsubscribeSecret = ""
+	emailRepo := new(MockEmailRepository)
+	mailService := NewMailService(emailRepo, new(MockUserRepository), new(MockCommentRepository), templates, cfg)
+
+	assert.Empty(t, mailService.UnsubscribeURL("alice@example.com", domain.EmailKindReply))
+
+	// 使用空密钥计算的签名同样被拒绝
+	mac := hmac.New(sha256.New, nil)
+	mac.Write([]byte("unsubscribe\nalice@example.com\n" + domain.EmailKindAll))
+	token := hex.EncodeToString(mac.Sum(nil))[:32]
+	err = mailService.Unsubscribe(context.Background(), "alice@example.com", domain.EmailKindAll, token)
+	assert.ErrorIs(t, err, ErrUnsubscribeDisabled)
+	emailRepo.AssertNotCalled(t, "SavePreference", mock.Anything, mock.Anything)
+}
+
+// TestMailBackoff 测试发送失败后的重试间隔
+func TestMailBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, mailBackoff(1))
+	assert.Equal(t, 4*time.Minute, mailBackoff(3))
+	assert.Equal(t, mailMaxBackoff, mailBackoff(20))
+}
+
+// failingSender 前几次发送失败的邮件发送器
+type failingSender struct {
+	failures int
+	sent     []*mailer.Message
+}
+
+func (s *failingSender) Send(ctx context.Context, msg *mailer.Message) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("connection refused")
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// TestMailWorkerRetries 测试发送失败后按退避时间重试，超过最大次数后不再重试
+func TestMailWorkerRetries(t *testing.T) {
+	emailRepo := new(MockEmailRepository)
+	sender := &failingSender{failures: 2}
+	worker := NewMailWorker(emailRepo, sender, testMailConfig)
+	ctx := context.Background()
+	email := domain.EmailOutbox{ID: 4, Recipient: "alice@example.com", Subject: "回复"}
+
+	// 第一次失败，1分钟后重试
+	email.Attempts = 1
+	emailRepo.On("ClaimDue", mock.Anything, 20, mailSendLease).Return([]domain.EmailOutbox{email}, nil).Once()
+	emailRepo.On("MarkFailed", mock.Anything, uint(4), "connection refused", mock.MatchedBy(func(next *time.Time) bool {
+		return next != nil && time.Until(*next) > 50*time.Second && time.Until(*next) <= time.Minute
+	})).Return(nil).Once()
+	n, err := worker.ProcessDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// 达到最大次数后不再安排重试
+	email.Attempts = 3
+	emailRepo.On("ClaimDue", mock.Anything, 20, mailSendLease).Return([]domain.EmailOutbox{email}, nil).Once()
+	emailRepo.On("MarkFailed", mock.Anything, uint(4), "connection refused", (*time.Time)(nil)).Return(nil).Once()
+	_, err = worker.ProcessDue(ctx)
+	require.NoError(t, err)
+
+	// 重试成功后标记为已发送
+	email.Attempts = 2
+	emailRepo.On("ClaimDue", mock.Anything, 20, mailSendLease).Return([]domain.EmailOutbox{email}, nil).Once()
+	emailRepo.On("MarkSent", mock.Anything, uint(4)).Return(nil).Once()
+	_, err = worker.ProcessDue(ctx)
+	require.NoError(t, err)
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, "alice@example.com", sender.sent[0].To)
+
+	emailRepo.AssertExpectations(t)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/mailer"
+	"Lin_studio/internal/repository"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// mailSendLease 取出的邮件在该时长内不会被再次取出，应大于单封邮件的发送超时
+	mailSendLease = 5 * time.Minute
+	// mailMaxBackoff 发送失败后的最长重试间隔
+	mailMaxBackoff = 6 * time.Hour
+)
+
+// MailWorker 发件箱后台发送任务接口
+type MailWorker interface {
+	ProcessDue(ctx context.Context) (int, error)
+	Start()
+	Stop()
+}
+
+// MailWorkerImpl 发件箱后台发送任务实现，定期取出到期的邮件发送，失败后按指数退避重试
+type MailWorkerImpl struct {
+	emailRepo repository.EmailRepository
+	sender    mailer.Sender
+	cfg       config.MailConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewMailWorker 创建发件箱后台发送任务实例
+func NewMailWorker(emailRepo repository.EmailRepository, sender mailer.Sender, cfg config.MailConfig) MailWorker {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 20
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MailWorkerImpl{
+		emailRepo: emailRepo,
+		sender:    sender,
+		cfg:       cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start 启动后台发送协程
+func (w *MailWorkerImpl) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			// 一批发满时说明还有积压，立即处理下一批
+			for {
+				n, err := w.ProcessDue(w.ctx)
+				if err != nil {
+					log.Printf("处理待发送邮件失败: %v", err)
+				}
+				if err != nil || n < w.cfg.BatchSize {
+					break
+				}
+			}
+
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台发送并等待正在发送的邮件完成
+func (w *MailWorkerImpl) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// ProcessDue 发送一批到期的邮件，返回取出的邮件数量
+func (w *MailWorkerImpl) ProcessDue(ctx context.Context) (int, error) {
+	emails, err := w.emailRepo.ClaimDue(ctx, w.cfg.BatchSize, mailSendLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range emails {
+		if ctx.Err() != nil {
+			// 未发送的邮件在租约到期后重新取出
+			return len(emails), nil
+		}
+		w.send(ctx, &emails[i])
+	}
+	return len(emails), nil
+}
+
+// send 发送单封邮件并记录结果
+func (w *MailWorkerImpl) send(ctx context.Context, email *domain.EmailOutbox) {
+	sendCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	err := w.sender.Send(sendCtx, &mailer.Message{
+		To:             email.Recipient,
+		Subject:        email.Subject,
+		Text:           email.TextBody,
+		HTML:           email.HTMLBody,
+		UnsubscribeURL: email.UnsubscribeURL,
+	})
+	if err == nil {
+		if err := w.emailRepo.MarkSent(ctx, email.ID); err != nil {
+			log.Printf("更新邮件状态失败，ID=%d: %v", email.ID, err)
+		}
+		return
+	}
+
+	var next *time.Time
+	if email.Attempts < w.cfg.MaxAttempts {
+		t := time.Now().Add(mailBackoff(email.Attempts))
+		next = &t
+	}
+	log.Printf("发送邮件失败，ID=%d，第%d次: %v", email.ID, email.Attempts, err)
+	if err := w.emailRepo.MarkFailed(ctx, email.ID, truncateUTF8(err.Error(), 500), next); err != nil {
+		log.Printf("更新邮件状态失败，ID=%d: %v", email.ID, err)
+	}
+}
+
+// mailBackoff 第attempts次发送失败后的重试间隔: 1分钟、2分钟、4分钟……最长6小时
+func mailBackoff(attempts int) time.Duration {
+	backoff := time.Minute
+	for i := 1; i < attempts && backoff < mailMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > mailMaxBackoff {
+		backoff = mailMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// ErrMediaNotFound 媒体文件不存在
+	ErrMediaNotFound = errors.New("媒体文件不存在")
+	// ErrMediaInUse 媒体文件仍被引用，不能删除
+	ErrMediaInUse = errors.New("媒体文件正在使用中")
+)
+
+var (
+	// 内容哈希命名的媒体文件及其缩略图的存储键: [variants/]media/<哈希前两位>/<SHA-256>[_w宽度].扩展名
+	mediaKeyPattern = regexp.MustCompile(`^(?:variants/)?media/([0-9a-f]{2})/([0-9a-f]{64})(?:[._][^/]*)?$`)
+	// Markdown图片语法 ![alt](url "title")
+	markdownImagePattern = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)`)
+	// HTML中的src属性
+	htmlSrcPattern = regexp.MustCompile(`(?i)\bsrc\s*=\s*["']([^"']+)["']`)
+)
+
+// MediaService 媒体库服务接口
+type MediaService interface {
+	Upload(ctx context.Context, file *multipart.FileHeader, uploaderID *uint) (*domain.Media, error)
+	GetMediaList(ctx context.Context, page, limit int, contentType *string, uploaderID *uint, unused bool) ([]domain.Media, map[uint]int64, domain.PaginationData, error)
+	GetMediaByID(ctx context.Context, id uint) (*domain.Media, error)
+	DeleteMedia(ctx context.Context, id uint) error
+	GetImageSets(ctx context.Context, urls []string) map[string]*domain.ImageSet
+	ResolveReferences(ctx context.Context, urls ...string) ([]uint, error)
+	SyncReferences(ctx context.Context, refType string, refID uint, urls ...string) error
+	SignURL(url string) string
+	SignImageSet(set *domain.ImageSet) *domain.ImageSet
+	CleanupUnreferenced(ctx context.Context, grace time.Duration, dryRun bool) ([]domain.Media, error)
+}
+
+// MediaServiceImpl 媒体库服务实现
+type MediaServiceImpl struct {
+	mediaRepo repository.MediaRepository
+	storage   storage.Storage
+	validator *utils.UploadValidator
+	processor ImageProcessor
+	signer    *utils.URLSigner
+}
+
+// NewMediaService 创建媒体库服务实例
+func NewMediaService(
+	mediaRepo repository.MediaRepository,
+	storage storage.Storage,
+	validator *utils.UploadValidator,
+	processor ImageProcessor,
+	signer *utils.URLSigner,
+) MediaService {
+	return &MediaServiceImpl{
+		mediaRepo: mediaRepo,
+		storage:   storage,
+		validator: validator,
+		processor: processor,
+		signer:    signer,
+	}
+}
+
+// Upload 上传媒体文件，按内容哈希命名，相同内容只保存一份
+func (s *MediaServiceImpl) Upload(ctx context.Context, file *multipart.FileHeader, uploaderID *uint) (*domain.Media, error) {
+	// 校验图片并去除元数据
+	img, err := s.validator.ValidateImageFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重新编码后的内容是确定的，相同图片得到相同哈希
+	sum := sha256.Sum256(img.Data)
+	hash := hex.EncodeToString(sum[:])
+
+	existing, err := s.mediaRepo.FindByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// 重新开始计算宽限期，避免文章保存前文件被当作未引用清理
+		if err := s.mediaRepo.TouchUploaded(ctx, existing.ID); err != nil {
+			return nil, err
+		}
+		existing.LastUploadedAt = time.Now()
+		return existing, nil
+	}
+
+	// 保存文件
+	key := fmt.Sprintf("media/%s/%s%s", hash[:2], hash, img.Ext)
+	if err := s.storage.Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+		return nil, err
+	}
+
+	media := &domain.Media{
+		Key:         key,
+		URL:         s.storage.URL(key),
+		Hash:        hash,
+		Name:        mediaName(file.Filename),
+		UploaderID:  uploaderID,
+		ContentType: img.ContentType,
+		Size:        int64(len(img.Data)),
+		Width:       img.Width,
+		Height:      img.Height,
+		Status:      "pending",
+	}
+	if err := s.mediaRepo.Create(ctx, media); err != nil {
+		// 并发上传相同文件时存储键冲突，返回先写入的记录
+		if existing, findErr := s.mediaRepo.FindByHash(ctx, hash); findErr == nil && existing != nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	// 后台生成缩略图和WebP版本
+	s.processor.Enqueue(media.ID)
+
+	return media, nil
+}
+
+// GetMediaList 分页获取媒体文件及其引用次数
+func (s *MediaServiceImpl) GetMediaList(
+	ctx context.Context,
+	page, limit int,
+	contentType *string,
+	uploaderID *uint,
+	unused bool,
+) ([]domain.Media, map[uint]int64, domain.PaginationData, error) {
+	filter := repository.MediaFilter{
+		Page:        page,
+		Limit:       limit,
+		ContentType: contentType,
+		UploaderID:  uploaderID,
+		Unused:      unused,
+	}
+
+	media, total, err := s.mediaRepo.FindAll(ctx, filter)
+	if err != nil {
+		return nil, nil, domain.PaginationData{}, err
+	}
+
+	ids := make([]uint, len(media))
+	for i := range media {
+		ids[i] = media[i].ID
+	}
+	counts, err := s.mediaRepo.CountReferences(ctx, ids)
+	if err != nil {
+		return nil, nil, domain.PaginationData{}, err
+	}
+
+	pagination := domain.PaginationData{
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (int(total) + limit - 1) / limit,
+	}
+
+	return media, counts, pagination, nil
+}
+
+// GetMediaByID 根据ID获取媒体文件
+func (s *MediaServiceImpl) GetMediaByID(ctx context.Context, id uint) (*domain.Media, error) {
+	media, err := s.mediaRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if media == nil {
+		return nil, ErrMediaNotFound
+	}
+
+	return media, nil
+}
+
+// DeleteMedia 删除未被引用的媒体文件
+func (s *MediaServiceImpl) DeleteMedia(ctx context.Context, id uint) error {
+	media, err := s.GetMediaByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	counts, err := s.mediaRepo.CountReferences(ctx, []uint{media.ID})
+	if err != nil {
+		return err
+	}
+	if counts[media.ID] > 0 {
+		return ErrMediaInUse
+	}
+
+	return s.remove(ctx, media)
+}
+
+// GetImageSets 批量获取图片地址对应的响应式图片数据
+func (s *MediaServiceImpl) GetImageSets(ctx context.Context, urls []string) map[string]*domain.ImageSet {
+	sets := make(map[string]*domain.ImageSet)
+	if len(urls) == 0 {
+		return sets
+	}
+
+	media, err := s.mediaRepo.FindByURLs(ctx, urls)
+	if err != nil {
+		return sets
+	}
+	for i := range media {
+		if set := media[i].ToImageSet(); set != nil {
+			sets[media[i].URL] = set
+		}
+	}
+	return sets
+}
+
+// SyncReferences 将引用方当前使用的文件地址同步为引用关系，不传地址即清除全部引用
+func (s *MediaServiceImpl) SyncReferences(ctx context.Context, refType string, refID uint, urls ...string) error {
+	ids, err := s.ResolveReferences(ctx, urls...)
+	if err != nil {
+		return err
+	}
+	return s.mediaRepo.ReplaceReferences(ctx, refType, refID, ids)
+}
+
+// ResolveReferences 查找文件地址对应的媒体文件ID，不是媒体库中的地址会被忽略
+func (s *MediaServiceImpl) ResolveReferences(ctx context.Context, urls ...string) ([]uint, error) {
+	var keys, hashes []string
+	for _, url := range urls {
+		url = strings.TrimSpace(url)
+		// 去掉签名等查询参数和锚点
+		if i := strings.IndexAny(url, "?#"); i >= 0 {
+			url = url[:i]
+		}
+		key, ok := s.storage.KeyFromURL(url)
+		if !ok {
+			continue
+		}
+		// 内容哈希命名的文件(包括其缩略图)按哈希匹配
+		if hash := mediaHashFromKey(key); hash != "" {
+			hashes = append(hashes, hash)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	ids := make([]uint, 0, len(keys)+len(hashes))
+	if len(hashes) > 0 {
+		media, err := s.mediaRepo.FindByHashes(ctx, hashes)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range media {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(keys) > 0 {
+		media, err := s.mediaRepo.FindByKeys(ctx, keys)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range media {
+			ids = append(ids, m.ID)
+		}
+	}
+	return ids, nil
+}
+
+// mediaHashFromKey 返回内容哈希命名的媒体文件或其缩略图存储键中的哈希，其他文件返回空字符串
+func mediaHashFromKey(key string) string {
+	m := mediaKeyPattern.FindStringSubmatch(key)
+	if m == nil || m[2][:2] != m[1] {
+		return ""
+	}
+	return m[2]
+}
+
+// SignURL 为本存储的文件地址生成签名URL，未启用签名时原样返回
+func (s *MediaServiceImpl) SignURL(url string) string {
+	if s.signer == nil || url == "" {
+		return url
+	}
+	if _, ok := s.storage.KeyFromURL(utils.StripURLSignature(url)); !ok {
+		return url
+	}
+	return s.signer.Sign(url)
+}
+
+// SignImageSet 返回地址全部签名后的响应式图片数据
+func (s *MediaServiceImpl) SignImageSet(set *domain.ImageSet) *domain.ImageSet {
+	if s.signer == nil || set == nil {
+		return set
+	}
+
+	signed := *set
+	signed.Src = s.SignURL(set.Src)
+	signed.Sources = make([]domain.ImageSource, len(set.Sources))
+	for i, source := range set.Sources {
+		candidates := strings.Split(source.Srcset, ", ")
+		for j, candidate := range candidates {
+			if url, width, ok := strings.Cut(candidate, " "); ok {
+				candidates[j] = s.SignURL(url) + " " + width
+			}
+		}
+		signed.Sources[i] = domain.ImageSource{
+			Type:   source.Type,
+			Srcset: strings.Join(candidates, ", "),
+		}
+	}
+	return &signed
+}
+
+// CleanupUnreferenced 删除超过宽限期仍未被引用的媒体文件
+// 宽限期用于保护刚上传、尚未保存到文章中的文件
+func (s *MediaServiceImpl) CleanupUnreferenced(ctx context.Context, grace time.Duration, dryRun bool) ([]domain.Media, error) {
+	media, err := s.mediaRepo.FindUnreferenced(ctx, time.Now().Add(-grace))
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return media, nil
+	}
+
+	removed := make([]domain.Media, 0, len(media))
+	for i := range media {
+		if err := s.remove(ctx, &media[i]); err != nil {
+			log.Printf("删除媒体文件 %d (%s) 失败: %v", media[i].ID, media[i].Key, err)
+			continue
+		}
+		removed = append(removed, media[i])
+	}
+	return removed, nil
+}
+
+// remove 删除原图、全部变体和数据库记录
+func (s *MediaServiceImpl) remove(ctx context.Context, media *domain.Media) error {
+	for _, v := range media.Variants {
+		if err := s.storage.Delete(ctx, v.Key); err != nil {
+			return err
+		}
+	}
+	if err := s.storage.Delete(ctx, media.Key); err != nil {
+		return err
+	}
+	return s.mediaRepo.Delete(ctx, media.ID)
+}
+
+// ArticleMediaURLs 返回文章引用的全部文件地址(封面和正文中的图片)
+func ArticleMediaURLs(article *domain.Article) []string {
+	urls := make([]string, 0)
+	if article.CoverImage != "" {
+		urls = append(urls, article.CoverImage)
+	}
+	return append(urls, extractImageURLs(article.Content)...)
+}
+
+// ProjectMediaURLs 返回项目引用的全部文件地址(图集和正文中的图片)
+func ProjectMediaURLs(project *domain.Project) []string {
+	urls := make([]string, 0, len(project.Images))
+	for _, img := range project.Images {
+		if img.URL != "" {
+			urls = append(urls, img.URL)
+		}
+	}
+	return append(urls, extractImageURLs(project.Content)...)
+}
+
+// extractImageURLs 提取Markdown/HTML内容中的图片地址
+func extractImageURLs(content string) []string {
+	var urls []string
+	for _, pattern := range []*regexp.Regexp{markdownImagePattern, htmlSrcPattern} {
+		for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+			urls = append(urls, match[1])
+		}
+	}
+	return urls
+}
+
+// mediaName 清理原始文件名，只保留文件名部分
+func mediaName(filename string) string {
+	name := filepath.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return truncateUTF8(name, 255)
+}
+
+// truncateUTF8 将字符串截断到不超过maxBytes字节，按字符截断，避免截断多字节字符
+func truncateUTF8(s string, maxBytes int) string {
+	for len(s) > maxBytes {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s
+}
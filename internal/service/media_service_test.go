@@ -0,0 +1,86 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testImageFile 构造上传的PNG图片
+func testImageFile(t *testing.T) *multipart.FileHeader {
+	var img bytes.Buffer
+	require.NoError(t, png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "photo.png")
+	require.NoError(t, err)
+	_, err = part.Write(img.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", "/media", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	_, file, err := req.FormFile("file")
+	require.NoError(t, err)
+	return file
+}
+
+// TestUploadDuplicateRefreshesGrace 测试重复上传相同内容时返回已有记录并重新开始计算清理宽限期
+func TestUploadDuplicateRefreshesGrace(t *testing.T) {
+	mockRepo := new(MockMediaRepository)
+	validator := utils.NewUploadValidator(config.UploadConfig{
+		MaxSize:      1024 * 1024,
+		AllowedTypes: []string{"image/png"},
+		MaxWidth:     100,
+		MaxHeight:    100,
+		MaxPixels:    100 * 100,
+	})
+	s := NewMediaService(mockRepo, storage.NewLocalStorage(t.TempDir(), "/uploads"), validator, nil, nil)
+
+	existing := &domain.Media{ID: 7, Key: "media/ab/photo.png"}
+	mockRepo.On("FindByHash", mock.Anything, mock.Anything).Return(existing, nil)
+	mockRepo.On("TouchUploaded", mock.Anything, uint(7)).Return(nil).Once()
+
+	media, err := s.Upload(context.Background(), testImageFile(t), nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), media.ID)
+	assert.False(t, media.LastUploadedAt.IsZero())
+	mockRepo.AssertExpectations(t)
+}
+
+// TestResolveReferences 测试只从本存储的媒体文件存储键中提取内容哈希
+func TestResolveReferences(t *testing.T) {
+	hash := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	other := "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+
+	mockRepo := new(MockMediaRepository)
+	mockRepo.On("FindByHashes", mock.Anything, []string{hash, hash}).Return([]domain.Media{{ID: 1}}, nil)
+	mockRepo.On("FindByKeys", mock.Anything, []string{"covers/cover.png", "media/ff/" + hash + ".png"}).Return([]domain.Media{{ID: 2}}, nil)
+	s := NewMediaService(mockRepo, storage.NewLocalStorage(t.TempDir(), "/uploads"), nil, nil, nil)
+
+	ids, err := s.ResolveReferences(context.Background(),
+		"/uploads/media/01/"+hash+".png?expires=1&signature=abc",
+		"/uploads/variants/media/01/"+hash+"_w640.webp",
+		// 其他站点的地址和查询参数中的哈希不匹配
+		"https://cdn.example.com/media/01/"+hash+".png",
+		"/uploads/covers/cover.png?v="+other,
+		// 目录与哈希前两位不一致的不是内容哈希命名的文件
+		"/uploads/media/ff/"+hash+".png",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2}, ids)
+	mockRepo.AssertExpectations(t)
+}
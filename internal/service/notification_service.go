@@ -0,0 +1,283 @@
+package service
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/repository"
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// notificationExcerptLength 通知中评论摘要的最大字符数
+const notificationExcerptLength = 120
+
+// NotificationService 站内通知服务接口，订阅评论和表态事件为注册用户生成通知
+type NotificationService interface {
+	Subscribe(bus event.Bus)
+	GetNotifications(ctx context.Context, userID uint, unreadOnly bool, page, limit int) ([]domain.Notification, domain.PaginationData, error)
+	CountUnread(ctx context.Context, userID uint) (int64, error)
+	MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error)
+	MarkAllRead(ctx context.Context, userID uint) (int64, error)
+}
+
+// NotificationServiceImpl 站内通知服务实现
+type NotificationServiceImpl struct {
+	notificationRepo repository.NotificationRepository
+	userRepo         repository.UserRepository
+	commentRepo      repository.CommentRepository
+	articleRepo      repository.ArticleRepository
+	events           event.Bus
+}
+
+// NewNotificationService 创建站内通知服务实例，events为nil时不发布新通知事件
+func NewNotificationService(
+	notificationRepo repository.NotificationRepository,
+	userRepo repository.UserRepository,
+	commentRepo repository.CommentRepository,
+	articleRepo repository.ArticleRepository,
+	events event.Bus,
+) NotificationService {
+	return &NotificationServiceImpl{
+		notificationRepo: notificationRepo,
+		userRepo:         userRepo,
+		commentRepo:      commentRepo,
+		articleRepo:      articleRepo,
+		events:           events,
+	}
+}
+
+// Subscribe 订阅需要生成站内通知的事件
+func (s *NotificationServiceImpl) Subscribe(bus event.Bus) {
+	bus.Subscribe(event.TopicCommentCreated, s.onCommentCreated)
+	bus.Subscribe(event.TopicCommentApproved, s.onCommentApproved)
+	bus.Subscribe(event.TopicCommentRejected, s.onCommentRejected)
+	bus.Subscribe(event.TopicCommentMentioned, s.onCommentMentioned)
+	bus.Subscribe(event.TopicReactionAdded, s.onReactionAdded)
+}
+
+// onCommentCreated 直接发布的回复通知被回复的人
+func (s *NotificationServiceImpl) onCommentCreated(ctx context.Context, e event.Event) error {
+	created := e.(event.CommentCreated)
+	if created.Comment.Status != "approved" {
+		return nil
+	}
+	return s.notifyReply(ctx, created.Comment, created.Target)
+}
+
+// onCommentApproved 评论从待审核或垃圾信息状态被批准后通知作者和被回复的人，管理员批准自己的评论时不通知作者
+func (s *NotificationServiceImpl) onCommentApproved(ctx context.Context, e event.Event) error {
+	approved := e.(event.CommentApproved)
+	if approved.FromStatus != "pending" && approved.FromStatus != "spam" {
+		return nil
+	}
+	comment := approved.Comment
+	target, err := s.commentRepo.FindTarget(ctx, comment.ItemType, comment.ItemID)
+	if err != nil || target == nil {
+		return err
+	}
+
+	if comment.UserID != nil && *comment.UserID != approved.ModeratorID {
+		err := s.save(ctx, s.commentNotification(domain.NotificationCommentApproved, *comment.UserID, comment, target))
+		if err != nil {
+			return err
+		}
+	}
+	return s.notifyReply(ctx, comment, target)
+}
+
+// onCommentRejected 评论被标记为垃圾信息后通知作者
+func (s *NotificationServiceImpl) onCommentRejected(ctx context.Context, e event.Event) error {
+	rejected := e.(event.CommentRejected)
+	comment := rejected.Comment
+	if comment.UserID == nil || *comment.UserID == rejected.ModeratorID {
+		return nil
+	}
+	target, err := s.commentRepo.FindTarget(ctx, comment.ItemType, comment.ItemID)
+	if err != nil || target == nil {
+		return err
+	}
+
+	return s.save(ctx, s.commentNotification(domain.NotificationCommentRejected, *comment.UserID, comment, target))
+}
+
+// onCommentMentioned 通知评论中被@提及的用户
+// 同时是被回复者的用户已经收到回复通知，不再重复通知
+func (s *NotificationServiceImpl) onCommentMentioned(ctx context.Context, e event.Event) error {
+	mentioned := e.(event.CommentMentioned)
+	comment := mentioned.Comment
+	target, err := s.commentRepo.FindTarget(ctx, comment.ItemType, comment.ItemID)
+	if err != nil || target == nil {
+		return err
+	}
+	actorName, err := s.commentAuthorName(ctx, comment)
+	if err != nil {
+		return err
+	}
+
+	notifications := make([]domain.Notification, 0, len(mentioned.UserIDs))
+	for _, userID := range mentioned.UserIDs {
+		n := s.commentNotification(domain.NotificationCommentMention, userID, comment, target)
+		n.ActorID = comment.UserID
+		n.ActorName = actorName
+		n.DedupeKey = commentDedupeKey(comment.ID)
+		notifications = append(notifications, n)
+	}
+	return s.save(ctx, notifications...)
+}
+
+// onReactionAdded 文章被点赞时通知文章作者，同一访问者重复点赞只通知一次
+func (s *NotificationServiceImpl) onReactionAdded(ctx context.Context, e event.Event) error {
+	reaction := e.(event.ReactionAdded)
+	if reaction.TargetType != domain.ReactionTargetArticle || reaction.Kind != domain.ReactionLike {
+		return nil
+	}
+	article, err := s.articleRepo.FindByID(ctx, reaction.TargetID)
+	if err != nil || article == nil {
+		return err
+	}
+	if reaction.UserID != nil && *reaction.UserID == article.AuthorID {
+		return nil
+	}
+
+	// 匿名访问者不显示名称
+	var actorName string
+	if reaction.UserID != nil {
+		user, err := s.userRepo.FindByID(ctx, *reaction.UserID)
+		if err != nil {
+			return err
+		}
+		if user != nil {
+			actorName = user.Username
+		}
+	}
+
+	dedupeKey := fmt.Sprintf("article_like:%d:%s", article.ID, reaction.ActorKey)
+	return s.save(ctx, domain.Notification{
+		UserID:    article.AuthorID,
+		Type:      domain.NotificationArticleLike,
+		ActorID:   reaction.UserID,
+		ActorName: actorName,
+		ItemType:  domain.ReactionTargetArticle,
+		ItemID:    article.ID,
+		Title:     article.Title,
+		Link:      "/articles/" + url.PathEscape(article.Slug),
+		DedupeKey: &dedupeKey,
+	})
+}
+
+// notifyReply 通知父评论的作者收到了回复，匿名的父评论和回复自己的评论不通知
+func (s *NotificationServiceImpl) notifyReply(ctx context.Context, comment *domain.Comment, target *domain.CommentTarget) error {
+	if comment.ParentID == nil {
+		return nil
+	}
+	parent, err := s.commentRepo.FindByID(ctx, *comment.ParentID)
+	if err != nil || parent == nil || parent.UserID == nil {
+		return err
+	}
+	if comment.UserID != nil && *comment.UserID == *parent.UserID {
+		return nil
+	}
+
+	actorName, err := s.commentAuthorName(ctx, comment)
+	if err != nil {
+		return err
+	}
+	n := s.commentNotification(domain.NotificationCommentReply, *parent.UserID, comment, target)
+	n.ActorID = comment.UserID
+	n.ActorName = actorName
+	n.DedupeKey = commentDedupeKey(comment.ID)
+	return s.save(ctx, n)
+}
+
+// save 逐条保存通知并发布新通知事件，去重忽略的通知不发布
+func (s *NotificationServiceImpl) save(ctx context.Context, notifications ...domain.Notification) error {
+	for i := range notifications {
+		n := &notifications[i]
+		created, err := s.notificationRepo.Create(ctx, n)
+		if err != nil {
+			return err
+		}
+		if created && s.events != nil {
+			s.events.Publish(ctx, event.NotificationCreated{Notification: n})
+		}
+	}
+	return nil
+}
+
+// commentNotification 生成与评论相关的通知
+func (s *NotificationServiceImpl) commentNotification(kind string, userID uint, comment *domain.Comment, target *domain.CommentTarget) domain.Notification {
+	commentID := comment.ID
+	return domain.Notification{
+		UserID:    userID,
+		Type:      kind,
+		ItemType:  target.ItemType,
+		ItemID:    target.ItemID,
+		CommentID: &commentID,
+		Title:     truncateUTF8(target.Title, 255),
+		Excerpt:   textExcerpt(comment.Content, notificationExcerptLength),
+		Link:      commentPath(target, comment.ID),
+	}
+}
+
+// commentAuthorName 返回评论作者的名称，匿名评论使用填写的名称
+func (s *NotificationServiceImpl) commentAuthorName(ctx context.Context, comment *domain.Comment) (string, error) {
+	if comment.UserID == nil {
+		return comment.AnonymousName, nil
+	}
+	if comment.User != nil {
+		return comment.User.Username, nil
+	}
+	user, err := s.userRepo.FindByID(ctx, *comment.UserID)
+	if err != nil || user == nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+// commentDedupeKey 同一条评论对同一用户只生成一条回复或提及通知
+func commentDedupeKey(commentID uint) *string {
+	key := fmt.Sprintf("comment:%d", commentID)
+	return &key
+}
+
+// GetNotifications 分页获取用户的通知
+func (s *NotificationServiceImpl) GetNotifications(
+	ctx context.Context,
+	userID uint,
+	unreadOnly bool,
+	page, limit int,
+) ([]domain.Notification, domain.PaginationData, error) {
+	notifications, total, err := s.notificationRepo.FindByUser(ctx, repository.NotificationFilter{
+		UserID:     userID,
+		UnreadOnly: unreadOnly,
+		Page:       page,
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, domain.PaginationData{}, err
+	}
+
+	pagination := domain.PaginationData{
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (int(total) + limit - 1) / limit,
+	}
+	return notifications, pagination, nil
+}
+
+// CountUnread 获取用户的未读通知数量
+func (s *NotificationServiceImpl) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	return s.notificationRepo.CountUnread(ctx, userID)
+}
+
+// MarkRead 将用户的指定通知标记为已读
+func (s *NotificationServiceImpl) MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error) {
+	return s.notificationRepo.MarkRead(ctx, userID, ids)
+}
+
+// MarkAllRead 将用户的全部通知标记为已读
+func (s *NotificationServiceImpl) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	return s.notificationRepo.MarkAllRead(ctx, userID)
+}
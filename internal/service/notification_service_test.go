@@ -0,0 +1,173 @@
+package service
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/repository"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockNotificationRepository 模拟站内通知仓储，保存的通知记录在created中
+type MockNotificationRepository struct {
+	mock.Mock
+	created []domain.Notification
+}
+
+func (m *MockNotificationRepository) Create(ctx context.Context, notification *domain.Notification) (bool, error) {
+	m.created = append(m.created, *notification)
+	return true, nil
+}
+
+func (m *MockNotificationRepository) FindByUser(ctx context.Context, filter repository.NotificationFilter) ([]domain.Notification, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.Notification), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockNotificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationRepository) MarkRead(ctx context.Context, userID uint, ids []uint) (int64, error) {
+	args := m.Called(ctx, userID, ids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationRepository) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockArticleRepository 模拟文章仓储
+type MockArticleRepository struct {
+	mock.Mock
+}
+
+func (m *MockArticleRepository) Create(ctx context.Context, article *domain.Article) error {
+	args := m.Called(ctx, article)
+	return args.Error(0)
+}
+
+func (m *MockArticleRepository) FindByID(ctx context.Context, id uint) (*domain.Article, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Article), args.Error(1)
+}
+
+func (m *MockArticleRepository) FindBySlug(ctx context.Context, slug string) (*domain.Article, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Article), args.Error(1)
+}
+
+func (m *MockArticleRepository) FindAll(ctx context.Context, filter repository.ArticleFilter) ([]domain.Article, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.Article), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockArticleRepository) FindFeatured(ctx context.Context, limit int) ([]domain.Article, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]domain.Article), args.Error(1)
+}
+
+func (m *MockArticleRepository) Update(ctx context.Context, article *domain.Article) error {
+	args := m.Called(ctx, article)
+	return args.Error(0)
+}
+
+func (m *MockArticleRepository) Delete(ctx context.Context, id, version uint) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockArticleRepository) AddViews(ctx context.Context, counts map[uint]uint) error {
+	args := m.Called(ctx, counts)
+	return args.Error(0)
+}
+
+func (m *MockArticleRepository) FindPublishedWithTags(ctx context.Context) ([]domain.Article, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Article), args.Error(1)
+}
+
+func (m *MockArticleRepository) FindRelated(ctx context.Context, articleID uint, limit int) ([]domain.Article, error) {
+	args := m.Called(ctx, articleID, limit)
+	return args.Get(0).([]domain.Article), args.Error(1)
+}
+
+func (m *MockArticleRepository) ReplaceRelated(ctx context.Context, related []domain.ArticleRelated) error {
+	args := m.Called(ctx, related)
+	return args.Error(0)
+}
+
+// TestNotificationsFromEvents 测试由评论和表态事件生成站内通知
+func TestNotificationsFromEvents(t *testing.T) {
+	notificationRepo := new(MockNotificationRepository)
+	userRepo := new(MockUserRepository)
+	commentRepo := new(MockCommentRepository)
+	articleRepo := new(MockArticleRepository)
+	bus := event.NewBus()
+	NewNotificationService(notificationRepo, userRepo, commentRepo, articleRepo, nil).Subscribe(bus)
+	ctx := context.Background()
+
+	aliceID, bobID, carolID := uint(5), uint(8), uint(9)
+	target := &domain.CommentTarget{ItemType: "article", ItemID: 3, Slug: "go-concurrency", Title: "Go并发"}
+	parentID := uint(1)
+	commentRepo.On("FindByID", mock.Anything, parentID).Return(&domain.Comment{ID: parentID, UserID: &aliceID}, nil)
+	commentRepo.On("FindTarget", mock.Anything, "article", uint(3)).Return(target, nil)
+	userRepo.On("FindByID", mock.Anything, bobID).Return(&domain.User{ID: bobID, Username: "bob"}, nil)
+
+	// 回复同时提及了被回复者和另一个用户
+	reply := &domain.Comment{ID: 2, ItemType: "article", ItemID: 3, UserID: &bobID, ParentID: &parentID, Content: "同意 @alice @carol", Status: "approved"}
+	bus.Publish(ctx, event.CommentCreated{Comment: reply, Target: target})
+	bus.Publish(ctx, event.CommentMentioned{Comment: reply, UserIDs: []uint{aliceID, carolID}})
+
+	require.Len(t, notificationRepo.created, 3)
+	replyNotice := notificationRepo.created[0]
+	assert.Equal(t, domain.NotificationCommentReply, replyNotice.Type)
+	assert.Equal(t, aliceID, replyNotice.UserID)
+	assert.Equal(t, "bob", replyNotice.ActorName)
+	assert.Equal(t, "/articles/go-concurrency#comment-2", replyNotice.Link)
+	// 被回复者的提及通知与回复通知使用相同的去重键，由数据库唯一索引忽略
+	assert.Equal(t, domain.NotificationCommentMention, notificationRepo.created[1].Type)
+	assert.Equal(t, *replyNotice.DedupeKey, *notificationRepo.created[1].DedupeKey)
+	assert.Equal(t, carolID, notificationRepo.created[2].UserID)
+
+	// 作者给自己的文章点赞不通知，其他人点赞通知作者
+	notificationRepo.created = nil
+	articleRepo.On("FindByID", mock.Anything, uint(3)).Return(&domain.Article{ID: 3, AuthorID: bobID, Title: "Go并发", Slug: "go-concurrency"}, nil)
+	bus.Publish(ctx, event.ReactionAdded{TargetType: "article", TargetID: 3, Kind: "like", ActorKey: "u:8", UserID: &bobID})
+	bus.Publish(ctx, event.ReactionAdded{TargetType: "article", TargetID: 3, Kind: "heart", ActorKey: "v:abc"})
+	bus.Publish(ctx, event.ReactionAdded{TargetType: "article", TargetID: 3, Kind: "like", ActorKey: "v:abc"})
+	require.Len(t, notificationRepo.created, 1)
+	like := notificationRepo.created[0]
+	assert.Equal(t, domain.NotificationArticleLike, like.Type)
+	assert.Equal(t, bobID, like.UserID)
+	assert.Equal(t, "article_like:3:v:abc", *like.DedupeKey)
+
+	// 评论从待审核状态批准后通知作者和被回复的人，已批准的评论再次批准时不重复通知
+	notificationRepo.created = nil
+	bus.Publish(ctx, event.CommentApproved{Comment: reply, FromStatus: "pending", ModeratorID: 1})
+	require.Len(t, notificationRepo.created, 2)
+	assert.Equal(t, domain.NotificationCommentApproved, notificationRepo.created[0].Type)
+	assert.Equal(t, bobID, notificationRepo.created[0].UserID)
+	notificationRepo.created = nil
+	bus.Publish(ctx, event.CommentApproved{Comment: reply, FromStatus: "approved", ModeratorID: 1})
+	assert.Empty(t, notificationRepo.created)
+
+	// 管理员将评论标记为垃圾信息后通知作者
+	notificationRepo.created = nil
+	bus.Publish(ctx, event.CommentRejected{Comment: reply, FromStatus: "approved", ModeratorID: 1})
+	require.Len(t, notificationRepo.created, 1)
+	assert.Equal(t, domain.NotificationCommentRejected, notificationRepo.created[0].Type)
+	assert.Equal(t, bobID, notificationRepo.created[0].UserID)
+}
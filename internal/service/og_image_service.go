@@ -0,0 +1,233 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/storage"
+	"Lin_studio/internal/utils"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ogFontCandidates 未配置OG_FONT_PATH时依次查找的常见中文字体
+var ogFontCandidates = []string{
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Bold.ttc",   // Debian/Ubuntu fonts-noto-cjk
+	"/usr/share/fonts/noto-cjk/NotoSansCJK-Bold.ttc",        // Alpine font-noto-cjk
+	"/usr/share/fonts/google-noto-cjk/NotoSansCJK-Bold.ttc", // Fedora google-noto-sans-cjk-fonts
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",        // 文泉驿微米黑
+	"/System/Library/Fonts/PingFang.ttc",                    // macOS
+}
+
+// OGImageService 分享图服务接口
+type OGImageService interface {
+	// GetArticleImage 返回文章分享图，缓存不存在或已过期时重新生成
+	GetArticleImage(ctx context.Context, slug string) (*ServedFile, error)
+}
+
+// OGImageServiceImpl 分享图服务实现
+type OGImageServiceImpl struct {
+	articleRepo  repository.ArticleRepository
+	userRepo     repository.UserRepository
+	categoryRepo repository.CategoryRepository
+	storage      storage.Storage
+	ogConfig     config.OGConfig
+	fontPath     string
+	mu           sync.Mutex // 防止并发请求重复生成同一张图片
+}
+
+// NewOGImageService 创建分享图服务实例
+// 生成的图片缓存在上传存储的og/目录中，未配置字体时使用系统中找到的中文字体
+func NewOGImageService(
+	articleRepo repository.ArticleRepository,
+	userRepo repository.UserRepository,
+	categoryRepo repository.CategoryRepository,
+	storage storage.Storage,
+	cfg config.OGConfig,
+) OGImageService {
+	fontPath := cfg.FontPath
+	if fontPath == "" {
+		for _, candidate := range ogFontCandidates {
+			if _, err := os.Stat(candidate); err == nil {
+				fontPath = candidate
+				break
+			}
+		}
+		if fontPath == "" {
+			log.Println("警告: 未找到中文字体，分享图中的中文无法显示，请设置OG_FONT_PATH")
+		}
+	}
+
+	return &OGImageServiceImpl{
+		articleRepo:  articleRepo,
+		userRepo:     userRepo,
+		categoryRepo: categoryRepo,
+		storage:      storage,
+		ogConfig:     cfg,
+		fontPath:     fontPath,
+	}
+}
+
+// GetArticleImage 获取文章分享图
+func (s *OGImageServiceImpl) GetArticleImage(ctx context.Context, slug string) (*ServedFile, error) {
+	article, err := s.articleRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if article == nil || article.Status != "published" {
+		return nil, errors.New("文章不存在")
+	}
+
+	data := utils.OGImageData{
+		Title:    article.Title,
+		SiteName: s.ogConfig.SiteName,
+	}
+
+	// 加载分类信息
+	if article.CategoryID != nil {
+		category, err := s.categoryRepo.FindByID(ctx, *article.CategoryID)
+		if err == nil && category != nil {
+			data.Category = category.Name
+		}
+	}
+
+	// 加载作者信息
+	var avatarKey string
+	author, err := s.userRepo.FindByID(ctx, article.AuthorID)
+	if err == nil && author != nil {
+		data.AuthorName = author.Username
+		avatarKey, _ = s.storage.KeyFromURL(author.Avatar)
+	}
+
+	// 缓存文件名包含渲染内容的摘要，标题等信息变化后自动重新生成
+	key := fmt.Sprintf("og/articles/%d-%s.png", article.ID, s.digest(data, avatarKey))
+	if file, err := s.open(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		return file, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 获取锁后再次检查，可能已被其他请求生成
+	if file, err := s.open(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		return file, err
+	}
+
+	if avatarKey != "" {
+		data.Avatar = s.loadStoredImage(ctx, avatarKey)
+	}
+	if s.ogConfig.TemplatePath != "" {
+		data.Background = loadImage(s.ogConfig.TemplatePath)
+	}
+
+	var fontData []byte
+	if s.fontPath != "" {
+		fontData, err = os.ReadFile(s.fontPath)
+		if err != nil {
+			return nil, fmt.Errorf("读取字体文件失败: %w", err)
+		}
+	}
+
+	content, err := utils.RenderOGImage(data, fontData)
+	if err != nil {
+		return nil, fmt.Errorf("生成分享图失败: %w", err)
+	}
+	if err := s.storage.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/png"); err != nil {
+		return nil, err
+	}
+	s.replaceCached(ctx, article.ID, key)
+
+	return s.open(ctx, key)
+}
+
+// open 打开缓存的分享图，不存在时返回storage.ErrNotFound
+func (s *OGImageServiceImpl) open(ctx context.Context, key string) (*ServedFile, error) {
+	obj, err := s.storage.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &ServedFile{
+		Content:     storage.NewReadSeeker(ctx, s.storage, key, obj.Size),
+		Size:        obj.Size,
+		ContentType: "image/png",
+		ModTime:     obj.ModTime,
+	}, nil
+}
+
+// replaceCached 记录文章当前的分享图并删除之前生成的图片
+// 存储不支持列出文件，通过og/articles/<ID>.latest记录最近一次生成的存储键
+func (s *OGImageServiceImpl) replaceCached(ctx context.Context, articleID uint, key string) {
+	latest := fmt.Sprintf("og/articles/%d.latest", articleID)
+	if obj, err := s.storage.Open(ctx, latest); err == nil {
+		previous, _ := io.ReadAll(io.LimitReader(obj.Body, 256))
+		obj.Body.Close()
+		if old := string(previous); old != "" && old != key {
+			if err := s.storage.Delete(ctx, old); err != nil {
+				log.Printf("删除过期的分享图 %s 失败: %v", old, err)
+			}
+		}
+	}
+	if err := s.storage.Put(ctx, latest, strings.NewReader(key), int64(len(key)), "text/plain"); err != nil {
+		log.Printf("记录文章 %d 的分享图失败: %v", articleID, err)
+	}
+}
+
+// digest 计算影响分享图渲染结果的内容摘要
+func (s *OGImageServiceImpl) digest(data utils.OGImageData, avatarKey string) string {
+	h := sha1.New()
+	for _, part := range []string{
+		data.Title,
+		data.Category,
+		data.AuthorName,
+		data.SiteName,
+		avatarKey,
+		s.ogConfig.TemplatePath,
+		s.fontPath,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// loadStoredImage 从上传存储中读取并解码图片，失败时返回nil
+func (s *OGImageServiceImpl) loadStoredImage(ctx context.Context, key string) image.Image {
+	obj, err := s.storage.Open(ctx, key)
+	if err != nil {
+		return nil
+	}
+	defer obj.Body.Close()
+
+	img, _, err := image.Decode(obj.Body)
+	if err != nil {
+		return nil
+	}
+	return img
+}
+
+// loadImage 读取并解码本地图片，失败时返回nil
+func loadImage(path string) image.Image {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil
+	}
+	return img
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/storage"
+	"context"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStorage 记录写入的分享图数量的存储
+type countingStorage struct {
+	storage.Storage
+	images []string
+}
+
+func (s *countingStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if strings.HasSuffix(key, ".png") {
+		s.images = append(s.images, key)
+	}
+	return s.Storage.Put(ctx, key, r, size, contentType)
+}
+
+// TestGetArticleImage 测试分享图缓存在上传存储中，内容变化后重新生成并删除过期的图片
+func TestGetArticleImage(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStorage{Storage: storage.NewLocalStorage(t.TempDir(), "/uploads")}
+	articleRepo := new(MockArticleRepository)
+	userRepo := new(MockUserRepository)
+	s := NewOGImageService(articleRepo, userRepo, nil, store, config.OGConfig{SiteName: "Lin Studio"})
+
+	article := &domain.Article{ID: 3, AuthorID: 8, Title: "Go Concurrency", Slug: "go-concurrency", Status: "published"}
+	articleRepo.On("FindBySlug", mock.Anything, "go-concurrency").Return(article, nil)
+	articleRepo.On("FindBySlug", mock.Anything, "draft").Return(&domain.Article{ID: 4, Status: "draft"}, nil)
+	userRepo.On("FindByID", mock.Anything, uint(8)).Return(&domain.User{ID: 8, Username: "bob"}, nil)
+
+	first, err := s.GetArticleImage(ctx, "go-concurrency")
+	require.NoError(t, err)
+	img, err := png.Decode(first.Content)
+	require.NoError(t, err)
+	first.Content.Close()
+	assert.Equal(t, 1200, img.Bounds().Dx())
+	assert.Equal(t, "image/png", first.ContentType)
+	require.Len(t, store.images, 1)
+	assert.True(t, strings.HasPrefix(store.images[0], "og/articles/3-"))
+
+	// 内容没有变化时直接返回缓存
+	cached, err := s.GetArticleImage(ctx, "go-concurrency")
+	require.NoError(t, err)
+	cached.Content.Close()
+	assert.Len(t, store.images, 1)
+
+	// 标题修改后重新生成，旧图片被删除
+	article.Title = "Go Concurrency Patterns"
+	updated, err := s.GetArticleImage(ctx, "go-concurrency")
+	require.NoError(t, err)
+	updated.Content.Close()
+	require.Len(t, store.images, 2)
+	assert.NotEqual(t, store.images[0], store.images[1])
+	exists, err := store.Exists(ctx, store.images[0])
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = store.Exists(ctx, store.images[1])
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// 未发布的文章没有分享图
+	_, err = s.GetArticleImage(ctx, "draft")
+	assert.Error(t, err)
+}
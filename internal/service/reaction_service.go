@@ -0,0 +1,153 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+var (
+	// ErrReactionTargetNotFound 表态的内容不存在或不可见
+	ErrReactionTargetNotFound = errors.New("内容不存在")
+	// ErrInvalidReaction 不支持的表态类型
+	ErrInvalidReaction = errors.New("不支持的表态类型")
+)
+
+// ReactionActor 表态的访问者，登录用户按用户ID区分，匿名访问者按IP和User-Agent的指纹区分
+type ReactionActor struct {
+	Key    string
+	UserID *uint
+}
+
+// ReactionService 表态服务接口
+type ReactionService interface {
+	Actor(userID *uint, ip, userAgent string) ReactionActor
+	Toggle(ctx context.Context, targetType string, targetID uint, kind string, actor ReactionActor) (bool, *domain.ReactionSummary, error)
+	GetSummaries(ctx context.Context, targetType string, targetIDs []uint, actor ReactionActor) (map[uint]domain.ReactionSummary, error)
+}
+
+// ReactionServiceImpl 表态服务实现
+type ReactionServiceImpl struct {
+	reactionRepo repository.ReactionRepository
+	events       event.Bus
+	cfg          config.ReactionConfig
+}
+
+// NewReactionService 创建表态服务实例，events为nil时不发布事件
+func NewReactionService(reactionRepo repository.ReactionRepository, events event.Bus, cfg config.ReactionConfig) ReactionService {
+	return &ReactionServiceImpl{
+		reactionRepo: reactionRepo,
+		events:       events,
+		cfg:          cfg,
+	}
+}
+
+// Actor 返回请求对应的访问者
+// 匿名访问者的指纹只保存哈希值，不记录原始IP
+func (s *ReactionServiceImpl) Actor(userID *uint, ip, userAgent string) ReactionActor {
+	if userID != nil {
+		return ReactionActor{
+			Key:    "u:" + strconv.FormatUint(uint64(*userID), 10),
+			UserID: userID,
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.cfg.VisitorSecret))
+	mac.Write([]byte(ip + "\n" + userAgent))
+	return ReactionActor{Key: "v:" + hex.EncodeToString(mac.Sum(nil))[:40]}
+}
+
+// Toggle 切换表态，返回切换后是否处于已表态状态和内容最新的表态统计
+func (s *ReactionServiceImpl) Toggle(
+	ctx context.Context,
+	targetType string,
+	targetID uint,
+	kind string,
+	actor ReactionActor,
+) (bool, *domain.ReactionSummary, error) {
+	if !domain.IsReactionKind(kind) {
+		return false, nil, ErrInvalidReaction
+	}
+
+	exists, err := s.reactionRepo.TargetExists(ctx, targetType, targetID)
+	if err != nil {
+		return false, nil, err
+	}
+	if !exists {
+		return false, nil, ErrReactionTargetNotFound
+	}
+
+	added, err := s.reactionRepo.Toggle(ctx, &domain.Reaction{
+		TargetType: targetType,
+		TargetID:   targetID,
+		Kind:       kind,
+		Actor:      actor.Key,
+		UserID:     actor.UserID,
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	if added && s.events != nil {
+		s.events.Publish(ctx, event.ReactionAdded{
+			TargetType: targetType,
+			TargetID:   targetID,
+			Kind:       kind,
+			ActorKey:   actor.Key,
+			UserID:     actor.UserID,
+		})
+	}
+
+	summaries, err := s.GetSummaries(ctx, targetType, []uint{targetID}, actor)
+	if err != nil {
+		return false, nil, err
+	}
+	summary := summaries[targetID]
+	if s.events != nil {
+		s.events.Publish(ctx, event.ReactionChanged{
+			TargetType: targetType,
+			TargetID:   targetID,
+			Counts:     summary.Counts,
+		})
+	}
+	return added, &summary, nil
+}
+
+// GetSummaries 批量获取内容的表态统计和访问者自己的表态
+func (s *ReactionServiceImpl) GetSummaries(
+	ctx context.Context,
+	targetType string,
+	targetIDs []uint,
+	actor ReactionActor,
+) (map[uint]domain.ReactionSummary, error) {
+	counts, err := s.reactionRepo.CountByTargets(ctx, targetType, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+	mine, err := s.reactionRepo.FindActorKinds(ctx, targetType, targetIDs, actor.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[uint]domain.ReactionSummary, len(targetIDs))
+	for _, id := range targetIDs {
+		summary := domain.ReactionSummary{
+			Counts: counts[id],
+			Mine:   mine[id],
+		}
+		if summary.Counts == nil {
+			summary.Counts = map[string]int64{}
+		}
+		if summary.Mine == nil {
+			summary.Mine = []string{}
+		}
+		summaries[id] = summary
+	}
+	return summaries, nil
+}
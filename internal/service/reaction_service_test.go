@@ -0,0 +1,88 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockReactionRepository 模拟表态仓储
+type MockReactionRepository struct {
+	mock.Mock
+}
+
+func (m *MockReactionRepository) TargetExists(ctx context.Context, targetType string, targetID uint) (bool, error) {
+	args := m.Called(ctx, targetType, targetID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReactionRepository) Toggle(ctx context.Context, reaction *domain.Reaction) (bool, error) {
+	args := m.Called(ctx, reaction)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockReactionRepository) CountByTargets(ctx context.Context, targetType string, targetIDs []uint) (map[uint]map[string]int64, error) {
+	args := m.Called(ctx, targetType, targetIDs)
+	return args.Get(0).(map[uint]map[string]int64), args.Error(1)
+}
+
+func (m *MockReactionRepository) FindActorKinds(ctx context.Context, targetType string, targetIDs []uint, actor string) (map[uint][]string, error) {
+	args := m.Called(ctx, targetType, targetIDs, actor)
+	return args.Get(0).(map[uint][]string), args.Error(1)
+}
+
+// TestReactionActor 测试访问者标识
+func TestReactionActor(t *testing.T) {
+	reactionService := NewReactionService(new(MockReactionRepository), nil, config.ReactionConfig{VisitorSecret: "test-secret"})
+
+	userID := uint(7)
+	actor := reactionService.Actor(&userID, "1.2.3.4", "Mozilla")
+	assert.Equal(t, "u:7", actor.Key)
+	assert.Equal(t, &userID, actor.UserID)
+
+	// 匿名访问者的标识不包含原始IP，同一IP不同浏览器视为不同访问者
+	visitor := reactionService.Actor(nil, "1.2.3.4", "Mozilla")
+	assert.Len(t, visitor.Key, 42)
+	assert.NotContains(t, visitor.Key, "1.2.3.4")
+	assert.Nil(t, visitor.UserID)
+	assert.Equal(t, visitor, reactionService.Actor(nil, "1.2.3.4", "Mozilla"))
+	assert.NotEqual(t, visitor, reactionService.Actor(nil, "1.2.3.4", "curl"))
+}
+
+// TestToggleReaction 测试切换表态
+func TestToggleReaction(t *testing.T) {
+	reactionRepo := new(MockReactionRepository)
+	reactionService := NewReactionService(reactionRepo, nil, config.ReactionConfig{VisitorSecret: "test-secret"})
+	ctx := context.Background()
+	actor := ReactionActor{Key: "v:abc"}
+
+	// 不支持的表态类型
+	_, _, err := reactionService.Toggle(ctx, domain.ReactionTargetComment, 1, "angry", actor)
+	assert.ErrorIs(t, err, ErrInvalidReaction)
+
+	// 内容不存在
+	reactionRepo.On("TargetExists", ctx, domain.ReactionTargetComment, uint(2)).Return(false, nil)
+	_, _, err = reactionService.Toggle(ctx, domain.ReactionTargetComment, 2, domain.ReactionLike, actor)
+	assert.ErrorIs(t, err, ErrReactionTargetNotFound)
+
+	reactionRepo.On("TargetExists", ctx, domain.ReactionTargetComment, uint(1)).Return(true, nil)
+	reactionRepo.On("Toggle", ctx, mock.MatchedBy(func(r *domain.Reaction) bool {
+		return r.TargetID == 1 && r.Kind == domain.ReactionLike && r.Actor == "v:abc" && r.UserID == nil
+	})).Return(true, nil)
+	reactionRepo.On("CountByTargets", ctx, domain.ReactionTargetComment, []uint{1}).
+		Return(map[uint]map[string]int64{1: {domain.ReactionLike: 3}}, nil)
+	reactionRepo.On("FindActorKinds", ctx, domain.ReactionTargetComment, []uint{1}, "v:abc").
+		Return(map[uint][]string{1: {domain.ReactionLike}}, nil)
+
+	added, summary, err := reactionService.Toggle(ctx, domain.ReactionTargetComment, 1, domain.ReactionLike, actor)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, int64(3), summary.Counts[domain.ReactionLike])
+	assert.Equal(t, []string{domain.ReactionLike}, summary.Mine)
+
+	reactionRepo.AssertExpectations(t)
+}
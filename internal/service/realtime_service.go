@@ -0,0 +1,235 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/event"
+	"Lin_studio/internal/realtime"
+	"Lin_studio/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidRealtimeTopic 订阅主题格式错误或不存在
+	ErrInvalidRealtimeTopic = errors.New("无效的订阅主题")
+	// ErrRealtimeTopicForbidden 无权订阅该主题
+	ErrRealtimeTopicForbidden = errors.New("无权订阅该主题")
+)
+
+const (
+	// RealtimeTopicModeration 审核队列主题，只有管理员可以订阅
+	RealtimeTopicModeration = "moderation"
+	// maxRealtimeTopics 单个连接最多订阅的主题数量
+	maxRealtimeTopics = 20
+)
+
+// RealtimeService 实时推送服务接口，把领域事件转换为客户端可以订阅的主题消息
+//
+// 支持的主题:
+//   - <内容类型>:<ID>，例如 article:3，内容下评论的新增、审核结果和表态统计，所有人可订阅
+//   - user:<ID>，用户的新站内通知，只有本人可订阅
+//   - moderation，待审核评论和审核结果，只有管理员可订阅
+type RealtimeService interface {
+	Subscribe(bus event.Bus)
+	Connect(topics []string, userID *uint, role string, lastEventID uint64) (*realtime.Subscription, []realtime.Message, bool, error)
+	Disconnect(sub *realtime.Subscription)
+	HeartbeatInterval() time.Duration
+}
+
+// RealtimeServiceImpl 实时推送服务实现
+type RealtimeServiceImpl struct {
+	hub         *realtime.Hub
+	userRepo    repository.UserRepository
+	commentRepo repository.CommentRepository
+	cfg         config.RealtimeConfig
+}
+
+// NewRealtimeService 创建实时推送服务实例
+func NewRealtimeService(
+	userRepo repository.UserRepository,
+	commentRepo repository.CommentRepository,
+	cfg config.RealtimeConfig,
+) RealtimeService {
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = 15 * time.Second
+	}
+	return &RealtimeServiceImpl{
+		hub:         realtime.NewHub(cfg.ReplayBufferSize, cfg.ClientBufferSize),
+		userRepo:    userRepo,
+		commentRepo: commentRepo,
+		cfg:         cfg,
+	}
+}
+
+// HeartbeatInterval 返回心跳间隔
+func (s *RealtimeServiceImpl) HeartbeatInterval() time.Duration {
+	return s.cfg.HeartbeatInterval
+}
+
+// Connect 校验主题权限并订阅
+// lastEventID大于0时同时返回断线期间错过的消息，complete为false表示部分消息已无法补发
+func (s *RealtimeServiceImpl) Connect(
+	topics []string,
+	userID *uint,
+	role string,
+	lastEventID uint64,
+) (*realtime.Subscription, []realtime.Message, bool, error) {
+	if len(topics) == 0 || len(topics) > maxRealtimeTopics {
+		return nil, nil, false, ErrInvalidRealtimeTopic
+	}
+	for _, topic := range topics {
+		if err := authorizeRealtimeTopic(topic, userID, role); err != nil {
+			return nil, nil, false, err
+		}
+	}
+
+	sub, replay, complete := s.hub.Subscribe(topics, lastEventID)
+	return sub, replay, complete, nil
+}
+
+// Disconnect 取消订阅
+func (s *RealtimeServiceImpl) Disconnect(sub *realtime.Subscription) {
+	s.hub.Unsubscribe(sub)
+}
+
+// authorizeRealtimeTopic 校验主题格式和订阅权限
+func authorizeRealtimeTopic(topic string, userID *uint, role string) error {
+	if topic == RealtimeTopicModeration {
+		if role != "admin" {
+			return ErrRealtimeTopicForbidden
+		}
+		return nil
+	}
+
+	kind, idStr, ok := strings.Cut(topic, ":")
+	if !ok {
+		return ErrInvalidRealtimeTopic
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil || id == 0 {
+		return ErrInvalidRealtimeTopic
+	}
+
+	if kind == "user" {
+		if userID == nil || uint(id) != *userID {
+			return ErrRealtimeTopicForbidden
+		}
+		return nil
+	}
+	if _, ok := repository.LookupCommentTargetType(kind); !ok {
+		return ErrInvalidRealtimeTopic
+	}
+	return nil
+}
+
+// itemTopic 返回内容对应的主题
+func itemTopic(itemType string, itemID uint) string {
+	return fmt.Sprintf("%s:%d", itemType, itemID)
+}
+
+// Subscribe 订阅需要推送的领域事件
+func (s *RealtimeServiceImpl) Subscribe(bus event.Bus) {
+	bus.Subscribe(event.TopicCommentCreated, s.onCommentCreated)
+	bus.Subscribe(event.TopicCommentApproved, s.onCommentApproved)
+	bus.Subscribe(event.TopicCommentRejected, s.onCommentRejected)
+	bus.Subscribe(event.TopicReactionChanged, s.onReactionChanged)
+	bus.Subscribe(event.TopicNotificationCreated, s.onNotificationCreated)
+}
+
+// onCommentCreated 已批准的评论推送给内容的订阅者，待审核的评论推送到审核队列
+func (s *RealtimeServiceImpl) onCommentCreated(ctx context.Context, e event.Event) error {
+	created := e.(event.CommentCreated)
+	payload, err := s.commentPayload(ctx, created.Comment)
+	if err != nil {
+		return err
+	}
+
+	if created.Comment.Status == "approved" {
+		return s.hub.Publish(itemTopic(created.Comment.ItemType, created.Comment.ItemID), "comment.created", payload)
+	}
+	return s.hub.Publish(RealtimeTopicModeration, "comment.pending", map[string]interface{}{
+		"comment": payload,
+		"target":  created.Target,
+	})
+}
+
+// onCommentApproved 批准后的评论推送给内容的订阅者，并通知审核队列移除该评论
+func (s *RealtimeServiceImpl) onCommentApproved(ctx context.Context, e event.Event) error {
+	approved := e.(event.CommentApproved)
+	payload, err := s.commentPayload(ctx, approved.Comment)
+	if err != nil {
+		return err
+	}
+
+	if err := s.publishModerated(approved.Comment, approved.FromStatus); err != nil {
+		return err
+	}
+	return s.hub.Publish(itemTopic(approved.Comment.ItemType, approved.Comment.ItemID), "comment.approved", payload)
+}
+
+// onCommentRejected 已公开的评论被标记为垃圾信息时通知内容的订阅者移除
+func (s *RealtimeServiceImpl) onCommentRejected(ctx context.Context, e event.Event) error {
+	rejected := e.(event.CommentRejected)
+	if err := s.publishModerated(rejected.Comment, rejected.FromStatus); err != nil {
+		return err
+	}
+	if rejected.FromStatus != "approved" {
+		return nil
+	}
+	return s.hub.Publish(itemTopic(rejected.Comment.ItemType, rejected.Comment.ItemID), "comment.removed", map[string]interface{}{
+		"id": rejected.Comment.ID,
+	})
+}
+
+// publishModerated 通知审核队列评论的状态已改变
+func (s *RealtimeServiceImpl) publishModerated(comment *domain.Comment, fromStatus string) error {
+	return s.hub.Publish(RealtimeTopicModeration, "comment.moderated", map[string]interface{}{
+		"id":          comment.ID,
+		"from_status": fromStatus,
+		"to_status":   comment.Status,
+	})
+}
+
+// onReactionChanged 推送最新的表态统计，评论的表态推送到评论所在内容的主题
+func (s *RealtimeServiceImpl) onReactionChanged(ctx context.Context, e event.Event) error {
+	changed := e.(event.ReactionChanged)
+
+	topic := itemTopic(changed.TargetType, changed.TargetID)
+	if changed.TargetType == domain.ReactionTargetComment {
+		comment, err := s.commentRepo.FindByID(ctx, changed.TargetID)
+		if err != nil || comment == nil {
+			return err
+		}
+		topic = itemTopic(comment.ItemType, comment.ItemID)
+	}
+
+	return s.hub.Publish(topic, "reactions.updated", map[string]interface{}{
+		"target_type": changed.TargetType,
+		"target_id":   changed.TargetID,
+		"reactions":   changed.Counts,
+	})
+}
+
+// onNotificationCreated 新站内通知推送给接收者
+func (s *RealtimeServiceImpl) onNotificationCreated(ctx context.Context, e event.Event) error {
+	created := e.(event.NotificationCreated)
+	return s.hub.Publish(fmt.Sprintf("user:%d", created.Notification.UserID), "notification.created", created.Notification)
+}
+
+// commentPayload 返回推送的评论数据，不包含匿名评论者的邮箱
+func (s *RealtimeServiceImpl) commentPayload(ctx context.Context, comment *domain.Comment) (domain.CommentResponse, error) {
+	c := *comment
+	if c.UserID != nil && c.User == nil {
+		user, err := s.userRepo.FindByID(ctx, *c.UserID)
+		if err != nil {
+			return domain.CommentResponse{}, err
+		}
+		c.User = user
+	}
+	return c.ToResponse(), nil
+}
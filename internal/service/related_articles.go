@@ -0,0 +1,194 @@
+package service
+
+import (
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/utils"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// relatedArticlesLimit 每篇文章保存和返回的相关文章数量上限
+	relatedArticlesLimit = 10
+	// relatedMaxTerms 每篇文章参与内容相似度计算的词数，只保留TF-IDF权重最高的词
+	relatedMaxTerms = 100
+	// relatedMinRelevance 标签、分类和内容的相似度之和低于该值时不视为相关
+	relatedMinRelevance = 0.05
+	// relatedRecencyHalfLife 发布时间得分减半的时间
+	relatedRecencyHalfLife = 180 * 24 * time.Hour
+	// relatedRebuildDelay 已发布文章变化后延迟重新计算的时间，期间的多次变化只计算一次
+	relatedRebuildDelay = 30 * time.Second
+)
+
+// 相关文章各项得分的权重，每项得分都在0到1之间
+const (
+	relatedTagWeight      = 0.4
+	relatedCategoryWeight = 0.15
+	relatedContentWeight  = 0.35
+	relatedRecencyWeight  = 0.1
+)
+
+// relatedIgnorePattern 计算内容相似度时忽略的代码块和链接
+var relatedIgnorePattern = regexp.MustCompile("(?s)```.*?```|https?://\\S+")
+
+// relatedDocument 参与相关文章计算的文章特征
+type relatedDocument struct {
+	article *domain.Article
+	tags    map[uint]bool
+	terms   map[string]float64 // 归一化后的TF-IDF向量
+}
+
+// computeRelatedArticles 计算每篇文章的相关文章
+// 得分由共同标签(Jaccard系数)、相同分类、内容的TF-IDF余弦相似度和候选文章的发布时间加权组成
+func computeRelatedArticles(articles []domain.Article, now time.Time) []domain.ArticleRelated {
+	docs := make([]relatedDocument, len(articles))
+	counts := make([]map[string]int, len(articles))
+	df := make(map[string]int)
+	for i := range articles {
+		article := &articles[i]
+		docs[i].article = article
+		docs[i].tags = make(map[uint]bool, len(article.Tags))
+		for _, tag := range article.Tags {
+			docs[i].tags[tag.ID] = true
+		}
+		// 标题计两次，提高标题中词语的权重
+		counts[i] = relatedTerms(article.Title + "\n" + article.Title + "\n" + article.Excerpt + "\n" + article.Content)
+		for term := range counts[i] {
+			df[term]++
+		}
+	}
+	for i := range docs {
+		docs[i].terms = tfidfVector(counts[i], df, len(docs))
+	}
+
+	type candidate struct {
+		id    uint
+		score float64
+	}
+	var related []domain.ArticleRelated
+	for i := range docs {
+		var candidates []candidate
+		for j := range docs {
+			if i == j {
+				continue
+			}
+			relevance := relatedTagWeight*jaccard(docs[i].tags, docs[j].tags) +
+				relatedContentWeight*cosine(docs[i].terms, docs[j].terms)
+			if a, b := docs[i].article.CategoryID, docs[j].article.CategoryID; a != nil && b != nil && *a == *b {
+				relevance += relatedCategoryWeight
+			}
+			if relevance < relatedMinRelevance {
+				continue
+			}
+			score := relevance + relatedRecencyWeight*recency(docs[j].article, now)
+			candidates = append(candidates, candidate{id: docs[j].article.ID, score: score})
+		}
+
+		sort.Slice(candidates, func(a, b int) bool {
+			if candidates[a].score != candidates[b].score {
+				return candidates[a].score > candidates[b].score
+			}
+			return candidates[a].id > candidates[b].id
+		})
+		if len(candidates) > relatedArticlesLimit {
+			candidates = candidates[:relatedArticlesLimit]
+		}
+		for k, c := range candidates {
+			related = append(related, domain.ArticleRelated{
+				ArticleID: docs[i].article.ID,
+				RelatedID: c.id,
+				Position:  uint8(k + 1),
+				Score:     math.Round(c.score*1e4) / 1e4,
+			})
+		}
+	}
+	return related
+}
+
+// relatedTerms 统计文本中每个词的出现次数，忽略代码块、链接和单独出现的汉字
+func relatedTerms(text string) map[string]int {
+	counts := make(map[string]int)
+	utils.Tokenize(relatedIgnorePattern.ReplaceAllString(text, " "), func(token string) {
+		if utf8.RuneCountInString(token) >= 2 {
+			counts[token]++
+		}
+	})
+	return counts
+}
+
+// tfidfVector 计算词的TF-IDF权重，只保留权重最高的词并归一化为单位向量
+// 所有文章都包含的词权重为0，不参与计算
+func tfidfVector(counts map[string]int, df map[string]int, total int) map[string]float64 {
+	type weighted struct {
+		term   string
+		weight float64
+	}
+	terms := make([]weighted, 0, len(counts))
+	for term, count := range counts {
+		weight := (1 + math.Log(float64(count))) * math.Log(float64(total)/float64(df[term]))
+		if weight > 0 {
+			terms = append(terms, weighted{term, weight})
+		}
+	}
+	sort.Slice(terms, func(a, b int) bool {
+		if terms[a].weight != terms[b].weight {
+			return terms[a].weight > terms[b].weight
+		}
+		return terms[a].term < terms[b].term
+	})
+	if len(terms) > relatedMaxTerms {
+		terms = terms[:relatedMaxTerms]
+	}
+
+	var norm float64
+	for _, t := range terms {
+		norm += t.weight * t.weight
+	}
+	norm = math.Sqrt(norm)
+	vector := make(map[string]float64, len(terms))
+	for _, t := range terms {
+		vector[t.term] = t.weight / norm
+	}
+	return vector
+}
+
+// cosine 计算两个单位向量的余弦相似度
+func cosine(a, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var dot float64
+	for term, weight := range a {
+		dot += weight * b[term]
+	}
+	return dot
+}
+
+// jaccard 计算两个标签集合的Jaccard系数
+func jaccard(a, b map[uint]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for id := range a {
+		if b[id] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// recency 返回文章发布时间的得分，刚发布为1，每过半衰期减半
+func recency(article *domain.Article, now time.Time) float64 {
+	if !article.PublishedAt.Valid {
+		return 0
+	}
+	age := now.Sub(article.PublishedAt.Time)
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(relatedRecencyHalfLife))
+}
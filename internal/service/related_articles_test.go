@@ -0,0 +1,100 @@
+package service
+
+import (
+	"Lin_studio/internal/cache"
+	"Lin_studio/internal/domain"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestComputeRelatedArticles 测试相关文章按标签、分类、内容和发布时间排序
+func TestComputeRelatedArticles(t *testing.T) {
+	now := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	published := func(days int) sql.NullTime {
+		return sql.NullTime{Time: now.AddDate(0, 0, -days), Valid: true}
+	}
+	golang, backend := uint(1), uint(2)
+	goTag, dbTag, cssTag := domain.Tag{ID: 1}, domain.Tag{ID: 2}, domain.Tag{ID: 3}
+
+	articles := []domain.Article{
+		{ID: 1, Title: "Go 并发编程", Content: "goroutine 和 channel 的使用方法，并发安全", CategoryID: &golang, Tags: []domain.Tag{goTag}, PublishedAt: published(10)},
+		{ID: 2, Title: "Go 并发模式", Content: "使用 channel 实现 worker pool，goroutine 泄漏排查", CategoryID: &golang, Tags: []domain.Tag{goTag}, PublishedAt: published(400)},
+		{ID: 3, Title: "GORM 事务", Content: "数据库事务和连接池配置", CategoryID: &backend, Tags: []domain.Tag{goTag, dbTag}, PublishedAt: published(5)},
+		{ID: 4, Title: "CSS 布局", Content: "flex 和 grid 布局技巧", Tags: []domain.Tag{cssTag}, PublishedAt: published(1)},
+	}
+
+	related := computeRelatedArticles(articles, now)
+	byArticle := make(map[uint][]uint)
+	for _, r := range related {
+		byArticle[r.ArticleID] = append(byArticle[r.ArticleID], r.RelatedID)
+		assert.NotEqual(t, r.ArticleID, r.RelatedID)
+		assert.Greater(t, r.Score, 0.0)
+	}
+
+	// 标签、分类和内容都相同的文章排在最前，即使发布时间较早
+	assert.Equal(t, []uint{2, 3}, byArticle[1])
+	assert.Equal(t, []uint{1, 3}, byArticle[2])
+	// 没有共同标签、分类和内容的文章不视为相关
+	assert.Empty(t, byArticle[4])
+	assert.Equal(t, uint8(1), related[0].Position)
+}
+
+// TestRelatedTerms 测试中文按两字组合、英文按单词切分，忽略代码块和链接
+func TestRelatedTerms(t *testing.T) {
+	terms := relatedTerms("并发编程 Go channel\n```go\nfunc main() {}\n```\n见 https://example.com/go")
+	assert.Equal(t, map[string]int{"并发": 1, "发编": 1, "编程": 1, "go": 1, "channel": 1}, terms)
+}
+
+// TestRelatedArticles 测试相关文章的数量上限和重新计算
+func TestRelatedArticles(t *testing.T) {
+	articleRepo := new(MockArticleRepository)
+	s := NewArticleService(articleRepo, nil, nil, nil, nil, nil, cache.NewMemoryCache(100, time.Minute)).(*ArticleServiceImpl)
+	ctx := context.Background()
+
+	// 超过上限时按上限查询
+	articleRepo.On("FindRelated", mock.Anything, uint(1), relatedArticlesLimit).Return([]domain.Article{}, nil).Once()
+	articles, err := s.RelatedArticles(ctx, 1, 50)
+	require.NoError(t, err)
+	assert.Empty(t, articles)
+
+	articleRepo.On("FindPublishedWithTags", mock.Anything).Return([]domain.Article{{ID: 1}}, nil).Once()
+	articleRepo.On("ReplaceRelated", mock.Anything, []domain.ArticleRelated(nil)).Return(nil).Once()
+	require.NoError(t, s.RebuildRelatedArticles(ctx))
+
+	articleRepo.AssertExpectations(t)
+}
+
+// TestRefreshRelatedArticlesDebounced 测试文章变化后在后台合并重新计算相关文章
+func TestRefreshRelatedArticlesDebounced(t *testing.T) {
+	articleRepo := new(MockArticleRepository)
+	s := NewArticleService(articleRepo, nil, nil, nil, nil, nil, cache.NewMemoryCache(100, time.Minute)).(*ArticleServiceImpl)
+	s.relatedDelay = 20 * time.Millisecond
+
+	rebuilt := make(chan struct{}, 2)
+	articleRepo.On("FindPublishedWithTags", mock.Anything).Return([]domain.Article{{ID: 1}}, nil)
+	articleRepo.On("ReplaceRelated", mock.Anything, []domain.ArticleRelated(nil)).Return(nil).Run(func(mock.Arguments) {
+		rebuilt <- struct{}{}
+	})
+
+	for i := 0; i < 3; i++ {
+		s.refreshRelatedArticles()
+	}
+	select {
+	case <-rebuilt:
+	case <-time.After(time.Second):
+		t.Fatal("相关文章没有在后台重新计算")
+	}
+	// 等待期间的多次变化只计算一次
+	select {
+	case <-rebuilt:
+		t.Fatal("相关文章重复计算")
+	case <-time.After(100 * time.Millisecond):
+	}
+	articleRepo.AssertNumberOfCalls(t, "FindPublishedWithTags", 1)
+}
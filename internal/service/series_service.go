@@ -0,0 +1,268 @@
+package service
+
+import (
+	"Lin_studio/internal/cache"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ErrSeriesNotFound 系列不存在
+var ErrSeriesNotFound = errors.New("系列不存在")
+
+// SeriesService 系列服务接口
+type SeriesService interface {
+	GetAllSeries(ctx context.Context) ([]domain.SeriesResponse, error)
+	GetSeriesByID(ctx context.Context, id uint, includeDrafts bool) (*domain.SeriesResponse, error)
+	GetSeriesBySlug(ctx context.Context, slug string, includeDrafts bool) (*domain.SeriesResponse, error)
+	CreateSeries(ctx context.Context, title, slug, description, coverImage string, articleIDs []uint) (*domain.Series, error)
+	UpdateSeries(ctx context.Context, id, version uint, title, slug, description, coverImage string, articleIDs []uint) (*domain.Series, error)
+	DeleteSeries(ctx context.Context, id, version uint) error
+}
+
+// SeriesServiceImpl 系列服务实现
+type SeriesServiceImpl struct {
+	seriesRepo   repository.SeriesRepository
+	articleRepo  repository.ArticleRepository
+	mediaService MediaService
+	cache        cache.Cache
+}
+
+// NewSeriesService 创建系列服务实例
+func NewSeriesService(seriesRepo repository.SeriesRepository, articleRepo repository.ArticleRepository, mediaService MediaService, appCache cache.Cache) SeriesService {
+	return &SeriesServiceImpl{
+		seriesRepo:   seriesRepo,
+		articleRepo:  articleRepo,
+		mediaService: mediaService,
+		cache:        appCache,
+	}
+}
+
+// GetAllSeries 获取有已发布文章的系列，不包含文章列表，结果在系列或文章修改前缓存
+func (s *SeriesServiceImpl) GetAllSeries(ctx context.Context) ([]domain.SeriesResponse, error) {
+	tags := []string{cache.TagSeries, cache.TagArticle}
+	return cache.Remember(ctx, s.cache, "series:all", 0, tags, func() ([]domain.SeriesResponse, error) {
+		list, err := s.seriesRepo.FindAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		response := make([]domain.SeriesResponse, 0, len(list))
+		for i := range list {
+			parts, err := s.seriesParts(ctx, list[i].ID, false)
+			if err != nil {
+				return nil, err
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			response = append(response, list[i].ToResponse(parts, false))
+		}
+		return response, nil
+	})
+}
+
+// GetSeriesByID 根据ID获取系列和其中的文章，includeDrafts为false时只包含已发布的文章
+func (s *SeriesServiceImpl) GetSeriesByID(ctx context.Context, id uint, includeDrafts bool) (*domain.SeriesResponse, error) {
+	series, err := s.seriesRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, ErrSeriesNotFound
+	}
+	return s.seriesResponse(ctx, series, includeDrafts)
+}
+
+// GetSeriesBySlug 根据Slug获取系列和其中的文章，公开的系列页面结果在系列或文章修改前缓存
+func (s *SeriesServiceImpl) GetSeriesBySlug(ctx context.Context, slug string, includeDrafts bool) (*domain.SeriesResponse, error) {
+	load := func() (*domain.SeriesResponse, error) {
+		series, err := s.seriesRepo.FindBySlug(ctx, slug)
+		if err != nil {
+			return nil, err
+		}
+		if series == nil {
+			return nil, ErrSeriesNotFound
+		}
+		return s.seriesResponse(ctx, series, includeDrafts)
+	}
+	if includeDrafts {
+		return load()
+	}
+
+	key := fmt.Sprintf("series:slug:%s", slug)
+	tags := []string{cache.TagSeries, cache.TagArticle}
+	return cache.Remember(ctx, s.cache, key, 0, tags, load)
+}
+
+// CreateSeries 创建系列，articleIDs为按顺序排列的文章
+func (s *SeriesServiceImpl) CreateSeries(ctx context.Context, title, slug, description, coverImage string, articleIDs []uint) (*domain.Series, error) {
+	slug, err := s.validate(ctx, 0, title, slug, articleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	series := &domain.Series{
+		Title:       title,
+		Slug:        slug,
+		Description: description,
+		CoverImage:  utils.StripURLSignature(coverImage),
+		Version:     1,
+	}
+	if err := s.seriesRepo.Create(ctx, series, articleIDs); err != nil {
+		return nil, err
+	}
+	cache.InvalidateLogged(ctx, s.cache, cache.TagSeries)
+	s.syncMediaReferences(ctx, series)
+
+	return series, nil
+}
+
+// UpdateSeries 更新系列并替换其中的文章
+// version为客户端读取时的版本号，大于0且与当前版本不一致时返回ErrVersionConflict
+func (s *SeriesServiceImpl) UpdateSeries(ctx context.Context, id, version uint, title, slug, description, coverImage string, articleIDs []uint) (*domain.Series, error) {
+	series, err := s.seriesRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, ErrSeriesNotFound
+	}
+	if version > 0 && series.Version != version {
+		return nil, ErrVersionConflict
+	}
+
+	slug, err = s.validate(ctx, id, title, slug, articleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	series.Title = title
+	series.Slug = slug
+	series.Description = description
+	series.CoverImage = utils.StripURLSignature(coverImage)
+	if err := s.seriesRepo.Update(ctx, series, articleIDs); err != nil {
+		return nil, err
+	}
+	cache.InvalidateLogged(ctx, s.cache, cache.TagSeries)
+	s.syncMediaReferences(ctx, series)
+
+	return series, nil
+}
+
+// DeleteSeries 删除系列，其中的文章不会被删除，version大于0时只删除该版本
+func (s *SeriesServiceImpl) DeleteSeries(ctx context.Context, id, version uint) error {
+	series, err := s.seriesRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if series == nil {
+		return ErrSeriesNotFound
+	}
+	if version > 0 && series.Version != version {
+		return ErrVersionConflict
+	}
+
+	if err := s.seriesRepo.Delete(ctx, id, version); err != nil {
+		return err
+	}
+	cache.InvalidateLogged(ctx, s.cache, cache.TagSeries)
+
+	// 释放系列封面引用的媒体文件
+	if err := s.mediaService.SyncReferences(ctx, domain.MediaRefSeries, id); err != nil {
+		log.Printf("清除系列 %d 的媒体引用失败: %v", id, err)
+	}
+	return nil
+}
+
+// syncMediaReferences 同步系列封面引用的媒体文件，失败不影响系列保存
+func (s *SeriesServiceImpl) syncMediaReferences(ctx context.Context, series *domain.Series) {
+	if err := s.mediaService.SyncReferences(ctx, domain.MediaRefSeries, series.ID, series.CoverImage); err != nil {
+		log.Printf("同步系列 %d 的媒体引用失败: %v", series.ID, err)
+	}
+}
+
+// validate 检查系列的标题、别名和文章，返回要保存的别名，id为0表示新建
+// 未填写别名时根据标题生成；文章必须存在、不能重复，也不能已属于其他系列
+func (s *SeriesServiceImpl) validate(ctx context.Context, id uint, title, slug string, articleIDs []uint) (string, error) {
+	if strings.TrimSpace(title) == "" {
+		return "", errors.New("系列标题不能为空")
+	}
+
+	if strings.TrimSpace(slug) == "" {
+		slug = title
+	}
+	slug = utils.GenerateSlug(slug)
+	if slug == "" {
+		return "", errors.New("无法根据标题生成别名，请填写英文或数字别名")
+	}
+	existing, err := s.seriesRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.ID != id {
+		return "", errors.New("已存在相同别名的系列")
+	}
+
+	seen := make(map[uint]bool, len(articleIDs))
+	for _, articleID := range articleIDs {
+		if seen[articleID] {
+			return "", fmt.Errorf("文章 %d 重复", articleID)
+		}
+		seen[articleID] = true
+
+		article, err := s.articleRepo.FindByID(ctx, articleID)
+		if err != nil {
+			return "", err
+		}
+		if article == nil {
+			return "", fmt.Errorf("文章 %d 不存在", articleID)
+		}
+	}
+
+	seriesIDs, err := s.seriesRepo.FindArticleSeriesIDs(ctx, articleIDs)
+	if err != nil {
+		return "", err
+	}
+	for _, articleID := range articleIDs {
+		if seriesID, ok := seriesIDs[articleID]; ok && seriesID != id {
+			return "", fmt.Errorf("文章 %d 已属于其他系列", articleID)
+		}
+	}
+	return slug, nil
+}
+
+// seriesResponse 加载系列中的文章并生成响应数据
+func (s *SeriesServiceImpl) seriesResponse(ctx context.Context, series *domain.Series, includeDrafts bool) (*domain.SeriesResponse, error) {
+	parts, err := s.seriesParts(ctx, series.ID, includeDrafts)
+	if err != nil {
+		return nil, err
+	}
+	response := series.ToResponse(parts, true)
+	return &response, nil
+}
+
+// seriesParts 按顺序获取系列中的文章，includeDrafts为false时只返回已发布的文章
+func (s *SeriesServiceImpl) seriesParts(ctx context.Context, seriesID uint, includeDrafts bool) ([]domain.Article, error) {
+	parts, err := s.seriesRepo.FindParts(ctx, seriesID)
+	if err != nil || includeDrafts {
+		return parts, err
+	}
+	return publishedParts(parts, 0), nil
+}
+
+// publishedParts 返回已发布的文章，keepID对应的文章即使未发布也保留，用于草稿预览时显示其在系列中的位置
+func publishedParts(parts []domain.Article, keepID uint) []domain.Article {
+	published := make([]domain.Article, 0, len(parts))
+	for _, part := range parts {
+		if part.Status == "published" || part.ID == keepID {
+			published = append(published, part)
+		}
+	}
+	return published
+}
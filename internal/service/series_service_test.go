@@ -0,0 +1,282 @@
+package service
+
+import (
+	"Lin_studio/internal/cache"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/storage"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockSeriesRepository 模拟系列仓储
+type MockSeriesRepository struct {
+	mock.Mock
+}
+
+func (m *MockSeriesRepository) Create(ctx context.Context, series *domain.Series, articleIDs []uint) error {
+	args := m.Called(ctx, series, articleIDs)
+	return args.Error(0)
+}
+
+func (m *MockSeriesRepository) FindByID(ctx context.Context, id uint) (*domain.Series, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Series), args.Error(1)
+}
+
+func (m *MockSeriesRepository) FindBySlug(ctx context.Context, slug string) (*domain.Series, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Series), args.Error(1)
+}
+
+func (m *MockSeriesRepository) FindByArticleID(ctx context.Context, articleID uint) (*domain.Series, error) {
+	args := m.Called(ctx, articleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Series), args.Error(1)
+}
+
+func (m *MockSeriesRepository) FindAll(ctx context.Context) ([]domain.Series, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.Series), args.Error(1)
+}
+
+func (m *MockSeriesRepository) FindParts(ctx context.Context, seriesID uint) ([]domain.Article, error) {
+	args := m.Called(ctx, seriesID)
+	return args.Get(0).([]domain.Article), args.Error(1)
+}
+
+func (m *MockSeriesRepository) FindArticleSeriesIDs(ctx context.Context, articleIDs []uint) (map[uint]uint, error) {
+	args := m.Called(ctx, articleIDs)
+	return args.Get(0).(map[uint]uint), args.Error(1)
+}
+
+func (m *MockSeriesRepository) Update(ctx context.Context, series *domain.Series, articleIDs []uint) error {
+	args := m.Called(ctx, series, articleIDs)
+	return args.Error(0)
+}
+
+func (m *MockSeriesRepository) Delete(ctx context.Context, id, version uint) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+// testSeriesParts 返回系列中按顺序排列的三篇文章，第二篇未发布
+func testSeriesParts() []domain.Article {
+	return []domain.Article{
+		{ID: 11, Title: "入门", Slug: "part-1", Status: "published", ReadTime: 5},
+		{ID: 12, Title: "进阶", Slug: "part-2", Status: "draft", ReadTime: 8},
+		{ID: 13, Title: "实战", Slug: "part-3", Status: "published", ReadTime: 10},
+	}
+}
+
+// TestSeriesPages 测试系列页面只向读者显示已发布的文章和阅读进度，编辑者可以看到全部文章
+func TestSeriesPages(t *testing.T) {
+	seriesRepo := new(MockSeriesRepository)
+	s := NewSeriesService(seriesRepo, new(MockArticleRepository), nil, cache.NewMemoryCache(100, time.Minute))
+	ctx := context.Background()
+	series := &domain.Series{ID: 1, Title: "Go 教程", Slug: "go-tutorial", Version: 2}
+
+	seriesRepo.On("FindBySlug", mock.Anything, "go-tutorial").Return(series, nil)
+	seriesRepo.On("FindParts", mock.Anything, uint(1)).Return(testSeriesParts(), nil)
+
+	public, err := s.GetSeriesBySlug(ctx, "go-tutorial", false)
+	require.NoError(t, err)
+	require.Len(t, public.Parts, 2)
+	assert.Equal(t, 2, public.PartsCount)
+	assert.Equal(t, 15, public.ReadTime)
+	assert.Equal(t, []int{1, 2}, []int{public.Parts[0].Position, public.Parts[1].Position})
+	assert.Equal(t, []int{50, 100}, []int{public.Parts[0].Progress, public.Parts[1].Progress})
+	assert.Equal(t, uint(13), public.Parts[1].ID)
+
+	drafts, err := s.GetSeriesBySlug(ctx, "go-tutorial", true)
+	require.NoError(t, err)
+	assert.Len(t, drafts.Parts, 3)
+	assert.Equal(t, 2, drafts.PublishedCount)
+	assert.Equal(t, 33, drafts.Parts[0].Progress)
+
+	seriesRepo.On("FindBySlug", mock.Anything, "missing").Return(nil, nil)
+	_, err = s.GetSeriesBySlug(ctx, "missing", false)
+	assert.ErrorIs(t, err, ErrSeriesNotFound)
+}
+
+// TestArticleSeriesNav 测试文章的上一篇、下一篇跳过未发布的文章，草稿预览时保留草稿本身的位置
+func TestArticleSeriesNav(t *testing.T) {
+	series := &domain.Series{ID: 1, Title: "Go 教程", Slug: "go-tutorial"}
+	parts := testSeriesParts()
+
+	nav := domain.NewArticleSeriesNav(series, publishedParts(parts, 11), 11)
+	require.NotNil(t, nav)
+	assert.Nil(t, nav.Prev)
+	assert.Equal(t, "part-3", nav.Next.Slug)
+	assert.Equal(t, 2, nav.Total)
+
+	nav = domain.NewArticleSeriesNav(series, publishedParts(parts, 12), 12)
+	require.NotNil(t, nav)
+	assert.Equal(t, 2, nav.Position)
+	assert.Equal(t, "part-1", nav.Prev.Slug)
+	assert.Equal(t, "part-3", nav.Next.Slug)
+
+	assert.Nil(t, domain.NewArticleSeriesNav(series, parts, 99))
+}
+
+// TestSeriesValidate 测试创建和更新系列时的别名和文章检查
+func TestSeriesValidate(t *testing.T) {
+	seriesRepo := new(MockSeriesRepository)
+	articleRepo := new(MockArticleRepository)
+	mediaRepo := new(MockMediaRepository)
+	mediaService := NewMediaService(mediaRepo, storage.NewLocalStorage(t.TempDir(), "/uploads"), nil, nil, nil)
+	s := NewSeriesService(seriesRepo, articleRepo, mediaService, cache.NewMemoryCache(100, time.Minute))
+	ctx := context.Background()
+	mediaRepo.On("ReplaceReferences", mock.Anything, domain.MediaRefSeries, mock.Anything, mock.Anything).Return(nil)
+
+	seriesRepo.On("FindBySlug", mock.Anything, "go-tutorial").Return(nil, nil)
+	articleRepo.On("FindByID", mock.Anything, uint(11)).Return(&domain.Article{ID: 11}, nil)
+	articleRepo.On("FindByID", mock.Anything, uint(12)).Return(&domain.Article{ID: 12}, nil)
+	articleRepo.On("FindByID", mock.Anything, uint(99)).Return(nil, nil)
+
+	_, err := s.CreateSeries(ctx, "并发教程", "", "", "", nil)
+	assert.EqualError(t, err, "无法根据标题生成别名，请填写英文或数字别名")
+	_, err = s.CreateSeries(ctx, "Go 教程", "Go Tutorial", "", "", []uint{11, 11})
+	assert.EqualError(t, err, "文章 11 重复")
+	_, err = s.CreateSeries(ctx, "Go 教程", "go-tutorial", "", "", []uint{99})
+	assert.EqualError(t, err, "文章 99 不存在")
+
+	// 文章已属于其他系列
+	seriesRepo.On("FindArticleSeriesIDs", mock.Anything, []uint{11, 12}).Return(map[uint]uint{12: 5}, nil).Once()
+	_, err = s.CreateSeries(ctx, "Go 教程", "go-tutorial", "", "", []uint{11, 12})
+	assert.EqualError(t, err, "文章 12 已属于其他系列")
+
+	// 更新时文章已属于当前系列
+	current := &domain.Series{ID: 5, Title: "Go 教程", Slug: "go-tutorial", Version: 3}
+	seriesRepo.On("FindByID", mock.Anything, uint(5)).Return(current, nil)
+	seriesRepo.On("FindArticleSeriesIDs", mock.Anything, []uint{12, 11}).Return(map[uint]uint{12: 5}, nil).Once()
+	seriesRepo.On("Update", mock.Anything, current, []uint{12, 11}).Return(nil).Once()
+
+	_, err = s.UpdateSeries(ctx, 5, 2, "Go 教程", "", "", "", []uint{12, 11})
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	updated, err := s.UpdateSeries(ctx, 5, 3, "Go 教程", "go-tutorial", "入门到实战", "", []uint{12, 11})
+	require.NoError(t, err)
+	assert.Equal(t, "入门到实战", updated.Description)
+
+	seriesRepo.AssertExpectations(t)
+}
+
+// memoryMediaRepository 在内存中保存媒体文件和引用关系的仓储，用于检查清理结果
+type memoryMediaRepository struct {
+	*MockMediaRepository
+	media []domain.Media
+	refs  map[string][]uint
+}
+
+func (m *memoryMediaRepository) FindByKeys(ctx context.Context, keys []string) ([]domain.Media, error) {
+	var found []domain.Media
+	for _, media := range m.media {
+		for _, key := range keys {
+			if media.Key == key {
+				found = append(found, media)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryMediaRepository) FindByHashes(ctx context.Context, hashes []string) ([]domain.Media, error) {
+	var found []domain.Media
+	for _, media := range m.media {
+		for _, hash := range hashes {
+			if media.Hash == hash {
+				found = append(found, media)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryMediaRepository) ReplaceReferences(ctx context.Context, refType string, refID uint, mediaIDs []uint) error {
+	m.refs[fmt.Sprintf("%s:%d", refType, refID)] = mediaIDs
+	return nil
+}
+
+func (m *memoryMediaRepository) FindUnreferenced(ctx context.Context, before time.Time) ([]domain.Media, error) {
+	referenced := make(map[uint]bool)
+	for _, ids := range m.refs {
+		for _, id := range ids {
+			referenced[id] = true
+		}
+	}
+	var found []domain.Media
+	for _, media := range m.media {
+		if !referenced[media.ID] && media.LastUploadedAt.Before(before) {
+			found = append(found, media)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryMediaRepository) Delete(ctx context.Context, id uint) error {
+	for i := range m.media {
+		if m.media[i].ID == id {
+			m.media = append(m.media[:i], m.media[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// TestSeriesCoverReferences 测试系列封面登记为媒体引用，清理未引用文件时不会被删除，删除系列后才会被清理
+func TestSeriesCoverReferences(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir(), "/uploads")
+	hash := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	key := "media/01/" + hash + ".png"
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("png"), 3, "image/png"))
+
+	mediaRepo := &memoryMediaRepository{
+		MockMediaRepository: new(MockMediaRepository),
+		media:               []domain.Media{{ID: 3, Key: key, Hash: hash, LastUploadedAt: time.Now().Add(-48 * time.Hour)}},
+		refs:                make(map[string][]uint),
+	}
+	mediaService := NewMediaService(mediaRepo, store, nil, nil, nil)
+
+	seriesRepo := new(MockSeriesRepository)
+	s := NewSeriesService(seriesRepo, new(MockArticleRepository), mediaService, cache.NewMemoryCache(100, time.Minute))
+	seriesRepo.On("FindBySlug", mock.Anything, "go-tutorial").Return(nil, nil)
+	seriesRepo.On("FindArticleSeriesIDs", mock.Anything, []uint(nil)).Return(map[uint]uint{}, nil)
+	seriesRepo.On("Create", mock.Anything, mock.Anything, []uint(nil)).Run(func(args mock.Arguments) {
+		args.Get(1).(*domain.Series).ID = 5
+	}).Return(nil)
+
+	series, err := s.CreateSeries(ctx, "Go 教程", "go-tutorial", "", store.URL(key)+"?expires=1&signature=abc", nil)
+	require.NoError(t, err)
+	assert.Equal(t, store.URL(key), series.CoverImage)
+
+	removed, err := mediaService.CleanupUnreferenced(ctx, 24*time.Hour, false)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+	assert.Len(t, mediaRepo.media, 1)
+
+	// 删除系列后封面不再被引用
+	seriesRepo.On("FindByID", mock.Anything, uint(5)).Return(series, nil)
+	seriesRepo.On("Delete", mock.Anything, uint(5), uint(0)).Return(nil)
+	require.NoError(t, s.DeleteSeries(ctx, 5, 0))
+
+	removed, err = mediaService.CleanupUnreferenced(ctx, 24*time.Hour, false)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, uint(3), removed[0].ID)
+	assert.Empty(t, mediaRepo.media)
+}
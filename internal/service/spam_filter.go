@@ -0,0 +1,313 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 反垃圾检查结论
+const (
+	SpamVerdictApprove = "approve" // 自动批准
+	SpamVerdictHold    = "hold"    // 等待人工审核
+	SpamVerdictReject  = "reject"  // 判定为垃圾信息
+)
+
+var (
+	linkPattern       = regexp.MustCompile(`(?i)https?://|www\.|\[url`)
+	linkDomainPattern = regexp.MustCompile(`(?i)https?://([^/\s?#"'<>]+)`)
+)
+
+// SpamInput 待检查的评论及提交信息
+type SpamInput struct {
+	Content        string
+	AnonymousName  string
+	AnonymousEmail string
+	IP             string
+	UserAgent      string
+	Honeypot       string // 表单中对用户隐藏的字段，正常用户不会填写
+	FormToken      string // 打开评论表单时获取的令牌
+}
+
+// SpamSignal 单个检查项的结果
+type SpamSignal struct {
+	Score   float64 // 正数倾向垃圾信息，负数倾向正常评论
+	Reason  string
+	Discard bool // 确定为机器人提交，不保存评论
+}
+
+// SpamChecker 反垃圾检查项，没有结论时返回nil
+type SpamChecker interface {
+	Check(ctx context.Context, input *SpamInput) (*SpamSignal, error)
+}
+
+// SpamResult 检查链的综合结果
+type SpamResult struct {
+	Score   float64
+	Reasons []string
+	Verdict string
+	Discard bool
+}
+
+// SpamFilter 反垃圾检查链接口
+type SpamFilter interface {
+	Check(ctx context.Context, input *SpamInput) (*SpamResult, error)
+	Train(ctx context.Context, comment *domain.Comment, class string) error
+	IssueToken() string
+}
+
+// SpamFilterImpl 反垃圾检查链实现，依次累加各检查项的分数
+type SpamFilterImpl struct {
+	checkers []SpamChecker
+	spamRepo repository.SpamRepository
+	cfg      config.SpamConfig
+}
+
+// NewSpamFilter 创建反垃圾检查链，默认包含隐藏字段、表单令牌、链接数量、屏蔽词和贝叶斯分类检查
+// 传入checkers时追加到默认检查项之后
+func NewSpamFilter(spamRepo repository.SpamRepository, cfg config.SpamConfig, checkers ...SpamChecker) SpamFilter {
+	tokens := &formTokenChecker{secret: cfg.TokenSecret, minAge: cfg.MinSubmitTime, maxAge: cfg.TokenMaxAge, now: time.Now}
+	defaults := []SpamChecker{
+		honeypotChecker{},
+		tokens,
+		linkChecker{maxLinks: cfg.MaxLinks},
+		newBlocklistChecker(cfg.Blocklist),
+		&bayesChecker{spamRepo: spamRepo, minTrain: cfg.BayesMinTrain},
+	}
+	return &SpamFilterImpl{
+		checkers: append(defaults, checkers...),
+		spamRepo: spamRepo,
+		cfg:      cfg,
+	}
+}
+
+// Check 运行检查链并给出结论
+// 单个检查项出错时跳过该项，避免依赖故障导致无法评论
+func (f *SpamFilterImpl) Check(ctx context.Context, input *SpamInput) (*SpamResult, error) {
+	result := &SpamResult{}
+	for _, checker := range f.checkers {
+		signal, err := checker.Check(ctx, input)
+		if err != nil {
+			log.Printf("反垃圾检查失败: %v", err)
+			continue
+		}
+		if signal == nil {
+			continue
+		}
+
+		result.Score += signal.Score
+		if signal.Reason != "" {
+			result.Reasons = append(result.Reasons, signal.Reason)
+		}
+		if signal.Discard {
+			result.Discard = true
+			result.Verdict = SpamVerdictReject
+			return result, nil
+		}
+	}
+
+	switch {
+	case result.Score >= f.cfg.RejectScore:
+		result.Verdict = SpamVerdictReject
+	case result.Score < f.cfg.ApproveScore:
+		result.Verdict = SpamVerdictApprove
+	default:
+		result.Verdict = SpamVerdictHold
+	}
+	return result, nil
+}
+
+// Train 将评论作为垃圾信息或正常评论样本训练贝叶斯分类器
+func (f *SpamFilterImpl) Train(ctx context.Context, comment *domain.Comment, class string) error {
+	return f.spamRepo.Train(ctx, comment.ID, spamTokens(comment.Content+" "+comment.AnonymousName), class)
+}
+
+// IssueToken 签发评论表单令牌，记录签发时间用于检查提交速度
+func (f *SpamFilterImpl) IssueToken() string {
+	issued := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return issued + "." + signFormToken(f.cfg.TokenSecret, issued)
+}
+
+// signFormToken 计算表单令牌签名
+func signFormToken(secret, issued string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("comment-form\n" + issued))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// honeypotChecker 隐藏字段检查，填写了隐藏字段的提交来自机器人
+type honeypotChecker struct{}
+
+// Check 检查隐藏字段
+func (honeypotChecker) Check(ctx context.Context, input *SpamInput) (*SpamSignal, error) {
+	if strings.TrimSpace(input.Honeypot) == "" {
+		return nil, nil
+	}
+	return &SpamSignal{Score: 10, Reason: "填写了隐藏字段", Discard: true}, nil
+}
+
+// formTokenChecker 表单令牌检查，缺少令牌、令牌无效或提交过快时计分
+type formTokenChecker struct {
+	secret string
+	minAge time.Duration
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// Check 检查表单令牌
+func (c *formTokenChecker) Check(ctx context.Context, input *SpamInput) (*SpamSignal, error) {
+	issued, signature, ok := strings.Cut(input.FormToken, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signFormToken(c.secret, issued))) {
+		return &SpamSignal{Score: 0.6, Reason: "缺少有效的表单令牌"}, nil
+	}
+
+	ms, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return &SpamSignal{Score: 0.6, Reason: "缺少有效的表单令牌"}, nil
+	}
+	age := c.now().Sub(time.UnixMilli(ms))
+	switch {
+	case age < c.minAge:
+		return &SpamSignal{Score: 1, Reason: fmt.Sprintf("提交过快(%.1f秒)", age.Seconds())}, nil
+	case c.maxAge > 0 && age > c.maxAge:
+		return &SpamSignal{Score: 0.3, Reason: "表单令牌已过期"}, nil
+	}
+	return nil, nil
+}
+
+// linkChecker 链接数量检查，超出允许数量的每个链接计分
+type linkChecker struct {
+	maxLinks int
+}
+
+// Check 统计评论内容和昵称中的链接
+func (c linkChecker) Check(ctx context.Context, input *SpamInput) (*SpamSignal, error) {
+	score := 0.0
+	links := len(linkPattern.FindAllStringIndex(input.Content, -1))
+	if links > c.maxLinks {
+		score += 0.4 * float64(links-c.maxLinks)
+	}
+	// 昵称中出现链接通常是推广
+	if linkPattern.MatchString(input.AnonymousName) {
+		score += 0.5
+		links++
+	}
+	if score == 0 {
+		return nil, nil
+	}
+	return &SpamSignal{Score: score, Reason: fmt.Sprintf("包含%d个链接", links)}, nil
+}
+
+// blocklistChecker 屏蔽词检查，匹配评论内容、昵称、邮箱和IP
+type blocklistChecker struct {
+	terms []string
+}
+
+// newBlocklistChecker 创建屏蔽词检查项，屏蔽词不区分大小写
+func newBlocklistChecker(terms []string) blocklistChecker {
+	c := blocklistChecker{}
+	for _, term := range terms {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term != "" {
+			c.terms = append(c.terms, term)
+		}
+	}
+	return c
+}
+
+// Check 检查屏蔽词
+func (c blocklistChecker) Check(ctx context.Context, input *SpamInput) (*SpamSignal, error) {
+	text := strings.ToLower(strings.Join([]string{input.Content, input.AnonymousName, input.AnonymousEmail, input.IP}, "\n"))
+	var matched []string
+	for _, term := range c.terms {
+		if strings.Contains(text, term) {
+			matched = append(matched, term)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	return &SpamSignal{Score: float64(len(matched)), Reason: "命中屏蔽词: " + strings.Join(matched, ",")}, nil
+}
+
+// bayesChecker 朴素贝叶斯分类检查，使用管理员审核过的评论训练
+// 分数为2p-1，p为垃圾信息概率，倾向正常评论时为负数
+type bayesChecker struct {
+	spamRepo repository.SpamRepository
+	minTrain int64
+}
+
+// Check 计算评论为垃圾信息的概率
+func (c *bayesChecker) Check(ctx context.Context, input *SpamInput) (*SpamSignal, error) {
+	spamDocs, hamDocs, err := c.spamRepo.CountSamples(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// 样本不足时分类结果不可靠
+	if spamDocs < c.minTrain || hamDocs < c.minTrain {
+		return nil, nil
+	}
+
+	tokens := spamTokens(input.Content + " " + input.AnonymousName)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	counts, err := c.spamRepo.FindTokens(ctx, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	p := bayesSpamProbability(tokens, counts, spamDocs, hamDocs)
+	return &SpamSignal{Score: 2*p - 1, Reason: fmt.Sprintf("贝叶斯分类垃圾概率%.2f", p)}, nil
+}
+
+// bayesSpamProbability 按伯努利朴素贝叶斯计算垃圾信息概率，词频使用拉普拉斯平滑
+// 未训练过的词不参与计算
+func bayesSpamProbability(tokens []string, counts map[string]domain.SpamToken, spamDocs, hamDocs int64) float64 {
+	logOdds := math.Log(float64(spamDocs) / float64(hamDocs))
+	for _, token := range tokens {
+		count, ok := counts[token]
+		if !ok || count.SpamCount+count.HamCount == 0 {
+			continue
+		}
+		pSpam := (float64(count.SpamCount) + 1) / (float64(spamDocs) + 2)
+		pHam := (float64(count.HamCount) + 1) / (float64(hamDocs) + 2)
+		logOdds += math.Log(pSpam / pHam)
+	}
+	return 1 / (1 + math.Exp(-logOdds))
+}
+
+// spamTokens 将文本切分为分类器使用的去重后的词，链接额外记录域名
+func spamTokens(text string) []string {
+	const maxTokens = 300
+
+	text = strings.ToLower(text)
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(token string) {
+		if len(token) > 64 || seen[token] || len(tokens) >= maxTokens {
+			return
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+
+	for _, m := range linkDomainPattern.FindAllStringSubmatch(text, -1) {
+		add("link:" + m[1])
+	}
+
+	utils.Tokenize(text, add)
+	return tokens
+}
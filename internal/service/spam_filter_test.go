@@ -0,0 +1,126 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSpamRepository 模拟反垃圾分类器仓储
+type MockSpamRepository struct {
+	mock.Mock
+}
+
+func (m *MockSpamRepository) FindTokens(ctx context.Context, tokens []string) (map[string]domain.SpamToken, error) {
+	args := m.Called(ctx, tokens)
+	return args.Get(0).(map[string]domain.SpamToken), args.Error(1)
+}
+
+func (m *MockSpamRepository) CountSamples(ctx context.Context) (int64, int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockSpamRepository) Train(ctx context.Context, commentID uint, tokens []string, class string) error {
+	args := m.Called(ctx, commentID, tokens, class)
+	return args.Error(0)
+}
+
+// testSpamConfig 测试使用的反垃圾配置
+var testSpamConfig = config.SpamConfig{
+	TokenSecret:   "test-secret",
+	MinSubmitTime: 3 * time.Second,
+	TokenMaxAge:   time.Hour,
+	MaxLinks:      1,
+	Blocklist:     []string{"Casino"},
+	ApproveScore:  -0.5,
+	RejectScore:   1,
+	BayesMinTrain: 2,
+}
+
+// formTokenAt 生成指定时间签发的表单令牌
+func formTokenAt(t time.Time) string {
+	issued := strconv.FormatInt(t.UnixMilli(), 10)
+	return issued + "." + signFormToken(testSpamConfig.TokenSecret, issued)
+}
+
+// TestSpamFilterVerdicts 测试检查链按分数给出结论
+func TestSpamFilterVerdicts(t *testing.T) {
+	spamRepo := new(MockSpamRepository)
+	// 样本不足，不启用贝叶斯分类
+	spamRepo.On("CountSamples", mock.Anything).Return(int64(0), int64(0), nil)
+
+	filter := NewSpamFilter(spamRepo, testSpamConfig)
+	ctx := context.Background()
+	valid := formTokenAt(time.Now().Add(-time.Minute))
+
+	// 填写隐藏字段直接丢弃
+	result, err := filter.Check(ctx, &SpamInput{Content: "你好", Honeypot: "http://spam.example", FormToken: valid})
+	assert.NoError(t, err)
+	assert.True(t, result.Discard)
+	assert.Equal(t, SpamVerdictReject, result.Verdict)
+
+	// 正常提交在分类器训练前等待审核
+	result, err = filter.Check(ctx, &SpamInput{Content: "写得很清楚，谢谢分享", FormToken: valid})
+	assert.NoError(t, err)
+	assert.Equal(t, SpamVerdictHold, result.Verdict)
+	assert.Zero(t, result.Score)
+
+	// 缺少令牌只计分不拒绝
+	result, err = filter.Check(ctx, &SpamInput{Content: "写得很清楚，谢谢分享"})
+	assert.NoError(t, err)
+	assert.Equal(t, SpamVerdictHold, result.Verdict)
+
+	// 提交过快且包含多个链接
+	result, err = filter.Check(ctx, &SpamInput{
+		Content:   "看这里 https://a.example https://b.example",
+		FormToken: formTokenAt(time.Now()),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, SpamVerdictReject, result.Verdict)
+	assert.Len(t, result.Reasons, 2)
+
+	// 屏蔽词不区分大小写
+	result, err = filter.Check(ctx, &SpamInput{Content: "best CASINO online", FormToken: valid})
+	assert.NoError(t, err)
+	assert.Equal(t, SpamVerdictReject, result.Verdict)
+}
+
+// TestSpamFilterBayes 测试贝叶斯分类结果影响结论
+func TestSpamFilterBayes(t *testing.T) {
+	spamRepo := new(MockSpamRepository)
+	spamRepo.On("CountSamples", mock.Anything).Return(int64(10), int64(10), nil)
+	spamRepo.On("FindTokens", mock.Anything, mock.Anything).Return(map[string]domain.SpamToken{
+		"代开": {Token: "代开", SpamCount: 9, HamCount: 0},
+		"开发": {Token: "开发", SpamCount: 9, HamCount: 0},
+		"发票": {Token: "发票", SpamCount: 9, HamCount: 1},
+		"文章": {Token: "文章", SpamCount: 0, HamCount: 8},
+		"章写": {Token: "章写", SpamCount: 0, HamCount: 6},
+		"写得": {Token: "写得", SpamCount: 1, HamCount: 9},
+	}, nil)
+
+	filter := NewSpamFilter(spamRepo, testSpamConfig)
+	ctx := context.Background()
+	valid := formTokenAt(time.Now().Add(-time.Minute))
+
+	result, err := filter.Check(ctx, &SpamInput{Content: "代开发票", FormToken: valid})
+	assert.NoError(t, err)
+	assert.Equal(t, SpamVerdictHold, result.Verdict)
+	assert.Greater(t, result.Score, 0.9)
+
+	result, err = filter.Check(ctx, &SpamInput{Content: "文章写得好", FormToken: valid})
+	assert.NoError(t, err)
+	assert.Equal(t, SpamVerdictApprove, result.Verdict)
+}
+
+// TestSpamTokens 测试分词
+func TestSpamTokens(t *testing.T) {
+	tokens := spamTokens("Buy NOW 代开发票 https://Spam.example/x")
+	assert.Equal(t, []string{"link:spam.example", "buy", "now", "代开", "开发", "发票", "https", "spam", "example"}, tokens)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"Lin_studio/internal/cache"
 	"Lin_studio/internal/domain"
 	"Lin_studio/internal/repository"
 	"context"
@@ -19,17 +20,27 @@ type TagService interface {
 // TagServiceImpl 标签服务实现
 type TagServiceImpl struct {
 	tagRepo repository.TagRepository
+	cache   cache.Cache
 }
 
 // NewTagService 创建标签服务实例
-func NewTagService(tagRepo repository.TagRepository) TagService {
+func NewTagService(tagRepo repository.TagRepository, appCache cache.Cache) TagService {
 	return &TagServiceImpl{
 		tagRepo: tagRepo,
+		cache:   appCache,
 	}
 }
 
-// GetAllTags 获取所有标签
+// GetAllTags 获取所有标签，统计数量随文章变化，结果在标签或文章修改前缓存
 func (s *TagServiceImpl) GetAllTags(ctx context.Context) ([]domain.TagResponse, error) {
+	tags := []string{cache.TagTag, cache.TagArticle}
+	return cache.Remember(ctx, s.cache, "tags:all", 0, tags, func() ([]domain.TagResponse, error) {
+		return s.loadAllTags(ctx)
+	})
+}
+
+// loadAllTags 从数据库加载所有标签和统计数量
+func (s *TagServiceImpl) loadAllTags(ctx context.Context) ([]domain.TagResponse, error) {
 	tags, err := s.tagRepo.FindAll(ctx)
 	if err != nil {
 		return nil, err
@@ -55,6 +66,9 @@ func (s *TagServiceImpl) GetAllTags(ctx context.Context) ([]domain.TagResponse,
 			Color:        tag.Color,
 			ArticleCount: articleCount,
 			ProjectCount: projectCount,
+			Version:      tag.Version,
+			CreatedAt:    tag.CreatedAt,
+			UpdatedAt:    tag.UpdatedAt,
 		})
 	}
 	
@@ -92,12 +106,14 @@ func (s *TagServiceImpl) CreateTag(ctx context.Context, name, description, color
 		Slug:        slug,
 		Description: description,
 		Color:       color,
+		Version:     1,
 	}
 	
 	err = s.tagRepo.Create(ctx, tag)
 	if err != nil {
 		return nil, err
 	}
+	cache.InvalidateLogged(ctx, s.cache, cache.TagTag)
 	
 	return tag, nil
 }
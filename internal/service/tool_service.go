@@ -15,9 +15,8 @@ type ToolService interface {
 	GetToolByID(ctx context.Context, id uint) (*domain.Tool, error)
 	GetToolBySlug(ctx context.Context, slug string) (*domain.Tool, error)
 	CreateTool(ctx context.Context, name, description, icon, category, content string, config domain.JSONConfig, status string) (*domain.Tool, error)
-	UpdateTool(ctx context.Context, id uint, name, description, icon, category, content string, config domain.JSONConfig, status string) (*domain.Tool, error)
-	DeleteTool(ctx context.Context, id uint) error
-	ViewTool(ctx context.Context, id uint) error
+	UpdateTool(ctx context.Context, id, version uint, name, description, icon, category, content string, config domain.JSONConfig, status string) (*domain.Tool, error)
+	DeleteTool(ctx context.Context, id, version uint) error
 }
 
 // ToolServiceImpl 工具服务实现
@@ -89,9 +88,6 @@ func (s *ToolServiceImpl) GetToolBySlug(ctx context.Context, slug string) (*doma
 		return nil, errors.New("工具不存在")
 	}
 
-	// 增加浏览量
-	go s.toolRepo.IncrementViews(context.Background(), tool.ID)
-
 	return tool, nil
 }
 
@@ -134,6 +130,7 @@ func (s *ToolServiceImpl) CreateTool(
 		Content:     content,
 		Config:      config,
 		Status:      status,
+		Version:     1,
 	}
 
 	// 保存工具
@@ -146,9 +143,10 @@ func (s *ToolServiceImpl) CreateTool(
 }
 
 // UpdateTool 更新工具
+// version为客户端读取时的版本号，大于0且与当前版本不一致时返回ErrVersionConflict
 func (s *ToolServiceImpl) UpdateTool(
 	ctx context.Context,
-	id uint,
+	id, version uint,
 	name, description, icon, category, content string,
 	config domain.JSONConfig,
 	status string,
@@ -161,6 +159,9 @@ func (s *ToolServiceImpl) UpdateTool(
 	if tool == nil {
 		return nil, errors.New("工具不存在")
 	}
+	if version > 0 && tool.Version != version {
+		return nil, ErrVersionConflict
+	}
 
 	// 检查名称是否为空
 	if strings.TrimSpace(name) == "" {
@@ -203,8 +204,8 @@ func (s *ToolServiceImpl) UpdateTool(
 	return tool, nil
 }
 
-// DeleteTool 删除工具
-func (s *ToolServiceImpl) DeleteTool(ctx context.Context, id uint) error {
+// DeleteTool 删除工具，version大于0时只删除该版本
+func (s *ToolServiceImpl) DeleteTool(ctx context.Context, id, version uint) error {
 	// 检查工具是否存在
 	tool, err := s.toolRepo.FindByID(ctx, id)
 	if err != nil {
@@ -214,11 +215,10 @@ func (s *ToolServiceImpl) DeleteTool(ctx context.Context, id uint) error {
 		return errors.New("工具不存在")
 	}
 
+	if version > 0 && tool.Version != version {
+		return ErrVersionConflict
+	}
+
 	// 删除工具
-	return s.toolRepo.Delete(ctx, id)
+	return s.toolRepo.Delete(ctx, id, version)
 }
-
-// ViewTool 增加工具浏览量
-func (s *ToolServiceImpl) ViewTool(ctx context.Context, id uint) error {
-	return s.toolRepo.IncrementViews(ctx, id)
-} 
\ No newline at end of file
@@ -7,10 +7,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"log"
 	"mime/multipart"
-	"os"
-	"path/filepath"
 	
 	"gorm.io/datatypes"
 )
@@ -24,13 +22,15 @@ type UserService interface {
 
 // UserServiceImpl 用户服务实现
 type UserServiceImpl struct {
-	userRepo repository.UserRepository
+	userRepo     repository.UserRepository
+	mediaService MediaService
 }
 
 // NewUserService 创建用户服务实例
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, mediaService MediaService) UserService {
 	return &UserServiceImpl{
-		userRepo: userRepo,
+		userRepo:     userRepo,
+		mediaService: mediaService,
 	}
 }
 
@@ -98,42 +98,24 @@ func (s *UserServiceImpl) UploadAvatar(ctx context.Context, userID uint, file *m
 		return "", errors.New("用户不存在")
 	}
 	
-	// 确保上传目录存在
-	uploadDir := "./uploads/avatars"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return "", err
-	}
-	
-	// 创建文件名
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%d_%d%s", userID, user.UpdatedAt.Unix(), ext)
-	filePath := filepath.Join(uploadDir, filename)
-	
-	// 保存文件
-	src, err := file.Open()
+	// 保存到媒体库，相同图片只保存一份
+	media, err := s.mediaService.Upload(ctx, file, &userID)
 	if err != nil {
 		return "", err
 	}
-	defer src.Close()
-	
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer dst.Close()
-	
-	// 将源文件内容复制到目标文件
-	if _, err = dst.ReadFrom(src); err != nil {
-		return "", err
-	}
 	
 	// 更新用户头像
-	avatarURL := fmt.Sprintf("/uploads/avatars/%s", filename)
+	avatarURL := media.URL
 	user.Avatar = avatarURL
 	
 	if err := s.userRepo.Update(user); err != nil {
 		return "", err
 	}
 	
+	// 记录头像引用，旧头像不再被引用后会被清理
+	if err := s.mediaService.SyncReferences(ctx, domain.MediaRefUser, user.ID, avatarURL); err != nil {
+		log.Printf("同步用户 %d 的媒体引用失败: %v", user.ID, err)
+	}
+	
 	return avatarURL, nil
 } 
\ No newline at end of file
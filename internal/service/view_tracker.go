@@ -0,0 +1,236 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/repository"
+	"Lin_studio/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 统计浏览量的内容类型
+const (
+	ViewTargetArticle = "article"
+	ViewTargetTool    = "tool"
+)
+
+// ViewVisitor 浏览内容的访问者，登录用户按用户ID区分，匿名访问者按IP和User-Agent的指纹区分
+type ViewVisitor struct {
+	UserID    *uint
+	IP        string
+	UserAgent string
+	Referrer  string       // 访问者进入页面前的来源地址
+	Campaign  ViewCampaign // 页面地址中的UTM参数
+}
+
+// ViewTracker 浏览量统计接口，同一访问者在去重窗口内重复浏览只计一次，爬虫的浏览不计入
+// 浏览量先在内存中累计，定期批量写入数据库，计入的浏览同时交给统计服务按天汇总
+type ViewTracker interface {
+	Track(targetType string, targetID uint, visitor ViewVisitor) bool
+	Flush(ctx context.Context) error
+	Start()
+	Stop()
+}
+
+// ViewTrackerImpl 浏览量统计实现，去重记录和待写入的浏览量只保存在当前进程内存中
+type ViewTrackerImpl struct {
+	flushers map[string]func(ctx context.Context, counts map[uint]uint) error
+	recorder ViewRecorder
+	cfg      config.ViewConfig
+	now      func() time.Time
+
+	mu      sync.Mutex
+	seen    map[string]time.Time     // 访问者浏览过的内容，值为去重窗口的结束时间
+	pending map[string]map[uint]uint // 内容类型到内容ID和待写入浏览量的映射
+	hits    []ViewHit                // 待写入统计的浏览记录
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewViewTracker 创建浏览量统计实例
+func NewViewTracker(
+	articleRepo repository.ArticleRepository,
+	toolRepo repository.ToolRepository,
+	recorder ViewRecorder,
+	cfg config.ViewConfig,
+) ViewTracker {
+	if cfg.DedupeWindow <= 0 {
+		cfg.DedupeWindow = 30 * time.Minute
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+	if cfg.MaxVisitors <= 0 {
+		cfg.MaxVisitors = 100000
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ViewTrackerImpl{
+		flushers: map[string]func(ctx context.Context, counts map[uint]uint) error{
+			ViewTargetArticle: articleRepo.AddViews,
+			ViewTargetTool:    toolRepo.AddViews,
+		},
+		recorder: recorder,
+		cfg:      cfg,
+		now:      time.Now,
+		seen:     make(map[string]time.Time),
+		pending:  make(map[string]map[uint]uint),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Track 记录一次浏览，返回是否计入浏览量
+func (t *ViewTrackerImpl) Track(targetType string, targetID uint, visitor ViewVisitor) bool {
+	if _, ok := t.flushers[targetType]; !ok || targetID == 0 {
+		return false
+	}
+	if visitor.UserID == nil && utils.IsBotUserAgent(visitor.UserAgent, t.cfg.BotPatterns...) {
+		return false
+	}
+
+	fingerprint := t.fingerprint(visitor)
+	key := fmt.Sprintf("%s:%d:%s", targetType, targetID, fingerprint)
+	now := t.now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if expiresAt, ok := t.seen[key]; ok && now.Before(expiresAt) {
+		return false
+	}
+	if len(t.seen) >= t.cfg.MaxVisitors {
+		t.pruneSeen(now)
+	}
+	// 清理后仍然超过上限时只计数不记录，避免内存无限增长
+	if len(t.seen) < t.cfg.MaxVisitors {
+		t.seen[key] = now.Add(t.cfg.DedupeWindow)
+	}
+
+	counts, ok := t.pending[targetType]
+	if !ok {
+		counts = make(map[uint]uint)
+		t.pending[targetType] = counts
+	}
+	counts[targetID]++
+
+	// 写入统计持续失败时不再积累，避免内存无限增长
+	if len(t.hits) < t.cfg.MaxVisitors {
+		t.hits = append(t.hits, ViewHit{
+			ItemType: targetType,
+			ItemID:   targetID,
+			Time:     now,
+			Visitor:  fingerprint,
+			Referrer: visitor.Referrer,
+			Campaign: visitor.Campaign,
+		})
+	}
+	return true
+}
+
+// Flush 把累计的浏览量和浏览记录写入数据库，写入失败的留到下次写入
+func (t *ViewTrackerImpl) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending, hits := t.pending, t.hits
+	t.pending = make(map[string]map[uint]uint)
+	t.hits = nil
+	t.pruneSeen(t.now())
+	t.mu.Unlock()
+
+	var firstErr error
+	for targetType, counts := range pending {
+		if err := t.flushers[targetType](ctx, counts); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("写入%s浏览量失败: %w", targetType, err)
+			}
+			t.requeue(targetType, counts)
+		}
+	}
+
+	if len(hits) > 0 {
+		if err := t.recorder.RecordViews(ctx, hits); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("写入浏览统计失败: %w", err)
+			}
+			t.mu.Lock()
+			t.hits = append(hits, t.hits...)
+			t.mu.Unlock()
+		}
+	}
+	return firstErr
+}
+
+// Start 启动定期写入的后台协程
+func (t *ViewTrackerImpl) Start() {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(t.cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Flush(t.ctx); err != nil {
+					log.Printf("写入浏览量失败: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止后台协程并写入剩余的浏览量
+func (t *ViewTrackerImpl) Stop() {
+	t.cancel()
+	t.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := t.Flush(ctx); err != nil {
+		log.Printf("退出前写入浏览量失败: %v", err)
+	}
+}
+
+// requeue 把写入失败的浏览量放回待写入列表
+func (t *ViewTrackerImpl) requeue(targetType string, counts map[uint]uint) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending, ok := t.pending[targetType]
+	if !ok {
+		pending = make(map[uint]uint)
+		t.pending[targetType] = pending
+	}
+	for id, n := range counts {
+		pending[id] += n
+	}
+}
+
+// pruneSeen 删除已过去重窗口的记录，调用方需持有锁
+func (t *ViewTrackerImpl) pruneSeen(now time.Time) {
+	for key, expiresAt := range t.seen {
+		if !now.Before(expiresAt) {
+			delete(t.seen, key)
+		}
+	}
+}
+
+// fingerprint 返回访问者的指纹，匿名访问者只保存IP和User-Agent的哈希值
+func (t *ViewTrackerImpl) fingerprint(visitor ViewVisitor) string {
+	if visitor.UserID != nil {
+		return "u:" + strconv.FormatUint(uint64(*visitor.UserID), 10)
+	}
+	mac := hmac.New(sha256.New, []byte(t.cfg.VisitorSecret))
+	mac.Write([]byte(visitor.IP + "\n" + visitor.UserAgent))
+	return "v:" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
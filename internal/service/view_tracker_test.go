@@ -0,0 +1,129 @@
+package service
+
+import (
+	"Lin_studio/internal/config"
+	"Lin_studio/internal/domain"
+	"Lin_studio/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockToolRepository 模拟工具仓储
+type MockToolRepository struct {
+	mock.Mock
+}
+
+func (m *MockToolRepository) Create(ctx context.Context, tool *domain.Tool) error {
+	args := m.Called(ctx, tool)
+	return args.Error(0)
+}
+
+func (m *MockToolRepository) FindByID(ctx context.Context, id uint) (*domain.Tool, error) {
+	args := m.Called(ctx, id)
+	tool, _ := args.Get(0).(*domain.Tool)
+	return tool, args.Error(1)
+}
+
+func (m *MockToolRepository) FindBySlug(ctx context.Context, slug string) (*domain.Tool, error) {
+	args := m.Called(ctx, slug)
+	tool, _ := args.Get(0).(*domain.Tool)
+	return tool, args.Error(1)
+}
+
+func (m *MockToolRepository) FindAll(ctx context.Context, filter repository.ToolFilter) ([]domain.Tool, int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]domain.Tool), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockToolRepository) Update(ctx context.Context, tool *domain.Tool) error {
+	args := m.Called(ctx, tool)
+	return args.Error(0)
+}
+
+func (m *MockToolRepository) Delete(ctx context.Context, id, version uint) error {
+	args := m.Called(ctx, id, version)
+	return args.Error(0)
+}
+
+func (m *MockToolRepository) AddViews(ctx context.Context, counts map[uint]uint) error {
+	args := m.Called(ctx, counts)
+	return args.Error(0)
+}
+
+// recordedViews 保存写入统计的浏览记录，fail为true时返回错误
+type recordedViews struct {
+	hits []ViewHit
+	fail bool
+}
+
+func (r *recordedViews) RecordViews(ctx context.Context, hits []ViewHit) error {
+	if r.fail {
+		return errors.New("数据库不可用")
+	}
+	r.hits = append(r.hits, hits...)
+	return nil
+}
+
+// TestViewTracker 测试浏览量去重、过滤爬虫和批量写入
+func TestViewTracker(t *testing.T) {
+	articleRepo := new(MockArticleRepository)
+	toolRepo := new(MockToolRepository)
+	recorder := &recordedViews{fail: true}
+	tracker := NewViewTracker(articleRepo, toolRepo, recorder, config.ViewConfig{
+		DedupeWindow:  30 * time.Minute,
+		FlushInterval: time.Hour,
+		VisitorSecret: "secret",
+		BotPatterns:   []string{"MyMonitor"},
+	}).(*ViewTrackerImpl)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	browser := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	alice := ViewVisitor{IP: "10.0.0.1", UserAgent: browser, Referrer: "https://news.ycombinator.com/"}
+	bob := ViewVisitor{IP: "10.0.0.2", UserAgent: browser}
+	userID := uint(7)
+	member := ViewVisitor{UserID: &userID, IP: "10.0.0.1", UserAgent: browser}
+
+	assert.True(t, tracker.Track(ViewTargetArticle, 1, alice))
+	assert.False(t, tracker.Track(ViewTargetArticle, 1, alice), "去重窗口内重复浏览不计入")
+	assert.True(t, tracker.Track(ViewTargetArticle, 1, bob))
+	assert.True(t, tracker.Track(ViewTargetArticle, 1, member), "登录用户按用户ID去重")
+	assert.True(t, tracker.Track(ViewTargetArticle, 2, alice))
+	assert.True(t, tracker.Track(ViewTargetTool, 1, alice))
+	assert.False(t, tracker.Track(ViewTargetArticle, 1, ViewVisitor{IP: "10.0.0.3", UserAgent: "Googlebot/2.1"}))
+	assert.False(t, tracker.Track(ViewTargetArticle, 1, ViewVisitor{IP: "10.0.0.3", UserAgent: "mymonitor/1.0"}))
+	assert.False(t, tracker.Track(ViewTargetArticle, 1, ViewVisitor{IP: "10.0.0.3"}))
+	assert.False(t, tracker.Track("comment", 1, alice))
+
+	// 写入失败的浏览量留到下次写入
+	articleRepo.On("AddViews", mock.Anything, map[uint]uint{1: 3, 2: 1}).Return(errors.New("数据库不可用")).Once()
+	toolRepo.On("AddViews", mock.Anything, map[uint]uint{1: 1}).Return(nil).Once()
+	assert.Error(t, tracker.Flush(context.Background()))
+
+	// 去重窗口过后再次浏览重新计入
+	now = now.Add(31 * time.Minute)
+	assert.True(t, tracker.Track(ViewTargetArticle, 1, alice))
+
+	recorder.fail = false
+	articleRepo.On("AddViews", mock.Anything, map[uint]uint{1: 4, 2: 1}).Return(nil).Once()
+	require.NoError(t, tracker.Flush(context.Background()))
+	require.Len(t, recorder.hits, 6, "统计只记录计入浏览量的浏览")
+	assert.Equal(t, "https://news.ycombinator.com/", recorder.hits[0].Referrer)
+	assert.Equal(t, recorder.hits[0].Visitor, recorder.hits[5].Visitor)
+	assert.Len(t, tracker.seen, 1, "过期的去重记录应被清理")
+
+	// 停止时写入剩余的浏览量
+	tracker.Start()
+	assert.True(t, tracker.Track(ViewTargetTool, 3, bob))
+	toolRepo.On("AddViews", mock.Anything, map[uint]uint{3: 1}).Return(nil).Once()
+	tracker.Stop()
+
+	articleRepo.AssertExpectations(t)
+	toolRepo.AssertExpectations(t)
+}
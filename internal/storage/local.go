@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage 本地文件系统存储
+type LocalStorage struct {
+	root      string
+	publicURL string
+}
+
+// NewLocalStorage 创建本地存储实例
+func NewLocalStorage(root, publicURL string) *LocalStorage {
+	return &LocalStorage{
+		root:      root,
+		publicURL: publicURL,
+	}
+}
+
+// Put 写入文件
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// 先写入临时文件再重命名，避免其他实例读取到写了一半的文件
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Open 读取文件
+func (s *LocalStorage) Open(ctx context.Context, key string) (*Object, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, ErrNotFound
+	}
+
+	return &Object{
+		Body:        f,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(filePath)),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+// Stat 获取文件信息
+func (s *LocalStorage) Stat(ctx context.Context, key string) (*Object, error) {
+	obj, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	obj.Body.Close()
+	obj.Body = nil
+	return obj, nil
+}
+
+// OpenRange 读取文件的指定区间
+func (s *LocalStorage) OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	obj, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	f := obj.Body.(*os.File)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return f, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, length), f}, nil
+}
+
+// Delete 删除文件
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Exists 判断文件是否存在
+func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
+
+// URL 返回文件的公开访问地址
+func (s *LocalStorage) URL(key string) string {
+	return joinURL(s.publicURL, key)
+}
+
+// KeyFromURL 将公开访问地址还原为存储键
+func (s *LocalStorage) KeyFromURL(url string) (string, bool) {
+	return trimURL(s.publicURL, url)
+}
+
+// path 将存储键转换为本地文件路径
+func (s *LocalStorage) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
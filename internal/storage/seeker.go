@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ReadSeeker 基于OpenRange按需读取的文件，可配合http.ServeContent响应Range请求
+// 每次Seek后的首次Read才会发起读取，远程存储只传输实际需要的区间
+type ReadSeeker struct {
+	ctx     context.Context
+	storage Storage
+	key     string
+	size    int64
+
+	offset int64
+	body   io.ReadCloser
+}
+
+// NewReadSeeker 创建按需读取的文件
+func NewReadSeeker(ctx context.Context, storage Storage, key string, size int64) *ReadSeeker {
+	return &ReadSeeker{
+		ctx:     ctx,
+		storage: storage,
+		key:     key,
+		size:    size,
+	}
+}
+
+// Read 从当前位置读取数据
+func (r *ReadSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.storage.OpenRange(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek 移动读取位置
+func (r *ReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.offset + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, errors.New("无效的whence参数")
+	}
+	if pos < 0 {
+		return 0, errors.New("无效的读取位置")
+	}
+
+	if pos != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = pos
+	return pos, nil
+}
+
+// Close 关闭底层读取
+func (r *ReadSeeker) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"Lin_studio/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// Object 存储中的文件
+type Object struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Storage 上传文件存储接口
+type Storage interface {
+	// Put 写入文件，已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取文件，调用方负责关闭Body
+	Open(ctx context.Context, key string) (*Object, error)
+	// Stat 获取文件信息，返回的Object不包含Body
+	Stat(ctx context.Context, key string) (*Object, error)
+	// OpenRange 从offset开始读取length字节，length小于0时读到文件末尾
+	OpenRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+	// Exists 判断文件是否存在
+	Exists(ctx context.Context, key string) (bool, error)
+	// URL 返回文件的公开访问地址
+	URL(key string) string
+	// KeyFromURL 将公开访问地址还原为存储键，非本存储的地址返回false
+	KeyFromURL(url string) (string, bool)
+}
+
+// New 根据配置创建存储实例
+func New(cfg config.UploadConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "", "local":
+		return NewLocalStorage(cfg.UploadDir, cfg.PublicURL), nil
+	case "s3":
+		return NewS3Storage(cfg.S3, cfg.PublicURL)
+	default:
+		return nil, fmt.Errorf("不支持的存储驱动: %s", cfg.Driver)
+	}
+}
+
+// cleanKey 规范化存储键，拒绝越界路径
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if key == "" || key == "." {
+		return "", errors.New("无效的文件路径")
+	}
+	return key, nil
+}
+
+// joinURL 拼接公开地址前缀与存储键
+func joinURL(base, key string) string {
+	return strings.TrimRight(base, "/") + "/" + key
+}
+
+// trimURL 从公开地址中去掉前缀得到存储键
+func trimURL(base, url string) (string, bool) {
+	prefix := strings.TrimRight(base, "/") + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	key, err := cleanKey(strings.TrimPrefix(url, prefix))
+	if err != nil {
+		return "", false
+	}
+	return key, true
+}
@@ -0,0 +1,240 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// maxMentionLength 用户名的最大长度
+const maxMentionLength = 50
+
+// commentMarkdown 评论使用的Markdown解析器，只支持代码、链接、强调和引用
+// 与文章不同，评论由访客提交，原始HTML会被转义，图片显示为链接
+var commentMarkdown = goldmark.New(
+	goldmark.WithParser(parser.NewParser(
+		parser.WithBlockParsers(
+			util.Prioritized(parser.NewCodeBlockParser(), 500),
+			util.Prioritized(parser.NewFencedCodeBlockParser(), 700),
+			util.Prioritized(parser.NewBlockquoteParser(), 800),
+			util.Prioritized(parser.NewParagraphParser(), 1000),
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(parser.NewCodeSpanParser(), 100),
+			util.Prioritized(parser.NewLinkParser(), 200),
+			util.Prioritized(parser.NewAutoLinkParser(), 300),
+			util.Prioritized(parser.NewEmphasisParser(), 500),
+			util.Prioritized(&mentionParser{}, 600),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(commentLinkTransformer{}, 100),
+		),
+	)),
+	goldmark.WithExtensions(extension.Linkify),
+	goldmark.WithRendererOptions(
+		html.WithHardWraps(),
+		html.WithXHTML(),
+		renderer.WithNodeRenderers(util.Prioritized(mentionRenderer{}, 100)),
+	),
+)
+
+// RenderCommentMarkdown 将评论内容渲染为HTML
+// mentions为已确认存在的用户名(小写)到个人主页地址的映射，其他@用户名按普通文本输出
+func RenderCommentMarkdown(source string, mentions map[string]string) (string, error) {
+	if source == "" {
+		return "", nil
+	}
+
+	src := []byte(source)
+	doc := commentMarkdown.Parser().Parse(text.NewReader(src))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if mention, ok := n.(*Mention); ok && entering {
+			mention.URL = mentions[strings.ToLower(mention.Username)]
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var buf bytes.Buffer
+	if err := commentMarkdown.Renderer().Render(&buf, src, doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ExtractMentions 提取评论中@提及的用户名，代码和链接中的@不算提及，结果按出现顺序去重
+func ExtractMentions(source string) []string {
+	if !strings.Contains(source, "@") {
+		return nil
+	}
+
+	doc := commentMarkdown.Parser().Parse(text.NewReader([]byte(source)))
+	seen := make(map[string]bool)
+	var usernames []string
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if mention, ok := n.(*Mention); ok && entering {
+			key := strings.ToLower(mention.Username)
+			if !seen[key] {
+				seen[key] = true
+				usernames = append(usernames, mention.Username)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return usernames
+}
+
+// KindMention @提及节点类型
+var KindMention = ast.NewNodeKind("Mention")
+
+// Mention @提及节点
+type Mention struct {
+	ast.BaseInline
+	Username string
+	URL      string // 被提及用户的主页地址，为空时按普通文本输出
+}
+
+// Kind 返回节点类型
+func (n *Mention) Kind() ast.NodeKind {
+	return KindMention
+}
+
+// Dump 输出节点调试信息
+func (n *Mention) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Username": n.Username}, nil)
+}
+
+// mentionParser 解析@用户名，@前面是字母或数字时(例如邮箱地址)不视为提及
+type mentionParser struct{}
+
+// Trigger 触发字符
+func (p *mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+// Parse 解析@提及
+func (p *mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if prev := block.PrecendingCharacter(); unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '_' {
+		return nil
+	}
+
+	line, _ := block.PeekLine()
+	i := 1
+	for i < len(line) {
+		r, size := utf8.DecodeRune(line[i:])
+		if !isMentionRune(r) {
+			break
+		}
+		i += size
+	}
+	// 句末的标点不属于用户名
+	name := strings.TrimRight(string(line[1:i]), ".-")
+	if n := utf8.RuneCountInString(name); n == 0 || n > maxMentionLength {
+		return nil
+	}
+
+	block.Advance(1 + len(name))
+	return &Mention{Username: name}
+}
+
+// isMentionRune 判断字符是否可以出现在被提及的用户名中
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// mentionRenderer 渲染@提及节点
+type mentionRenderer struct{}
+
+// RegisterFuncs 注册渲染函数
+func (r mentionRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMention, r.render)
+}
+
+// render 已确认的用户渲染为个人主页链接，其他按原文输出
+func (r mentionRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*Mention)
+	if n.URL == "" {
+		_, _ = w.WriteString("@")
+		_, _ = w.Write(util.EscapeHTML([]byte(n.Username)))
+		return ast.WalkSkipChildren, nil
+	}
+
+	_, _ = w.WriteString(`<a href="`)
+	_, _ = w.Write(util.EscapeHTML(util.URLEscape([]byte(n.URL), true)))
+	_, _ = w.WriteString(`" class="mention">@`)
+	_, _ = w.Write(util.EscapeHTML([]byte(n.Username)))
+	_, _ = w.WriteString("</a>")
+	return ast.WalkSkipChildren, nil
+}
+
+// commentLinkTransformer 将图片替换为链接，并为访客提交的链接添加rel属性
+// 链接文字中的@用户名不视为提及，避免生成嵌套的链接
+type commentLinkTransformer struct{}
+
+// Transform 处理评论中的链接和图片
+func (commentLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var images []*ast.Image
+	var links []ast.Node
+	var linkedMentions []*Mention
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Image:
+			images = append(images, node)
+		case *ast.Link, *ast.AutoLink:
+			links = append(links, node)
+		case *Mention:
+			if insideLink(node) {
+				linkedMentions = append(linkedMentions, node)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, mention := range linkedMentions {
+		mention.Parent().ReplaceChild(mention.Parent(), mention, ast.NewString([]byte("@"+mention.Username)))
+	}
+
+	for _, image := range images {
+		link := ast.NewLink()
+		link.Destination = image.Destination
+		link.Title = image.Title
+		for child := image.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			link.AppendChild(link, child)
+			child = next
+		}
+		image.Parent().ReplaceChild(image.Parent(), image, link)
+		links = append(links, link)
+	}
+
+	for _, link := range links {
+		link.SetAttributeString("rel", []byte("nofollow ugc noopener"))
+	}
+}
+
+// insideLink 判断节点是否位于链接或图片中
+func insideLink(n ast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		switch p.(type) {
+		case *ast.Link, *ast.Image:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderCommentMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "支持的语法",
+			source:   "*强调* `code`\n\n> 引用\n\n[链接](https://example.com)",
+			contains: []string{"<em>强调</em>", "<code>code</code>", "<blockquote>", `<a href="https://example.com" rel="nofollow ugc noopener">链接</a>`},
+		},
+		{
+			name:     "原始HTML被转义",
+			source:   `<script>alert(1)</script><img src=x onerror=alert(1)>`,
+			contains: []string{"&lt;script&gt;"},
+			excludes: []string{"<script", "<img"},
+		},
+		{
+			name:     "危险链接和图片",
+			source:   "[点我](javascript:alert(1)) ![图](https://example.com/a.png)",
+			contains: []string{`<a href="https://example.com/a.png" rel="nofollow ugc noopener">图</a>`},
+			excludes: []string{"javascript:", "<img"},
+		},
+		{
+			name:     "不支持标题和列表",
+			source:   "# 标题\n- 列表",
+			contains: []string{"# 标题"},
+			excludes: []string{"<h1", "<ul"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := RenderCommentMarkdown(tt.source, nil)
+			require.NoError(t, err)
+			for _, s := range tt.contains {
+				assert.Contains(t, html, s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, html, s)
+			}
+		})
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	mentions := ExtractMentions("@lin 你好，@Bob. 邮箱 a@b.com，`@code` 和 [@link](https://example.com) 不算，@lin 重复")
+	assert.Equal(t, []string{"lin", "Bob"}, mentions)
+
+	html, err := RenderCommentMarkdown("@lin 和 @bob", map[string]string{"lin": "/users/lin"})
+	require.NoError(t, err)
+	assert.Equal(t, "<p><a href=\"/users/lin\" class=\"mention\">@lin</a> 和 @bob</p>\n", html)
+}
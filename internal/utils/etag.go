@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// VersionETag 根据记录的版本号生成ETag，例如 "v3"
+func VersionETag(version uint) string {
+	return `"v` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// ParseVersionETag 从VersionETag或CacheValidator.VersionETag生成的ETag中解析版本号
+// 弱ETag(W/前缀)不能用于If-Match的强比较，视为无法解析
+func ParseVersionETag(tag string) (uint, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 4 || !strings.HasPrefix(tag, `"v`) || !strings.HasSuffix(tag, `"`) {
+		return 0, false
+	}
+	value := tag[2 : len(tag)-1]
+	// 版本号后的摘要只用于If-None-Match，If-Match只比较版本号
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		value = value[:i]
+	}
+	version, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || version == 0 {
+		return 0, false
+	}
+	return uint(version), true
+}
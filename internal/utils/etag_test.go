@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionETag(t *testing.T) {
+	tag := VersionETag(3)
+	assert.Equal(t, `"v3"`, tag)
+
+	version, ok := ParseVersionETag(" " + tag + " ")
+	assert.True(t, ok)
+	assert.Equal(t, uint(3), version)
+
+	// 带校验数据摘要的ETag只比较版本号
+	version, ok = ParseVersionETag(`"v3-0123456789abcdef"`)
+	assert.True(t, ok)
+	assert.Equal(t, uint(3), version)
+
+	// 弱ETag和其他格式的ETag无法用于If-Match
+	for _, invalid := range []string{`W/"v3"`, `"3"`, `"v0"`, `"vx"`, `v3`, `"v-3"`} {
+		_, ok := ParseVersionETag(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
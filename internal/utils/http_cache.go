@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheValidatorKey 上下文中保存缓存校验数据的键
+const cacheValidatorKey = "cache_validator"
+
+// CacheValidator 响应内容的缓存校验数据
+type CacheValidator struct {
+	LastModified time.Time     // 内容的最后修改时间，通常为实体的UpdatedAt
+	Parts        []interface{} // 其他会影响响应内容的数据，例如列表的总数和各项的统计数量
+}
+
+// SetCacheValidator 记录响应内容的缓存校验数据，缓存中间件据此生成ETag和Last-Modified
+func SetCacheValidator(c *gin.Context, lastModified time.Time, parts ...interface{}) {
+	c.Set(cacheValidatorKey, CacheValidator{LastModified: lastModified, Parts: parts})
+}
+
+// GetCacheValidator 获取处理函数记录的缓存校验数据
+func GetCacheValidator(c *gin.Context) (CacheValidator, bool) {
+	value, exists := c.Get(cacheValidatorKey)
+	if !exists {
+		return CacheValidator{}, false
+	}
+	validator, ok := value.(CacheValidator)
+	return validator, ok
+}
+
+// ETag 生成弱ETag，key用于区分同一内容的不同表示，例如带查询参数的请求地址
+func (v CacheValidator) ETag(key string) string {
+	return `W/"` + v.digest(key) + `"`
+}
+
+// VersionETag 生成带版本号的ETag，例如 "v3-0123456789abcdef"
+// 版本号用于修改时的If-Match，其余部分随统计数量等校验数据变化，用于If-None-Match
+func (v CacheValidator) VersionETag(version uint, key string) string {
+	return `"v` + strconv.FormatUint(uint64(version), 10) + "-" + v.digest(key) + `"`
+}
+
+// digest 计算校验数据的摘要
+func (v CacheValidator) digest(key string) string {
+	h := sha1.New()
+	fmt.Fprint(h, key, "|", v.LastModified.UnixNano())
+	for _, part := range v.Parts {
+		fmt.Fprint(h, "|", part)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
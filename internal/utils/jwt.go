@@ -9,6 +9,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// WebSocketTokenProtocol 通过Sec-WebSocket-Protocol传递访问令牌时使用的子协议名
+// 客户端以 new WebSocket(url, ["bearer", token]) 建立连接，服务端握手时只回应该子协议
+const WebSocketTokenProtocol = "bearer"
+
 // 自定义JWT声明
 type JWTClaims struct {
 	UserID   uint   `json:"user_id"`
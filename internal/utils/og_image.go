@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// OG图片尺寸
+const (
+	OGImageWidth  = 1200
+	OGImageHeight = 630
+)
+
+// 布局参数
+const (
+	ogPadding      = 80
+	ogTitleSize    = 64
+	ogTitleLines   = 3
+	ogCategorySize = 28
+	ogFooterSize   = 30
+	ogAvatarSize   = 72
+	ogLineSpacing  = 1.25
+	ogCategoryPadX = 20
+	ogCategoryPadY = 10
+)
+
+// OGImageData 生成分享图所需的数据
+type OGImageData struct {
+	Title      string
+	Category   string
+	AuthorName string
+	SiteName   string
+	Avatar     image.Image // 可为空
+	Background image.Image // 可为空，为空时使用默认渐变背景
+}
+
+// RenderOGImage 将文章信息渲染为1200x630的PNG图片
+// fontData 为自定义字体数据，为空时使用内置的Go字体（仅支持拉丁字符，中文标题需要提供中文字体）
+func RenderOGImage(data OGImageData, fontData []byte) ([]byte, error) {
+	titleFont, err := parseOGFont(fontData, gobold.TTF)
+	if err != nil {
+		return nil, err
+	}
+	textFont, err := parseOGFont(fontData, goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+
+	titleFace, err := newOGFace(titleFont, ogTitleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+
+	categoryFace, err := newOGFace(textFont, ogCategorySize)
+	if err != nil {
+		return nil, err
+	}
+	defer categoryFace.Close()
+
+	footerFace, err := newOGFace(textFont, ogFooterSize)
+	if err != nil {
+		return nil, err
+	}
+	defer footerFace.Close()
+
+	canvas := image.NewRGBA(image.Rect(0, 0, OGImageWidth, OGImageHeight))
+
+	// 绘制背景
+	if data.Background != nil {
+		drawCover(canvas, data.Background)
+		// 叠加半透明遮罩，保证文字可读
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{0, 0, 0, 110}), image.Point{}, draw.Over)
+	} else {
+		drawGradient(canvas, color.RGBA{30, 41, 59, 255}, color.RGBA{76, 29, 149, 255})
+	}
+
+	white := image.NewUniform(color.White)
+	muted := image.NewUniform(color.RGBA{226, 232, 240, 255})
+
+	// 绘制分类标签
+	y := ogPadding
+	if data.Category != "" {
+		textWidth := font.MeasureString(categoryFace, data.Category).Ceil()
+		metrics := categoryFace.Metrics()
+		textHeight := (metrics.Ascent + metrics.Descent).Ceil()
+		pill := image.Rect(ogPadding, y, ogPadding+textWidth+ogCategoryPadX*2, y+textHeight+ogCategoryPadY*2)
+		fillRoundedRect(canvas, pill, pill.Dy()/2, color.NRGBA{255, 255, 255, 48})
+		drawText(canvas, categoryFace, white, data.Category, ogPadding+ogCategoryPadX, y+ogCategoryPadY+metrics.Ascent.Ceil())
+		y = pill.Max.Y + 40
+	} else {
+		y += 40
+	}
+
+	// 绘制标题
+	lineHeight := int(float64(ogTitleSize) * ogLineSpacing)
+	lines := wrapText(titleFace, data.Title, OGImageWidth-ogPadding*2, ogTitleLines)
+	ascent := titleFace.Metrics().Ascent.Ceil()
+	for i, line := range lines {
+		drawText(canvas, titleFace, white, line, ogPadding, y+ascent+i*lineHeight)
+	}
+
+	// 绘制底部作者信息
+	footerTop := OGImageHeight - ogPadding - ogAvatarSize
+	footerMetrics := footerFace.Metrics()
+	baseline := footerTop + (ogAvatarSize+footerMetrics.Ascent.Ceil()-footerMetrics.Descent.Ceil())/2
+	nameX := ogPadding
+	if data.Avatar != nil {
+		drawCircleImage(canvas, data.Avatar, image.Rect(ogPadding, footerTop, ogPadding+ogAvatarSize, footerTop+ogAvatarSize))
+		nameX += ogAvatarSize + 24
+	}
+	if data.AuthorName != "" {
+		drawText(canvas, footerFace, white, data.AuthorName, nameX, baseline)
+	}
+	if data.SiteName != "" {
+		siteWidth := font.MeasureString(footerFace, data.SiteName).Ceil()
+		drawText(canvas, footerFace, muted, data.SiteName, OGImageWidth-ogPadding-siteWidth, baseline)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseOGFont 解析字体，未提供自定义字体时使用内置字体
+// 自定义字体可以是TTF/OTF或字体集合(TTC/OTC)，字体集合使用其中的第一个字体
+func parseOGFont(custom, fallback []byte) (*opentype.Font, error) {
+	if len(custom) == 0 {
+		return opentype.Parse(fallback)
+	}
+	collection, err := opentype.ParseCollection(custom)
+	if err != nil {
+		return nil, err
+	}
+	return collection.Font(0)
+}
+
+// newOGFace 创建指定字号的字体
+func newOGFace(f *opentype.Font, size float64) (font.Face, error) {
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
+
+// drawText 在基线位置绘制文字
+func drawText(dst draw.Image, face font.Face, src image.Image, text string, x, y int) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  src,
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(text)
+}
+
+// wrapText 按最大宽度对文字换行，超出最大行数时以省略号结尾
+// 英文按单词换行，中日韩字符可在任意字符间换行
+func wrapText(face font.Face, text string, maxWidth, maxLines int) []string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return nil
+	}
+
+	limit := fixed.I(maxWidth)
+	var lines []string
+	runes := []rune(text)
+	start := 0
+	for start < len(runes) {
+		end := start
+		lastBreak := -1
+		for end < len(runes) {
+			if font.MeasureString(face, string(runes[start:end+1])) > limit {
+				break
+			}
+			if runes[end] == ' ' || isWideRune(runes[end]) {
+				lastBreak = end
+			}
+			end++
+		}
+
+		if end < len(runes) && lastBreak > start {
+			// 在最后一个可断行位置换行
+			if runes[lastBreak] == ' ' {
+				end = lastBreak
+			} else {
+				end = lastBreak + 1
+			}
+		}
+		if end == start {
+			// 单个字符也放不下时强制输出，避免死循环
+			end = start + 1
+		}
+
+		lines = append(lines, strings.TrimSpace(string(runes[start:end])))
+		start = end
+		for start < len(runes) && runes[start] == ' ' {
+			start++
+		}
+
+		if len(lines) == maxLines && start < len(runes) {
+			lines[maxLines-1] = truncateWithEllipsis(face, lines[maxLines-1], limit)
+			break
+		}
+	}
+	return lines
+}
+
+// truncateWithEllipsis 截断文字并追加省略号，保证总宽度不超过限制
+func truncateWithEllipsis(face font.Face, line string, limit fixed.Int26_6) string {
+	runes := []rune(line)
+	for len(runes) > 0 {
+		candidate := strings.TrimRight(string(runes), " ") + "…"
+		if font.MeasureString(face, candidate) <= limit {
+			return candidate
+		}
+		runes = runes[:len(runes)-1]
+	}
+	return "…"
+}
+
+// isWideRune 判断是否为可在任意位置断行的中日韩字符
+func isWideRune(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK标点
+		(r >= 0xFF00 && r <= 0xFFEF) // 全角字符
+}
+
+// drawGradient 绘制从左上到右下的线性渐变背景
+func drawGradient(dst *image.RGBA, from, to color.RGBA) {
+	b := dst.Bounds()
+	total := float64(b.Dx() + b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			t := float64(x+y) / total
+			dst.SetRGBA(x, y, color.RGBA{
+				R: lerp(from.R, to.R, t),
+				G: lerp(from.G, to.G, t),
+				B: lerp(from.B, to.B, t),
+				A: 255,
+			})
+		}
+	}
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
+
+// drawCover 将图片等比缩放并裁剪铺满画布
+func drawCover(dst *image.RGBA, src image.Image) {
+	sb := src.Bounds()
+	db := dst.Bounds()
+	scale := max(float64(db.Dx())/float64(sb.Dx()), float64(db.Dy())/float64(sb.Dy()))
+	cropW := int(float64(db.Dx()) / scale)
+	cropH := int(float64(db.Dy()) / scale)
+	crop := image.Rect(0, 0, cropW, cropH).Add(image.Pt(
+		sb.Min.X+(sb.Dx()-cropW)/2,
+		sb.Min.Y+(sb.Dy()-cropH)/2,
+	))
+	draw.CatmullRom.Scale(dst, db, src, crop, draw.Src, nil)
+}
+
+// drawCircleImage 将图片缩放后以圆形绘制到指定区域
+func drawCircleImage(dst *image.RGBA, src image.Image, rect image.Rectangle) {
+	scaled := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	drawCover(scaled, src)
+	mask := &circleMask{r: rect.Dx() / 2}
+	draw.DrawMask(dst, rect, scaled, image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// fillRoundedRect 填充圆角矩形
+func fillRoundedRect(dst *image.RGBA, rect image.Rectangle, radius int, c color.Color) {
+	mask := &roundedRectMask{w: rect.Dx(), h: rect.Dy(), r: radius}
+	draw.DrawMask(dst, rect, image.NewUniform(c), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// circleMask 圆形遮罩
+type circleMask struct {
+	r int
+}
+
+func (m *circleMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (m *circleMask) Bounds() image.Rectangle { return image.Rect(0, 0, m.r*2, m.r*2) }
+
+func (m *circleMask) At(x, y int) color.Color {
+	dx, dy := float64(x-m.r)+0.5, float64(y-m.r)+0.5
+	if dx*dx+dy*dy <= float64(m.r*m.r) {
+		return color.Alpha{A: 255}
+	}
+	return color.Alpha{}
+}
+
+// roundedRectMask 圆角矩形遮罩
+type roundedRectMask struct {
+	w, h, r int
+}
+
+func (m *roundedRectMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (m *roundedRectMask) Bounds() image.Rectangle { return image.Rect(0, 0, m.w, m.h) }
+
+func (m *roundedRectMask) At(x, y int) color.Color {
+	cx, cy := x, y
+	if x < m.r {
+		cx = m.r
+	} else if x >= m.w-m.r {
+		cx = m.w - m.r - 1
+	}
+	if y < m.r {
+		cy = m.r
+	} else if y >= m.h-m.r {
+		cy = m.h - m.r - 1
+	}
+	dx, dy := float64(x-cx), float64(y-cy)
+	if dx*dx+dy*dy <= float64(m.r*m.r) {
+		return color.Alpha{A: 255}
+	}
+	return color.Alpha{}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/math/fixed"
+)
+
+// TestWrapText 测试标题按宽度换行，英文不拆分单词，超出行数时以省略号结尾
+func TestWrapText(t *testing.T) {
+	f, err := parseOGFont(nil, gobold.TTF)
+	require.NoError(t, err)
+	face, err := newOGFace(f, ogTitleSize)
+	require.NoError(t, err)
+	defer face.Close()
+
+	maxWidth := OGImageWidth - ogPadding*2
+	assert.Equal(t, []string{"Hello World"}, wrapText(face, "  Hello   World ", maxWidth, ogTitleLines))
+	assert.Nil(t, wrapText(face, "   ", maxWidth, ogTitleLines))
+
+	title := "Understanding Go concurrency patterns with goroutines channels and the select statement in production services"
+	lines := wrapText(face, title, maxWidth, ogTitleLines)
+	require.Len(t, lines, ogTitleLines)
+	for _, line := range lines {
+		assert.LessOrEqual(t, font.MeasureString(face, line), fixed.I(maxWidth))
+	}
+	assert.True(t, strings.HasSuffix(lines[2], "…"))
+	words := strings.Fields(title)
+	for _, word := range strings.Fields(lines[0] + " " + lines[1]) {
+		assert.Contains(t, words, word)
+	}
+
+	// 中文没有空格，可以在任意字符间换行
+	cjk := strings.Repeat("并发编程", 8)
+	lines = wrapText(face, cjk, maxWidth, ogTitleLines)
+	require.Greater(t, len(lines), 1)
+	assert.True(t, strings.HasPrefix(cjk, strings.Join(lines, "")))
+	for _, line := range lines {
+		assert.LessOrEqual(t, font.MeasureString(face, line), fixed.I(maxWidth))
+	}
+}
+
+// TestRenderOGImage 测试生成1200x630的PNG图片，支持自定义字体
+func TestRenderOGImage(t *testing.T) {
+	data := OGImageData{Title: "Go 并发编程", Category: "Go", AuthorName: "alice", SiteName: "Lin Studio"}
+
+	for _, fontData := range [][]byte{nil, gobold.TTF} {
+		content, err := RenderOGImage(data, fontData)
+		require.NoError(t, err)
+		img, err := png.Decode(bytes.NewReader(content))
+		require.NoError(t, err)
+		assert.Equal(t, OGImageWidth, img.Bounds().Dx())
+		assert.Equal(t, OGImageHeight, img.Bounds().Dy())
+	}
+
+	_, err := RenderOGImage(data, []byte("not a font"))
+	assert.Error(t, err)
+}
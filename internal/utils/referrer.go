@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// referrerHostPrefixes 归一化来源域名时去掉的子域名前缀，移动版、AMP和跳转页面计入主站
+var referrerHostPrefixes = []string{"www.", "m.", "mobile.", "amp.", "l.", "lm."}
+
+// campaignValueMaxLength UTM参数值的最大字符数
+const campaignValueMaxLength = 100
+
+// ReferrerDomain 返回来源地址归一化后的域名，只保留主机名，去掉端口和常见的子域名前缀并转为小写
+// 不保存路径和查询参数，无法解析或不是http(s)地址时返回空字符串
+func ReferrerDomain(referrer string) string {
+	referrer = strings.TrimSpace(referrer)
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	host := strings.ToLower(u.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, ".")
+	for _, prefix := range referrerHostPrefixes {
+		// 只有去掉前缀后仍是完整域名时才去掉，例如保留 m.cn
+		if rest := strings.TrimPrefix(host, prefix); rest != host && strings.Contains(rest, ".") {
+			host = rest
+			break
+		}
+	}
+	if host == "" || len(host) > 255 {
+		return ""
+	}
+	return host
+}
+
+// NormalizeCampaignValue 归一化UTM参数值：去掉首尾空白、合并连续空白、转为小写并截断
+func NormalizeCampaignValue(value string) string {
+	value = strings.ToLower(strings.Join(strings.Fields(value), " "))
+	if utf8.RuneCountInString(value) > campaignValueMaxLength {
+		value = string([]rune(value)[:campaignValueMaxLength])
+	}
+	return value
+}
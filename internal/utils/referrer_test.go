@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferrerDomain(t *testing.T) {
+	cases := map[string]string{
+		"https://www.Google.com/search?q=golang":        "google.com",
+		"https://l.facebook.com/l.php?u=https%3A%2F%2F": "facebook.com",
+		"https://m.weibo.cn/status/1":                   "weibo.cn",
+		"http://news.ycombinator.com:80/item?id=1":      "news.ycombinator.com",
+		"https://t.co/abc":                              "t.co",
+		"http://m.cn/":                                  "m.cn",
+		"android-app://com.slack/":                      "",
+		"not a url":                                     "",
+		"":                                              "",
+	}
+	for referrer, want := range cases {
+		assert.Equal(t, want, ReferrerDomain(referrer), referrer)
+	}
+}
+
+func TestNormalizeCampaignValue(t *testing.T) {
+	assert.Equal(t, "spring sale", NormalizeCampaignValue("  Spring   Sale "))
+	assert.Equal(t, 100, len([]rune(NormalizeCampaignValue(strings.Repeat("新", 150)))))
+}
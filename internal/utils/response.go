@@ -62,6 +62,11 @@ func NotFoundResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusNotFound, message, nil)
 }
 
+// PreconditionFailedResponse 返回412错误响应
+func PreconditionFailedResponse(c *gin.Context, message string) {
+	ErrorResponse(c, http.StatusPreconditionFailed, message, nil)
+}
+
 // InternalServerErrorResponse 返回500错误响应
 func InternalServerErrorResponse(c *gin.Context, message string) {
 	ErrorResponse(c, http.StatusInternalServerError, message, nil)
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize 将文本转为小写后切分为词，按出现顺序依次传给emit，重复的词会多次传入
+// 英文和数字按单词切分，只保留2到30个字符的词；中文使用相邻两字组合，单独出现的汉字作为一个词
+func Tokenize(text string, emit func(token string)) {
+	var word, han []rune
+	flush := func() {
+		if n := len(word); n >= 2 && n <= 30 {
+			emit(string(word))
+		}
+		switch {
+		case len(han) == 1:
+			emit(string(han))
+		case len(han) > 1:
+			for i := 0; i+1 < len(han); i++ {
+				emit(string(han[i : i+2]))
+			}
+		}
+		word, han = word[:0], han[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if len(word) > 0 {
+				flush()
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+}
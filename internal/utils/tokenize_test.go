@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	var tokens []string
+	Tokenize("Go语言 并发编程 a 见 Go", func(token string) {
+		tokens = append(tokens, token)
+	})
+	assert.Equal(t, []string{"go", "语言", "并发", "发编", "编程", "见", "go"}, tokens)
+}
@@ -0,0 +1,447 @@
+package utils
+
+import (
+	"Lin_studio/internal/config"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+)
+
+// ErrInvalidUpload 上传文件未通过校验
+var ErrInvalidUpload = errors.New("上传文件无效")
+
+// 图片文件的魔数
+var (
+	jpegMagic = []byte{0xFF, 0xD8, 0xFF}
+	pngMagic  = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+	gif87a    = []byte("GIF87a")
+	gif89a    = []byte("GIF89a")
+)
+
+// 检查可疑内容时扫描的文件首尾长度，浏览器内容嗅探通常只读取前512字节
+const polyglotHeadSize = 1024
+
+// 图片中不应出现的可执行内容标记，用于识别多格式(polyglot)文件
+var polyglotMarkers = [][]byte{
+	[]byte("<html"),
+	[]byte("<!doctype"),
+	[]byte("<script"),
+	[]byte("<iframe"),
+	[]byte("<svg"),
+	[]byte("<?php"),
+	[]byte("<?xml"),
+	[]byte("javascript:"),
+}
+
+// ValidatedImage 通过校验并清理元数据后的图片
+type ValidatedImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// UploadValidator 上传文件校验器
+type UploadValidator struct {
+	maxSize      int64
+	allowedTypes []string
+	maxWidth     int
+	maxHeight    int
+	maxPixels    int64
+}
+
+// NewUploadValidator 根据上传配置创建校验器
+func NewUploadValidator(cfg config.UploadConfig) *UploadValidator {
+	return &UploadValidator{
+		maxSize:      cfg.MaxSize,
+		allowedTypes: cfg.AllowedTypes,
+		maxWidth:     cfg.MaxWidth,
+		maxHeight:    cfg.MaxHeight,
+		maxPixels:    cfg.MaxPixels,
+	}
+}
+
+// ValidateImageFile 校验表单上传的图片文件
+func (v *UploadValidator) ValidateImageFile(file *multipart.FileHeader) (*ValidatedImage, error) {
+	if v.maxSize > 0 && file.Size > v.maxSize {
+		return nil, fmt.Errorf("%w: 文件大小不能超过%dMB", ErrInvalidUpload, v.maxSize/1024/1024)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return v.ValidateImage(src)
+}
+
+// ValidateImage 校验图片内容并重新编码以去除EXIF等元数据
+// 文件类型仅根据文件头判断，不信任客户端提供的文件名和Content-Type
+func (v *UploadValidator) ValidateImage(r io.Reader) (*ValidatedImage, error) {
+	// 多读取一个字节用于判断是否超过大小限制
+	reader := r
+	if v.maxSize > 0 {
+		reader = io.LimitReader(r, v.maxSize+1)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if v.maxSize > 0 && int64(len(data)) > v.maxSize {
+		return nil, fmt.Errorf("%w: 文件大小不能超过%dMB", ErrInvalidUpload, v.maxSize/1024/1024)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: 文件为空", ErrInvalidUpload)
+	}
+
+	// 根据魔数识别文件类型
+	contentType, ext := sniffImageType(data)
+	if contentType == "" {
+		return nil, fmt.Errorf("%w: 不支持的文件类型", ErrInvalidUpload)
+	}
+	if !v.isAllowed(contentType) {
+		return nil, fmt.Errorf("%w: 不允许上传%s类型的文件", ErrInvalidUpload, contentType)
+	}
+
+	// 拒绝夹带其他格式内容的文件
+	if err := checkPolyglot(data, contentType); err != nil {
+		return nil, err
+	}
+
+	// 解码前先检查尺寸，避免解压炸弹耗尽内存
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 无法解析图片", ErrInvalidUpload)
+	}
+	if err := v.checkDimensions(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+
+	// 重新编码图片，丢弃EXIF、注释等元数据
+	clean, width, height, err := reencodeImage(data, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 无法解析图片", ErrInvalidUpload)
+	}
+
+	return &ValidatedImage{
+		Data:        clean,
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       width,
+		Height:      height,
+	}, nil
+}
+
+// isAllowed 判断文件类型是否在允许列表中
+func (v *UploadValidator) isAllowed(contentType string) bool {
+	for _, t := range v.allowedTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDimensions 检查图片尺寸限制
+func (v *UploadValidator) checkDimensions(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("%w: 无效的图片尺寸", ErrInvalidUpload)
+	}
+	if (v.maxWidth > 0 && width > v.maxWidth) || (v.maxHeight > 0 && height > v.maxHeight) {
+		return fmt.Errorf("%w: 图片尺寸不能超过%dx%d", ErrInvalidUpload, v.maxWidth, v.maxHeight)
+	}
+	if v.maxPixels > 0 && int64(width)*int64(height) > v.maxPixels {
+		return fmt.Errorf("%w: 图片像素总数过大", ErrInvalidUpload)
+	}
+	return nil
+}
+
+// sniffImageType 根据文件头识别图片类型
+func sniffImageType(data []byte) (contentType, ext string) {
+	switch {
+	case bytes.HasPrefix(data, jpegMagic):
+		return "image/jpeg", ".jpg"
+	case bytes.HasPrefix(data, pngMagic):
+		return "image/png", ".png"
+	case bytes.HasPrefix(data, gif87a), bytes.HasPrefix(data, gif89a):
+		return "image/gif", ".gif"
+	default:
+		return "", ""
+	}
+}
+
+// checkPolyglot 检查文件是否夹带HTML/脚本内容或在图片结束标记后附加数据
+// 压缩后的像素数据可能偶然包含标记字符串，因此只检查文件首尾和元数据段
+func checkPolyglot(data []byte, contentType string) error {
+	regions := [][]byte{
+		data[:min(len(data), polyglotHeadSize)],
+		data[max(0, len(data)-polyglotHeadSize):],
+	}
+
+	var end int
+	switch contentType {
+	case "image/jpeg":
+		end = jpegEnd(data)
+		regions = append(regions, jpegMetadata(data)...)
+	case "image/png":
+		end = pngEnd(data)
+		regions = append(regions, pngMetadata(data)...)
+	case "image/gif":
+		end = gifEnd(data)
+	}
+	if end <= 0 {
+		return fmt.Errorf("%w: 图片文件不完整", ErrInvalidUpload)
+	}
+
+	// 结束标记后只允许少量填充字节
+	trailing := bytes.Trim(data[end:], "\x00\r\n\t ")
+	if len(trailing) > 0 {
+		return fmt.Errorf("%w: 图片结束后包含额外数据", ErrInvalidUpload)
+	}
+
+	for _, region := range regions {
+		lower := bytes.ToLower(region)
+		for _, marker := range polyglotMarkers {
+			if bytes.Contains(lower, marker) {
+				return fmt.Errorf("%w: 文件包含可疑内容", ErrInvalidUpload)
+			}
+		}
+	}
+	return nil
+}
+
+// jpegMetadata 返回JPEG中APPn和注释段的内容
+func jpegMetadata(data []byte) [][]byte {
+	var segments [][]byte
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			break
+		}
+		marker := data[pos+1]
+		if marker == 0xDA {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			break
+		}
+		if (marker >= 0xE0 && marker <= 0xEF) || marker == 0xFE {
+			segments = append(segments, data[pos+4:end])
+		}
+		pos = end
+	}
+	return segments
+}
+
+// pngMetadata 返回PNG中文本和EXIF数据块的内容
+func pngMetadata(data []byte) [][]byte {
+	var chunks [][]byte
+	pos := len(pngMagic)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		next := pos + 12 + length
+		if length < 0 || next > len(data) {
+			break
+		}
+		switch chunkType {
+		case "tEXt", "iTXt", "eXIf":
+			chunks = append(chunks, data[pos+8:pos+8+length])
+		case "IEND":
+			return chunks
+		}
+		pos = next
+	}
+	return chunks
+}
+
+// jpegEnd 返回JPEG结束标记(FFD9)之后的位置
+func jpegEnd(data []byte) int {
+	i := bytes.LastIndex(data, []byte{0xFF, 0xD9})
+	if i < 0 {
+		return -1
+	}
+	return i + 2
+}
+
+// pngEnd 遍历PNG数据块，返回IEND块之后的位置
+func pngEnd(data []byte) int {
+	pos := len(pngMagic)
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		next := pos + 12 + length
+		if length < 0 || next > len(data) {
+			return -1
+		}
+		if chunkType == "IEND" {
+			return next
+		}
+		pos = next
+	}
+	return -1
+}
+
+// gifEnd 返回GIF结束符(0x3B)之后的位置
+func gifEnd(data []byte) int {
+	i := bytes.LastIndexByte(data, 0x3B)
+	if i < 0 {
+		return -1
+	}
+	return i + 1
+}
+
+// reencodeImage 解码后重新编码图片
+func reencodeImage(data []byte, contentType string) ([]byte, int, int, error) {
+	var buf bytes.Buffer
+
+	switch contentType {
+	case "image/jpeg":
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		// 去除EXIF前按方向标记旋转图片，避免照片方向错误
+		img = applyOrientation(img, jpegOrientation(data))
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, 0, 0, err
+		}
+		b := img.Bounds()
+		return buf.Bytes(), b.Dx(), b.Dy(), nil
+	case "image/png":
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, 0, 0, err
+		}
+		b := img.Bounds()
+		return buf.Bytes(), b.Dx(), b.Dy(), nil
+	case "image/gif":
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		if err := gif.EncodeAll(&buf, g); err != nil {
+			return nil, 0, 0, err
+		}
+		return buf.Bytes(), g.Config.Width, g.Config.Height, nil
+	default:
+		return nil, 0, 0, errors.New("不支持的图片类型")
+	}
+}
+
+// jpegOrientation 读取JPEG中EXIF的方向标记，未找到时返回1
+func jpegOrientation(data []byte) int {
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		// SOS之后是图像数据，不再有元数据段
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		segment := pos + 4
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+		if marker == 0xE1 && end-segment > 14 && string(data[segment:segment+6]) == "Exif\x00\x00" {
+			return exifOrientation(data[segment+6 : end])
+		}
+		pos = end
+	}
+	return 1
+}
+
+// exifOrientation 从TIFF结构的IFD0中读取方向标记(0x0112)
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation 按EXIF方向标记变换图片
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// 5-8 需要交换宽高
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转180度
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿主对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转90度
+				dx, dy = h-1-y, x
+			case 7: // 沿副对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转90度
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
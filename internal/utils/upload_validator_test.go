@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"Lin_studio/internal/config"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestValidator() *UploadValidator {
+	return NewUploadValidator(config.UploadConfig{
+		MaxSize:      1024 * 1024,
+		AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"},
+		MaxWidth:     1000,
+		MaxHeight:    1000,
+		MaxPixels:    500 * 500,
+	})
+}
+
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// insertPNGChunk 在IHDR之后插入数据块
+func insertPNGChunk(data []byte, chunkType string, payload []byte) []byte {
+	var chunk bytes.Buffer
+	binary.Write(&chunk, binary.BigEndian, uint32(len(payload)))
+	chunk.WriteString(chunkType)
+	chunk.Write(payload)
+	binary.Write(&chunk, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(chunkType), payload...)))
+
+	ihdrEnd := 8 + 12 + 13
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, chunk.Bytes()...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+// TestValidateImageStripsMetadata 测试重新编码后去除PNG文本块
+func TestValidateImageStripsMetadata(t *testing.T) {
+	data := insertPNGChunk(encodeTestPNG(t, 20, 10), "tEXt", []byte("Comment\x00secret gps"))
+
+	result, err := newTestValidator().ValidateImage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", result.ContentType)
+	assert.Equal(t, ".png", result.Ext)
+	assert.Equal(t, 20, result.Width)
+	assert.Equal(t, 10, result.Height)
+	assert.NotContains(t, string(result.Data), "secret gps")
+}
+
+// TestValidateImageAppliesEXIFOrientation 测试去除EXIF时按方向标记旋转JPEG
+func TestValidateImageAppliesEXIFOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil))
+	raw := buf.Bytes()
+
+	// 构造只包含方向标记(顺时针旋转90度)的EXIF段
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, 0x01, 0x00,
+		0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(payload)+2))
+	app1 = append(app1, payload...)
+
+	data := append([]byte{}, raw[:2]...)
+	data = append(data, app1...)
+	data = append(data, raw[2:]...)
+
+	result, err := newTestValidator().ValidateImage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 20, result.Width)
+	assert.Equal(t, 40, result.Height)
+	assert.NotContains(t, string(result.Data), "Exif")
+}
+
+// TestValidateImageRejectsInvalidFiles 测试拒绝伪装、夹带内容和超限的文件
+func TestValidateImageRejectsInvalidFiles(t *testing.T) {
+	validPNG := encodeTestPNG(t, 10, 10)
+
+	cases := map[string][]byte{
+		"html":            []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"),
+		"trailing script": append(append([]byte{}, validPNG...), []byte("<script>alert(1)</script>")...),
+		"text chunk html": insertPNGChunk(validPNG, "tEXt", []byte("Comment\x00<html><body>")),
+		"too wide":        encodeTestPNG(t, 1200, 10),
+		"too many pixels": encodeTestPNG(t, 600, 600),
+		"truncated":       validPNG[:len(validPNG)-12],
+		"too large":       append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, 2*1024*1024)...),
+	}
+
+	validator := newTestValidator()
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := validator.ValidateImage(bytes.NewReader(data))
+			assert.ErrorIs(t, err, ErrInvalidUpload)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package utils
+
+import "strings"
+
+// botUserAgentPatterns 常见爬虫、监控和命令行工具User-Agent中的关键字(小写)
+var botUserAgentPatterns = []string{
+	"bot", "crawl", "spider", "slurp", "archiver", "scrapy",
+	"curl", "wget", "python-requests", "python-urllib", "go-http-client", "java/", "okhttp", "axios",
+	"headless", "phantomjs", "lighthouse", "pingdom", "uptime", "monitor",
+	"facebookexternalhit", "embedly", "preview", "feedfetcher", "rss",
+}
+
+// IsBotUserAgent 判断User-Agent是否来自爬虫或自动化工具，空User-Agent也视为爬虫
+// extra为额外的关键字，不区分大小写
+func IsBotUserAgent(userAgent string, extra ...string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+	for _, pattern := range botUserAgentPatterns {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	for _, pattern := range extra {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" && strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}